@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Handle listing the audit log of changes made through the API
+func audit(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Time range as unix timestamps, either end may be left open
+	var from, to int64
+	if r.URL.Query().Get("from") != "" {
+		if from, err = strconv.ParseInt(r.URL.Query().Get("from"), 10, 64); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'from' must be a unix timestamp")
+			return
+		}
+	}
+	if r.URL.Query().Get("to") != "" {
+		if to, err = strconv.ParseInt(r.URL.Query().Get("to"), 10, 64); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'to' must be a unix timestamp")
+			return
+		}
+	}
+
+	// Page through the matching entries, all of them without a limit
+	offset, limit := 0, 0
+	if r.URL.Query().Get("offset") != "" {
+		if offset, err = strconv.Atoi(r.URL.Query().Get("offset")); err != nil || offset < 0 {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'offset' must be a non-negative integer")
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") != "" {
+		if limit, err = strconv.Atoi(r.URL.Query().Get("limit")); err != nil || limit < 1 {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'limit' must be a positive integer")
+			return
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'format' must be one of 'json' or 'csv'")
+		return
+	}
+
+	entries, err := db.QueryAudit(db.AuditFilter{
+		From:      from,
+		To:        to,
+		Username:  r.URL.Query().Get("username"),
+		Operation: r.URL.Query().Get("operation"),
+		Resource:  r.URL.Query().Get("resource"),
+		Type:      r.URL.Query().Get("type"),
+	}, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve audit log: "+err.Error())
+		return
+	}
+
+	// Exports hold the requested page too
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(entries)))
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	entries = entries[offset:]
+	if limit != 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+
+	switch format {
+	case "json":
+		exportAudit(w, "application/json", "audit.json", func(out io.Writer) error {
+			return json.NewEncoder(out).Encode(entries)
+		})
+	case "csv":
+		exportAudit(w, "text/csv", "audit.csv", func(out io.Writer) error {
+			writer := csv.NewWriter(out)
+			if err := writer.Write([]string{"time", "username", "operation", "resource", "type"}); err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := writer.Write([]string{strconv.FormatInt(entry.Time, 10), entry.Username, entry.Operation, entry.Resource, entry.Type}); err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		})
+	default:
+		util.Responses.SuccessWithData(w, entries)
+	}
+}
+
+// Send audit log entries as a file to download
+func exportAudit(w http.ResponseWriter, contentType, filename string, write func(out io.Writer) error) {
+	var buffer bytes.Buffer
+	if err := write(&buffer); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to export audit log: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Open a fresh database holding a user of the given role, returning a token
+// for the user
+func testDatabase(t *testing.T, role string) (*db.Database, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	user := db.NewUser("Test", "test", "", role)
+	if err := user.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return database, token
+}
+
+// Write audit entries at the given times
+func testAudit(t *testing.T, database *db.Database, entries ...db.AuditEntry) {
+	t.Helper()
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for i := range entries {
+			if err := db.WriteAudit(&entries[i], tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// List the audit log through the API
+func testListAudit(t *testing.T, database *db.Database, token, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/audit?"+query, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	AuditHandler(database)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestAuditPagesFilteredEntries(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	// Entries written out of time order are still returned by time
+	testAudit(t, database,
+		db.AuditEntry{Time: 500, Username: "alice", Operation: "update", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 100, Username: "alice", Operation: "create", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 200, Username: "bob", Operation: "create", Resource: "mail.example.com", Type: "MX"},
+		db.AuditEntry{Time: 300, Username: "alice", Operation: "update", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 400, Username: "alice", Operation: "update", Resource: "api.example.com", Type: "A"},
+		db.AuditEntry{Time: 600, Username: "alice", Operation: "update", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 900, Username: "alice", Operation: "update", Resource: "www.example.com", Type: "A"},
+	)
+
+	// Updates by alice to www.example.com up to time 800 are at 300, 500 and 600
+	filter := "username=alice&operation=update&resource=www.example.com&type=a&from=150&to=800"
+	var times []int64
+	for offset := 0; offset < 4; offset += 2 {
+		w := testListAudit(t, database, token, filter+"&limit=2&offset="+strconv.Itoa(offset))
+		if total := w.Header().Get("X-Total-Count"); total != "3" {
+			t.Errorf("expected 3 matching entries in total, got %s", total)
+		}
+
+		var response struct {
+			Data []db.AuditEntry `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range response.Data {
+			times = append(times, entry.Time)
+		}
+	}
+	if len(times) != 3 || times[0] != 300 || times[1] != 500 || times[2] != 600 {
+		t.Errorf("expected entries at 300, 500 and 600, got %v", times)
+	}
+}
+
+func TestAuditExportsCSV(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testAudit(t, database,
+		db.AuditEntry{Time: 100, Username: "alice", Operation: "create", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 200, Username: "bob", Operation: "delete", Resource: "www.example.com", Type: "A"},
+		db.AuditEntry{Time: 300, Username: "alice", Operation: "delete", Resource: "mail.example.com", Type: "MX"},
+	)
+
+	w := testListAudit(t, database, token, "operation=delete&format=csv")
+	if w.Header().Get("Content-Type") != "text/csv" || !strings.Contains(w.Header().Get("Content-Disposition"), "audit.csv") {
+		t.Errorf("expected a CSV attachment, got %s %s", w.Header().Get("Content-Type"), w.Header().Get("Content-Disposition"))
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"time", "username", "operation", "resource", "type"},
+		{"200", "bob", "delete", "www.example.com", "A"},
+		{"300", "alice", "delete", "mail.example.com", "MX"},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %v", len(expected), rows)
+	}
+	for i := range expected {
+		if strings.Join(rows[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("expected row %d to be %v, got %v", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestAuditRequiresAdmin(t *testing.T) {
+	database, token := testDatabase(t, "user")
+
+	r := httptest.NewRequest("GET", "/api/audit", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	AuditHandler(database)(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+}
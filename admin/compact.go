@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+)
+
+// Handle compacting the database into a fresh file
+func compact(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Writes wait until the fresh file is swapped in
+	before, after, err := database.Compact()
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to compact database: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]int64{
+		"before": before,
+		"after":  after,
+	})
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+)
+
+// Keys containing any of these are replaced before being returned
+var secretKeys = []string{"password", "secret", "token", "key"}
+
+// Maps holding a secret under each of their keys, such as TSIG secrets by
+// key name, whose names are kept while every value is replaced
+var secretMaps = []string{"tsig-keys"}
+
+func config(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	util.Responses.SuccessWithData(w, redact(viper.AllSettings()))
+}
+
+// Replace the values of secret keys in a nested configuration
+func redact(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		if nested, ok := value.(map[string]interface{}); ok && util.StringInArray(strings.ToLower(key), secretMaps) {
+			names := make(map[string]interface{}, len(nested))
+			for name := range nested {
+				names[name] = "[redacted]"
+			}
+			redacted[key] = names
+			continue
+		} else if ok {
+			redacted[key] = redact(nested)
+			continue
+		}
+
+		redacted[key] = value
+		for _, secret := range secretKeys {
+			if strings.Contains(strings.ToLower(key), secret) {
+				redacted[key] = "[redacted]"
+				break
+			}
+		}
+	}
+	return redacted
+}
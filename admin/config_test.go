@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Get the effective configuration through the API
+func testConfig(t *testing.T, handler http.HandlerFunc, token string) (int, map[string]interface{}) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/config", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, response.Data
+}
+
+func TestConfigReflectsToggles(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	t.Cleanup(func() { viper.Set("dns.doh.enabled", false) })
+
+	for _, enabled := range []bool{true, false} {
+		viper.Set("dns.doh.enabled", enabled)
+		status, settings := testConfig(t, ConfigHandler(database), token)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		doh, _ := settings["dns"].(map[string]interface{})["doh"].(map[string]interface{})
+		if doh["enabled"] != enabled {
+			t.Errorf("expected dns.doh.enabled to be %t, got %v", enabled, doh)
+		}
+	}
+}
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("http.admin.password", "hunter2")
+	// Maps read from a config file decode to generic maps
+	viper.Set("dns.tsig-keys", map[string]interface{}{"updater": "c2VjcmV0c2VjcmV0"})
+	t.Cleanup(func() {
+		viper.Set("http.admin.password", "")
+		viper.Set("dns.tsig-keys", map[string]string{})
+	})
+
+	status, settings := testConfig(t, ConfigHandler(database), token)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	encoded, _ := json.Marshal(settings)
+	if strings.Contains(string(encoded), "hunter2") || strings.Contains(string(encoded), "c2VjcmV0c2VjcmV0") {
+		t.Errorf("expected secrets to be redacted, got %s", encoded)
+	}
+
+	// The names of TSIG keys are kept
+	keys, _ := settings["dns"].(map[string]interface{})["tsig-keys"].(map[string]interface{})
+	if keys["updater"] != "[redacted]" {
+		t.Errorf("expected the key name to be listed with its secret redacted, got %v", keys)
+	}
+}
+
+func TestConfigRequiresAdmin(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if status, _ := testConfig(t, ConfigHandler(database), token); status != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", status)
+	}
+}
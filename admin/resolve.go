@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Compare a locally stored record against the answer of another authoritative server
+func resolve(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type, query parameters, and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("name") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'name' is required")
+		return
+	} else if r.URL.Query().Get("type") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' is required")
+		return
+	} else if r.URL.Query().Get("server") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'server' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate record type
+	recordType := strings.ToUpper(r.URL.Query().Get("type"))
+	if !util.StringInArray(recordType, db.RecordTypes) {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: "+strings.Join(db.RecordTypes, ", "))
+		return
+	}
+
+	// Default to the standard DNS port
+	server := r.URL.Query().Get("server")
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	// Query the other server without recursion
+	name := dns.Fqdn(strings.ToLower(r.URL.Query().Get("name")))
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, dns.StringToType[recordType])
+	msg.RecursionDesired = false
+
+	c := new(dns.Client)
+	in, _, err := c.Exchange(msg, server)
+	if err != nil {
+		util.Responses.Error(w, http.StatusBadGateway, "failed to query server: "+err.Error())
+		return
+	}
+
+	// Collect remote record data of the requested type
+	var remote []string
+	for _, rr := range in.Answer {
+		if rr.Header().Rrtype == dns.StringToType[recordType] {
+			remote = append(remote, util.RData(rr))
+		}
+	}
+
+	// Collect local record data
+	var local []string
+	if record := db.Get.Record(name, recordType); record != nil {
+		hdr := dns.RR_Header{Name: name, Rrtype: dns.StringToType[recordType], Class: dns.ClassINET}
+		for _, rr := range util.RecordToRRs(hdr, record) {
+			local = append(local, util.RData(rr))
+		}
+	}
+
+	// Diff both sides
+	missingLocal := []string{}
+	for _, v := range remote {
+		if !util.StringInArray(v, local) {
+			missingLocal = append(missingLocal, v)
+		}
+	}
+	missingRemote := []string{}
+	for _, v := range local {
+		if !util.StringInArray(v, remote) {
+			missingRemote = append(missingRemote, v)
+		}
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{
+		"name":           name,
+		"type":           recordType,
+		"server":         server,
+		"rcode":          dns.RcodeToString[in.Rcode],
+		"local":          local,
+		"remote":         remote,
+		"missing-local":  missingLocal,
+		"missing-remote": missingRemote,
+		"match":          len(missingLocal) == 0 && len(missingRemote) == 0,
+	})
+}
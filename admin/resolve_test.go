@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Serve fixed answers from another authoritative server on a local port,
+// returning its address
+func testUpstream(t *testing.T, answers map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+		r := new(dns.Msg)
+		r.SetReply(m)
+		r.Authoritative = true
+		for _, address := range answers[m.Question[0].Name] {
+			r.Answer = append(r.Answer, &dns.A{Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.ParseIP(address)})
+		}
+		if len(r.Answer) == 0 {
+			r.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(r)
+	})}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+// The comparison returned by the resolve endpoint
+type testComparison struct {
+	Rcode         string   `json:"rcode"`
+	Local         []string `json:"local"`
+	Remote        []string `json:"remote"`
+	MissingLocal  []string `json:"missing-local"`
+	MissingRemote []string `json:"missing-remote"`
+	Match         bool     `json:"match"`
+}
+
+// Compare a record against another server through the API
+func testResolve(t *testing.T, database *db.Database, token, name, server string) testComparison {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/resolve?type=A&name="+name+"&server="+server, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	ResolveHandler(database)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data testComparison `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Data
+}
+
+func TestResolveComparesWithServer(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	server := testUpstream(t, map[string][]string{
+		"www.example.com.":  {"192.0.2.1"},
+		"mail.example.com.": {"192.0.2.5", "192.0.2.9"},
+	})
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("mail.example.com", "192.0.2.5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("api.example.com", "192.0.2.7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if comparison := testResolve(t, database, token, "www.example.com", server); !comparison.Match || len(comparison.MissingLocal) != 0 || len(comparison.MissingRemote) != 0 {
+		t.Errorf("expected www.example.com to match, got %+v", comparison)
+	}
+
+	// Addresses only the other server has are missing locally
+	comparison := testResolve(t, database, token, "mail.example.com", server)
+	if comparison.Match || len(comparison.MissingLocal) != 1 || comparison.MissingLocal[0] != "192.0.2.9" || len(comparison.MissingRemote) != 0 {
+		t.Errorf("expected 192.0.2.9 to be missing locally, got %+v", comparison)
+	}
+
+	// Records the other server doesn't know are missing remotely
+	comparison = testResolve(t, database, token, "api.example.com", server)
+	if comparison.Match || comparison.Rcode != "NXDOMAIN" || len(comparison.MissingRemote) != 1 || comparison.MissingRemote[0] != "192.0.2.7" {
+		t.Errorf("expected 192.0.2.7 to be missing remotely, got %+v", comparison)
+	}
+}
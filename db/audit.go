@@ -0,0 +1,126 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// A change made through the API, kept in an append only log
+type AuditEntry struct {
+	Time      int64  `json:"time"`
+	Username  string `json:"username"`
+	Operation string `json:"operation"`
+	Resource  string `json:"resource"`
+	// Record type for record changes, otherwise "user", "role" or "group"
+	Type string `json:"type"`
+}
+
+func NewAuditEntry(username, operation, resource, resourceType string) *AuditEntry {
+	return &AuditEntry{
+		Time:      time.Now().Unix(),
+		Username:  username,
+		Operation: operation,
+		Resource:  resource,
+		Type:      resourceType,
+	}
+}
+
+// Criteria entries of the audit log must match, empty fields match anything
+// A zero time leaves that end of the range open
+type AuditFilter struct {
+	From      int64
+	To        int64
+	Username  string
+	Operation string
+	Resource  string
+	Type      string
+}
+
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	return (f.From == 0 || entry.Time >= f.From) &&
+		(f.To == 0 || entry.Time <= f.To) &&
+		(f.Username == "" || entry.Username == f.Username) &&
+		(f.Operation == "" || entry.Operation == f.Operation) &&
+		(f.Resource == "" || entry.Resource == f.Resource) &&
+		(f.Type == "" || strings.EqualFold(entry.Type, f.Type))
+}
+
+// Keys of the time index sort entries by when they were written, followed by
+// the key of the entry in the log
+func auditIndexKey(entryTime int64, key []byte) []byte {
+	indexKey := make([]byte, 8, 8+len(key))
+	binary.BigEndian.PutUint64(indexKey, uint64(entryTime))
+	return append(indexKey, key...)
+}
+
+// Append an entry to the audit log within an open transaction, so the entry
+// is only kept if the change it describes is
+func WriteAudit(entry *AuditEntry, tx *bolt.Tx) error {
+	audit := tx.Bucket([]byte("audit"))
+
+	// Keys are sequential to keep entries in the order they were written
+	sequence, err := audit.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	} else if err := audit.Put(key, data); err != nil {
+		return err
+	}
+	if err := tx.Bucket([]byte("audit-time")).Put(auditIndexKey(entry.Time, key), nil); err != nil {
+		return err
+	}
+
+	// Notify subscribers of changes to records
+	for _, recordType := range RecordTypes {
+		if strings.EqualFold(entry.Type, recordType) {
+			return queueDeliveries(entry, tx)
+		}
+	}
+	return nil
+}
+
+// Run a change and append its entry to the audit log in the same transaction
+func Audited(entry *AuditEntry, fn func(tx *bolt.Tx) error, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return WriteAudit(entry, tx)
+	})
+}
+
+// Get the audit log entries matching a filter, oldest first
+// Only entries within the time range are read
+func QueryAudit(filter AuditFilter, db *Database) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		audit := tx.Bucket([]byte("audit"))
+		cursor := tx.Bucket([]byte("audit-time")).Cursor()
+
+		for k, _ := cursor.Seek(auditIndexKey(filter.From, nil)); k != nil; k, _ = cursor.Next() {
+			if filter.To != 0 && int64(binary.BigEndian.Uint64(k[:8])) > filter.To {
+				break
+			}
+
+			var entry AuditEntry
+			if err := json.Unmarshal(audit.Get(k[8:]), &entry); err != nil {
+				return err
+			} else if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+
+	return entries, err
+}
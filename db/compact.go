@@ -0,0 +1,74 @@
+package db
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"os"
+)
+
+// Largest amount of data copied in a single transaction while compacting
+const compactTxSize = 1 << 20
+
+// Rewrite the database into a fresh file and swap it in, returning the file
+// size before and after. Writes wait while the copy is made, reads continue.
+func (d *Database) Compact() (int64, int64, error) {
+	d.writes.Lock()
+	defer d.writes.Unlock()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	before := info.Size()
+
+	tmp := d.path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := bolt.Open(tmp, d.mode, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Copies buckets, keys and sequences in bounded transactions
+	err = bolt.Compact(dst, d.current(), compactTxSize)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, 0, err
+	}
+
+	if err := d.swap(tmp); err != nil {
+		return 0, 0, err
+	}
+
+	info, err = os.Stat(d.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return before, info.Size(), nil
+}
+
+// Replace the database file with the one at path and reopen it
+func (d *Database) swap(path string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// Waits for open read transactions to finish
+	if err := d.bolt.Close(); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	// Rename over the original so it is replaced atomically
+	renameErr := os.Rename(path, d.path)
+	if renameErr != nil {
+		_ = os.Remove(path)
+	}
+
+	handle, err := bolt.Open(d.path, d.mode, d.options)
+	if err != nil {
+		return err
+	}
+	d.bolt = handle
+	return renameErr
+}
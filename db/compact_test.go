@@ -0,0 +1,138 @@
+package db
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Open a fresh database with every bucket created
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+	viper.Set("http.disabled", true)
+
+	database, err := Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	if err := Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	Get.Db, Set.Db, Delete.Db = database, database, database
+	return database
+}
+
+func TestCompactPreservesRecords(t *testing.T) {
+	database := testDatabase(t)
+
+	if err := Set.A("kept.example.com", "192.0.2.1", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set.MX("kept.example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set.TXT("kept.example.com", []string{"v=spf1 -all"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := database.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	if a := Get.A("kept.example.com."); a == nil || len(a.Addresses) != 2 {
+		t.Errorf("A records not preserved: %+v", a)
+	}
+	if mx := Get.MX("kept.example.com."); mx == nil || mx.Priority != 10 || mx.Host != "mail.example.com." {
+		t.Errorf("MX record not preserved: %+v", mx)
+	}
+	if txt := Get.TXT("kept.example.com."); txt == nil || strings.Join(txt.Text, "") != "v=spf1 -all" {
+		t.Errorf("TXT record not preserved: %+v", txt)
+	}
+
+	// Writes keep working against the swapped in file
+	if err := Set.AAAA("after.example.com", "2001:db8::1"); err != nil {
+		t.Fatalf("failed to write after compacting: %v", err)
+	}
+	if aaaa := Get.AAAA("after.example.com."); aaaa == nil {
+		t.Error("record written after compacting not found")
+	}
+}
+
+func TestCompactShrinksBloatedFile(t *testing.T) {
+	database := testDatabase(t)
+
+	// Fill the file then delete nearly everything, leaving free pages behind
+	padding := strings.Repeat("x", 512)
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for i := 0; i < 5000; i++ {
+			if err := tx.Bucket([]byte("TXT")).Put([]byte(fmt.Sprintf("bloat%d.example.com", i)), []byte(padding)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for i := 1; i < 5000; i++ {
+			if err := tx.Bucket([]byte("TXT")).Delete([]byte(fmt.Sprintf("bloat%d.example.com", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	before, after, err := database.Compact()
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if after >= before {
+		t.Errorf("expected file to shrink, went from %d to %d bytes", before, after)
+	}
+	if info, err := os.Stat(database.Path()); err != nil || info.Size() != after {
+		t.Errorf("reported size %d does not match file: %v", after, err)
+	}
+
+	if err := database.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("TXT")).Get([]byte("bloat0.example.com")) == nil {
+			t.Error("remaining record lost while compacting")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompactPreservesSequences(t *testing.T) {
+	database := testDatabase(t)
+
+	for i := 0; i < 3; i++ {
+		if err := Set.WithAudit(NewAuditEntry("admin", "create", fmt.Sprintf("r%d.example.com", i), "A")).A(fmt.Sprintf("r%d.example.com", i), "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := database.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	// A restarted sequence would overwrite the first entry
+	if err := Set.WithAudit(NewAuditEntry("admin", "create", "r3.example.com", "A")).A("r3.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := QueryAudit(AuditFilter{}, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected 4 audit entries after compacting, got %d", len(entries))
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"sync"
+)
+
+// A bolt database whose underlying file can be swapped out while the server
+// is running, such as when it is compacted
+type Database struct {
+	path    string
+	mode    os.FileMode
+	options *bolt.Options
+
+	// Guards the current handle while it is being swapped
+	lock sync.RWMutex
+	bolt *bolt.DB
+
+	// Held exclusively while the database is read-only
+	writes sync.RWMutex
+
+	// Names in the tree, dropped after every write
+	names nameIndex
+}
+
+// Open the database at path, creating it if it does not exist
+func Open(path string, mode os.FileMode, options *bolt.Options) (*Database, error) {
+	handle, err := bolt.Open(path, mode, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{path: path, mode: mode, options: options, bolt: handle}, nil
+}
+
+// The current handle to the database file
+func (d *Database) current() *bolt.DB {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.bolt
+}
+
+// Run a read-only transaction
+func (d *Database) View(fn func(tx *bolt.Tx) error) error {
+	for {
+		handle := d.current()
+		err := handle.View(fn)
+
+		// Retry against the new file if it was swapped before the transaction began
+		if err == bolt.ErrDatabaseNotOpen && d.current() != handle {
+			continue
+		}
+		return err
+	}
+}
+
+// Run a read-write transaction, waiting while the database is read-only
+func (d *Database) Update(fn func(tx *bolt.Tx) error) error {
+	d.writes.RLock()
+	defer d.writes.RUnlock()
+	defer d.names.invalidate()
+	return d.current().Update(fn)
+}
+
+// The path of the database file
+func (d *Database) Path() string {
+	return d.path
+}
+
+// Close the database file
+func (d *Database) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.bolt.Close()
+}
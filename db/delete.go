@@ -1,40 +1,42 @@
 package db
 
 import (
+	"fmt"
 	bolt "go.etcd.io/bbolt"
+	"strings"
 )
 
 func (d deleteRecord) A(qname string) error {
-	return  d.Db.Update(func(tx *bolt.Tx) error {
+	return  d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("A")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) AAAA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("AAAA")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) CNAME(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("CNAME")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) MX(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("MX"))
 
 		if err := records.Delete([]byte(qname + "*host")); err != nil {
 			return err
 		}
 		return records.Delete([]byte(qname + "*priority"))
-	})
+	}))
 }
 
 func (d deleteRecord) LOC(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("LOC"))
 
 		if err := records.Delete([]byte(qname + "*version")); err != nil {
@@ -56,11 +58,11 @@ func (d deleteRecord) LOC(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*alt"))
-	})
+	}))
 }
 
 func (d deleteRecord) SRV(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SRV"))
 
 		if err := records.Delete([]byte(qname + "*priority")); err != nil {
@@ -73,46 +75,46 @@ func (d deleteRecord) SRV(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*target"))
-	})
+	}))
 }
 
 func (d deleteRecord) SPF(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("SPF")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) TXT(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("TXT")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) NS(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("NS")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) CAA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CAA"))
 
 		if err := records.Delete([]byte(qname + "*tag")); err != nil {
 			return err
 		}
 		return records.Delete([]byte(qname + "*content"))
-	})
+	}))
 }
 
 func (d deleteRecord) PTR(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("PTR")).Delete([]byte(qname))
-	})
+	}))
 }
 
 func (d deleteRecord) CERT(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CERT"))
 
 		if err := records.Delete([]byte(qname + "*type")); err != nil {
@@ -125,11 +127,11 @@ func (d deleteRecord) CERT(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*certificate"))
-	})
+	}))
 }
 
 func (d deleteRecord) DNSKEY(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DNSKEY"))
 
 		if err := records.Delete([]byte(qname + "*flags")); err != nil {
@@ -142,11 +144,11 @@ func (d deleteRecord) DNSKEY(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*publickey"))
-	})
+	}))
 }
 
 func (d deleteRecord) DS(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DS"))
 
 		if err := records.Delete([]byte(qname + "*keytag")); err != nil {
@@ -159,11 +161,11 @@ func (d deleteRecord) DS(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*digest"))
-	})
+	}))
 }
 
 func (d deleteRecord) NAPTR(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("NAPTR"))
 
 		if err := records.Delete([]byte(qname + "*order")); err != nil {
@@ -182,11 +184,11 @@ func (d deleteRecord) NAPTR(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*replacement"))
-	})
+	}))
 }
 
 func (d deleteRecord) SMIMEA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SMIMEA"))
 
 		if err := records.Delete([]byte(qname + "*usage")); err != nil {
@@ -199,11 +201,11 @@ func (d deleteRecord) SMIMEA(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*certificate"))
-	})
+	}))
 }
 
 func (d deleteRecord) SSHFP(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SSHFP"))
 
 		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
@@ -213,11 +215,11 @@ func (d deleteRecord) SSHFP(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*fingerprint"))
-	})
+	}))
 }
 
 func (d deleteRecord) TLSA(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("TLSA"))
 
 		if err := records.Delete([]byte(qname + "*usage")); err != nil {
@@ -230,11 +232,11 @@ func (d deleteRecord) TLSA(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*certificate"))
-	})
+	}))
 }
 
 func (d deleteRecord) URI(qname string) error {
-	return d.Db.Update(func(tx *bolt.Tx) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("URI"))
 
 		if err := records.Delete([]byte(qname + "*priority")); err != nil {
@@ -244,5 +246,160 @@ func (d deleteRecord) URI(qname string) error {
 			return err
 		}
 		return records.Delete([]byte(qname + "*target"))
+	}))
+}
+
+func (d deleteRecord) CSYNC(qname string) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CSYNC"))
+
+		if err := records.Delete([]byte(qname + "*serial")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*flags")); err != nil {
+			return err
+		}
+		return records.Delete([]byte(qname + "*types"))
+	}))
+}
+
+func (d deleteRecord) AMTRELAY(qname string) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("AMTRELAY"))
+
+		if err := records.Delete([]byte(qname + "*precedence")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*discovery")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*type")); err != nil {
+			return err
+		}
+		return records.Delete([]byte(qname + "*relay"))
+	}))
+}
+
+func (d deleteRecord) HINFO(qname string) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("HINFO"))
+
+		if err := records.Delete([]byte(qname + "*cpu")); err != nil {
+			return err
+		}
+		return records.Delete([]byte(qname + "*os"))
+	}))
+}
+
+func (d deleteRecord) CDNSKEY(qname string) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CDNSKEY"))
+
+		if err := records.Delete([]byte(qname + "*flags")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*protocol")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*algorithm")); err != nil {
+			return err
+		}
+		return records.Delete([]byte(qname + "*publickey"))
+	}))
+}
+
+func (d deleteRecord) SVCB(qname string) error {
+	return d.serviceBinding("SVCB", qname)
+}
+
+func (d deleteRecord) HTTPS(qname string) error {
+	return d.serviceBinding("HTTPS", qname)
+}
+
+// Delete an SVCB or HTTPS record, which are stored the same way
+func (d deleteRecord) serviceBinding(recordType, qname string) error {
+	return d.update(zonedDelete(qname, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte(recordType))
+
+		if err := records.Delete([]byte(qname + "*priority")); err != nil {
+			return err
+		}
+		if err := records.Delete([]byte(qname + "*target")); err != nil {
+			return err
+		}
+		return records.Delete([]byte(qname + "*params"))
+	}))
+}
+
+// Delete a record by its type name
+func (d deleteRecord) Record(qname, recordType string) error {
+	switch strings.ToUpper(recordType) {
+	case "A":
+		return d.A(qname)
+	case "AAAA":
+		return d.AAAA(qname)
+	case "CNAME":
+		return d.CNAME(qname)
+	case "MX":
+		return d.MX(qname)
+	case "LOC":
+		return d.LOC(qname)
+	case "SRV":
+		return d.SRV(qname)
+	case "SPF":
+		return d.SPF(qname)
+	case "TXT":
+		return d.TXT(qname)
+	case "NS":
+		return d.NS(qname)
+	case "CAA":
+		return d.CAA(qname)
+	case "PTR":
+		return d.PTR(qname)
+	case "CERT":
+		return d.CERT(qname)
+	case "DNSKEY":
+		return d.DNSKEY(qname)
+	case "DS":
+		return d.DS(qname)
+	case "NAPTR":
+		return d.NAPTR(qname)
+	case "SMIMEA":
+		return d.SMIMEA(qname)
+	case "SSHFP":
+		return d.SSHFP(qname)
+	case "TLSA":
+		return d.TLSA(qname)
+	case "URI":
+		return d.URI(qname)
+	case "CSYNC":
+		return d.CSYNC(qname)
+	case "AMTRELAY":
+		return d.AMTRELAY(qname)
+	case "HINFO":
+		return d.HINFO(qname)
+	case "CDNSKEY":
+		return d.CDNSKEY(qname)
+	case "SVCB":
+		return d.SVCB(qname)
+	case "HTTPS":
+		return d.HTTPS(qname)
+	case "SOA":
+		return d.SOA(qname)
+	default:
+		return fmt.Errorf("unsupported record type %s", recordType)
+	}
+}
+
+func (d deleteRecord) SOA(qname string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("SOA"))
+
+		for _, field := range []string{"nameserver", "mailbox", "serial", "refresh", "retry", "expire"} {
+			if err := records.Delete([]byte(qname + "*" + field)); err != nil {
+				return err
+			}
+		}
+		return records.Delete([]byte(qname + "*minimum"))
 	})
 }
@@ -6,23 +6,34 @@ import (
 	bolt "go.etcd.io/bbolt"
 	"log"
 	"net"
+	"reflect"
+	"strings"
 )
 
 func (g get) A(qname string) *A {
 	a := &A{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("A"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
-			a.Address = net.ParseIP(string(value))
+			hosts, err := decodeAddresses(value)
+			if err != nil {
+				return err
+			}
+
+			for _, host := range hosts {
+				if address := net.ParseIP(host); address != nil {
+					a.Addresses = append(a.Addresses, address)
+				}
+			}
 		}
 
 		return nil
 	}); err != nil {
 		log.Printf("Failed to retrieve A record for '%s': %v", qname, err)
         return nil
-	} else if len(a.Address) == 0 {
+	} else if len(a.Addresses) == 0 {
 		return nil
 	}
 	return a
@@ -31,7 +42,7 @@ func (g get) A(qname string) *A {
 func (g get) AAAA(qname string) *AAAA {
 	a := &AAAA{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("AAAA"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -51,7 +62,7 @@ func (g get) AAAA(qname string) *AAAA {
 func (g get) CNAME(qname string) *CNAME {
 	c := &CNAME{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CNAME"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -71,7 +82,7 @@ func (g get) CNAME(qname string) *CNAME {
 func (g get) MX(qname string) *MX {
 	m := &MX{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("MX"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -95,7 +106,7 @@ func (g get) MX(qname string) *MX {
 func (g get) LOC(qname string) *LOC {
 	l := &LOC{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("LOC"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -152,7 +163,7 @@ func (g get) LOC(qname string) *LOC {
 func (g get) SRV(qname string) *SRV {
 	s := &SRV{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SRV"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -182,7 +193,7 @@ func (g get) SRV(qname string) *SRV {
 func (g get) SPF(qname string) *SPF {
 	var content []string
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SPF"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -199,7 +210,8 @@ func (g get) SPF(qname string) *SPF {
 		return nil
 	}
 
-	// Prune all empty strings
+	// Prune empty strings padding the strings written by earlier versions,
+	// keeping the submitted order
 	var text []string
 	for _, v := range content {
 		if len(v) != 0 {
@@ -207,13 +219,13 @@ func (g get) SPF(qname string) *SPF {
 		}
 	}
 
-	return &SPF{Text: content}
+	return &SPF{Text: text}
 }
 
 func (g get) TXT(qname string) *TXT {
 	var content []string
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("TXT"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -230,7 +242,8 @@ func (g get) TXT(qname string) *TXT {
 		return nil
 	}
 
-	// Prune all empty strings
+	// Prune empty strings padding the strings written by earlier versions,
+	// keeping the submitted order
 	var text []string
 	for _, v := range content {
 		if len(v) != 0 {
@@ -244,7 +257,7 @@ func (g get) TXT(qname string) *TXT {
 func (g get) NS(qname string) *NS {
 	n := &NS{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("NS"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -264,7 +277,7 @@ func (g get) NS(qname string) *NS {
 func (g get) CAA(qname string) *CAA {
 	c := &CAA{Flag: 0}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CAA"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -288,7 +301,7 @@ func (g get) CAA(qname string) *CAA {
 func (g get) PTR(qname string) *PTR {
 	p := &PTR{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("PTR"))
 
 		if value := records.Get([]byte(qname[:len(qname)-1])); len(value) != 0 {
@@ -308,7 +321,7 @@ func (g get) PTR(qname string) *PTR {
 func (g get) CERT(qname string) *CERT {
 	c := &CERT{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CERT"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -338,7 +351,7 @@ func (g get) CERT(qname string) *CERT {
 func (g get) DNSKEY(qname string) *DNSKEY {
 	d := &DNSKEY{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DNSKEY"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -368,7 +381,7 @@ func (g get) DNSKEY(qname string) *DNSKEY {
 func (g get) DS(qname string) *DS {
 	d := &DS{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DS"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -398,7 +411,7 @@ func (g get) DS(qname string) *DS {
 func (g get) NAPTR(qname string) *NAPTR {
 	n := &NAPTR{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("NAPTR"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -434,7 +447,7 @@ func (g get) NAPTR(qname string) *NAPTR {
 func (g get) SMIMEA(qname string) *SMIMEA {
 	s := &SMIMEA{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SMIMEA"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -464,7 +477,7 @@ func (g get) SMIMEA(qname string) *SMIMEA {
 func (g get) SSHFP(qname string) *SSHFP {
 	s := &SSHFP{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SSHFP"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -491,7 +504,7 @@ func (g get) SSHFP(qname string) *SSHFP {
 func (g get) TLSA(qname string) *TLSA {
 	t := &TLSA{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("TLSA"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -521,7 +534,7 @@ func (g get) TLSA(qname string) *TLSA {
 func (g get) URI(qname string) *URI {
 	u := &URI{}
 
-	if err := g.Db.View(func(tx *bolt.Tx) error {
+	if err := g.view(func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("URI"))
 		shortenedName := qname[:len(qname)-1]
 
@@ -544,3 +557,281 @@ func (g get) URI(qname string) *URI {
 	}
 	return u
 }
+
+func (g get) CSYNC(qname string) *CSYNC {
+	c := &CSYNC{}
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CSYNC"))
+		shortenedName := qname[:len(qname)-1]
+
+		if serialValue := records.Get([]byte(shortenedName + "*serial")); len(serialValue) != 0 {
+			c.Serial = binary.BigEndian.Uint32(serialValue)
+		}
+		if flagsValue := records.Get([]byte(shortenedName + "*flags")); len(flagsValue) != 0 {
+			c.Flags = binary.BigEndian.Uint16(flagsValue)
+		}
+		if typesValue := records.Get([]byte(shortenedName + "*types")); len(typesValue) != 0 {
+			c.Types = strings.Fields(string(typesValue))
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve CSYNC record for '%s': %v", qname, err)
+		return nil
+	} else if len(c.Types) == 0 {
+		return nil
+	}
+	return c
+}
+
+func (g get) AMTRELAY(qname string) *AMTRELAY {
+	a := &AMTRELAY{}
+	found := false
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("AMTRELAY"))
+		shortenedName := qname[:len(qname)-1]
+
+		if precedenceValue := records.Get([]byte(shortenedName + "*precedence")); len(precedenceValue) != 0 {
+			a.Precedence = precedenceValue[0]
+		}
+		if discoveryValue := records.Get([]byte(shortenedName + "*discovery")); len(discoveryValue) != 0 {
+			a.Discovery = discoveryValue[0] == 1
+		}
+		if typeValue := records.Get([]byte(shortenedName + "*type")); len(typeValue) != 0 {
+			a.RelayType = typeValue[0]
+			found = true
+		}
+		if relayValue := records.Get([]byte(shortenedName + "*relay")); len(relayValue) != 0 {
+			a.Relay = string(relayValue)
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve AMTRELAY record for '%s': %v", qname, err)
+		return nil
+	} else if !found {
+		return nil
+	}
+	return a
+}
+
+func (g get) HINFO(qname string) *HINFO {
+	h := &HINFO{}
+	found := false
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("HINFO"))
+		shortenedName := qname[:len(qname)-1]
+
+		// Either field may be empty so presence is tracked separately
+		if cpuValue := records.Get([]byte(shortenedName + "*cpu")); cpuValue != nil {
+			h.CPU = string(cpuValue)
+			found = true
+		}
+		if osValue := records.Get([]byte(shortenedName + "*os")); osValue != nil {
+			h.OS = string(osValue)
+			found = true
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve HINFO record for '%s': %v", qname, err)
+		return nil
+	} else if !found {
+		return nil
+	}
+	return h
+}
+
+func (g get) CDNSKEY(qname string) *CDNSKEY {
+	d := &CDNSKEY{}
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CDNSKEY"))
+		shortenedName := qname[:len(qname)-1]
+
+		if flagsValue := records.Get([]byte(shortenedName + "*flags")); len(flagsValue) != 0 {
+			d.Flags = binary.BigEndian.Uint16(flagsValue)
+		}
+		if protoValue := records.Get([]byte(shortenedName + "*protocol")); len(protoValue) != 0 {
+			d.Protocol = protoValue[0]
+		}
+		if algoValue := records.Get([]byte(shortenedName + "*algorithm")); len(algoValue) != 0 {
+			d.Algorithm = algoValue[0]
+		}
+		if pubValue := records.Get([]byte(shortenedName + "*publickey")); len(pubValue) != 0 {
+			d.PublicKey = string(pubValue)
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve CDNSKEY record for '%s': %v", qname, err)
+		return nil
+	} else if len(d.PublicKey) == 0 {
+		return nil
+	}
+	return d
+}
+
+func (g get) SVCB(qname string) *SVCB {
+	return g.serviceBinding("SVCB", qname)
+}
+
+func (g get) HTTPS(qname string) *HTTPS {
+	if s := g.serviceBinding("HTTPS", qname); s != nil {
+		return &HTTPS{SVCB: *s}
+	}
+	return nil
+}
+
+// Get an SVCB or HTTPS record, which are stored the same way
+func (g get) serviceBinding(recordType, qname string) *SVCB {
+	s := &SVCB{}
+	found := false
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte(recordType))
+		shortenedName := qname[:len(qname)-1]
+
+		// The target may be the root, so presence is tracked separately
+		if targetValue := records.Get([]byte(shortenedName + "*target")); targetValue != nil {
+			s.Target = string(targetValue)
+			found = true
+		}
+		if priorityValue := records.Get([]byte(shortenedName + "*priority")); len(priorityValue) != 0 {
+			s.Priority = binary.BigEndian.Uint16(priorityValue)
+		}
+		if paramsValue := records.Get([]byte(shortenedName + "*params")); len(paramsValue) != 0 {
+			return json.Unmarshal(paramsValue, &s.Params)
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve %s record for '%s': %v", recordType, qname, err)
+		return nil
+	} else if !found {
+		return nil
+	}
+	return s
+}
+
+func (g get) SOA(qname string) *SOA {
+	s := &SOA{}
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("SOA"))
+		shortenedName := qname[:len(qname)-1]
+
+		if nameserverValue := records.Get([]byte(shortenedName + "*nameserver")); len(nameserverValue) != 0 {
+			s.Nameserver = string(nameserverValue)
+		}
+		if mailboxValue := records.Get([]byte(shortenedName + "*mailbox")); len(mailboxValue) != 0 {
+			s.Mailbox = string(mailboxValue)
+		}
+		if serialValue := records.Get([]byte(shortenedName + "*serial")); len(serialValue) != 0 {
+			s.Serial = binary.BigEndian.Uint32(serialValue)
+		}
+		if refreshValue := records.Get([]byte(shortenedName + "*refresh")); len(refreshValue) != 0 {
+			s.Refresh = binary.BigEndian.Uint32(refreshValue)
+		}
+		if retryValue := records.Get([]byte(shortenedName + "*retry")); len(retryValue) != 0 {
+			s.Retry = binary.BigEndian.Uint32(retryValue)
+		}
+		if expireValue := records.Get([]byte(shortenedName + "*expire")); len(expireValue) != 0 {
+			s.Expire = binary.BigEndian.Uint32(expireValue)
+		}
+		if minimumValue := records.Get([]byte(shortenedName + "*minimum")); len(minimumValue) != 0 {
+			s.Minimum = binary.BigEndian.Uint32(minimumValue)
+		}
+
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve SOA record for '%s': %v", qname, err)
+		return nil
+	} else if len(s.Nameserver) == 0 {
+		return nil
+	}
+	return s
+}
+
+// Get a record by its type name, returns nil if it does not exist
+func (g get) Record(qname, recordType string) Record {
+	var record Record
+	switch strings.ToUpper(recordType) {
+	case "A":
+		record = g.A(qname)
+	case "AAAA":
+		record = g.AAAA(qname)
+	case "CNAME":
+		record = g.CNAME(qname)
+	case "MX":
+		record = g.MX(qname)
+	case "LOC":
+		record = g.LOC(qname)
+	case "SRV":
+		record = g.SRV(qname)
+	case "SPF":
+		record = g.SPF(qname)
+	case "TXT":
+		record = g.TXT(qname)
+	case "NS":
+		record = g.NS(qname)
+	case "CAA":
+		record = g.CAA(qname)
+	case "PTR":
+		record = g.PTR(qname)
+	case "CERT":
+		record = g.CERT(qname)
+	case "DNSKEY":
+		record = g.DNSKEY(qname)
+	case "DS":
+		record = g.DS(qname)
+	case "NAPTR":
+		record = g.NAPTR(qname)
+	case "SMIMEA":
+		record = g.SMIMEA(qname)
+	case "SSHFP":
+		record = g.SSHFP(qname)
+	case "TLSA":
+		record = g.TLSA(qname)
+	case "URI":
+		record = g.URI(qname)
+	case "CSYNC":
+		record = g.CSYNC(qname)
+	case "AMTRELAY":
+		record = g.AMTRELAY(qname)
+	case "HINFO":
+		record = g.HINFO(qname)
+	case "CDNSKEY":
+		record = g.CDNSKEY(qname)
+	case "SVCB":
+		record = g.SVCB(qname)
+	case "HTTPS":
+		record = g.HTTPS(qname)
+	case "SOA":
+		record = g.SOA(qname)
+	}
+
+	// Typed nil pointers do not compare equal to a nil interface
+	if record == nil || reflect.ValueOf(record).IsNil() {
+		return nil
+	}
+	return record
+}
+
+// Decode a stored set of addresses
+// Records written before names could hold several addresses store a
+// single address as plain text
+func decodeAddresses(value []byte) ([]string, error) {
+	if value[0] != '[' {
+		return []string{string(value)}, nil
+	}
+
+	var hosts []string
+	if err := json.Unmarshal(value, &hosts); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
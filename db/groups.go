@@ -0,0 +1,133 @@
+package db
+
+import (
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// Settings shared by the records belonging to a group
+type Group struct {
+	Description string `json:"description,omitempty"`
+	// TTL inherited by members without a TTL of their own, 0 uses the configured TTL
+	TTL uint32 `json:"ttl"`
+}
+
+// A record belonging to a group
+type GroupMember struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Get a group by its name
+// Returns false if no such group exists
+func GetGroup(name string, db *Database) (Group, bool, error) {
+	var g Group
+	exists := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("groups")).Get([]byte(name)); len(value) != 0 {
+			exists = true
+			return json.Unmarshal(value, &g)
+		}
+		return nil
+	})
+
+	return g, exists, err
+}
+
+// Get every group by its name
+func ListGroups(db *Database) (map[string]Group, error) {
+	groups := map[string]Group{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("groups")).ForEach(func(k, v []byte) error {
+			var g Group
+			if err := json.Unmarshal(v, &g); err != nil {
+				return err
+			}
+			groups[string(k)] = g
+			return nil
+		})
+	})
+
+	return groups, err
+}
+
+func SetGroup(name string, g Group, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return SetGroupTx(name, g, tx)
+	})
+}
+
+// Create or replace a group within an open transaction
+func SetGroupTx(name string, g Group, tx *bolt.Tx) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte("groups")).Put([]byte(name), data)
+}
+
+// Delete a group, removing its members from it
+func DeleteGroup(name string, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return DeleteGroupTx(name, tx)
+	})
+}
+
+// Delete a group within an open transaction, removing its members from it
+func DeleteGroupTx(name string, tx *bolt.Tx) error {
+	metadata := tx.Bucket([]byte("metadata"))
+
+	// Collect members first as the bucket cannot change while iterating
+	updated := map[string][]byte{}
+	if err := metadata.ForEach(func(k, v []byte) error {
+		var m Metadata
+		if err := json.Unmarshal(v, &m); err != nil {
+			return err
+		} else if m.Group != name {
+			return nil
+		}
+
+		m.Group = ""
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		updated[string(k)] = data
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for k, data := range updated {
+		if err := metadata.Put([]byte(k), data); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket([]byte("groups")).Delete([]byte(name))
+}
+
+// Get the records belonging to a group
+func GroupMembers(name string, db *Database) ([]GroupMember, error) {
+	members := []GroupMember{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("metadata")).ForEach(func(k, v []byte) error {
+			var m Metadata
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			} else if m.Group != name {
+				return nil
+			}
+
+			key := string(k)
+			i := strings.LastIndex(key, "*")
+			members = append(members, GroupMember{Name: key[:i], Type: key[i+1:]})
+			return nil
+		})
+	})
+
+	return members, err
+}
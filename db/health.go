@@ -0,0 +1,104 @@
+package db
+
+import (
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// How the addresses of an A record are checked before being answered with
+type HealthCheck struct {
+	// "tcp" to connect to the port, or "http" to also expect a successful
+	// response for the path
+	Protocol string `json:"protocol"`
+	Port     uint16 `json:"port"`
+	Path     string `json:"path,omitempty"`
+}
+
+// The last result of checking an address
+type AddressHealth struct {
+	Healthy bool  `json:"healthy"`
+	Checked int64 `json:"checked"`
+}
+
+// Health is kept apart from the metadata of a record, as it changes with
+// every check rather than with the record
+func healthPrefix(name string) []byte {
+	return []byte(name + "*A*")
+}
+
+// Get the last results of checking the addresses of a record, by address
+// Addresses not checked yet are missing
+func GetHealth(name string, db *Database) (map[string]AddressHealth, error) {
+	health := map[string]AddressHealth{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		prefix := healthPrefix(name)
+		cursor := tx.Bucket([]byte("health")).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = cursor.Next() {
+			var h AddressHealth
+			if err := json.Unmarshal(v, &h); err != nil {
+				return err
+			}
+			health[string(k[len(prefix):])] = h
+		}
+		return nil
+	})
+
+	return health, err
+}
+
+// Replace the results of checking the addresses of a record, dropping those of
+// addresses the record no longer holds
+func SetHealth(name string, health map[string]AddressHealth, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("health"))
+		prefix := healthPrefix(name)
+
+		// Collect keys first as the bucket can't be changed while iterating
+		var stale [][]byte
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = cursor.Next() {
+			stale = append(stale, append([]byte{}, k...))
+		}
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		for address, h := range health {
+			data, err := json.Marshal(h)
+			if err != nil {
+				return err
+			} else if err := bucket.Put(append(healthPrefix(name), address...), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get the health checks of every A record with one, by name
+func ListHealthChecks(db *Database) (map[string]HealthCheck, error) {
+	checks := map[string]HealthCheck{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("metadata")).ForEach(func(k, v []byte) error {
+			name := strings.TrimSuffix(string(k), "*A")
+			if name == string(k) {
+				return nil
+			}
+
+			var m Metadata
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			} else if m.HealthCheck != nil {
+				checks[name] = *m.HealthCheck
+			}
+			return nil
+		})
+	})
+
+	return checks, err
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+// A value a record held after a write
+type RecordVersion struct {
+	Version  int             `json:"version"`
+	Time     int64           `json:"time"`
+	Username string          `json:"username"`
+	Record   json.RawMessage `json:"record"`
+}
+
+// Decode the value of a version into a record of its type
+func (v RecordVersion) Decode(recordType string) (Record, error) {
+	record := NewRecord(recordType)
+	if record == nil {
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+	return record, json.Unmarshal(v.Record, record)
+}
+
+// Add the value of a record following a write to its history, keeping only
+// the configured number of versions
+func appendHistory(tx *bolt.Tx, name, recordType, username string) error {
+	size := viper.GetInt("records.history-size")
+	record := get{Tx: tx}.Record(name+".", recordType)
+	if size <= 0 || record == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	history := tx.Bucket([]byte("history"))
+	var versions []RecordVersion
+	if value := history.Get(metadataKey(name, recordType)); len(value) != 0 {
+		if err := json.Unmarshal(value, &versions); err != nil {
+			return err
+		}
+	}
+
+	// Versions keep counting up as older ones are dropped
+	next := 1
+	if len(versions) != 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+	versions = append(versions, RecordVersion{Version: next, Time: time.Now().Unix(), Username: username, Record: encoded})
+	if len(versions) > size {
+		versions = versions[len(versions)-size:]
+	}
+
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return history.Put(metadataKey(name, recordType), data)
+}
+
+// Get the kept versions of a record, oldest first
+func GetHistory(name, recordType string, db *Database) ([]RecordVersion, error) {
+	versions := []RecordVersion{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("history")).Get(metadataKey(name, recordType)); len(value) != 0 {
+			return json.Unmarshal(value, &versions)
+		}
+		return nil
+	})
+
+	return versions, err
+}
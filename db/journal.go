@@ -0,0 +1,117 @@
+package db
+
+import (
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"reflect"
+)
+
+// A change made to a record, with its value before and after
+type RecordChange struct {
+	Time      int64           `json:"time"`
+	Username  string          `json:"username"`
+	Operation string          `json:"operation"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	// Fields whose value differs between before and after, when the record
+	// existed on both sides
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// The value of a single field before and after a change
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Encode a record for the journal, nil if it does not exist
+func journalValue(record Record) (json.RawMessage, error) {
+	if record == nil {
+		return nil, nil
+	}
+	return json.Marshal(record)
+}
+
+// Compare the fields of two encoded values of a record
+func FieldChanges(before, after json.RawMessage) (map[string]FieldChange, error) {
+	var b, a map[string]interface{}
+	if err := json.Unmarshal(before, &b); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for field, value := range a {
+		if !reflect.DeepEqual(b[field], value) {
+			changes[field] = FieldChange{Before: b[field], After: value}
+		}
+	}
+	for field, value := range b {
+		if _, ok := a[field]; !ok {
+			changes[field] = FieldChange{Before: value}
+		}
+	}
+	return changes, nil
+}
+
+// Add a change to the journal of a record once a write or delete succeeds,
+// unless the record was left as it was
+func journaled(entry *AuditEntry, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		before, err := journalValue(get{Tx: tx}.Record(entry.Resource+".", entry.Type))
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		after, err := journalValue(get{Tx: tx}.Record(entry.Resource+".", entry.Type))
+		if err != nil {
+			return err
+		} else if string(before) == string(after) {
+			return nil
+		}
+
+		change := RecordChange{Time: entry.Time, Username: entry.Username, Operation: entry.Operation, Before: before, After: after}
+		if before != nil && after != nil {
+			if change.Changes, err = FieldChanges(before, after); err != nil {
+				return err
+			}
+		}
+		return appendJournal(entry.Resource, entry.Type, change, tx)
+	}
+}
+
+// Add a change to the end of the journal of a record within an open
+// transaction
+func appendJournal(name, recordType string, change RecordChange, tx *bolt.Tx) error {
+	journal := tx.Bucket([]byte("journal"))
+	key := metadataKey(name, recordType)
+	var changes []RecordChange
+	if value := journal.Get(key); len(value) != 0 {
+		if err := json.Unmarshal(value, &changes); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(append(changes, change))
+	if err != nil {
+		return err
+	}
+	return journal.Put(key, data)
+}
+
+// Get every change made to a record, oldest first
+func GetJournal(name, recordType string, db *Database) ([]RecordChange, error) {
+	changes := []RecordChange{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("journal")).Get(metadataKey(name, recordType)); len(value) != 0 {
+			return json.Unmarshal(value, &changes)
+		}
+		return nil
+	})
+
+	return changes, err
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Get the key exports are signed with, generating it the first time
+func ExportKey(db *Database) (ed25519.PrivateKey, error) {
+	var key ed25519.PrivateKey
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket([]byte("keys"))
+		if seed := keys.Get([]byte("export")); len(seed) == ed25519.SeedSize {
+			key = ed25519.NewKeyFromSeed(seed)
+			return nil
+		}
+
+		_, generated, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		key = generated
+		return keys.Put([]byte("export"), generated.Seed())
+	})
+
+	return key, err
+}
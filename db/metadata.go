@@ -0,0 +1,177 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// Returned when a user owns as many records as they are allowed to
+var ErrQuotaExceeded = fmt.Errorf("record quota exceeded")
+
+// Information about a record kept outside of its type bucket
+type Metadata struct {
+	Owner          string   `json:"owner"`
+	Modified       int64    `json:"modified,omitempty"`
+	AllowedSources []string `json:"allowed-sources,omitempty"`
+	AdminNotes     string   `json:"admin-notes,omitempty"`
+	Locked         bool     `json:"locked,omitempty"`
+	// TTL to serve the record with, 0 uses the configured TTL
+	TTL uint32 `json:"ttl,omitempty"`
+	// Group to inherit a TTL from when the record has none of its own
+	Group string `json:"group,omitempty"`
+	// Check addresses of the record must pass to be answered with
+	HealthCheck *HealthCheck `json:"health-check,omitempty"`
+	// Value of a record with several values to always answer with first
+	Preferred string `json:"preferred,omitempty"`
+	// Domain valued fields as written before being lowercased, by field
+	OriginalCase map[string]string `json:"original-case,omitempty"`
+}
+
+func metadataKey(name, recordType string) []byte {
+	return []byte(name + "*" + strings.ToUpper(recordType))
+}
+
+func GetMetadata(name, recordType string, db *Database) (Metadata, error) {
+	var m Metadata
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("metadata")).Get(metadataKey(name, recordType)); len(value) != 0 {
+			return json.Unmarshal(value, &m)
+		}
+		return nil
+	})
+
+	return m, err
+}
+
+func SetMetadata(name, recordType string, m Metadata, db *Database) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("metadata")).Put(metadataKey(name, recordType), data)
+	})
+}
+
+func DeleteMetadata(name, recordType string, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return DeleteMetadataTx(name, recordType, tx)
+	})
+}
+
+// Remove the metadata of a record within an open transaction
+func DeleteMetadataTx(name, recordType string, tx *bolt.Tx) error {
+	return tx.Bucket([]byte("metadata")).Delete(metadataKey(name, recordType))
+}
+
+// Modify the metadata of a record in place
+func UpdateMetadata(name, recordType string, fn func(m *Metadata), db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return UpdateMetadataTx(name, recordType, fn, tx)
+	})
+}
+
+// Modify the metadata of a record in place within an open transaction
+func UpdateMetadataTx(name, recordType string, fn func(m *Metadata), tx *bolt.Tx) error {
+	metadata := tx.Bucket([]byte("metadata"))
+
+	var m Metadata
+	if value := metadata.Get(metadataKey(name, recordType)); len(value) != 0 {
+		if err := json.Unmarshal(value, &m); err != nil {
+			return err
+		}
+	}
+	fn(&m)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return metadata.Put(metadataKey(name, recordType), data)
+}
+
+// Mark a record as modified at the current time
+func TouchMetadata(name, recordType string, db *Database) error {
+	return UpdateMetadata(name, recordType, func(m *Metadata) {
+		m.Modified = time.Now().Unix()
+	}, db)
+}
+
+// Count the records owned by a user
+func CountOwned(username string, db *Database) (int, error) {
+	count := 0
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("metadata")).ForEach(func(k, v []byte) error {
+			var m Metadata
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+
+			if m.Owner == username {
+				count++
+			}
+			return nil
+		})
+	})
+
+	return count, err
+}
+
+// Record a user as the owner of a new record if within their quota
+// A quota of 0 or less is unlimited. Returns whether the record was newly
+// claimed and how many records the user owns.
+func ClaimRecord(name, recordType, username string, quota int, db *Database) (bool, int, error) {
+	claimed := false
+	usage := 0
+
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		claimed, usage, err = ClaimRecordTx(name, recordType, username, quota, tx)
+		return err
+	})
+
+	return claimed, usage, err
+}
+
+// Record a user as the owner of a new record within an open transaction, so
+// the claim is only kept if the record is written
+func ClaimRecordTx(name, recordType, username string, quota int, tx *bolt.Tx) (bool, int, error) {
+	metadata := tx.Bucket([]byte("metadata"))
+
+	// Existing records keep their owner
+	if value := metadata.Get(metadataKey(name, recordType)); len(value) != 0 {
+		return false, 0, nil
+	}
+
+	// Count the records already owned
+	usage := 0
+	if err := metadata.ForEach(func(k, v []byte) error {
+		var m Metadata
+		if err := json.Unmarshal(v, &m); err != nil {
+			return err
+		}
+
+		if m.Owner == username {
+			usage++
+		}
+		return nil
+	}); err != nil {
+		return false, 0, err
+	}
+
+	if quota > 0 && usage >= quota {
+		return false, usage, ErrQuotaExceeded
+	}
+
+	// Write new owner
+	data, err := json.Marshal(Metadata{Owner: username})
+	if err != nil {
+		return false, 0, err
+	}
+	return true, usage + 1, metadata.Put(metadataKey(name, recordType), data)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"testing"
+)
+
+// Write an A record claimed by a user in the same transaction
+func claimedA(name, username string, quota int) error {
+	return Set.WithMetadata(func(tx *bolt.Tx) error {
+		_, _, err := ClaimRecordTx(name, "A", username, quota, tx)
+		return err
+	}).A(name, "192.0.2.1")
+}
+
+func TestClaimRecordQuota(t *testing.T) {
+	database := testDatabase(t)
+
+	if err := claimedA("one.example.com", "user", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := claimedA("two.example.com", "user", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The record over the quota is not written along with its claim
+	if err := claimedA("three.example.com", "user", 2); err != ErrQuotaExceeded {
+		t.Fatalf("expected quota to be exceeded, got %v", err)
+	}
+	if Get.A("three.example.com.") != nil {
+		t.Error("record over the quota was written")
+	}
+	if usage, err := CountOwned("user", database); err != nil || usage != 2 {
+		t.Errorf("expected usage of 2, got %d: %v", usage, err)
+	}
+
+	// Rewriting an owned record does not count against the quota
+	if err := claimedA("one.example.com", "user", 2); err != nil {
+		t.Errorf("failed to rewrite an owned record: %v", err)
+	}
+
+	// Deleting a record frees its place in the quota
+	if err := Delete.Record("two.example.com", "A"); err != nil {
+		t.Fatal(err)
+	} else if err := DeleteMetadata("two.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	}
+	if err := claimedA("three.example.com", "user", 2); err != nil {
+		t.Errorf("expected freed quota to allow the record, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"bytes"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names holding records along with every name above them, so existence
+// checks don't have to scan every bucket
+type nameIndex struct {
+	lock       sync.Mutex
+	generation uint64
+	names      map[string]bool
+}
+
+// Drop the index after a write so the next lookup rebuilds it
+func (i *nameIndex) invalidate() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.generation++
+	i.names = nil
+}
+
+// Get the index, building it from the database if a write dropped it
+func (i *nameIndex) get(d *Database) (map[string]bool, error) {
+	i.lock.Lock()
+	names, generation := i.names, i.generation
+	i.lock.Unlock()
+	if names != nil {
+		return names, nil
+	}
+
+	if err := d.View(func(tx *bolt.Tx) error {
+		var err error
+		names, err = indexNames(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	// Only keep the index if no write committed while it was being built
+	i.lock.Lock()
+	if i.generation == generation {
+		i.names = names
+	}
+	i.lock.Unlock()
+	return names, nil
+}
+
+// Collect every name holding records, and every name above those
+func indexNames(tx *bolt.Tx) (map[string]bool, error) {
+	names := map[string]bool{}
+	for _, recordType := range RecordTypes {
+		if err := tx.Bucket([]byte(recordType)).ForEach(func(k, v []byte) error {
+			// Strip field suffix from multi-value records
+			if i := bytes.IndexByte(k, '*'); i > 0 {
+				k = k[:i]
+			}
+
+			for name := string(k); !names[name]; {
+				names[name] = true
+				i := strings.IndexByte(name, '.')
+				if i < 0 {
+					break
+				}
+				name = name[i+1:]
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Check if a name exists in the tree, either with records of any type or
+// as an empty non-terminal with records below it
+func (g get) NameExists(name string) bool {
+	var names map[string]bool
+	var err error
+
+	// Writes made within an open transaction aren't in the index yet
+	if g.Tx != nil {
+		names, err = indexNames(g.Tx)
+	} else {
+		names, err = g.Db.names.get(g.Db)
+	}
+	if err != nil {
+		log.Printf("Failed to check existence of '%s': %v", name, err)
+		return false
+	}
+
+	return names[name]
+}
+
+// Check if any name below a name holds records
+func (g get) HasDescendants(name string) bool {
+	var names map[string]bool
+	var err error
+	if g.Tx != nil {
+		names, err = indexNames(g.Tx)
+	} else {
+		names, err = g.Db.names.get(g.Db)
+	}
+	if err != nil {
+		log.Printf("Failed to check descendants of '%s': %v", name, err)
+		return false
+	}
+
+	for existing := range names {
+		if strings.HasSuffix(existing, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the types of records held by each name
+func (g get) Index() map[string][]string {
+	index := map[string][]string{}
+
+	if err := g.view(func(tx *bolt.Tx) error {
+		for _, recordType := range RecordTypes {
+			recordType := recordType
+			if err := tx.Bucket([]byte(recordType)).ForEach(func(k, v []byte) error {
+				// Strip field suffix from multi-value records
+				if i := bytes.IndexByte(k, '*'); i > 0 {
+					k = k[:i]
+				}
+
+				name := string(k)
+				if types := index[name]; len(types) == 0 || types[len(types)-1] != recordType {
+					index[name] = append(types, recordType)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Printf("Failed to retrieve record index: %v", err)
+		return map[string][]string{}
+	}
+
+	return index
+}
+
+// Get all unique names holding records, sorted alphabetically
+func (g get) Names() []string {
+	names := []string{}
+	for name := range g.Index() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"testing"
+)
+
+func TestNameExists(t *testing.T) {
+	database := testDatabase(t)
+
+	if err := Set.A("www.sub.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set.MX("example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exists := range map[string]bool{
+		"www.sub.example.com": true,
+		"sub.example.com":     true,
+		"example.com":         true,
+		"com":                 true,
+		"other.example.com":   false,
+		"ub.example.com":      false,
+	} {
+		if Get.NameExists(name) != exists {
+			t.Errorf("expected existence of '%s' to be %t", name, exists)
+		}
+	}
+
+	// Writes are reflected in later lookups
+	if err := Delete.A("www.sub.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if Get.NameExists("sub.example.com") {
+		t.Error("expected 'sub.example.com' to be gone with its only record")
+	}
+
+	// Writes within an open transaction are seen before they are committed
+	if err := database.Update(func(tx *bolt.Tx) error {
+		if err := Set.WithTx(tx).A("new.example.com", "192.0.2.2"); err != nil {
+			return err
+		}
+		if !Get.WithTx(tx).NameExists("new.example.com") {
+			t.Error("expected 'new.example.com' to exist within the transaction")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !Get.NameExists("new.example.com") {
+		t.Error("expected 'new.example.com' to exist after the transaction")
+	}
+}
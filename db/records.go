@@ -9,12 +9,93 @@ type Record interface {
 	Name() string
 }
 
-// Parts of an A record
+// All supported record types
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "LOC", "SRV", "SPF", "TXT", "NS", "CAA", "PTR", "CERT", "DNSKEY", "DS", "NAPTR", "SMIMEA", "SSHFP", "TLSA", "URI", "CSYNC", "AMTRELAY", "HINFO", "CDNSKEY", "SVCB", "HTTPS", "SOA"}
+
+// Get an empty record of a type, returns nil for unsupported types
+func NewRecord(recordType string) Record {
+	switch recordType {
+	case "A":
+		return &A{}
+	case "AAAA":
+		return &AAAA{}
+	case "CNAME":
+		return &CNAME{}
+	case "MX":
+		return &MX{}
+	case "LOC":
+		return &LOC{}
+	case "SRV":
+		return &SRV{}
+	case "SPF":
+		return &SPF{}
+	case "TXT":
+		return &TXT{}
+	case "NS":
+		return &NS{}
+	case "CAA":
+		return &CAA{}
+	case "PTR":
+		return &PTR{}
+	case "CERT":
+		return &CERT{}
+	case "DNSKEY":
+		return &DNSKEY{}
+	case "DS":
+		return &DS{}
+	case "NAPTR":
+		return &NAPTR{}
+	case "SMIMEA":
+		return &SMIMEA{}
+	case "SSHFP":
+		return &SSHFP{}
+	case "TLSA":
+		return &TLSA{}
+	case "URI":
+		return &URI{}
+	case "CSYNC":
+		return &CSYNC{}
+	case "AMTRELAY":
+		return &AMTRELAY{}
+	case "HINFO":
+		return &HINFO{}
+	case "CDNSKEY":
+		return &CDNSKEY{}
+	case "SVCB":
+		return &SVCB{}
+	case "HTTPS":
+		return &HTTPS{}
+	case "SOA":
+		return &SOA{}
+	}
+	return nil
+}
+
+// Parts of an A record, a name may hold several addresses
 type A struct {
-	Address net.IP `json:"host"`
+	Addresses []net.IP `json:"hosts"`
 }
 func (a A) Name() string { return "A" }
 
+// Check if an address is one of the record's addresses
+func (a A) Contains(address net.IP) bool {
+	for _, stored := range a.Addresses {
+		if stored.Equal(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the record's addresses in their text form
+func (a A) Hosts() []string {
+	hosts := make([]string, 0, len(a.Addresses))
+	for _, address := range a.Addresses {
+		hosts = append(hosts, address.String())
+	}
+	return hosts
+}
+
 // Parts of an AAAA record
 type AAAA struct {
 	Address net.IP `json:"host"`
@@ -43,7 +124,7 @@ type LOC struct {
 	Altitude            uint32 `json:"altitude"`
 	LatDegrees			uint8  `json:"lat-degrees"`
 	LatMinutes			uint8  `json:"lat-minutes"`
-	LatSeconds			uint8  `json:"lat-minutes"`
+	LatSeconds			uint8  `json:"lat-seconds"`
 	LatDirection		string `json:"lat-direction"`
 	LongDegrees			uint8  `json:"long-degrees"`
 	LongMinutes			uint8  `json:"long-minutes"`
@@ -167,3 +248,64 @@ type URI struct {
 	Target   string `json:"target"`
 }
 func (u URI) Name() string { return "URI" }
+
+// Parts of a CSYNC record
+type CSYNC struct {
+	Serial uint32   `json:"serial"`
+	Flags  uint16   `json:"flags"`
+	Types  []string `json:"types"`
+}
+func (c CSYNC) Name() string { return "CSYNC" }
+
+// Parts of an AMTRELAY record
+type AMTRELAY struct {
+	Precedence uint8  `json:"precedence"`
+	Discovery  bool   `json:"discovery-optional"`
+	RelayType  uint8  `json:"relay-type"`
+	Relay      string `json:"relay"`
+}
+func (a AMTRELAY) Name() string { return "AMTRELAY" }
+
+// Parts of an HINFO record
+type HINFO struct {
+	CPU string `json:"cpu"`
+	OS  string `json:"os"`
+}
+func (h HINFO) Name() string { return "HINFO" }
+
+// Parts of a CDNSKEY record, the DNSKEY a child asks its parent to publish
+// a DS record for
+type CDNSKEY struct {
+	Flags     uint16 `json:"flags"`
+	Protocol  uint8  `json:"protocol"`
+	Algorithm uint8  `json:"algorithm"`
+	PublicKey string `json:"public-key"`
+}
+func (c CDNSKEY) Name() string { return "CDNSKEY" }
+
+// Parts of an SVCB record
+type SVCB struct {
+	Priority uint16 `json:"priority"`
+	Target   string `json:"target"`
+	// Service parameters in presentation format by key, such as "alpn"
+	Params map[string]string `json:"params,omitempty"`
+}
+func (s SVCB) Name() string { return "SVCB" }
+
+// Parts of an HTTPS record, an SVCB record for HTTPS origins
+type HTTPS struct {
+	SVCB
+}
+func (h HTTPS) Name() string { return "HTTPS" }
+
+// Parts of an SOA record
+type SOA struct {
+	Nameserver string `json:"nameserver"`
+	Mailbox    string `json:"mailbox"`
+	Serial     uint32 `json:"serial"`
+	Refresh    uint32 `json:"refresh"`
+	Retry      uint32 `json:"retry"`
+	Expire     uint32 `json:"expire"`
+	Minimum    uint32 `json:"minimum"`
+}
+func (s SOA) Name() string { return "SOA" }
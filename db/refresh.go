@@ -0,0 +1,171 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Returned when a refresh token is unknown, already used, or expired
+var ErrInvalidRefreshToken = fmt.Errorf("refresh token is invalid or has expired")
+
+// A long lived token exchanged for new login tokens
+// Tokens are stored by the hash of their value so the database alone can't
+// be used to resume a session
+type RefreshToken struct {
+	Username string `json:"username"`
+	Expires  int64  `json:"expires"`
+}
+
+func refreshKey(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// Issue a refresh token to a user within an open transaction
+func NewRefreshTokenTx(username string, tx *bolt.Tx) (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: " + err.Error())
+	}
+	token := base64.RawURLEncoding.EncodeToString(value)
+
+	data, err := json.Marshal(RefreshToken{
+		Username: username,
+		Expires:  Now().Add(viper.GetDuration("http.refresh-token-ttl")).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Bucket([]byte("refresh-tokens")).Put(refreshKey(token), data)
+}
+
+func NewRefreshToken(username string, db *Database) (string, error) {
+	var token string
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		token, err = NewRefreshTokenTx(username, tx)
+		return err
+	})
+	return token, err
+}
+
+// Exchange a refresh token for a new one, returning the user it belongs to
+// The old token is removed in the same transaction so it can only be used once
+func RotateRefreshToken(token string, db *Database) (string, string, error) {
+	var username, rotated string
+	expired := false
+	err := db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket([]byte("refresh-tokens"))
+
+		value := tokens.Get(refreshKey(token))
+		if len(value) == 0 {
+			return ErrInvalidRefreshToken
+		}
+		var t RefreshToken
+		if err := json.Unmarshal(value, &t); err != nil {
+			return err
+		} else if err := tokens.Delete(refreshKey(token)); err != nil {
+			return err
+		}
+
+		// Expired tokens are still removed, so the transaction must succeed
+		if t.Expires < Now().Unix() {
+			expired = true
+			return nil
+		}
+
+		username = t.Username
+		var err error
+		rotated, err = NewRefreshTokenTx(t.Username, tx)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	} else if expired {
+		return "", "", ErrInvalidRefreshToken
+	}
+	return username, rotated, nil
+}
+
+// Remove a single refresh token
+// Returns false if no such token exists
+func RevokeRefreshToken(token string, db *Database) (bool, error) {
+	exists := false
+	err := db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket([]byte("refresh-tokens"))
+		if len(tokens.Get(refreshKey(token))) == 0 {
+			return nil
+		}
+		exists = true
+		return tokens.Delete(refreshKey(token))
+	})
+	return exists, err
+}
+
+// Remove every refresh token of a user within an open transaction, returning
+// how many were removed
+func RevokeRefreshTokensTx(username string, tx *bolt.Tx) (int, error) {
+	return removeRefreshTokens(tx, func(t RefreshToken) bool { return t.Username == username })
+}
+
+func RevokeRefreshTokens(username string, db *Database) (int, error) {
+	revoked := 0
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		revoked, err = RevokeRefreshTokensTx(username, tx)
+		return err
+	})
+	return revoked, err
+}
+
+// Remove expired refresh tokens, returning how many were removed
+func PruneRefreshTokens(db *Database) (int, error) {
+	pruned := 0
+	now := Now().Unix()
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		pruned, err = removeRefreshTokens(tx, func(t RefreshToken) bool { return t.Expires < now })
+		return err
+	})
+	return pruned, err
+}
+
+// Remove the refresh tokens matching a condition
+func removeRefreshTokens(tx *bolt.Tx, matches func(t RefreshToken) bool) (int, error) {
+	tokens := tx.Bucket([]byte("refresh-tokens"))
+
+	// Collect keys first as the bucket can't be changed while iterating
+	var removed [][]byte
+	if err := tokens.ForEach(func(k, v []byte) error {
+		var t RefreshToken
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		if matches(t) {
+			removed = append(removed, k)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, k := range removed {
+		if err := tokens.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(removed), nil
+}
+
+// End every session of a user within an open transaction by removing both
+// their login and refresh tokens, such as after their password changes
+func RevokeSessionsTx(username string, tx *bolt.Tx) error {
+	if _, err := RevokeTokensTx(username, tx); err != nil {
+		return err
+	}
+	_, err := RevokeRefreshTokensTx(username, tx)
+	return err
+}
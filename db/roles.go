@@ -5,6 +5,7 @@ import (
 	"fmt"
 	bolt "go.etcd.io/bbolt"
 	"regexp"
+	"strings"
 )
 
 type Role struct {
@@ -12,9 +13,19 @@ type Role struct {
 	Description string `json:"description"`
 	Allow       string `json:"allow"`
 	Deny        string `json:"deny"`
+	// DNS wildcard patterns, evaluated before the regular expressions
+	AllowNames []string `json:"allow-names,omitempty"`
+	DenyNames  []string `json:"deny-names,omitempty"`
 }
 
-func CreateRole(name, description, allowFilter, denyFilter string, db *bolt.DB) error {
+func CreateRole(name, description, allowFilter, denyFilter string, allowNames, denyNames []string, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return CreateRoleTx(name, description, allowFilter, denyFilter, allowNames, denyNames, tx)
+	})
+}
+
+// Write a role within an open transaction
+func CreateRoleTx(name, description, allowFilter, denyFilter string, allowNames, denyNames []string, tx *bolt.Tx) error {
 	if name == "admin" {
 		return fmt.Errorf("cannot add permissions to role 'admin'")
 	} else if _, err := regexp.Compile(allowFilter); err != nil {
@@ -22,27 +33,32 @@ func CreateRole(name, description, allowFilter, denyFilter string, db *bolt.DB)
 	} else if _, err := regexp.Compile(denyFilter); err != nil {
 		return fmt.Errorf("invalid regular expression for deny")
 	}
+	for _, pattern := range append(append([]string{}, allowNames...), denyNames...) {
+		if err := ValidateNamePattern(pattern); err != nil {
+			return err
+		}
+	}
 
 	r := Role{
 		Name: name,
 		Description: description,
 		Allow: allowFilter,
 		Deny: denyFilter,
+		AllowNames: allowNames,
+		DenyNames: denyNames,
 	}
 	data, err := json.Marshal(r)
 	if err != nil {
 		return err
 	}
 
-	return db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("roles")).Put([]byte(name), data)
-	})
+	return tx.Bucket([]byte("roles")).Put([]byte(name), data)
 }
 
-func GetRole(name string, db *bolt.DB) (*Role, error) {
+func GetRole(name string, db *Database) (*Role, error) {
 	var r Role
 
-	if err := db.Update(func(tx *bolt.Tx) error {
+	if err := db.View(func(tx *bolt.Tx) error {
 		if value := tx.Bucket([]byte("roles")).Get([]byte(name)); len(value) != 0 {
 			return json.Unmarshal(value, &r)
 		}
@@ -54,41 +70,205 @@ func GetRole(name string, db *bolt.DB) (*Role, error) {
 	return &r, nil
 }
 
-func DeleteRole(name string, db *bolt.DB) error {
+func DeleteRole(name string, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return DeleteRoleTx(name, tx)
+	})
+}
+
+// Delete a role within an open transaction
+func DeleteRoleTx(name string, tx *bolt.Tx) error {
 	if name == "admin" {
 		return fmt.Errorf("cannot delete role 'admin'")
 	}
 
-	return db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("roles")).Delete([]byte(name))
+	return tx.Bucket([]byte("roles")).Delete([]byte(name))
+}
+
+// Returned when deleting a role that users still hold
+var ErrRoleInUse = fmt.Errorf("role is still held by users")
+
+// Get the usernames of the users holding a role within an open transaction
+func RoleMembersTx(name string, tx *bolt.Tx) ([]string, error) {
+	var members []string
+	err := tx.Bucket([]byte("users")).ForEach(func(k, v []byte) error {
+		var u User
+		if err := json.Unmarshal(v, &u); err != nil {
+			return err
+		} else if u.Role == name {
+			members = append(members, u.Username)
+		}
+		return nil
 	})
+	return members, err
 }
 
-func EvaluateRole(name, record string, db *bolt.DB) (bool, error) {
-	// Allow by default
-	approved := true
+// Move every user holding a role to another within an open transaction
+func ReassignRoleTx(from, to string, tx *bolt.Tx) error {
+	members, err := RoleMembersTx(from, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, username := range members {
+		var u User
+		if err := json.Unmarshal(tx.Bucket([]byte("users")).Get([]byte(username)), &u); err != nil {
+			return err
+		}
+		u.Role = to
+		if err := u.EncodeTx(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outcome of evaluating a role against a record
+type RoleDecision struct {
+	Role    string `json:"role"`
+	Rule    string `json:"rule"`
+	Source  string `json:"source"`
+	Allowed bool   `json:"allowed"`
+}
 
+func ExplainRole(name, record string, db *Database) (*RoleDecision, error) {
 	// Retrieve role
 	role, err := GetRole(name, db)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	role.Name = name
+
+	return role.Explain(record)
+}
+
+// Evaluate the rules of a role against a record
+// Name patterns are checked first, where the most specific matching pattern
+// decides and a deny wins over an equally specific allow. Otherwise the
+// regular expressions decide, with allow taking precedence over deny. Roles
+// allowing names by pattern deny anything not matched by any rule.
+func (r *Role) Explain(record string) (*RoleDecision, error) {
+	// Allow by default
+	decision := &RoleDecision{Role: r.Name, Source: "default", Allowed: true}
+
+	if pattern, allowed, matched := r.matchNames(record); matched {
+		decision.Rule = pattern
+		decision.Source = "deny-names"
+		if allowed {
+			decision.Source = "allow-names"
+		}
+		decision.Allowed = allowed
+		return decision, nil
+	} else if len(r.AllowNames) != 0 {
+		decision.Allowed = false
 	}
 
-	// Evaluate rules if they exist
-	if role.Deny != "" {
-		matched, err := regexp.Match(role.Deny, []byte(record))
+	// Evaluate rules if they exist, allow takes precedence over deny
+	if r.Deny != "" {
+		matched, err := regexp.Match(r.Deny, []byte(record))
 		if err != nil {
-			return false, err
+			return nil, err
+		} else if matched {
+			decision.Rule = r.Deny
+			decision.Source = "deny"
+			decision.Allowed = false
 		}
-		approved = !matched
 	}
-	if role.Allow != "" {
-		matched, err := regexp.Match(role.Allow, []byte(record))
+	if r.Allow != "" {
+		matched, err := regexp.Match(r.Allow, []byte(record))
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		approved = matched
+		decision.Rule = r.Allow
+		decision.Source = "allow"
+		decision.Allowed = matched
 	}
 
-	return approved, nil
+	return decision, nil
+}
+
+// Find the most specific name pattern of a role matching a record
+func (r *Role) matchNames(record string) (string, bool, bool) {
+	best, allowed, found := "", false, false
+	bestLiteral, bestWildcards := 0, 0
+
+	check := func(pattern string, allow bool) {
+		literal, wildcards, ok := matchNamePattern(pattern, record)
+		if !ok {
+			return
+		}
+
+		// More literal labels are more specific, then fewer wildcards, then deny
+		better := !found || literal > bestLiteral ||
+			(literal == bestLiteral && wildcards < bestWildcards) ||
+			(literal == bestLiteral && wildcards == bestWildcards && !allow)
+		if better {
+			best, allowed, found = pattern, allow, true
+			bestLiteral, bestWildcards = literal, wildcards
+		}
+	}
+	for _, pattern := range r.AllowNames {
+		check(pattern, true)
+	}
+	for _, pattern := range r.DenyNames {
+		check(pattern, false)
+	}
+
+	return best, allowed, found
+}
+
+// Check that a name pattern only uses whole label wildcards
+func ValidateNamePattern(pattern string) error {
+	labels := strings.Split(strings.Trim(pattern, "."), ".")
+	for _, label := range labels {
+		if label == "" {
+			return fmt.Errorf("name pattern '%s' has an empty label", pattern)
+		} else if label != "*" && strings.Contains(label, "*") {
+			return fmt.Errorf("name pattern '%s' may only use '*' as a whole label", pattern)
+		}
+	}
+	return nil
+}
+
+// Match a name against a pattern, where a leading '*' matches one or more
+// labels and any other '*' matches exactly one. Returns the number of literal
+// and wildcard labels in the pattern for ranking matches.
+func matchNamePattern(pattern, name string) (int, int, bool) {
+	patternLabels := strings.Split(strings.ToLower(strings.Trim(pattern, ".")), ".")
+	nameLabels := strings.Split(strings.ToLower(strings.Trim(name, ".")), ".")
+
+	// A leading wildcard covers the whole subtree but not the name itself
+	subtree := patternLabels[0] == "*"
+	if subtree {
+		patternLabels = patternLabels[1:]
+		if len(nameLabels) <= len(patternLabels) {
+			return 0, 0, false
+		}
+		nameLabels = nameLabels[len(nameLabels)-len(patternLabels):]
+	} else if len(nameLabels) != len(patternLabels) {
+		return 0, 0, false
+	}
+
+	literal, wildcards := 0, 0
+	if subtree {
+		wildcards++
+	}
+	for i, label := range patternLabels {
+		if label == "*" {
+			wildcards++
+		} else if label != nameLabels[i] {
+			return 0, 0, false
+		} else {
+			literal++
+		}
+	}
+	return literal, wildcards, true
+}
+
+func EvaluateRole(name, record string, db *Database) (bool, error) {
+	decision, err := ExplainRole(name, record, db)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed, nil
 }
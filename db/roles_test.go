@@ -0,0 +1,71 @@
+package db
+
+import "testing"
+
+func TestEvaluateRoleWildcards(t *testing.T) {
+	database := testDatabase(t)
+	roles := []struct {
+		name                  string
+		allow, deny           string
+		allowNames, denyNames []string
+	}{
+		// A whole subtree, minus one name
+		{"subtree", "", "", []string{"*.example.com"}, []string{"secret.example.com"}},
+		// A wildcard in the middle covers exactly one label
+		{"middle", "", "", []string{"www.*.example.com"}, nil},
+		// A more specific allow wins over a broader deny
+		{"specific", "", "", []string{"www.internal.example.com"}, []string{"*.internal.example.com"}},
+		// Deny wins over an allow of the same specificity
+		{"tie", "", "", []string{"*.example.org"}, []string{"*.example.org"}},
+		// Regular expressions only decide names no pattern matches
+		{"mixed", `\.example\.net$`, "", nil, []string{"*.internal.example.net"}},
+	}
+	for _, role := range roles {
+		if err := CreateRole(role.name, "", role.allow, role.deny, role.allowNames, role.denyNames, database); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		role    string
+		name    string
+		allowed bool
+	}{
+		{"subtree", "www.example.com", true},
+		{"subtree", "a.b.example.com", true},
+		{"subtree", "WWW.Example.COM.", true},
+		{"subtree", "example.com", false},
+		{"subtree", "secret.example.com", false},
+		{"subtree", "www.example.org", false},
+		{"middle", "www.eu.example.com", true},
+		{"middle", "www.a.b.example.com", false},
+		{"middle", "mail.eu.example.com", false},
+		{"specific", "www.internal.example.com", true},
+		{"specific", "db.internal.example.com", false},
+		{"tie", "www.example.org", false},
+		{"mixed", "www.example.net", true},
+		{"mixed", "db.internal.example.net", false},
+		{"mixed", "www.example.com", false},
+	}
+	for _, test := range tests {
+		if allowed, err := EvaluateRole(test.role, test.name, database); err != nil {
+			t.Fatal(err)
+		} else if allowed != test.allowed {
+			t.Errorf("%s on %s: expected allowed to be %t", test.role, test.name, test.allowed)
+		}
+	}
+}
+
+func TestValidateNamePattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{
+		"*.example.com":     true,
+		"www.*.example.com": true,
+		"example.com.":      true,
+		"w*.example.com":    false,
+		"www..example.com":  false,
+	} {
+		if err := ValidateNamePattern(pattern); (err == nil) != valid {
+			t.Errorf("%s: expected valid to be %t, got %v", pattern, valid, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// The last copy of a zone transferred from its primary
+type SecondaryZone struct {
+	// Records of the zone in presentation format, SOA first
+	Records []string `json:"records"`
+	// When the zone was last transferred, and last attempted, as unix timestamps
+	Transferred int64 `json:"transferred"`
+	Attempted   int64 `json:"attempted"`
+}
+
+// Get the copy of a zone kept as its secondary
+// The zone has no records if it was never transferred
+func GetSecondaryZone(zone string, db *Database) (SecondaryZone, error) {
+	var s SecondaryZone
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("secondary")).Get([]byte(strings.ToLower(zone))); len(value) != 0 {
+			return json.Unmarshal(value, &s)
+		}
+		return nil
+	})
+
+	return s, err
+}
+
+func SetSecondaryZone(zone string, s SecondaryZone, db *Database) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("secondary")).Put([]byte(strings.ToLower(zone)), data)
+	})
+}
@@ -3,29 +3,59 @@ package db
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	bolt "go.etcd.io/bbolt"
+	"strings"
 )
 
-func (s set) A(name, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("A")).Put([]byte(name), []byte(host))
-	})
+// Add addresses to the A records of a name, keeping those already stored
+func (s set) A(name string, hosts ...string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("A"))
+
+		var stored []string
+		if value := records.Get([]byte(name)); len(value) != 0 {
+			var err error
+			if stored, err = decodeAddresses(value); err != nil {
+				return err
+			}
+		}
+
+		for _, host := range hosts {
+			duplicate := false
+			for _, existing := range stored {
+				duplicate = duplicate || existing == host
+			}
+			if !duplicate {
+				stored = append(stored, host)
+			}
+		}
+
+		return putAddresses(records, name, stored)
+	}))
+}
+
+// Replace the A records of a name with the given addresses
+func (s set) ReplaceA(name string, hosts []string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		return putAddresses(tx.Bucket([]byte("A")), name, hosts)
+	}))
 }
 
 func (s set) AAAA(name, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("AAAA")).Put([]byte(name), []byte(host))
-	})
+	}))
 }
 
 func (s set)CNAME(name, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("CNAME")).Put([]byte(name), []byte(target))
-	})
+	}))
 }
 
 func (s set) MX(name string, priority uint16, host string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("MX"))
 
 		// Convert uint16 to binary
@@ -41,11 +71,11 @@ func (s set) MX(name string, priority uint16, host string) error {
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) LOC(name string, version, size, horizontal, vertical uint8, altitude uint32, latDegrees, latMinutes, latSeconds uint8, latDirection string, longDegrees, longMinutes, longSeconds uint8, longDirection string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("LOC"))
 
 		// Convert uint32s to binary
@@ -94,11 +124,11 @@ func (s set) LOC(name string, version, size, horizontal, vertical uint8, altitud
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) SRV(name string, priority, weight, port uint16, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SRV"))
 
 		// Convert uint16s to binary
@@ -124,11 +154,11 @@ func (s set) SRV(name string, priority, weight, port uint16, target string) erro
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) SPF(name string, text []string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		// Encode to JSON
 		arr, err := json.Marshal(text)
 		if err != nil {
@@ -137,11 +167,11 @@ func (s set) SPF(name string, text []string) error {
 
 		// Write to bucket
 		return tx.Bucket([]byte("SPF")).Put([]byte(name), arr)
-	})
+	}))
 }
 
 func (s set) TXT(name string, text []string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		// Encode to JSON
 		arr, err := json.Marshal(text)
 		if err != nil {
@@ -150,17 +180,17 @@ func (s set) TXT(name string, text []string) error {
 
 		// Write to bucket
 		return tx.Bucket([]byte("TXT")).Put([]byte(name), arr)
-	})
+	}))
 }
 
 func (s set) NS(name, nameserver string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("NS")).Put([]byte(name), []byte(nameserver))
-	})
+	}))
 }
 
 func (s set) CAA(name, tag, content string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CAA"))
 
 		if err := records.Put([]byte(name + "*tag"), []byte(tag)); err != nil {
@@ -171,17 +201,17 @@ func (s set) CAA(name, tag, content string) error {
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) PTR(name, domain string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		return tx.Bucket([]byte("PTR")).Put([]byte(name), []byte(domain))
-	})
+	}))
 }
 
 func (s set) CERT(name string, tpe, keytag uint16, algorithm uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("CERT"))
 
 		// Convert uint16s to binary
@@ -205,11 +235,11 @@ func (s set) CERT(name string, tpe, keytag uint16, algorithm uint8, certificate
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) DNSKEY(name string, flags uint16, protocol, algorithm uint8, publickey string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DNSKEY"))
 
 		// Convert uint16 to binary
@@ -231,11 +261,11 @@ func (s set) DNSKEY(name string, flags uint16, protocol, algorithm uint8, public
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) DS(name string, keytag uint16, algorithm, digesttype uint8, digest string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("DS"))
 
 		// Convert uint16 to binary
@@ -257,11 +287,11 @@ func (s set) DS(name string, keytag uint16, algorithm, digesttype uint8, digest
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) NAPTR(name string, order, preference uint16, flags, service, regexp, replacement string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("NAPTR"))
 
 		// Convert uint16s to binary
@@ -291,11 +321,11 @@ func (s set) NAPTR(name string, order, preference uint16, flags, service, regexp
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) SMIMEA(name string, usage, selector, matchingtype uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SMIMEA"))
 
 		// Write data to bucket
@@ -313,11 +343,11 @@ func (s set) SMIMEA(name string, usage, selector, matchingtype uint8, certificat
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) SSHFP(name string, algorithm, tpe uint8, fingerprint string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("SSHFP"))
 
 		// Write data to bucket
@@ -332,11 +362,11 @@ func (s set) SSHFP(name string, algorithm, tpe uint8, fingerprint string) error
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) TLSA(name string, usage, selector, matchingtype uint8, certificate string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("TLSA"))
 
 		// Write data to bucket
@@ -354,11 +384,11 @@ func (s set) TLSA(name string, usage, selector, matchingtype uint8, certificate
 		}
 
 		return nil
-	})
+	}))
 }
 
 func (s set) URI(name string, priority, weight uint16, target string) error {
-	return s.Db.Update(func(tx *bolt.Tx) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
 		records := tx.Bucket([]byte("URI"))
 
 		// Convert uint16s to binary
@@ -378,6 +408,241 @@ func (s set) URI(name string, priority, weight uint16, target string) error {
 			return err
 		}
 
+		return nil
+	}))
+}
+
+// A serial of 0 takes the serial after the one currently stored
+func (s set) SOA(name, nameserver, mailbox string, serial, refresh, retry, expire, minimum uint32) error {
+	return s.update(func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("SOA"))
+
+		// Increment the stored serial, skipping 0 when it wraps
+		if serial == 0 {
+			if serialValue := records.Get([]byte(name + "*serial")); len(serialValue) != 0 {
+				serial = binary.BigEndian.Uint32(serialValue) + 1
+			}
+			if serial == 0 {
+				serial = 1
+			}
+		}
+
+		// Convert integers to binary
+		fields := map[string]uint32{"serial": serial, "refresh": refresh, "retry": retry, "expire": expire, "minimum": minimum}
+		for field, value := range fields {
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, value)
+			if err := records.Put([]byte(name + "*" + field), b); err != nil {
+				return err
+			}
+		}
+
+		// Write data to bucket
+		if err := records.Put([]byte(name + "*nameserver"), []byte(nameserver)); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*mailbox"), []byte(mailbox)); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
+
+func (s set) CSYNC(name string, serial uint32, flags uint16, types []string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CSYNC"))
+
+		// Convert integers to binary
+		ser := make([]byte, 4)
+		binary.BigEndian.PutUint32(ser, serial)
+		fla := make([]byte, 2)
+		binary.BigEndian.PutUint16(fla, flags)
+
+		// Write data to bucket
+		if err := records.Put([]byte(name + "*serial"), ser); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*flags"), fla); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*types"), []byte(strings.Join(types, " "))); err != nil {
+			return err
+		}
+
+		return nil
+	}))
+}
+
+func (s set) AMTRELAY(name string, precedence uint8, discovery bool, relayType uint8, relay string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("AMTRELAY"))
+
+		// Convert boolean to a byte
+		var dis byte
+		if discovery {
+			dis = 1
+		}
+
+		// Write data to bucket
+		if err := records.Put([]byte(name + "*precedence"), []byte{precedence}); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*discovery"), []byte{dis}); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*type"), []byte{relayType}); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*relay"), []byte(relay)); err != nil {
+			return err
+		}
+
+		return nil
+	}))
+}
+
+func (s set) HINFO(name, cpu, os string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("HINFO"))
+
+		// Write data to bucket
+		if err := records.Put([]byte(name + "*cpu"), []byte(cpu)); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name + "*os"), []byte(os)); err != nil {
+			return err
+		}
+
+		return nil
+	}))
+}
+
+func (s set) CDNSKEY(name string, flags uint16, protocol, algorithm uint8, publickey string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte("CDNSKEY"))
+
+		// Convert uint16 to binary
+		flgs := make([]byte, binary.MaxVarintLen16)
+		binary.BigEndian.PutUint16(flgs, flags)
+
+		// Write data to bucket
+		if err := records.Put([]byte(name+"*flags"), flgs); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name+"*protocol"), []byte{protocol}); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name+"*algorithm"), []byte{algorithm}); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name+"*publickey"), []byte(publickey)); err != nil {
+			return err
+		}
+
+		return nil
+	}))
+}
+
+func (s set) SVCB(name string, priority uint16, target string, params map[string]string) error {
+	return s.serviceBinding("SVCB", name, priority, target, params)
+}
+
+func (s set) HTTPS(name string, priority uint16, target string, params map[string]string) error {
+	return s.serviceBinding("HTTPS", name, priority, target, params)
+}
+
+// Write an SVCB or HTTPS record, which are stored the same way
+func (s set) serviceBinding(recordType, name string, priority uint16, target string, params map[string]string) error {
+	return s.update(zoned(name, func(tx *bolt.Tx) error {
+		records := tx.Bucket([]byte(recordType))
+
+		// Convert uint16 to binary
+		prio := make([]byte, 2)
+		binary.BigEndian.PutUint16(prio, priority)
+
+		// Encode parameters to JSON
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+
+		// Write data to bucket
+		if err := records.Put([]byte(name+"*priority"), prio); err != nil {
+			return err
+		}
+		if err := records.Put([]byte(name+"*target"), []byte(target)); err != nil {
+			return err
+		}
+		return records.Put([]byte(name+"*params"), encoded)
+	}))
+}
+
+// Write any record under a name
+func (s set) Record(name string, record Record) error {
+	switch r := record.(type) {
+	case *A:
+		return s.ReplaceA(name, r.Hosts())
+	case *AAAA:
+		return s.AAAA(name, r.Address.String())
+	case *CNAME:
+		return s.CNAME(name, r.Target)
+	case *MX:
+		return s.MX(name, r.Priority, r.Host)
+	case *LOC:
+		return s.LOC(name, r.Version, r.Size, r.HorizontalPrecision, r.VerticalPrecision, r.Altitude, r.LatDegrees, r.LatMinutes, r.LatSeconds, r.LatDirection, r.LongDegrees, r.LongMinutes, r.LongSeconds, r.LongDirection)
+	case *SRV:
+		return s.SRV(name, r.Priority, r.Weight, r.Port, r.Target)
+	case *SPF:
+		return s.SPF(name, r.Text)
+	case *TXT:
+		return s.TXT(name, r.Text)
+	case *NS:
+		return s.NS(name, r.Nameserver)
+	case *CAA:
+		return s.CAA(name, r.Tag, r.Content)
+	case *PTR:
+		return s.PTR(name, r.Domain)
+	case *CERT:
+		return s.CERT(name, r.Type, r.KeyTag, r.Algorithm, r.Certificate)
+	case *DNSKEY:
+		return s.DNSKEY(name, r.Flags, r.Protocol, r.Algorithm, r.PublicKey)
+	case *DS:
+		return s.DS(name, r.KeyTag, r.Algorithm, r.DigestType, r.Digest)
+	case *NAPTR:
+		return s.NAPTR(name, r.Order, r.Preference, r.Flags, r.Service, r.Regexp, r.Replacement)
+	case *SMIMEA:
+		return s.SMIMEA(name, r.Usage, r.Selector, r.MatchingType, r.Certificate)
+	case *SSHFP:
+		return s.SSHFP(name, r.Algorithm, r.Type, r.Fingerprint)
+	case *TLSA:
+		return s.TLSA(name, r.Usage, r.Selector, r.MatchingType, r.Certificate)
+	case *URI:
+		return s.URI(name, r.Priority, r.Weight, r.Target)
+	case *CSYNC:
+		return s.CSYNC(name, r.Serial, r.Flags, r.Types)
+	case *AMTRELAY:
+		return s.AMTRELAY(name, r.Precedence, r.Discovery, r.RelayType, r.Relay)
+	case *HINFO:
+		return s.HINFO(name, r.CPU, r.OS)
+	case *CDNSKEY:
+		return s.CDNSKEY(name, r.Flags, r.Protocol, r.Algorithm, r.PublicKey)
+	case *SVCB:
+		return s.SVCB(name, r.Priority, r.Target, r.Params)
+	case *HTTPS:
+		return s.HTTPS(name, r.Priority, r.Target, r.Params)
+	case *SOA:
+		return s.SOA(name, r.Nameserver, r.Mailbox, r.Serial, r.Refresh, r.Retry, r.Expire, r.Minimum)
+	default:
+		return fmt.Errorf("unsupported record type %T", record)
+	}
+}
+
+// Store a set of addresses in order as a JSON array
+func putAddresses(records *bolt.Bucket, name string, hosts []string) error {
+	encoded, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+	return records.Put([]byte(name), encoded)
+}
@@ -7,7 +7,7 @@ import (
 	"log"
 )
 
-func Setup(db *bolt.DB) error {
+func Setup(db *Database) error {
 	// Create buckets for data
 	if err := db.Update(func(tx *bolt.Tx) error {
 		// Setup records
@@ -30,11 +30,33 @@ func Setup(db *bolt.DB) error {
 		if _, err := tx.CreateBucketIfNotExists([]byte("SSHFP")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("TLSA")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("URI")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("CSYNC")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("AMTRELAY")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("HINFO")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("CDNSKEY")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("SVCB")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("HTTPS")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("SOA")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("metadata")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("journal")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("zones")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("signing-keys")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("secondary")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("deleted")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("health")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("groups")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("subscriptions")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("deliveries")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("history")); err != nil { return err }
 
 		// Setup authentication
 		if _, err := tx.CreateBucketIfNotExists([]byte("users")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("tokens")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("refresh-tokens")); err != nil { return err }
 		if _, err := tx.CreateBucketIfNotExists([]byte("roles")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("audit")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("audit-time")); err != nil { return err }
+		if _, err := tx.CreateBucketIfNotExists([]byte("keys")); err != nil { return err }
 		return nil
 	}); err != nil {
 		return err
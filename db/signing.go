@@ -0,0 +1,178 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when a zone has no key with the given tag
+var ErrSigningKeyNotFound = fmt.Errorf("zone has no key with the given tag")
+
+// Stages a signing key moves through during a rollover
+const (
+	// Published in the zone's DNSKEY records so resolvers cache it before use
+	KeyPrePublished = "pre-publish"
+	// Published and used to sign answers
+	KeyActive = "active"
+	// Replaced by a newer key but still published, so signatures made with it
+	// that resolvers have cached keep validating until they expire
+	KeyInactive = "inactive"
+	// No longer published or used, kept until deleted
+	KeyRetired = "retired"
+)
+
+// A key a zone is signed with
+type SigningKey struct {
+	KeyTag    uint16 `json:"key-tag"`
+	Flags     uint16 `json:"flags"`
+	Algorithm uint8  `json:"algorithm"`
+	PublicKey string `json:"public-key"`
+	State     string `json:"state"`
+	Created   int64  `json:"created"`
+	Changed   int64  `json:"changed"`
+	// Private key in the BIND format, left out whenever keys are listed
+	PrivateKey string `json:"private-key,omitempty"`
+}
+
+// Keys of a zone are kept together under the zone's name
+func signingKeyPrefix(zone string) []byte {
+	return []byte(strings.ToLower(zone) + "*")
+}
+
+func signingKeyKey(zone string, tag uint16) []byte {
+	return append(signingKeyPrefix(zone), strconv.FormatUint(uint64(tag), 10)...)
+}
+
+// Get every key of a zone, in the order they were added
+// Private keys are only included when asked for
+func GetSigningKeys(zone string, private bool, db *Database) ([]SigningKey, error) {
+	keys := []SigningKey{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		prefix := signingKeyPrefix(zone)
+		cursor := tx.Bucket([]byte("signing-keys")).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = cursor.Next() {
+			var key SigningKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			if !private {
+				key.PrivateKey = ""
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+
+	// Keys are stored by tag, so order them by age instead
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Created < keys[j].Created })
+	return keys, err
+}
+
+// Add a new key to a zone, pre-published until it is activated
+func AddSigningKey(zone string, key SigningKey, db *Database) (SigningKey, error) {
+	key.State = KeyPrePublished
+	key.Created = time.Now().Unix()
+	key.Changed = key.Created
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("signing-keys"))
+		if bucket.Get(signingKeyKey(zone, key.KeyTag)) != nil {
+			return fmt.Errorf("zone already has a key with tag %d", key.KeyTag)
+		}
+
+		data, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(signingKeyKey(zone, key.KeyTag), data)
+	})
+
+	key.PrivateKey = ""
+	return key, err
+}
+
+// Move a key of a zone on to the next stage of its rollover
+// A key can only be activated from pre-publication and retired once it has
+// been used. Activating a key deactivates the key of the same kind it
+// replaces, which stays published until it is retired once cached
+// signatures made with it have expired
+func TransitionSigningKey(zone string, tag uint16, state string, db *Database) (SigningKey, error) {
+	var key SigningKey
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("signing-keys"))
+		value := bucket.Get(signingKeyKey(zone, tag))
+		if value == nil {
+			return ErrSigningKeyNotFound
+		} else if err := json.Unmarshal(value, &key); err != nil {
+			return err
+		}
+
+		if (state == KeyActive && key.State != KeyPrePublished) || (state == KeyRetired && key.State != KeyActive && key.State != KeyInactive) || (state != KeyActive && state != KeyRetired) {
+			return fmt.Errorf("key in state '%s' can't move to state '%s'", key.State, state)
+		}
+		now := time.Now().Unix()
+
+		// Stop signing with the key being replaced
+		if state == KeyActive {
+			prefix := signingKeyPrefix(zone)
+			cursor := bucket.Cursor()
+			replaced := map[string][]byte{}
+			for k, v := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = cursor.Next() {
+				var other SigningKey
+				if err := json.Unmarshal(v, &other); err != nil {
+					return err
+				} else if other.State != KeyActive || other.Flags != key.Flags {
+					continue
+				}
+
+				other.State, other.Changed = KeyInactive, now
+				data, err := json.Marshal(other)
+				if err != nil {
+					return err
+				}
+				replaced[string(k)] = data
+			}
+			for k, data := range replaced {
+				if err := bucket.Put([]byte(k), data); err != nil {
+					return err
+				}
+			}
+		}
+
+		key.State, key.Changed = state, now
+		data, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(signingKeyKey(zone, tag), data)
+	})
+
+	key.PrivateKey = ""
+	return key, err
+}
+
+// Remove a key of a zone that is neither used nor published for past use
+func DeleteSigningKey(zone string, tag uint16, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("signing-keys"))
+		value := bucket.Get(signingKeyKey(zone, tag))
+		if value == nil {
+			return ErrSigningKeyNotFound
+		}
+
+		var key SigningKey
+		if err := json.Unmarshal(value, &key); err != nil {
+			return err
+		} else if key.State == KeyActive || key.State == KeyInactive {
+			return fmt.Errorf("%s key must be retired before it is deleted", key.State)
+		}
+		return bucket.Delete(signingKeyKey(zone, tag))
+	})
+}
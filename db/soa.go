@@ -0,0 +1,73 @@
+package db
+
+import (
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"strings"
+)
+
+// Timers of SOA records created for zones without one
+const (
+	defaultSOARefresh = 3600
+	defaultSOARetry   = 600
+	defaultSOAExpire  = 604800
+	defaultSOAMinimum = 300
+)
+
+// Get the nameserver of SOA records created for zones without one, the host
+// name of the server unless configured
+func defaultSOANameserver() string {
+	nameserver := viper.GetString("records.auto-soa.nameserver")
+	if nameserver == "" {
+		nameserver, _ = os.Hostname()
+	}
+	if !strings.HasSuffix(nameserver, ".") {
+		nameserver += "."
+	}
+	return strings.ToLower(nameserver)
+}
+
+// Keep the SOA of the zone a changed record belongs to in step with the
+// change, within the same transaction
+func zoneChanged(tx *bolt.Tx, name string, written bool) error {
+	zone := ZoneFor(name)
+	if zone == "" {
+		return nil
+	}
+
+	// Make a zone servable as soon as its first record is written
+	soa := get{Tx: tx}.SOA(zone)
+	if soa == nil {
+		if !written || !viper.GetBool("records.auto-soa.enabled") {
+			return nil
+		}
+		return set{Tx: tx}.SOA(strings.TrimSuffix(zone, "."), defaultSOANameserver(), "hostmaster."+zone, 1, defaultSOARefresh, defaultSOARetry, defaultSOAExpire, defaultSOAMinimum)
+	}
+
+	// Move the serial on so secondaries pick up the change
+	if !viper.GetBool("records.bump-serial") {
+		return nil
+	}
+	return set{Tx: tx}.SOA(strings.TrimSuffix(zone, "."), soa.Nameserver, soa.Mailbox, 0, soa.Refresh, soa.Retry, soa.Expire, soa.Minimum)
+}
+
+// Update the SOA of the zone a record belongs to once a write succeeds
+func zoned(name string, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return zoneChanged(tx, name, true)
+	}
+}
+
+// Update the SOA of the zone a record belongs to once a delete succeeds
+func zonedDelete(name string, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return zoneChanged(tx, name, false)
+	}
+}
@@ -13,15 +13,118 @@ var (
 
 // Getters for different record types
 type get struct {
-	Db *bolt.DB
+	Db *Database
+	Tx *bolt.Tx
 }
 
 // Setters for different record types
 type set struct {
-	Db *bolt.DB
+	Db *Database
+	Tx *bolt.Tx
+	// Written to the audit log along with each change when set
+	Audit *AuditEntry
+	// Run in the same transaction as each change when set, such as to claim
+	// the record and write its metadata
+	Metadata func(tx *bolt.Tx) error
 }
 
 // Delete different record types
 type deleteRecord struct {
-	Db *bolt.DB
+	Db *Database
+	Tx *bolt.Tx
+	// Written to the audit log along with each change when set
+	Audit *AuditEntry
+}
+
+// Run a read in the batch transaction if one is open
+func (g get) view(fn func(tx *bolt.Tx) error) error {
+	if g.Tx != nil {
+		return fn(g.Tx)
+	}
+	return g.Db.View(fn)
+}
+
+// Run gets within an already open transaction
+func (g get) WithTx(tx *bolt.Tx) get {
+	return get{Db: g.Db, Tx: tx}
+}
+
+// Run a write in the open transaction if there is one
+func (s set) update(fn func(tx *bolt.Tx) error) error {
+	if s.Metadata != nil {
+		fn = then(fn, s.Metadata)
+	}
+	if s.Audit != nil {
+		fn = versioned(s.Audit, audited(s.Audit, journaled(s.Audit, fn)))
+	}
+	if s.Tx != nil {
+		return fn(s.Tx)
+	}
+	return s.Db.Update(fn)
+}
+
+// Run sets within an already open transaction
+func (s set) WithTx(tx *bolt.Tx) set {
+	return set{Db: s.Db, Tx: tx, Audit: s.Audit, Metadata: s.Metadata}
+}
+
+// Record sets in the audit log as the given entry
+func (s set) WithAudit(entry *AuditEntry) set {
+	return set{Db: s.Db, Tx: s.Tx, Audit: entry, Metadata: s.Metadata}
+}
+
+// Run a function in the same transaction as each set once it succeeds
+func (s set) WithMetadata(fn func(tx *bolt.Tx) error) set {
+	return set{Db: s.Db, Tx: s.Tx, Audit: s.Audit, Metadata: fn}
+}
+
+// Run a delete in the open transaction if there is one
+func (d deleteRecord) update(fn func(tx *bolt.Tx) error) error {
+	if d.Audit != nil {
+		fn = audited(d.Audit, journaled(d.Audit, fn))
+	}
+	if d.Tx != nil {
+		return fn(d.Tx)
+	}
+	return d.Db.Update(fn)
+}
+
+// Run deletes within an already open transaction
+func (d deleteRecord) WithTx(tx *bolt.Tx) deleteRecord {
+	return deleteRecord{Db: d.Db, Tx: tx, Audit: d.Audit}
+}
+
+// Record deletes in the audit log as the given entry
+func (d deleteRecord) WithAudit(entry *AuditEntry) deleteRecord {
+	return deleteRecord{Db: d.Db, Tx: d.Tx, Audit: entry}
+}
+
+// Add the written value to the history of the record once a write succeeds
+func versioned(entry *AuditEntry, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return appendHistory(tx, entry.Resource, entry.Type, entry.Username)
+	}
+}
+
+// Append an entry to the audit log once a write succeeds
+func audited(entry *AuditEntry, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return WriteAudit(entry, tx)
+	}
+}
+
+// Run a second function in a transaction once the first succeeds
+func then(fn, next func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return next(tx)
+	}
 }
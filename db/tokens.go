@@ -6,16 +6,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/spf13/viper"
 	bolt "go.etcd.io/bbolt"
+	"log"
 	"time"
 )
 
+// Clock tokens are issued, checked and pruned against, replaced in tests to
+// move expiry along
+var Now = time.Now
+
+func init() {
+	// Check token claims against the same clock
+	jwt.TimeFunc = func() time.Time { return Now() }
+}
+
 type Token struct {
 	SigningKey string `json:"signing-key"`
 	Username   string `json:"username"`
+	Expires    int64  `json:"expires,omitempty"`
 }
 
-func NewToken(user User, db *bolt.DB) (string, error) {
+func NewToken(user User, db *Database) (string, error) {
 	// Generate key
 	signingKey := make([]byte, 128)
 	if _, err := rand.Read(signingKey); err != nil {
@@ -23,10 +35,11 @@ func NewToken(user User, db *bolt.DB) (string, error) {
 	}
 
 	// Create claims
+	expires := Now().Add(viper.GetDuration("http.token-ttl")).Unix()
 	claims := &jwt.StandardClaims{
-		ExpiresAt: time.Now().Unix() + (60*60*24),
+		ExpiresAt: expires,
 		Issuer: "dns.iznow",
-		IssuedAt: time.Now().Unix(),
+		IssuedAt: Now().Unix(),
 		Subject: user.Username,
 	}
 
@@ -45,6 +58,7 @@ func NewToken(user User, db *bolt.DB) (string, error) {
 	t := Token{
 		SigningKey: base64.StdEncoding.EncodeToString(signingKey),
 		Username: user.Username,
+		Expires: expires,
 	}
 	j, err := json.Marshal(t)
 	if err != nil {
@@ -66,7 +80,7 @@ func NewToken(user User, db *bolt.DB) (string, error) {
 	return signed, nil
 }
 
-func TokenFromString(tokenStr string, db *bolt.DB) (*jwt.Token, error) {
+func TokenFromString(tokenStr string, db *Database) (*jwt.Token, error) {
 	// Retrieve token
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (i interface{}, e error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -97,7 +111,18 @@ func TokenFromString(tokenStr string, db *bolt.DB) (*jwt.Token, error) {
 
 		return signingKey, nil
 	})
-	if err != nil {
+	if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors == jwt.ValidationErrorExpired {
+		// Remove the expired token now rather than waiting for the next sweep
+		// Only expiry may have failed so the signature is known to be valid
+		if kid, ok := token.Header["kid"].(string); ok {
+			if err := db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte("tokens")).Delete([]byte(kid))
+			}); err != nil {
+				log.Printf("Failed to remove expired token '%s': %v", kid, err)
+			}
+		}
+		return nil, fmt.Errorf("token has expired")
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -108,3 +133,73 @@ func TokenFromString(tokenStr string, db *bolt.DB) (*jwt.Token, error) {
 
 	return token, nil
 }
+
+// Remove expired tokens from the database, returning how many were removed
+func PruneTokens(db *Database) (int, error) {
+	pruned := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket([]byte("tokens"))
+
+		// Collect keys first as the bucket can't be changed while iterating
+		var expired [][]byte
+		now := Now().Unix()
+		if err := tokens.ForEach(func(k, v []byte) error {
+			var t Token
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.Expires != 0 && t.Expires < now {
+				expired = append(expired, k)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := tokens.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+		return nil
+	})
+	return pruned, err
+}
+
+// Remove every token belonging to a user, returning how many were removed
+func RevokeTokens(username string, db *Database) (int, error) {
+	revoked := 0
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		revoked, err = RevokeTokensTx(username, tx)
+		return err
+	})
+	return revoked, err
+}
+
+// Remove every token belonging to a user within an open transaction
+func RevokeTokensTx(username string, tx *bolt.Tx) (int, error) {
+	tokens := tx.Bucket([]byte("tokens"))
+
+	// Key prefixes are ambiguous between usernames containing dashes
+	var owned [][]byte
+	if err := tokens.ForEach(func(k, v []byte) error {
+		var t Token
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		if t.Username == username {
+			owned = append(owned, k)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, k := range owned {
+		if err := tokens.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(owned), nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+// Move the token clock forward for the rest of a test
+func advanceClock(t *testing.T, d time.Duration) {
+	t.Helper()
+	previous := Now
+	Now = func() time.Time { return previous().Add(d) }
+	t.Cleanup(func() { Now = previous })
+}
+
+func TestTokenExpiry(t *testing.T) {
+	database := testDatabase(t)
+	viper.Set("http.token-ttl", time.Hour)
+
+	user := NewUser("Test", "test", "", "admin")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	token, err := NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advanceClock(t, 30*time.Minute)
+	if _, err := TokenFromString(token, database); err != nil {
+		t.Fatalf("expected the token to be valid before it expires: %v", err)
+	}
+
+	advanceClock(t, time.Hour)
+	if _, err := TokenFromString(token, database); err == nil || err.Error() != "token has expired" {
+		t.Fatalf("expected the token to have expired, got %v", err)
+	}
+
+	// The expired token was removed when it was used
+	if pruned, err := PruneTokens(database); err != nil {
+		t.Fatal(err)
+	} else if pruned != 0 {
+		t.Fatalf("expected nothing left to prune, pruned %d", pruned)
+	}
+}
+
+func TestPruneTokens(t *testing.T) {
+	database := testDatabase(t)
+	viper.Set("http.token-ttl", time.Hour)
+
+	user := NewUser("Test", "test", "", "admin")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the user so each token gets its own key id
+	for i := 0; i < 2; i++ {
+		user, err := UserFromDatabase("test", database)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := NewToken(user, database); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if pruned, err := PruneTokens(database); err != nil {
+		t.Fatal(err)
+	} else if pruned != 0 {
+		t.Fatalf("expected no tokens to be pruned before they expire, pruned %d", pruned)
+	}
+
+	advanceClock(t, 2*time.Hour)
+	if pruned, err := PruneTokens(database); err != nil {
+		t.Fatal(err)
+	} else if pruned != 2 {
+		t.Fatalf("expected both expired tokens to be pruned, pruned %d", pruned)
+	}
+}
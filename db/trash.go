@@ -0,0 +1,185 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotTrashed   = fmt.Errorf("specified record is not in the trash")
+	ErrRecordExists = fmt.Errorf("a record of the same name and type exists")
+)
+
+// A deleted record kept until it is restored or purged
+type TrashedRecord struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Deleted  int64           `json:"deleted"`
+	Record   json.RawMessage `json:"record"`
+	Metadata Metadata        `json:"metadata"`
+}
+
+// Keys hold the deletion time so a name can be deleted again after being
+// recreated without replacing the older copy
+func trashKey(name, recordType string, deleted time.Time) []byte {
+	return []byte(name + "*" + strings.ToUpper(recordType) + "*" + strconv.FormatInt(deleted.UnixNano(), 10))
+}
+
+// Move a record and its metadata into the trash
+// Deleting a record that does not exist leaves nothing in the trash
+func (d deleteRecord) Trash(name, recordType string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		record := get{Db: d.Db, Tx: tx}.Record(name+".", recordType)
+		if record == nil {
+			return deleteRecord{Db: d.Db, Tx: tx}.Record(name, recordType)
+		}
+
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		trashed := TrashedRecord{
+			ID:      string(trashKey(name, recordType, now)),
+			Name:    name,
+			Type:    strings.ToUpper(recordType),
+			Deleted: now.Unix(),
+			Record:  encoded,
+		}
+		if value := tx.Bucket([]byte("metadata")).Get(metadataKey(name, recordType)); len(value) != 0 {
+			if err := json.Unmarshal(value, &trashed.Metadata); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(trashed)
+		if err != nil {
+			return err
+		} else if err := tx.Bucket([]byte("deleted")).Put([]byte(trashed.ID), data); err != nil {
+			return err
+		}
+		return deleteRecord{Db: d.Db, Tx: tx}.Record(name, recordType)
+	})
+}
+
+// Move a record out of the trash along with its metadata
+// Fails with ErrNotTrashed once the record has been purged, or with
+// ErrRecordExists if a record of the same name and type was created since
+func (s set) Restore(id string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		deleted := tx.Bucket([]byte("deleted"))
+
+		value := deleted.Get([]byte(id))
+		if len(value) == 0 {
+			return ErrNotTrashed
+		}
+		var trashed TrashedRecord
+		if err := json.Unmarshal(value, &trashed); err != nil {
+			return err
+		}
+
+		if (get{Db: s.Db, Tx: tx}).Record(trashed.Name+".", trashed.Type) != nil {
+			return ErrRecordExists
+		}
+
+		record := NewRecord(trashed.Type)
+		if record == nil {
+			return fmt.Errorf("unsupported record type '%s'", trashed.Type)
+		} else if err := json.Unmarshal(trashed.Record, record); err != nil {
+			return err
+		} else if err := (set{Db: s.Db, Tx: tx}).Record(trashed.Name, record); err != nil {
+			return err
+		}
+
+		metadata, err := json.Marshal(trashed.Metadata)
+		if err != nil {
+			return err
+		} else if err := tx.Bucket([]byte("metadata")).Put(metadataKey(trashed.Name, trashed.Type), metadata); err != nil {
+			return err
+		}
+		return deleted.Delete([]byte(id))
+	})
+}
+
+// Get a record in the trash by its ID
+// Returns false if no such record is in the trash
+func GetTrashed(id string, db *Database) (TrashedRecord, bool, error) {
+	var trashed TrashedRecord
+	exists := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("deleted")).Get([]byte(id)); len(value) != 0 {
+			exists = true
+			return json.Unmarshal(value, &trashed)
+		}
+		return nil
+	})
+
+	return trashed, exists, err
+}
+
+// Get every record in the trash
+func ListTrash(db *Database) ([]TrashedRecord, error) {
+	trash := []TrashedRecord{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("deleted")).ForEach(func(k, v []byte) error {
+			var trashed TrashedRecord
+			if err := json.Unmarshal(v, &trashed); err != nil {
+				return err
+			}
+			trash = append(trash, trashed)
+			return nil
+		})
+	})
+
+	return trash, err
+}
+
+// Permanently remove records deleted before a time, returning how many were
+// removed
+// Each removal is added to the record's journal and the audit log as a purge
+// by the system
+func PurgeTrash(before time.Time, db *Database) (int, error) {
+	purged := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		deleted := tx.Bucket([]byte("deleted"))
+
+		// Collect records first as the bucket can't be changed while iterating
+		var expired []TrashedRecord
+		if err := deleted.ForEach(func(k, v []byte) error {
+			var trashed TrashedRecord
+			if err := json.Unmarshal(v, &trashed); err != nil {
+				return err
+			}
+			if trashed.Deleted < before.Unix() {
+				expired = append(expired, trashed)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		for _, trashed := range expired {
+			if err := deleted.Delete([]byte(trashed.ID)); err != nil {
+				return err
+			}
+			change := RecordChange{Time: now, Username: "system", Operation: "purge", Before: trashed.Record}
+			if err := appendJournal(trashed.Name, trashed.Type, change, tx); err != nil {
+				return err
+			} else if err := WriteAudit(&AuditEntry{Time: now, Username: "system", Operation: "purge", Resource: trashed.Name, Type: trashed.Type}, tx); err != nil {
+				return err
+			}
+		}
+		purged = len(expired)
+		return nil
+	})
+	return purged, err
+}
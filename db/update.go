@@ -0,0 +1,81 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// A grant letting an updater change records of a zone through dynamic
+// updates (RFC 2136)
+type UpdateGrant struct {
+	// Name of the TSIG key updates must be signed with, any if empty
+	Key string `json:"key,omitempty"`
+	// Network updates must come from, any if empty
+	Source string `json:"source,omitempty"`
+	// Pattern of the names that may be changed, as in the names of a role
+	Name string `json:"name"`
+	// Types that may be changed, any if empty
+	Types []string `json:"types,omitempty"`
+}
+
+// Check that a grant identifies its updater and only names valid patterns
+// and types
+func (g UpdateGrant) Validate() error {
+	if g.Key == "" && g.Source == "" {
+		return fmt.Errorf("grant must name a key, a source or both")
+	} else if _, _, err := net.ParseCIDR(g.Source); g.Source != "" && err != nil {
+		return fmt.Errorf("grant source '%s' must be a network in CIDR notation", g.Source)
+	} else if err := ValidateNamePattern(g.Name); err != nil {
+		return err
+	}
+
+	for _, recordType := range g.Types {
+		valid := false
+		for _, t := range RecordTypes {
+			valid = valid || t == strings.ToUpper(recordType)
+		}
+		if !valid {
+			return fmt.Errorf("grant type '%s' must be one of: %s", recordType, strings.Join(RecordTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// Check if a grant lets the updater signing with key from ip change records of
+// a type at a name
+// Only grants without a type restriction allow changing all types at once,
+// given as ANY
+func (g UpdateGrant) Allows(key string, ip net.IP, name, recordType string) bool {
+	if g.Key != "" && !strings.EqualFold(strings.TrimSuffix(g.Key, "."), strings.TrimSuffix(key, ".")) {
+		return false
+	}
+	if g.Source != "" {
+		if _, network, err := net.ParseCIDR(g.Source); err != nil || ip == nil || !network.Contains(ip) {
+			return false
+		}
+	}
+	if _, _, ok := matchNamePattern(g.Name, name); !ok {
+		return false
+	}
+
+	if len(g.Types) == 0 {
+		return true
+	}
+	for _, t := range g.Types {
+		if strings.EqualFold(t, recordType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if any grant of a zone's update policy allows a change
+func (s ZoneSettings) UpdateAllowed(key string, ip net.IP, name, recordType string) bool {
+	for _, grant := range s.UpdatePolicy {
+		if grant.Allows(key, ip, name, recordType) {
+			return true
+		}
+	}
+	return false
+}
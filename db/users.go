@@ -13,6 +13,9 @@ type User struct {
 	Password string `json:"password"`
 	Role     string `json:"role"`
 	Tokens   int64  `json:"tokens"`
+
+	// Set when the password must be changed before the next login
+	ResetRequired bool `json:"reset-required"`
 }
 
 func NewUser(name, username, password, role string) User {
@@ -25,7 +28,7 @@ func NewUser(name, username, password, role string) User {
 	}
 }
 
-func UserFromToken(token *jwt.Token, db *bolt.DB) (User, error) {
+func UserFromToken(token *jwt.Token, db *Database) (User, error) {
 	// Get username from token
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
@@ -41,7 +44,7 @@ func UserFromToken(token *jwt.Token, db *bolt.DB) (User, error) {
 	return user, nil
 }
 
-func UserFromDatabase(username string, db *bolt.DB) (User, error) {
+func UserFromDatabase(username string, db *Database) (User, error) {
 	var u User
 
 	err := db.View(func(tx *bolt.Tx) error {
@@ -58,13 +61,63 @@ func UserFromDatabase(username string, db *bolt.DB) (User, error) {
 	return u, err
 }
 
-func (u *User) Encode(db *bolt.DB) error {
+func (u *User) Encode(db *Database) error {
+	return db.Update(u.EncodeTx)
+}
+
+// Write a user within an open transaction
+func (u *User) EncodeTx(tx *bolt.Tx) error {
 	j, err := json.Marshal(u)
 	if err != nil {
 		return err
 	}
 
-	return db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket([]byte("users")).Put([]byte(u.Username), j)
+	return tx.Bucket([]byte("users")).Put([]byte(u.Username), j)
+}
+
+// Require a password reset from all users whose hash is not compliant
+// Returns the usernames of the users flagged
+func FlagUsers(compliant func(hash string) bool, db *Database) ([]string, error) {
+	var flagged []string
+	err := db.Update(func(tx *bolt.Tx) (err error) {
+		flagged, err = FlagUsersTx(compliant, tx)
+		return err
 	})
+	return flagged, err
+}
+
+// Require a password reset from all users whose hash is not compliant within
+// an open transaction
+func FlagUsersTx(compliant func(hash string) bool, tx *bolt.Tx) ([]string, error) {
+	flagged := []string{}
+	users := tx.Bucket([]byte("users"))
+
+	// Collect users first as the bucket cannot be modified while iterating
+	var pending []User
+	if err := users.ForEach(func(k, v []byte) error {
+		var u User
+		if err := json.Unmarshal(v, &u); err != nil {
+			return err
+		}
+
+		if !u.ResetRequired && !compliant(u.Password) {
+			pending = append(pending, u)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, u := range pending {
+		u.ResetRequired = true
+		data, err := json.Marshal(u)
+		if err != nil {
+			return nil, err
+		}
+		if err := users.Put([]byte(u.Username), data); err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, u.Username)
+	}
+	return flagged, nil
 }
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// Events a webhook subscription can be notified of
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// Returned when a user has no subscription with the given id
+var ErrSubscriptionNotFound = fmt.Errorf("subscription does not exist")
+
+// A user's request to be notified of changes to records through a webhook
+type Subscription struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	// Pattern of the names to be notified about, as in the names of a role
+	Pattern string `json:"pattern"`
+	Created int64  `json:"created"`
+}
+
+// A notification waiting to be delivered to a subscription
+// Each subscription is retried on its own, so one failing endpoint does not
+// hold back the others
+type Delivery struct {
+	Subscription string `json:"subscription"`
+	Event        string `json:"event"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Username     string `json:"username"`
+	Time         int64  `json:"time"`
+	Attempts     int    `json:"attempts"`
+	// When the next attempt is due and why the last one failed
+	NextAttempt int64  `json:"next-attempt"`
+	LastError   string `json:"last-error,omitempty"`
+}
+
+// Get the webhook event a change in the audit log amounts to, if any
+func auditEvent(operation string) string {
+	switch operation {
+	case "create", "restore":
+		return EventCreated
+	case "update":
+		return EventUpdated
+	case "delete":
+		return EventDeleted
+	}
+	return ""
+}
+
+// Check if a subscription wants to be notified of an event about a name
+func (s Subscription) Matches(event, name string) bool {
+	if _, _, ok := matchNamePattern(s.Pattern, name); !ok {
+		return false
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Add a subscription for a user
+func CreateSubscription(s Subscription, db *Database) (Subscription, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return s, err
+	}
+	s.ID = hex.EncodeToString(raw)
+	s.Created = time.Now().Unix()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return s, err
+	}
+	return s, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("subscriptions")).Put([]byte(s.ID), data)
+	})
+}
+
+// Get every subscription, or only those of a user if given
+func ListSubscriptions(username string, db *Database) ([]Subscription, error) {
+	subscriptions := []Subscription{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("subscriptions")).ForEach(func(k, v []byte) error {
+			var s Subscription
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			} else if username == "" || s.Username == username {
+				subscriptions = append(subscriptions, s)
+			}
+			return nil
+		})
+	})
+
+	return subscriptions, err
+}
+
+// Remove a subscription of a user along with its pending deliveries
+func DeleteSubscription(id, username string, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("subscriptions"))
+		var s Subscription
+		if value := bucket.Get([]byte(id)); value == nil {
+			return ErrSubscriptionNotFound
+		} else if err := json.Unmarshal(value, &s); err != nil {
+			return err
+		} else if s.Username != username {
+			return ErrSubscriptionNotFound
+		}
+
+		deliveries := tx.Bucket([]byte("deliveries"))
+		var pending [][]byte
+		prefix := []byte(id + "*")
+		cursor := deliveries.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = cursor.Next() {
+			pending = append(pending, append([]byte{}, k...))
+		}
+		for _, k := range pending {
+			if err := deliveries.Delete(k); err != nil {
+				return err
+			}
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// Queue a delivery to every subscription wanting to know of a change to a
+// record, within the transaction making the change
+func queueDeliveries(entry *AuditEntry, tx *bolt.Tx) error {
+	event := auditEvent(entry.Operation)
+	if event == "" {
+		return nil
+	}
+
+	deliveries := tx.Bucket([]byte("deliveries"))
+	return tx.Bucket([]byte("subscriptions")).ForEach(func(k, v []byte) error {
+		var s Subscription
+		if err := json.Unmarshal(v, &s); err != nil {
+			return err
+		} else if !s.Matches(event, entry.Resource) {
+			return nil
+		}
+
+		// Keys are sequential within a subscription to deliver in order
+		sequence, err := deliveries.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, sequence)
+
+		data, err := json.Marshal(Delivery{Subscription: s.ID, Event: event, Name: entry.Resource, Type: entry.Type, Username: entry.Username, Time: entry.Time, NextAttempt: entry.Time})
+		if err != nil {
+			return err
+		}
+		return deliveries.Put(append([]byte(s.ID+"*"), key...), data)
+	})
+}
+
+// Get the pending deliveries of every subscription, oldest first
+func PendingDeliveries(db *Database) (map[string][]Delivery, error) {
+	pending := map[string][]Delivery{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("deliveries")).ForEach(func(k, v []byte) error {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			pending[d.Subscription] = append(pending[d.Subscription], d)
+			return nil
+		})
+	})
+
+	return pending, err
+}
+
+// Record the outcome of an attempt at the oldest pending delivery of a
+// subscription, removing it once delivered or given up on
+func FinishDelivery(d Delivery, done bool, db *Database) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket([]byte("deliveries"))
+		prefix := []byte(d.Subscription + "*")
+		k, _ := deliveries.Cursor().Seek(prefix)
+		if k == nil || !strings.HasPrefix(string(k), string(prefix)) {
+			return nil
+		} else if done {
+			return deliveries.Delete(k)
+		}
+
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		return deliveries.Put(append([]byte{}, k...), data)
+	})
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"encoding/json"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+)
+
+// Get the origins of all zones served, as lowercase absolute names
+func Zones() []string {
+	var zones []string
+	for _, zone := range viper.GetStringSlice("dns.zones") {
+		zone = strings.ToLower(zone)
+		if !strings.HasSuffix(zone, ".") {
+			zone += "."
+		}
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+// Get the closest zone a name belongs to
+// Returns an empty string if the name is outside of all zones
+func ZoneFor(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	zone := ""
+	for _, origin := range Zones() {
+		if (name == origin || origin == "." || strings.HasSuffix(name, "."+origin)) && len(origin) > len(zone) {
+			zone = origin
+		}
+	}
+	return zone
+}
+
+// Settings applying to all records within a zone
+type ZoneSettings struct {
+	Maintenance bool   `json:"maintenance"`
+	MaxTTL      uint32 `json:"max-ttl"`
+	// When the zone last left maintenance, as a unix timestamp
+	Recovered int64 `json:"recovered,omitempty"`
+	// ZONEMD digest served at the apex while the SOA serial matches
+	Digest       string `json:"digest,omitempty"`
+	DigestSerial uint32 `json:"digest-serial,omitempty"`
+	// Whether answers are signed with the zone's active keys
+	Signed bool `json:"signed"`
+	// Grants of dynamic updates, which are refused without any
+	UpdatePolicy []UpdateGrant `json:"update-policy,omitempty"`
+}
+
+func GetZoneSettings(zone string, db *Database) (ZoneSettings, error) {
+	var s ZoneSettings
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte("zones")).Get([]byte(zone)); len(value) != 0 {
+			return json.Unmarshal(value, &s)
+		}
+		return nil
+	})
+
+	return s, err
+}
+
+func SetZoneSettings(zone string, s ZoneSettings, db *Database) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("zones")).Put([]byte(zone), data)
+	})
+}
@@ -59,7 +59,7 @@ export default class extends Component {
     onSelectionChange = selectedItems => this.setState({selectedItems});
     toggleCreateModal = () => this.setState({createModalOpen: !this.state.createModalOpen});
     toggleEditModal = () => this.setState({editModalOpen: !this.state.editModalOpen});
-    refreshRecords = () => ApiRecords.List("", Authentication.getToken()).then(res => this.setState({items: res.data.map((value, index) => {return {...value, id: index}})})).catch(err => {
+    refreshRecords = () => ApiRecords.List("", Authentication.getToken()).then(res => this.setState({items: res.data.records.map((value, index) => {return {...value, id: index}})})).catch(err => {
         switch (err.response.status) {
             case 401:
                 this.props.addToast("Unable to retrieve records", "Please log in again", "danger");
@@ -74,7 +74,7 @@ export default class extends Component {
 
     componentWillMount() {
         ApiRecords.List("", Authentication.getToken())
-            .then(res => this.setState({items: res.data.map((value, index) => {return {...value, id: index * Math.floor(Math.random() * 1000000)}})}))
+            .then(res => this.setState({items: res.data.records.map((value, index) => {return {...value, id: index * Math.floor(Math.random() * 1000000)}})}))
             .catch(err => {
                 switch (err.response.status) {
                     case 401:
@@ -17,7 +17,7 @@ export default class extends Component {
 
         this.state = {
             a: {
-                host: props.initial.host || ""
+                hosts: props.initial.hosts || []
             },
             aaaa: {
                 host: props.initial.host || ""
@@ -129,8 +129,8 @@ export default class extends Component {
         switch (this.props.record) {
             case "A":
                 return (
-                    <EuiFormRow label="Host" helpText="Must be an IPv4 address">
-                        <EuiFieldText value={this.state.a.host} onChange={(e) => this.setState({a: {...this.state.a, host: e.target.value}})}/>
+                    <EuiFormRow label="Hosts" helpText="Must be IPv4 addresses separated by commas">
+                        <EuiFieldText value={this.state.a.hosts.join(", ")} onChange={(e) => this.setState({a: {...this.state.a, hosts: e.target.value.split(",").map(host => host.trim())}})}/>
                     </EuiFormRow>
                 );
 
@@ -3,222 +3,24 @@ package main
 import (
 	"flag"
 	rice "github.com/GeertJohan/go.rice"
+	"github.com/iznotek/dns/admin"
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/records"
 	"github.com/iznotek/dns/roles"
+	"github.com/iznotek/dns/server"
 	"github.com/iznotek/dns/users"
 	"github.com/iznotek/dns/util"
-	"github.com/gorilla/handlers"
-	"github.com/miekg/dns"
-	"github.com/rs/cors"
+	"github.com/iznotek/dns/zones"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	bolt "go.etcd.io/bbolt"
 	"gopkg.in/hlandau/passlib.v1"
 	"log"
-	"math/rand"
+	"net"
 	"net/http"
-	"os"
 	"time"
 )
 
-var database *bolt.DB
-
-type handler struct {}
-func (h *handler) ServeDNS(w dns.ResponseWriter, m *dns.Msg) {
-	// Set database into getter and setter
-	db.Get.Db = database
-	db.Set.Db = database
-
-	// Time request for logging
-	start := time.Now()
-
-	// Assemble response
-	r := new(dns.Msg)
-	r.SetReply(m)
-	r.Authoritative = true
-	r.RecursionAvailable = true
-
-	// Iterate over all questions
-	for _, q := range r.Question {
-		var recordFound bool
-		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: q.Qclass}
-
-		// Do different things based on record type
-		switch q.Qtype {
-		case dns.TypeA:
-			record := db.Get.A(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.A{Hdr: hdr, A: record.Address})
-			}
-		case dns.TypeAAAA:
-			record :=  db.Get.AAAA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.AAAA{Hdr: hdr, AAAA: record.Address})
-			}
-		case dns.TypeCNAME:
-			record :=  db.Get.CNAME(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CNAME{Hdr: hdr, Target: record.Target})
-			}
-		case dns.TypeMX:
-			record :=  db.Get.MX(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.MX{Hdr: hdr, Preference: record.Priority, Mx: record.Host})
-			}
-		case dns.TypeLOC:
-			record :=  db.Get.LOC(q.Name)
-			if record != nil {
-				recordFound = true
-				locString, vers := record.ToParsable()
-				r.Answer = append(r.Answer, util.ParseLOCString(locString, vers, hdr))
-			}
-		case dns.TypeSRV:
-			record :=  db.Get.SRV(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SRV{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: record.Target})
-			}
-		case dns.TypeSPF:
-			record :=  db.Get.SPF(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SPF{Hdr: hdr, Txt: record.Text})
-			}
-		case dns.TypeTXT:
-			record :=  db.Get.TXT(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.TXT{Hdr: hdr, Txt: record.Text})
-			}
-		case dns.TypeNS:
-			record :=  db.Get.NS(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.NS{Hdr: hdr, Ns: record.Nameserver})
-			}
-		case dns.TypeCAA:
-			record :=  db.Get.CAA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CAA{Hdr: hdr, Flag: record.Flag, Tag: record.Tag, Value: record.Content})
-			}
-		case dns.TypePTR:
-			record := db.Get.PTR(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.PTR{Hdr: hdr, Ptr: record.Domain})
-			}
-		case dns.TypeCERT:
-			record :=  db.Get.CERT(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.CERT{Hdr: hdr, Type: record.Type, KeyTag: record.KeyTag, Algorithm: record.Algorithm, Certificate: record.Certificate})
-			}
-		case dns.TypeDNSKEY:
-			record :=  db.Get.DNSKEY(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.DNSKEY{Hdr: hdr, Flags: record.Flags, Protocol: record.Protocol, Algorithm: record.Algorithm, PublicKey: record.PublicKey})
-			}
-		case dns.TypeDS:
-			record :=  db.Get.DS(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.DS{Hdr: hdr, KeyTag: record.KeyTag, Algorithm: record.Algorithm, DigestType: record.DigestType, Digest: record.Digest})
-			}
-		case dns.TypeNAPTR:
-			record :=  db.Get.NAPTR(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.NAPTR{Hdr: hdr, Order: record.Order, Preference: record.Preference, Flags: record.Flags, Service: record.Service, Regexp: record.Regexp, Replacement: record.Replacement})
-			}
-		case dns.TypeSMIMEA:
-			record :=  db.Get.SMIMEA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SMIMEA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
-			}
-		case dns.TypeSSHFP:
-			record :=  db.Get.SSHFP(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.SSHFP{Hdr: hdr, Algorithm: record.Algorithm, Type: record.Type, FingerPrint: record.Fingerprint})
-			}
-		case dns.TypeTLSA:
-			record :=  db.Get.TLSA(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.TLSA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
-			}
-		case dns.TypeURI:
-			record :=  db.Get.URI(q.Name)
-			if record != nil {
-				recordFound = true
-				r.Answer = append(r.Answer, &dns.URI{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Target: record.Target})
-			}
-		default:
-			recordFound = false
-		}
-
-		if !recordFound {
-			// Look up recursively
-			recursMsg := new(dns.Msg)
-			recursMsg.SetQuestion(dns.Fqdn(q.Name), q.Qtype)
-			recursMsg.SetEdns0(4096, true)
-			recursMsg.RecursionDesired = true
-
-			// Get random upstream resolver
-			rand.Seed(time.Now().UnixNano())
-			resolvers := viper.GetStringSlice("dns.upstream")
-
-			// Send new response
-			resp, err := dns.Exchange(recursMsg, resolvers[rand.Intn(len(resolvers))])
-			if err != nil {
-				r.Rcode = dns.RcodeNameError
-				continue
-			}
-
-			// Add new responses
-			if resp.Rcode != dns.RcodeSuccess {
-				r.Rcode = resp.Rcode
-			}
-			r.Answer = append(r.Answer, resp.Answer...)
-		}
-	}
-
-	// Throw error if no answers
-	if len(r.Answer) == 0 {
-		r.Rcode = dns.RcodeNameError
-	}
-
-	// Write response
-	if err := w.WriteMsg(r); err != nil {
-		log.Printf("Unable to send response: %v", err)
-	}
-
-	// Log to console
-	util.LogResponse(w, r, start)
-}
-
-func queryDNS(q string, t uint16) ([]dns.RR, int) {
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(q), t)
-	msg.SetEdns0(4096, true)
-
-	c := new(dns.Client)
-	rand.Seed(time.Now().UnixNano())
-	resolvers := viper.GetStringSlice("dns.upstream")
-	in, _, err := c.Exchange(msg, resolvers[rand.Intn(len(resolvers))])
-	if err != nil {
-		return []dns.RR{}, dns.RcodeNameError
-	}
-	return in.Answer, dns.RcodeSuccess
-}
+var database *db.Database
 
 func main() {
 	// Configuration setup
@@ -251,9 +53,54 @@ func main() {
 	viper.SetDefault("dns.host", "127.0.0.1")
 	viper.SetDefault("dns.port", 53)
 	viper.SetDefault("dns.database", "./records.db")
+	viper.SetDefault("dns.compact-interval", 0)
 	viper.SetDefault("dns.disable-tcp", false)
 	viper.SetDefault("dns.disable-udp", false)
+	viper.SetDefault("dns.max-tcp-connections", 1000)
+	viper.SetDefault("dns.transfer.allow", []string{})
+	viper.SetDefault("dns.transfer.max-concurrent", 10)
+	viper.SetDefault("dns.transfer.max-per-peer", 2)
+	viper.SetDefault("dns.secondary.primaries", map[string]string{})
+	viper.SetDefault("dns.tsig-keys", map[string]string{})
+	viper.SetDefault("dns.secondary.check-interval", "1m")
+	viper.SetDefault("dns.ttl", 3600)
+	viper.SetDefault("dns.ttl-jitter", 0)
+	viper.SetDefault("dns.zones", []string{})
+	viper.SetDefault("dns.authoritative-only", false)
+	viper.SetDefault("dns.propagation-delay", "0s")
+	viper.SetDefault("dns.recovery.ttl", 60)
+	viper.SetDefault("dns.recovery.window", "0s")
+	viper.SetDefault("dns.follow-cnames", true)
+	viper.SetDefault("dns.signature-validity", "168h")
+	viper.SetDefault("dns.minimal-any", true)
+	viper.SetDefault("dns.minimal-any-types", false)
+	viper.SetDefault("dns.additional-records", true)
+	viper.SetDefault("dns.nodata-for-missing-types", true)
 	viper.SetDefault("dns.upstream", []string{"1.1.1.1:53", "8.8.8.8:53"})
+	viper.SetDefault("dns.blocklist", []string{})
+	viper.SetDefault("dns.chaos.version", "")
+	viper.SetDefault("dns.chaos.hostname", "")
+	viper.SetDefault("dns.debug.ttl-override.enabled", false)
+	viper.SetDefault("dns.debug.ttl-override.option", 65001)
+	viper.SetDefault("dns.debug.ttl-override.sources", []string{"127.0.0.1/32", "::1/128"})
+
+	viper.SetDefault("records.max-text-strings", 32)
+	viper.SetDefault("records.high-ttl", 604800)
+	viper.SetDefault("records.quota", 0)
+	viper.SetDefault("records.check-srv-targets", false)
+	viper.SetDefault("records.strict-ptr-owners", false)
+	viper.SetDefault("records.lowercase-domains", false)
+	viper.SetDefault("records.allow-experimental-dnskey", false)
+	viper.SetDefault("records.text-normalization", "off")
+	viper.SetDefault("records.skip-identical", true)
+	viper.SetDefault("records.transaction-ttl", "10m")
+	viper.SetDefault("records.auto-soa.enabled", false)
+	viper.SetDefault("records.auto-soa.nameserver", "")
+	viper.SetDefault("records.bump-serial", false)
+	viper.SetDefault("records.trash-retention", "720h")
+	viper.SetDefault("records.history-size", 10)
+	viper.SetDefault("records.health-check.interval", "30s")
+	viper.SetDefault("records.health-check.timeout", "5s")
 
 	viper.SetDefault("http.host", "127.0.0.1")
 	viper.SetDefault("http.port", 8080)
@@ -262,6 +109,24 @@ func main() {
 	viper.SetDefault("http.admin.password", "admin")
 	viper.SetDefault("http.disable-frontend", false)
 	viper.SetDefault("http.disabled", false)
+	viper.SetDefault("http.max-connections", 1000)
+	viper.SetDefault("http.password-policy.min-length", 8)
+	viper.SetDefault("http.password-policy.classes", []string{})
+	viper.SetDefault("http.password-policy.denied", []string{})
+	viper.SetDefault("http.rate-limit.enabled", false)
+	viper.SetDefault("http.rate-limit.rate", 10)
+	viper.SetDefault("http.rate-limit.burst", 20)
+	viper.SetDefault("http.rate-limit.admin-rate", 0)
+	viper.SetDefault("http.rate-limit.admin-burst", 0)
+	viper.SetDefault("http.log-format", "common")
+	viper.SetDefault("http.cors.allowed-origins", []string{"*"})
+	viper.SetDefault("http.cors.max-age", 600)
+	viper.SetDefault("http.token-ttl", "24h")
+	viper.SetDefault("http.refresh-token-ttl", "720h")
+	viper.SetDefault("http.webhooks.interval", "10s")
+	viper.SetDefault("http.webhooks.timeout", "10s")
+	viper.SetDefault("http.webhooks.retry-interval", "30s")
+	viper.SetDefault("http.webhooks.max-attempts", 10)
 
 	// Parse configuration
 	if err := viper.ReadInConfig(); err != nil {
@@ -275,7 +140,7 @@ func main() {
 
 	// Open database
 	var err error
-	database, err = bolt.Open(viper.GetString("dns.database"), 0666, nil)
+	database, err = db.Open(viper.GetString("dns.database"), 0666, nil)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -286,6 +151,79 @@ func main() {
 		log.Fatalf("Failed setting up database structure: %v", err)
 	}
 
+	// Periodically remove expired tokens
+	go func() {
+		for range time.Tick(time.Hour) {
+			if pruned, err := db.PruneTokens(database); err != nil {
+				log.Printf("Failed to remove expired tokens: %v", err)
+			} else if pruned != 0 {
+				log.Printf("Removed %d expired tokens", pruned)
+			}
+			if pruned, err := db.PruneRefreshTokens(database); err != nil {
+				log.Printf("Failed to remove expired refresh tokens: %v", err)
+			} else if pruned != 0 {
+				log.Printf("Removed %d expired refresh tokens", pruned)
+			}
+		}
+	}()
+
+	// Periodically compact the database, reclaiming space left by deleted records
+	if viper.GetDuration("dns.compact-interval") > 0 {
+		go func() {
+			for range time.Tick(viper.GetDuration("dns.compact-interval")) {
+				if before, after, err := database.Compact(); err != nil {
+					log.Printf("Failed to compact database: %v", err)
+				} else {
+					log.Printf("Compacted database from %d to %d bytes", before, after)
+				}
+			}
+		}()
+	}
+
+	// Periodically purge deleted records past their retention
+	go func() {
+		for range time.Tick(time.Hour) {
+			if viper.GetDuration("records.trash-retention") <= 0 {
+				continue
+			} else if purged, err := db.PurgeTrash(time.Now().Add(-viper.GetDuration("records.trash-retention")), database); err != nil {
+				log.Printf("Failed to purge deleted records: %v", err)
+			} else if purged != 0 {
+				log.Printf("Purged %d deleted records", purged)
+			}
+		}
+	}()
+
+	// Keep zones served as a secondary in step with their primary
+	go func() {
+		if len(util.SecondaryZones()) == 0 {
+			return
+		}
+		util.RefreshSecondaries(database)
+		for range time.Tick(viper.GetDuration("dns.secondary.check-interval")) {
+			util.RefreshSecondaries(database)
+		}
+	}()
+
+	// Periodically check the addresses of records with health checks
+	go func() {
+		if viper.GetDuration("records.health-check.interval") <= 0 {
+			return
+		}
+		for range time.Tick(viper.GetDuration("records.health-check.interval")) {
+			util.CheckHealth(database)
+		}
+	}()
+
+	// Deliver changes to records to the webhooks users subscribed with
+	go func() {
+		if viper.GetDuration("http.webhooks.interval") <= 0 {
+			return
+		}
+		for range time.Tick(viper.GetDuration("http.webhooks.interval")) {
+			util.DeliverWebhooks(database)
+		}
+	}()
+
 	// Setup hashing
 	if err := passlib.UseDefaults(passlib.DefaultsLatest); err != nil {
 		log.Fatal("invalid hash configuration")
@@ -298,20 +236,14 @@ func main() {
 	tcpErr := make(chan error)
 	go func() {
 		if viper.GetBool("dns.disable-tcp") { return }
-		tcp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "tcp"}
-		tcp.Handler = &handler{}
-
-		if err := tcp.ListenAndServe(); err != nil { tcpErr <- err }
+		if err := server.ListenTCP(database); err != nil { tcpErr <- err }
 	}()
 
 	// Handle UDP connections
 	udpErr := make(chan error)
 	go func() {
 		if viper.GetBool("dns.disable-udp") { return }
-		udp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "udp"}
-		udp.Handler = &handler{}
-
-		if err := udp.ListenAndServe(); err != nil { udpErr <- err }
+		if err := server.ListenUDP(database); err != nil { udpErr <- err }
 	}()
 
 	// Handle REST API
@@ -319,17 +251,55 @@ func main() {
 	go func() {
 		if viper.GetBool("http.disabled") { return }
 
-		// Allow CORS
-		c := cors.AllowAll()
+		// Allow CORS from the configured origins
+		c := util.CORS()
 
 		// Setup API routes
-		http.Handle("/api/records", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(records.AllRecordsHandler(database)))))
-		http.Handle("/api/records/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(records.SingleRecordHandler("/api/records/", database)))))
-		http.Handle("/api/users", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(users.AllUsersHandler(database)))))
-		http.Handle("/api/users/login", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(users.Login(database)))))
-		http.Handle("/api/users/logout", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(users.Logout(database)))))
-		http.Handle("/api/roles", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(roles.AllRolesHandler(database)))))
-		http.Handle("/api/roles/", c.Handler(handlers.LoggingHandler(os.Stdout, http.HandlerFunc(roles.SingleRoleHandler("/api/roles/", database)))))
+		http.Handle("/api/records", c.Handler(util.AccessLog(http.HandlerFunc(records.AllRecordsHandler(database)))))
+		http.Handle("/api/records/", c.Handler(util.AccessLog(http.HandlerFunc(records.SingleRecordHandler("/api/records/", database)))))
+		http.Handle("/api/records/batch", c.Handler(util.AccessLog(http.HandlerFunc(records.BatchRecordsHandler("/api/records/", database)))))
+		http.Handle("/api/records/journal", c.Handler(util.AccessLog(http.HandlerFunc(records.JournalHandler(database)))))
+		http.Handle("/api/records/acme", c.Handler(util.AccessLog(http.HandlerFunc(records.AcmeHandler(database)))))
+		http.Handle("/api/records/swap", c.Handler(util.AccessLog(http.HandlerFunc(records.SwapRecordsHandler(database)))))
+		http.Handle("/api/records/import", c.Handler(util.AccessLog(http.HandlerFunc(records.ImportRecordsHandler(database)))))
+		http.Handle("/api/records/lock", c.Handler(util.AccessLog(http.HandlerFunc(records.LockRecordHandler(database)))))
+		http.Handle("/api/records/transactions", c.Handler(util.AccessLog(http.HandlerFunc(records.TransactionsHandler(database)))))
+		http.Handle("/api/records/transactions/", c.Handler(util.AccessLog(http.HandlerFunc(records.TransactionHandler("/api/records/transactions/", database)))))
+		http.Handle("/api/records/groups", c.Handler(util.AccessLog(http.HandlerFunc(records.GroupsHandler(database)))))
+		http.Handle("/api/records/trash", c.Handler(util.AccessLog(http.HandlerFunc(records.TrashHandler(database)))))
+		http.Handle("/api/records/restore", c.Handler(util.AccessLog(http.HandlerFunc(records.RestoreRecordHandler(database)))))
+		http.Handle("/api/records/history", c.Handler(util.AccessLog(http.HandlerFunc(records.HistoryHandler(database)))))
+		http.Handle("/api/records/revert", c.Handler(util.AccessLog(http.HandlerFunc(records.RevertRecordHandler(database)))))
+		http.Handle("/api/records/groups/", c.Handler(util.AccessLog(http.HandlerFunc(records.GroupHandler("/api/records/groups/", database)))))
+		http.Handle("/api/users", c.Handler(util.AccessLog(http.HandlerFunc(users.AllUsersHandler(database)))))
+		http.Handle("/api/users/login", c.Handler(util.AccessLog(http.HandlerFunc(users.Login(database)))))
+		http.Handle("/api/users/logout", c.Handler(util.AccessLog(http.HandlerFunc(users.Logout(database)))))
+		http.Handle("/api/users/rotate", c.Handler(util.AccessLog(http.HandlerFunc(users.RotateHandler(database)))))
+		http.Handle("/api/users/refresh", c.Handler(util.AccessLog(http.HandlerFunc(users.RefreshHandler(database)))))
+		http.Handle("/api/users/tokens", c.Handler(util.AccessLog(http.HandlerFunc(users.TokensHandler(database)))))
+		http.Handle("/api/users/activity", c.Handler(util.AccessLog(http.HandlerFunc(users.ActivityHandler(database)))))
+		http.Handle("/api/users/webhooks", c.Handler(util.AccessLog(http.HandlerFunc(users.WebhooksHandler(database)))))
+		http.Handle("/api/roles", c.Handler(util.AccessLog(http.HandlerFunc(roles.AllRolesHandler(database)))))
+		http.Handle("/api/roles/", c.Handler(util.AccessLog(http.HandlerFunc(roles.SingleRoleHandler("/api/roles/", database)))))
+		http.Handle("/api/roles/preview", c.Handler(util.AccessLog(http.HandlerFunc(roles.PreviewRoleHandler(database)))))
+		http.Handle("/api/roles/access", c.Handler(util.AccessLog(http.HandlerFunc(roles.AccessHandler(database)))))
+		http.Handle("/api/zones", c.Handler(util.AccessLog(http.HandlerFunc(zones.AllZonesHandler(database)))))
+		http.Handle("/api/zones/records", c.Handler(util.AccessLog(http.HandlerFunc(zones.RecordsHandler(database)))))
+		http.Handle("/api/zones/settings/", c.Handler(util.AccessLog(http.HandlerFunc(zones.SettingsHandler("/api/zones/settings/", database)))))
+		http.Handle("/api/zones/digest/", c.Handler(util.AccessLog(http.HandlerFunc(zones.DigestHandler("/api/zones/digest/", database)))))
+		http.Handle("/api/zones/keys/", c.Handler(util.AccessLog(http.HandlerFunc(zones.KeysHandler("/api/zones/keys/", database)))))
+		http.Handle("/api/zones/ttl", c.Handler(util.AccessLog(http.HandlerFunc(zones.TTLHandler(database)))))
+		http.Handle("/api/zones/export", c.Handler(util.AccessLog(http.HandlerFunc(zones.ExportHandler(database)))))
+		http.Handle("/api/zones/export/key", c.Handler(util.AccessLog(http.HandlerFunc(zones.ExportKeyHandler(database)))))
+		http.Handle("/api/zones/validate", c.Handler(util.AccessLog(http.HandlerFunc(zones.ValidateHandler(database)))))
+		http.Handle("/api/zones/health", c.Handler(util.AccessLog(http.HandlerFunc(zones.HealthHandler(database)))))
+		http.Handle("/api/zones/cache", c.Handler(util.AccessLog(http.HandlerFunc(zones.CacheHandler(database)))))
+		http.Handle("/api/metrics", c.Handler(util.AccessLog(http.HandlerFunc(admin.MetricsHandler(database)))))
+		http.Handle("/api/resolve", c.Handler(util.AccessLog(http.HandlerFunc(admin.ResolveHandler(database)))))
+		http.Handle("/api/config", c.Handler(util.AccessLog(http.HandlerFunc(admin.ConfigHandler(database)))))
+		http.Handle("/api/audit", c.Handler(util.AccessLog(http.HandlerFunc(admin.AuditHandler(database)))))
+		http.Handle("/api/compact", c.Handler(util.AccessLog(http.HandlerFunc(admin.CompactHandler(database)))))
+		http.Handle("/dns-query", c.Handler(util.AccessLog(http.HandlerFunc(server.DoHHandler(database)))))
 
 		// Setup frontend routes
 		if !viper.GetBool("http.disable-frontend") {
@@ -338,7 +308,15 @@ func main() {
 		}
 
 		// Start HTTP
-		if err := http.ListenAndServe(viper.GetString("http.host") + ":" + viper.GetString("http.port"), nil); err != nil { httpErr <- err }
+		listener, err := net.Listen("tcp", viper.GetString("http.host") + ":" + viper.GetString("http.port"))
+		if err != nil { httpErr <- err; return }
+
+		// Limit concurrent connections
+		limited := util.NewLimitedListener(listener, viper.GetInt64("http.max-connections"))
+		util.Metrics.Gauge("http-connections", limited.Active)
+
+		// Limit the request rate of each user
+		if err := http.Serve(limited, util.RequestLog(util.RateLimit(http.DefaultServeMux, database), database)); err != nil { httpErr <- err }
 	}()
 
 	// Assemble log
@@ -0,0 +1,213 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handle publishing an ACME DNS-01 challenge and verifying the addresses of its host
+func acmeChallenge(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exists, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"hostname", "token", "addresses"}, map[string]map[string]string{
+		"hostname": {"required": "true", "type": "string"},
+		"token": {"required": "true", "type": "string"},
+		"addresses": {"required": "false", "type": "stringarray"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Parse the expected addresses before anything is written
+	var expected []net.IP
+	if util.Exists(body, "addresses") {
+		for _, value := range body["addresses"].([]interface{}) {
+			address := net.ParseIP(value.(string))
+			if address == nil {
+				util.Responses.Error(w, http.StatusBadRequest, "field 'addresses' must only contain IP addresses")
+				return
+			}
+			expected = append(expected, address)
+		}
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(body["hostname"].(string)), ".")
+	challenge := "_acme-challenge." + hostname
+
+	// Check if allowed
+	if allowed, err := db.EvaluateRole(user.Role, challenge, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to create record")
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(challenge, "TXT", database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
+		return
+	}
+
+	// Admins are not limited by the quota
+	quota := viper.GetInt("records.quota")
+	if user.Role == "admin" {
+		quota = 0
+	}
+
+	// Claim the challenge in the same transaction that publishes it, so a
+	// failed write leaves no claim behind
+	usage := 0
+	if err := db.Set.WithAudit(db.NewAuditEntry(user.Username, "create", challenge, "TXT")).WithMetadata(func(tx *bolt.Tx) (err error) {
+		if _, usage, err = db.ClaimRecordTx(challenge, "TXT", user.Username, quota, tx); err != nil {
+			return err
+		}
+		return db.UpdateMetadataTx(challenge, "TXT", func(m *db.Metadata) {
+			m.Modified = time.Now().Unix()
+		}, tx)
+	}).TXT(challenge, []string{body["token"].(string)}); err == db.ErrQuotaExceeded {
+		util.Responses.ErrorWithData(w, http.StatusForbidden, err.Error(), map[string]int{"usage": usage, "quota": quota})
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		return
+	}
+
+	// Compare the expected addresses against those served for the host
+	a := db.Get.A(hostname + ".")
+	aaaa := db.Get.AAAA(hostname + ".")
+	verified := true
+	addresses := []map[string]interface{}{}
+	for _, address := range expected {
+		match := false
+		if address.To4() != nil {
+			match = a != nil && a.Contains(address)
+		} else {
+			match = aaaa != nil && aaaa.Address.Equal(address)
+		}
+		verified = verified && match
+		addresses = append(addresses, map[string]interface{}{"address": address.String(), "match": match})
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{
+		"challenge": challenge,
+		"addresses": addresses,
+		"verified": verified,
+	})
+}
+
+// Handle removing a published ACME DNS-01 challenge
+func acmeCleanup(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("hostname") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'hostname' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	challenge := "_acme-challenge." + strings.TrimSuffix(strings.ToLower(r.URL.Query().Get("hostname")), ".")
+
+	// Check if allowed
+	if allowed, err := db.EvaluateRole(user.Role, challenge, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to delete record")
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(challenge, "TXT", database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
+		return
+	}
+
+	if err := db.Delete.WithAudit(db.NewAuditEntry(user.Username, "delete", challenge, "TXT")).TXT(challenge); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete record: "+err.Error())
+		return
+	} else if err := db.DeleteMetadata(challenge, "TXT", database); err != nil {
+		log.Printf("Failed to remove metadata for record '%s': %v", challenge, err)
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,59 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+func TestAcmeRejectsInvalidAddressBeforeWriting(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	status, _ := testRequest(t, AcmeHandler(database), "POST", "/api/records/acme", token, map[string]interface{}{
+		"hostname":  "www.example.com",
+		"token":     "challenge-token",
+		"addresses": []string{"192.0.2.1", "not-an-address"},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", status)
+	}
+
+	if record := db.Get.TXT("_acme-challenge.www.example.com."); record != nil {
+		t.Fatalf("expected no challenge to be published, got %v", record)
+	}
+	if metadata, err := db.GetMetadata("_acme-challenge.www.example.com", "TXT", database); err != nil {
+		t.Fatal(err)
+	} else if metadata.Owner != "" {
+		t.Fatalf("expected the challenge not to be claimed, owned by '%s'", metadata.Owner)
+	}
+}
+
+func TestAcmeQuotaLeavesNothingBehind(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if err := db.CreateRole("user", "", ".*", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("records.quota", 1)
+	t.Cleanup(func() { viper.Set("records.quota", 0) })
+
+	if _, _, err := db.ClaimRecord("owned.example.com", "A", "test", 0, database); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, AcmeHandler(database), "POST", "/api/records/acme", token, map[string]interface{}{
+		"hostname": "www.example.com",
+		"token":    "challenge-token",
+	})
+	if status != http.StatusForbidden || response.Reason != db.ErrQuotaExceeded.Error() {
+		t.Fatalf("expected 403 over the quota, got %d: %s", status, response.Reason)
+	}
+	if record := db.Get.TXT("_acme-challenge.www.example.com."); record != nil {
+		t.Fatalf("expected no challenge to be published, got %v", record)
+	}
+	if count, err := db.CountOwned("test", database); err != nil {
+		t.Fatal(err)
+	} else if count != 1 {
+		t.Fatalf("expected the user to still own a single record, got %d", count)
+	}
+}
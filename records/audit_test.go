@@ -0,0 +1,61 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+// Check the last entry written to the audit log
+func expectAudit(t *testing.T, database *db.Database, operation, resource, resourceType string) {
+	t.Helper()
+	entries, err := db.QueryAudit(db.AuditFilter{}, database)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) == 0 {
+		t.Fatal("expected an audit entry")
+	}
+
+	last := entries[len(entries)-1]
+	if last.Username != "test" || last.Operation != operation || last.Resource != resource || last.Type != resourceType {
+		t.Fatalf("unexpected audit entry %+v", last)
+	}
+}
+
+func TestLockIsAudited(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, locked := range []bool{true, false} {
+		status, response := testRequest(t, LockRecordHandler(database), "POST", "/api/records/lock", token, map[string]interface{}{"name": "www.example.com", "type": "A", "locked": locked})
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, response.Reason)
+		}
+
+		operation := "unlock"
+		if locked {
+			operation = "lock"
+		}
+		expectAudit(t, database, operation, "www.example.com", "A")
+	}
+}
+
+func TestGroupChangesAreAudited(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	for _, operation := range []string{"create", "update"} {
+		status, response := testRequest(t, GroupsHandler(database), "POST", "/api/records/groups", token, map[string]interface{}{"name": "web", "ttl": 60})
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, response.Reason)
+		}
+		expectAudit(t, database, operation, "web", "group")
+	}
+
+	status, response := testRequest(t, GroupHandler("/api/records/groups/", database), "DELETE", "/api/records/groups/web", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, response.Reason)
+	}
+	expectAudit(t, database, "delete", "web", "group")
+}
@@ -0,0 +1,100 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+)
+
+// Handle the retrieval of multiple records in one request
+func batch(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Decode list of record selectors
+	var body struct {
+		Records []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if len(body.Records) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'records' must be of at least length 1")
+		return
+	}
+
+	// Check permissions before opening the read transaction
+	results := make([]map[string]interface{}, len(body.Records))
+	for i, selector := range body.Records {
+		results[i] = map[string]interface{}{"name": selector.Name, "type": strings.ToUpper(selector.Type)}
+
+		if selector.Name == "" || !util.StringInArray(strings.ToUpper(selector.Type), db.RecordTypes) {
+			results[i]["error"] = "invalid selector"
+		} else if allowed, err := db.EvaluateRole(user.Role, strings.ToLower(selector.Name), database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if !allowed {
+			results[i]["error"] = "forbidden"
+		}
+	}
+
+	// Retrieve all permitted records in a single transaction
+	if err := database.View(func(tx *bolt.Tx) error {
+		batch := db.Get.WithTx(tx)
+		for i, selector := range body.Records {
+			if _, failed := results[i]["error"]; failed {
+				continue
+			}
+
+			// Accounts for extra dot and all lowercase in DNS request
+			if record := batch.Record(strings.ToLower(selector.Name+"."), strings.ToUpper(selector.Type)); record != nil {
+				results[i]["record"] = record
+			} else {
+				results[i]["error"] = "record does not exist"
+			}
+		}
+		return nil
+	}); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve records: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, results)
+}
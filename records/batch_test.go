@@ -0,0 +1,91 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, BatchRecordsHandler("/api/records/", database), "POST", "/api/records/batch", token, map[string]interface{}{
+		"records": []map[string]string{
+			{"name": "www.example.com", "type": "a"},
+			{"name": "mail.example.com", "type": "A"},
+			{"name": "www.example.com", "type": "BOGUS"},
+		},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to retrieve records: %d %s", status, response.Reason)
+	}
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal(response.Data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if results[0]["record"] == nil {
+		t.Errorf("expected record for a lowercase type, got %v", results[0])
+	}
+	if results[1]["error"] != "record does not exist" {
+		t.Errorf("expected missing record, got %v", results[1])
+	}
+	if results[2]["error"] != "invalid selector" {
+		t.Errorf("expected invalid selector, got %v", results[2])
+	}
+}
+
+func TestBatchRecordName(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("batch", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only POST is a batch, other methods reach the record named batch
+	status, response := testRequest(t, BatchRecordsHandler("/api/records/", database), "GET", "/api/records/batch?type=A", token, nil)
+	if status != http.StatusOK {
+		t.Errorf("failed to read record named batch: %d %s", status, response.Reason)
+	}
+}
+
+func TestBatchMarksForbiddenNames(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if err := db.CreateRole("user", "", "", "", []string{"*.team.example.com"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"www.team.example.com", "mail.team.example.com", "api.team.example.com", "www.example.com", "mail.example.com"}
+	for _, name := range names {
+		if err := db.Set.A(name, "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var selectors []map[string]string
+	for _, name := range names {
+		selectors = append(selectors, map[string]string{"name": name, "type": "A"})
+	}
+	status, response := testRequest(t, BatchRecordsHandler("/api/records/", database), "POST", "/api/records/batch", token, map[string]interface{}{"records": selectors})
+	if status != http.StatusOK {
+		t.Fatalf("failed to retrieve records: %d %s", status, response.Reason)
+	}
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal(response.Data, &results); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 5 {
+		t.Fatalf("expected five results, got %v", results)
+	}
+
+	// Names outside the role are marked forbidden without their records
+	for i, result := range results {
+		if i < 3 && (result["record"] == nil || result["error"] != nil) {
+			t.Errorf("expected the record of %s, got %v", names[i], result)
+		} else if i >= 3 && (result["error"] != "forbidden" || result["record"] != nil) {
+			t.Errorf("expected %s to be forbidden, got %v", names[i], result)
+		}
+	}
+}
@@ -2,15 +2,20 @@ package records
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
 	bolt "go.etcd.io/bbolt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Handle the creation of records
-func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func create(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Set database into operations
 	db.Get.Db = database
 	db.Set.Db = database
@@ -44,6 +49,9 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
+	// Names are matched case-insensitively when serving
+	name := strings.ToLower(body["name"].(string))
+
 	// Verify JWT in headers
 	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
 	if err != nil {
@@ -59,49 +67,204 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 	}
 
 	// Check if allowed
-	if allowed, err := db.EvaluateRole(user.Role, body["name"].(string), database); err != nil {
-		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+	if !checkRole(w, r, user, name, "create", database) {
+		return
+	}
+
+	// Check prerequisites and keep them from changing until the record is written
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	if !checkPrerequisites(w, body, user.Role, database) {
+		return
+	}
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(name, body["type"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
+		return
+	}
+
+	// Skip the write if the identical record is already stored
+	if viper.GetBool("records.skip-identical") && !util.Exists(body, "sources") && !util.Exists(body, "admin-notes") && !util.Exists(body, "ttl") && !util.Exists(body, "group") && !util.Exists(body, "health-check") && !util.Exists(body, "preferred") && util.RecordMatchesBody(db.Get.Record(name+".", body["type"].(string)), body) {
+		util.Responses.SuccessWithData(w, map[string]bool{"unchanged": true})
+		return
+	}
+
+	// Parse the clients the record is restricted to
+	var sources []string
+	if util.Exists(body, "sources") {
+		if sources, err = util.ParseSources(body["sources"]); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Only admins may annotate records
+	notes, annotated := "", util.Exists(body, "admin-notes")
+	if annotated && user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin' to set field 'admin-notes'")
+		return
+	} else if annotated && !util.Types.String(body["admin-notes"]) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'admin-notes' must be a string")
+		return
+	} else if annotated {
+		notes = body["admin-notes"].(string)
+	}
+
+	// Advisories about the record, returned once it is written
+	var warnings []string
+
+	// Parse the TTL to serve the record with, 0 uses the configured TTL
+	var ttl uint32
+	hasTTL := util.Exists(body, "ttl")
+	if hasTTL {
+		if err, _ := util.ValidateBody(body, []string{"ttl"}, map[string]map[string]string{"ttl": {"type": "uint32", "required": "true"}}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		ttl = uint32(body["ttl"].(float64))
+
+		// TTLs above the maximum of the zone are lowered to it
+		var warning string
+		if ttl, warning = util.LimitTTL(name, ttl); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	// Parse the group to inherit a TTL from
+	group, _, ok := groupFromBody(w, body, database)
+	if !ok {
 		return
-	} else if !allowed {
-		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to create record")
+	}
+
+	// Parse the check addresses must pass to be answered with
+	check, _, validationErr := healthCheckFromBody(body, strings.ToUpper(body["type"].(string)))
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
 		return
 	}
 
+	// Parse the address to list first in answers
+	preferred, _, validationErr := preferredFromBody(body, strings.ToUpper(body["type"].(string)))
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Admins are not limited by the quota
+	quota := viper.GetInt("records.quota")
+	if user.Role == "admin" {
+		quota = 0
+	}
+	recordType := strings.ToUpper(body["type"].(string))
+
+	// Apply custom policies, bodies failing to decode are rejected below
+	// Addresses added to an A record are checked along with those it holds
+	var stored db.Record
+	if recordType == "A" && body["replace"] != true {
+		stored = db.Get.A(name + ".")
+	}
+	if proposed, err := util.ProposedRecord(recordType, nil, appendedHosts(stored, body)); err == nil {
+		if rejections := util.ValidateRecord(name, proposed); len(rejections) != 0 {
+			util.Responses.ErrorWithDetails(w, http.StatusBadRequest, "record rejected by validators", util.RejectionDetails(rejections))
+			return
+		}
+	}
+
+	// Domain valued fields as written, kept when they are stored lowercased
+	originals := map[string]string{}
+	domain := func(field string) string { return domainFromBody(body, field, originals) }
+
+	// Claim the record and write its metadata in the same transaction as the
+	// record, along with its entry in the audit log
+	usage := 0
+	setter := db.Set.WithAudit(db.NewAuditEntry(user.Username, "create", name, recordType)).WithMetadata(func(tx *bolt.Tx) (err error) {
+		if _, usage, err = db.ClaimRecordTx(name, recordType, user.Username, quota, tx); err != nil {
+			return err
+		}
+		return db.UpdateMetadataTx(name, recordType, func(m *db.Metadata) {
+			m.Modified = time.Now().Unix()
+			m.AllowedSources = sources
+			m.AdminNotes = notes
+			m.TTL = ttl
+			m.Group = group
+			m.HealthCheck = check
+			m.Preferred = preferred
+			m.OriginalCase = nil
+			if len(originals) != 0 {
+				m.OriginalCase = originals
+			}
+		}, tx)
+	})
+
+	// Respond to a failed write, including one over the quota
+	failed := func(err error) {
+		if err == db.ErrQuotaExceeded {
+			util.Responses.ErrorWithData(w, http.StatusForbidden, err.Error(), map[string]int{"usage": usage, "quota": quota})
+			return
+		}
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+	}
+
 	// Parse out body by type
 	switch strings.ToUpper(body["type"].(string)) {
 	case "A":
-		if err, _ := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"required": "true", "type": "ipv4"}}); err != "" {
+		hosts, err := hostsFromBody(body, true)
+		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.A(body["name"].(string), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		}
+
+		// Addresses are added to those of the name unless replacing them
+		all := hosts
+		if stored := db.Get.A(name + "."); stored != nil && body["replace"] != true {
+			all = append(stored.Hosts(), hosts...)
+		}
+		if preferred != "" && !containsAddress(all, preferred) {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'preferred' must be one of the record's addresses")
+			return
+		}
+
+		if body["replace"] == true {
+			if err := setter.ReplaceA(name, hosts); err != nil {
+				failed(err)
+				return
+			}
+		} else if err := setter.A(name, hosts...); err != nil {
+			failed(err)
 			return
 		}
 	case "AAAA":
 		if err, _ := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"required": "true", "type": "ipv6"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.AAAA(body["name"].(string), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.AAAA(name, body["host"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "CNAME":
-		if err, _ := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"required": "true", "type": "string"}}); err != "" {
+		if err, _ := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"required": "true", "type": "hostname"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CNAME(body["name"].(string), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if chain, loop := util.CNAMELoop(name, domain("target")); loop {
+			util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, "record would form a CNAME loop", map[string][]string{"chain": chain})
+			return
+		} else if err := setter.CNAME(name, domain("target")); err != nil {
+			failed(err)
 			return
 		}
 	case "MX":
 		if err, _ := util.ValidateBody(body, []string{"priority", "host"}, map[string]map[string]string{
 			"priority": {"type": "uint16", "required": "true"},
-			"host": {"type": "string", "required": "true"},
+			"host": {"type": "hostname", "required": "true"},
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.MX(body["name"].(string), uint16(body["priority"].(float64)), body["host"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		} else if err := setter.MX(name, uint16(body["priority"].(float64)), domain("host")); err != nil {
+			failed(err)
 			return
 		}
 	case "LOC":
@@ -122,8 +285,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.LOC(body["name"].(string), uint8(body["version"].(float64)), uint8(body["size"].(float64)), uint8(body["horizontal-precision"].(float64)), uint8(body["vertical-precision"].(float64)), uint32(body["altitude"].(float64)), uint8(body["lat-degrees"].(float64)), uint8(body["lat-minutes"].(float64)), uint8(body["lat-seconds"].(float64)), body["lat-direction"].(string), uint8(body["long-degrees"].(float64)), uint8(body["long-minutes"].(float64)), uint8(body["long-seconds"].(float64)), body["long-direction"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.LOC(name, uint8(body["version"].(float64)), uint8(body["size"].(float64)), uint8(body["horizontal-precision"].(float64)), uint8(body["vertical-precision"].(float64)), uint32(body["altitude"].(float64)), uint8(body["lat-degrees"].(float64)), uint8(body["lat-minutes"].(float64)), uint8(body["lat-seconds"].(float64)), body["lat-direction"].(string), uint8(body["long-degrees"].(float64)), uint8(body["long-minutes"].(float64)), uint8(body["long-seconds"].(float64)), body["long-direction"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "SRV":
@@ -131,40 +294,52 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			"priority": {"type": "uint16", "required": "true"},
 			"weight": {"type": "uint16", "required": "true"},
 			"port": {"type": "uint16", "required": "true"},
-			"target": {"type": "string", "required": "true"},
+			"target": {"type": "hostname", "required": "true"},
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SRV(body["name"].(string), uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), uint16(body["port"].(float64)), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.SRV(name, uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), uint16(body["port"].(float64)), domain("target")); err != nil {
+			failed(err)
 			return
 		}
 	case "SPF":
-		if err, _ := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "true"}}); err != "" {
+		if err, _ := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "true", "max": viper.GetString("records.max-text-strings")}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
 		text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
-		if err := db.Set.SPF(body["name"].(string), text); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		text, normalized, err := util.NormalizeText(text)
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		warnings = append(warnings, normalized...)
+		if err := setter.SPF(name, text); err != nil {
+			failed(err)
 			return
 		}
 	case "TXT":
-		if err, _ := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "true"}}); err != "" {
+		if err, _ := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "true", "max": viper.GetString("records.max-text-strings")}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
 		}
 		text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
-		if err := db.Set.TXT(body["name"].(string), text); err != nil {
-			util.Responses.Error(w, http.StatusBadRequest, "failed to write record to database: "+err.Error())
+		text, normalized, err := util.NormalizeText(text)
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		warnings = append(warnings, normalized...)
+		if err := setter.TXT(name, text); err != nil {
+			failed(err)
 			return
 		}
 	case "NS":
-		if err, _ := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "string", "required": "true"}}); err != "" {
+		if err, _ := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "hostname", "required": "true"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.NS(body["name"].(string), body["nameserver"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.NS(name, domain("nameserver")); err != nil {
+			failed(err)
 			return
 		}
 	case "CAA":
@@ -174,16 +349,19 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CAA(body["name"].(string), body["tag"].(string), body["content"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.CAA(name, body["tag"].(string), body["content"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "PTR":
-		if err, _ := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "string", "required": "true"}}); err != "" {
+		if err, _ := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "hostname", "required": "true"}}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.PTR(body["name"].(string), body["domain"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if viper.GetBool("records.strict-ptr-owners") && !util.IsReverseName(name) {
+			util.Responses.Error(w, http.StatusBadRequest, "PTR records must be within in-addr.arpa or ip6.arpa")
+			return
+		} else if err := setter.PTR(name, util.NormalizePTRTarget(body["domain"].(string))); err != nil {
+			failed(err)
 			return
 		}
 	case "CERT":
@@ -191,12 +369,12 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			"c-type": {"type": "uint16", "required": "true"},
 			"key-tag": {"type": "uint16", "required": "true"},
 			"algorithm": {"type": "uint8", "required": "true"},
-			"certificate": {"type": "string", "required": "true"},
+			"certificate": {"type": "string", "required": "true", "pattern": util.PatternBase64, "format": "base64 encoded"},
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.CERT(body["name"].(string), uint16(body["c-type"].(float64)), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.CERT(name, uint16(body["c-type"].(float64)), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), body["certificate"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "DNSKEY":
@@ -208,8 +386,11 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.DNSKEY(body["name"].(string), uint16(body["flags"].(float64)), uint8(body["protocol"].(float64)), uint8(body["algorithm"].(float64)), body["public-key"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := util.ValidateDNSKEY(uint16(body["flags"].(float64)), uint8(body["protocol"].(float64))); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err := setter.DNSKEY(name, uint16(body["flags"].(float64)), uint8(body["protocol"].(float64)), uint8(body["algorithm"].(float64)), body["public-key"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "DS":
@@ -217,12 +398,12 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			"key-tag": {"type": "uint16", "required": "true"},
 			"algorithm": {"type": "uint8", "required": "true"},
 			"digest-type": {"type": "uint8", "required": "true"},
-			"digest": {"type": "string", "required": "true"},
+			"digest": {"type": "string", "required": "true", "pattern": util.PatternHex, "format": "hex encoded"},
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.DS(body["name"].(string), uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), uint8(body["digest-type"].(float64)), body["digest"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.DS(name, uint16(body["key-tag"].(float64)), uint8(body["algorithm"].(float64)), uint8(body["digest-type"].(float64)), body["digest"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "NAPTR":
@@ -236,8 +417,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.NAPTR(body["name"].(string), uint16(body["order"].(float64)), uint16(body["preference"].(float64)), body["flags"].(string), body["service"].(string), body["regexp"].(string), body["replacement"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.NAPTR(name, uint16(body["order"].(float64)), uint16(body["preference"].(float64)), body["flags"].(string), body["service"].(string), body["regexp"].(string), domain("replacement")); err != nil {
+			failed(err)
 			return
 		}
 	case "SMIMEA":
@@ -249,8 +430,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SMIMEA(body["name"].(string), uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.SMIMEA(name, uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "SSHFP":
@@ -261,8 +442,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.SSHFP(body["name"].(string), uint8(body["algorithm"].(float64)), uint8(body["s-type"].(float64)), body["fingerprint"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.SSHFP(name, uint8(body["algorithm"].(float64)), uint8(body["s-type"].(float64)), body["fingerprint"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "TLSA":
@@ -274,8 +455,8 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.TLSA(body["name"].(string), uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.TLSA(name, uint8(body["usage"].(float64)), uint8(body["selector"].(float64)), uint8(body["matching-type"].(float64)), body["certificate"].(string)); err != nil {
+			failed(err)
 			return
 		}
 	case "URI":
@@ -286,14 +467,288 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
-		} else if err := db.Set.URI(body["name"].(string), uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), body["target"].(string)); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		} else if err := setter.URI(name, uint16(body["priority"].(float64)), uint16(body["weight"].(float64)), body["target"].(string)); err != nil {
+			failed(err)
+			return
+		}
+	case "CSYNC":
+		if err, _ := util.ValidateBody(body, []string{"serial", "flags", "types"}, map[string]map[string]string{
+			"serial": {"type": "uint32", "required": "true"},
+			"flags": {"type": "uint16", "required": "true"},
+			"types": {"type": "stringarray", "required": "true"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		} else if err := util.ValidateCSYNCFlags(uint16(body["flags"].(float64))); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		types, err := util.ParseTypeBitmap(body["types"].([]interface{}))
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err := setter.CSYNC(name, uint32(body["serial"].(float64)), uint16(body["flags"].(float64)), types); err != nil {
+			failed(err)
+			return
+		}
+	case "AMTRELAY":
+		if err, _ := util.ValidateBody(body, []string{"precedence", "discovery-optional", "relay-type", "relay"}, map[string]map[string]string{
+			"precedence": {"type": "uint8", "required": "true"},
+			"discovery-optional": {"type": "bool", "required": "true"},
+			"relay-type": {"type": "uint8", "required": "true"},
+			"relay": {"type": "string", "required": "false"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		relay := ""
+		if util.Exists(body, "relay") {
+			relay = body["relay"].(string)
+		}
+		if err := util.ValidateAMTRELAY(uint8(body["relay-type"].(float64)), relay); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err := setter.AMTRELAY(name, uint8(body["precedence"].(float64)), body["discovery-optional"].(bool), uint8(body["relay-type"].(float64)), relay); err != nil {
+			failed(err)
+			return
+		}
+	case "HINFO":
+		if err, _ := util.ValidateBody(body, []string{"cpu", "os"}, map[string]map[string]string{
+			"cpu": {"type": "string", "required": "true"},
+			"os": {"type": "string", "required": "true"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		} else if err := setter.HINFO(name, body["cpu"].(string), body["os"].(string)); err != nil {
+			failed(err)
+			return
+		}
+	case "CDNSKEY":
+		if err, _ := util.ValidateBody(body, []string{"flags", "protocol", "algorithm", "public-key"}, map[string]map[string]string{
+			"flags": {"type": "uint16", "required": "true"},
+			"protocol": {"type": "uint8", "required": "true"},
+			"algorithm": {"type": "uint8", "required": "true"},
+			"public-key": {"type": "string", "required": "true"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		} else if err := util.ValidateDNSKEY(uint16(body["flags"].(float64)), uint8(body["protocol"].(float64))); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err := setter.CDNSKEY(name, uint16(body["flags"].(float64)), uint8(body["protocol"].(float64)), uint8(body["algorithm"].(float64)), body["public-key"].(string)); err != nil {
+			failed(err)
+			return
+		}
+	case "SVCB", "HTTPS":
+		if err, _ := util.ValidateBody(body, []string{"priority", "target", "params"}, map[string]map[string]string{
+			"priority": {"type": "uint16", "required": "true"},
+			"target": {"type": "string", "required": "true"},
+			"params": {"type": "stringmap", "required": "false"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var params map[string]string
+		if util.Exists(body, "params") {
+			params, _ = util.ConvertMapToString(body["params"].(map[string]interface{}))
+		}
+		if err := util.ValidateSVCB(uint16(body["priority"].(float64)), body["target"].(string), params); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		write := setter.SVCB
+		if recordType == "HTTPS" {
+			write = setter.HTTPS
+		}
+		if err := write(name, uint16(body["priority"].(float64)), body["target"].(string), params); err != nil {
+			failed(err)
+			return
+		}
+	case "SOA":
+		if err, _ := util.ValidateBody(body, []string{"nameserver", "mailbox", "serial", "refresh", "retry", "expire", "minimum"}, map[string]map[string]string{
+			"nameserver": {"type": "string", "required": "true"},
+			"mailbox": {"type": "string", "required": "true"},
+			"serial": {"type": "uint32", "required": "false"},
+			"refresh": {"type": "uint32", "required": "true"},
+			"retry": {"type": "uint32", "required": "true"},
+			"expire": {"type": "uint32", "required": "true"},
+			"minimum": {"type": "uint32", "required": "true"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Leaving out the serial increments it
+		var serial uint32
+		if util.Exists(body, "serial") {
+			serial = uint32(body["serial"].(float64))
+		}
+		if err := setter.SOA(name, domain("nameserver"), domain("mailbox"), serial, uint32(body["refresh"].(float64)), uint32(body["retry"].(float64)), uint32(body["expire"].(float64)), uint32(body["minimum"].(float64))); err != nil {
+			failed(err)
 			return
 		}
 	default:
-		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
+		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI, CSYNC, AMTRELAY, HINFO, CDNSKEY, SVCB, HTTPS, SOA")
 		return
 	}
 
+	if warnings := append(warnings, util.RecordWarnings(name, db.Get.Record(name+".", recordType))...); len(warnings) != 0 {
+		util.Responses.SuccessWithWarnings(w, warnings)
+		return
+	}
 	util.Responses.Success(w)
 }
+
+// Parse how the addresses of a record are checked before being answered with,
+// null removes the check
+// Returns whether the body sets a check
+func healthCheckFromBody(body map[string]interface{}, recordType string) (*db.HealthCheck, bool, string) {
+	if !util.Exists(body, "health-check") {
+		return nil, false, ""
+	} else if recordType != "A" {
+		return nil, false, "field 'health-check' is only supported for A records"
+	} else if body["health-check"] == nil {
+		return nil, true, ""
+	}
+
+	fields, ok := body["health-check"].(map[string]interface{})
+	if !ok {
+		return nil, false, "field 'health-check' must be an object"
+	} else if err, _ := util.ValidateBody(fields, []string{"protocol", "port", "path"}, map[string]map[string]string{
+		"protocol": {"type": "string", "required": "true"},
+		"port":     {"type": "uint16", "required": "true"},
+		"path":     {"type": "string", "required": "false"},
+	}); err != "" {
+		return nil, false, "field 'health-check': " + err
+	}
+
+	check := &db.HealthCheck{Protocol: strings.ToLower(fields["protocol"].(string)), Port: uint16(fields["port"].(float64))}
+	if check.Protocol != "tcp" && check.Protocol != "http" {
+		return nil, false, "field 'health-check': protocol must be one of 'tcp' or 'http'"
+	} else if check.Port == 0 {
+		return nil, false, "field 'health-check': port must not be 0"
+	}
+
+	// Only HTTP checks request a path, the root unless given
+	if util.Exists(fields, "path") && check.Protocol != "http" {
+		return nil, false, "field 'health-check': path is only supported for HTTP checks"
+	} else if check.Protocol == "http" {
+		check.Path = "/"
+		if util.Exists(fields, "path") {
+			check.Path = fields["path"].(string)
+		}
+		if !strings.HasPrefix(check.Path, "/") {
+			return nil, false, "field 'health-check': path must start with '/'"
+		}
+	}
+	return check, true, ""
+}
+
+// Get a domain valued field of a body with the configured case policy
+// applied, noting the value as written if it was changed
+func domainFromBody(body map[string]interface{}, field string, originals map[string]string) string {
+	value, changed := util.NormalizeDomain(body[field].(string))
+	if changed {
+		originals[field] = body[field].(string)
+	}
+	return value
+}
+
+// Get the addresses of an A record from a body holding either a single
+// host or a set of hosts
+// Returns nil if neither is present and they are not required
+func hostsFromBody(body map[string]interface{}, required bool) ([]string, string) {
+	if !util.Exists(body, "hosts") {
+		err, valid := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"type": "ipv4", "required": strconv.FormatBool(required)}})
+		if err != "" || !valid["host"] {
+			return nil, err
+		}
+		return []string{body["host"].(string)}, ""
+	}
+
+	if err, _ := util.ValidateBody(body, []string{"hosts"}, map[string]map[string]string{"hosts": {"type": "stringarray", "required": "true"}}); err != "" {
+		return nil, err
+	}
+	var hosts []string
+	if len(body["hosts"].([]interface{})) == 0 {
+		return nil, "field 'hosts' must not be empty"
+	}
+	for _, value := range body["hosts"].([]interface{}) {
+		if address := net.ParseIP(value.(string)); address == nil || address.To4() == nil {
+			return nil, "field 'hosts' must only contain IPv4 addresses"
+		}
+		hosts = append(hosts, value.(string))
+	}
+	return hosts, ""
+}
+
+// Get a body holding the addresses of a stored A record followed by those
+// being added to it
+func appendedHosts(stored db.Record, body map[string]interface{}) map[string]interface{} {
+	a, ok := stored.(*db.A)
+	if !ok || a == nil {
+		return body
+	}
+	hosts, err := hostsFromBody(body, true)
+	if err != "" {
+		return body
+	}
+
+	appended := map[string]interface{}{}
+	for key, value := range body {
+		appended[key] = value
+	}
+	var all []interface{}
+	for _, host := range append(a.Hosts(), hosts...) {
+		all = append(all, host)
+	}
+	appended["hosts"] = all
+	delete(appended, "host")
+	return appended
+}
+
+// Parse the address a body prefers to be listed first in answers, an empty
+// address removes the preference
+// Returns whether the body sets a preference
+func preferredFromBody(body map[string]interface{}, recordType string) (string, bool, string) {
+	if !util.Exists(body, "preferred") {
+		return "", false, ""
+	} else if recordType != "A" {
+		return "", false, "field 'preferred' is only supported for A records"
+	} else if !util.Types.String(body["preferred"]) {
+		return "", false, "field 'preferred' must be a string"
+	}
+
+	preferred := body["preferred"].(string)
+	if address := net.ParseIP(preferred); preferred != "" && (address == nil || address.To4() == nil) {
+		return "", false, "field 'preferred' must be an IPv4 address"
+	}
+	return preferred, true, ""
+}
+
+// Check if a set of addresses holds an address
+func containsAddress(hosts []string, host string) bool {
+	address := net.ParseIP(host)
+	for _, h := range hosts {
+		if net.ParseIP(h).Equal(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returned from a transaction when a write would form a loop of aliases
+var errCNAMELoop = fmt.Errorf("record would form a CNAME loop")
+
+// Get the chain of names leading back to a name if the CNAME it holds within
+// a transaction forms a loop of aliases
+func cnameLoop(name string, tx *bolt.Tx) []string {
+	if cname := db.Get.WithTx(tx).CNAME(name + "."); cname != nil {
+		if chain, loop := util.CNAMELoopTx(name, cname.Target, tx); loop {
+			return chain
+		}
+	}
+	return nil
+}
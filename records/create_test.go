@@ -0,0 +1,379 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateClampsTTL(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{MaxTTL: 600}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1", "ttl": 86400,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	} else if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "clamped") {
+		t.Errorf("expected a warning about the clamped TTL, got %v", response.Warnings)
+	}
+
+	_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/www.example.com?type=A", token, nil)
+	var record struct {
+		TTL uint32 `json:"ttl"`
+	}
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if record.TTL != 600 {
+		t.Errorf("expected stored TTL of 600, got %d", record.TTL)
+	}
+
+	// Serving clamps records written before the maximum was lowered
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{MaxTTL: 60}, database); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := util.RecordTTL("www.example.com.", "www.example.com.", dns.TypeA); ttl != 60 {
+		t.Errorf("expected served TTL of 60, got %d", ttl)
+	}
+}
+
+func TestCreateWarnsHighTTL(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.high-ttl", 604800)
+	t.Cleanup(func() { viper.Set("records.high-ttl", 0) })
+
+	for ttl, warned := range map[int]bool{604800: false, 1209600: true} {
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "A", "name": "www.example.com", "host": "192.0.2.1", "ttl": ttl,
+		})
+		if status != http.StatusOK {
+			t.Fatalf("ttl %d: failed to create record: %d %s", ttl, status, response.Reason)
+		} else if warned && (len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "may take a long time")) {
+			t.Errorf("ttl %d: expected a warning about the high TTL, got %v", ttl, response.Warnings)
+		} else if !warned && len(response.Warnings) != 0 {
+			t.Errorf("ttl %d: expected no warnings, got %v", ttl, response.Warnings)
+		}
+
+		// The record is still written with the TTL as given
+		_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/www.example.com?type=A", token, nil)
+		var record struct {
+			TTL uint32 `json:"ttl"`
+		}
+		if err := json.Unmarshal(response.Data, &record); err != nil {
+			t.Fatal(err)
+		} else if record.TTL != uint32(ttl) {
+			t.Errorf("expected stored TTL of %d, got %d", ttl, record.TTL)
+		}
+	}
+}
+
+func TestCreateRejectsCNAMELoop(t *testing.T) {
+	for _, zone := range []string{"example.com", ""} {
+		t.Run("zone="+zone, func(t *testing.T) {
+			database, token := testDatabase(t, "admin")
+			if zone != "" {
+				testZone(t, zone)
+			}
+			records := AllRecordsHandler(database)
+			cname := func(name, target string) (int, testResponse) {
+				return testRequest(t, records, "POST", "/api/records", token, map[string]interface{}{"type": "CNAME", "name": name, "target": target})
+			}
+
+			// A chain ending in a name without an alias is accepted
+			for _, link := range [][2]string{{"a.example.com", "b.example.com"}, {"b.example.com", "c.example.com"}} {
+				if status, response := cname(link[0], link[1]); status != http.StatusOK {
+					t.Fatalf("failed to create %s: %d %s", link[0], status, response.Reason)
+				}
+			}
+
+			// Closing the chain into a cycle is rejected
+			status, response := cname("c.example.com", "a.example.com")
+			if status != http.StatusUnprocessableEntity {
+				t.Fatalf("expected loop to be rejected, got %d %s", status, response.Reason)
+			}
+			var data struct {
+				Chain []string `json:"chain"`
+			}
+			if err := json.Unmarshal(response.Data, &data); err != nil {
+				t.Fatal(err)
+			} else if strings.Join(data.Chain, " ") != "c.example.com. a.example.com. b.example.com. c.example.com." {
+				t.Errorf("unexpected chain: %v", data.Chain)
+			}
+			if db.Get.CNAME("c.example.com.") != nil {
+				t.Error("record forming the loop was written")
+			}
+		})
+	}
+}
+
+func TestCreateMultipleA(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	records := AllRecordsHandler(database)
+	create := func(body map[string]interface{}) (int, testResponse) {
+		body["type"], body["name"] = "A", "www.example.com"
+		return testRequest(t, records, "POST", "/api/records", token, body)
+	}
+
+	// A single host and a set of hosts are both added to the name
+	if status, response := create(map[string]interface{}{"host": "192.0.2.1"}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if status, response := create(map[string]interface{}{"hosts": []string{"192.0.2.2", "192.0.2.3"}}); status != http.StatusOK {
+		t.Fatalf("failed to add records: %d %s", status, response.Reason)
+	}
+	if a := db.Get.A("www.example.com."); a == nil || strings.Join(a.Hosts(), " ") != "192.0.2.1 192.0.2.2 192.0.2.3" {
+		t.Errorf("expected all addresses in order, got %+v", a)
+	}
+
+	// An empty set of hosts is not a record
+	if status, _ := create(map[string]interface{}{"hosts": []string{}}); status != http.StatusBadRequest {
+		t.Errorf("expected empty hosts to be rejected, got %d", status)
+	}
+
+	// Replacing drops the addresses held before
+	if status, response := create(map[string]interface{}{"hosts": []string{"192.0.2.4"}, "replace": true}); status != http.StatusOK {
+		t.Fatalf("failed to replace records: %d %s", status, response.Reason)
+	} else if a := db.Get.A("www.example.com."); a == nil || strings.Join(a.Hosts(), " ") != "192.0.2.4" {
+		t.Errorf("expected only the replacing address, got %+v", a)
+	}
+}
+
+func TestCreateSkipsIdenticalHost(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.skip-identical", true)
+	t.Cleanup(func() { viper.Set("records.skip-identical", false) })
+
+	body := map[string]interface{}{"type": "A", "name": "www.example.com", "host": "192.0.2.1"}
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	_, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body)
+	if string(response.Data) != `{"unchanged":true}` {
+		t.Errorf("expected identical host to be skipped, got %s", response.Data)
+	}
+
+	// Prerequisites may also give a single host
+	body = map[string]interface{}{"type": "TXT", "name": "www.example.com", "text": []string{"checked"}, "prerequisites": []map[string]interface{}{
+		{"name": "www.example.com", "type": "A", "exists": true, "value": map[string]interface{}{"host": "192.0.2.1"}},
+	}}
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusOK {
+		t.Errorf("expected prerequisite on a single host to hold, got %d %s", status, response.Reason)
+	}
+}
+
+func TestCreateValidatesAppendedHosts(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	util.RegisterValidator("A", func(name string, record db.Record) error {
+		if name == "limited.example.com" && len(record.(*db.A).Addresses) > 2 {
+			return util.ValidationError{Field: "hosts", Reason: "at most two addresses"}
+		}
+		return nil
+	})
+
+	body := map[string]interface{}{"type": "A", "name": "limited.example.com", "hosts": []string{"192.0.2.1", "192.0.2.2"}}
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	// Validators see the addresses held along with the one being added
+	body = map[string]interface{}{"type": "A", "name": "limited.example.com", "host": "192.0.2.3"}
+	if status, _ := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusBadRequest {
+		t.Errorf("expected third address to be rejected, got %d", status)
+	}
+}
+
+func TestCreateCapsTextStrings(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.max-text-strings", 3)
+	t.Cleanup(func() { viper.Set("records.max-text-strings", 32) })
+
+	for _, recordType := range []string{"TXT", "SPF"} {
+		name := strings.ToLower(recordType) + ".example.com"
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": recordType, "name": name, "text": []string{"one", "two", "three"},
+		})
+		if status != http.StatusOK {
+			t.Errorf("%s: expected strings at the limit to be accepted, got %d %s", recordType, status, response.Reason)
+		}
+
+		status, response = testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": recordType, "name": "over." + name, "text": []string{"one", "two", "three", "four"},
+		})
+		if status != http.StatusBadRequest || response.Reason != "field 'text' must have at most 3 elements" {
+			t.Errorf("%s: expected strings past the limit to be rejected, got %d %s", recordType, status, response.Reason)
+		}
+	}
+}
+
+func TestCreateWarnsSRVTargetCNAME(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	viper.Set("records.check-srv-targets", true)
+	t.Cleanup(func() { viper.Set("records.check-srv-targets", false) })
+
+	for _, body := range []map[string]interface{}{
+		{"type": "CNAME", "name": "alias.example.com", "target": "host.example.com"},
+		{"type": "A", "name": "host.example.com", "host": "192.0.2.1"},
+	} {
+		if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusOK {
+			t.Fatalf("failed to create %s record: %d %s", body["type"], status, response.Reason)
+		}
+	}
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "SRV", "name": "_sip._tcp.example.com", "priority": 10, "weight": 5, "port": 5060, "target": "alias.example.com",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	} else if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "is a CNAME") {
+		t.Errorf("expected a warning about the CNAME target, got %v", response.Warnings)
+	}
+
+	status, response = testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "SRV", "name": "_xmpp._tcp.example.com", "priority": 10, "weight": 5, "port": 5222, "target": "host.example.com",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	} else if len(response.Warnings) != 0 {
+		t.Errorf("expected no warnings for an address target, got %v", response.Warnings)
+	}
+}
+
+func TestCreateCSYNC(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "CSYNC", "name": "example.com", "serial": 2024010101, "flags": 3, "types": []string{"NS", "A", "a"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	// The bitmap is deduplicated and kept in ascending order of type
+	_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=CSYNC", token, nil)
+	var record db.CSYNC
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if record.Serial != 2024010101 || record.Flags != 3 || strings.Join(record.Types, " ") != "A NS" {
+		t.Errorf("expected CSYNC 2024010101 3 A NS, got %+v", record)
+	}
+
+	for _, test := range []struct {
+		flags  int
+		types  []string
+		reason string
+	}{
+		{4, []string{"NS"}, "field 'flags' may only contain the immediate (1) and soaminimum (2) bits"},
+		{1, []string{"NS", "BOGUS"}, "field 'types' contains unknown record type 'BOGUS'"},
+	} {
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "CSYNC", "name": "example.com", "serial": 1, "flags": test.flags, "types": test.types,
+		})
+		if status != http.StatusBadRequest || response.Reason != test.reason {
+			t.Errorf("flags %d types %v: expected %q, got %d %s", test.flags, test.types, test.reason, status, response.Reason)
+		}
+	}
+}
+
+func TestCreateAMTRELAY(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	tests := []struct {
+		name      string
+		relayType int
+		relay     string
+	}{
+		{"none.example.com", 0, ""},
+		{"ipv4.example.com", 1, "192.0.2.1"},
+		{"ipv6.example.com", 2, "2001:db8::1"},
+		{"domain.example.com", 3, "relay.example.com."},
+	}
+	for _, test := range tests {
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "AMTRELAY", "name": test.name, "precedence": 10, "discovery-optional": true, "relay-type": test.relayType, "relay": test.relay,
+		})
+		if status != http.StatusOK {
+			t.Fatalf("relay type %d: failed to create record: %d %s", test.relayType, status, response.Reason)
+		}
+
+		_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/"+test.name+"?type=AMTRELAY", token, nil)
+		var record db.AMTRELAY
+		if err := json.Unmarshal(response.Data, &record); err != nil {
+			t.Fatal(err)
+		} else if record.Precedence != 10 || !record.Discovery || int(record.RelayType) != test.relayType || record.Relay != test.relay {
+			t.Errorf("relay type %d: expected AMTRELAY 10 1 %d %s, got %+v", test.relayType, test.relayType, test.relay, record)
+		}
+	}
+
+	// The relay must match the relay type
+	for _, test := range []struct {
+		relayType int
+		relay     string
+		reason    string
+	}{
+		{0, "192.0.2.1", "field 'relay' must be empty for relay type 0"},
+		{1, "2001:db8::1", "field 'relay' must be an IPv4 address for relay type 1"},
+		{2, "192.0.2.1", "field 'relay' must be an IPv6 address for relay type 2"},
+		{3, "", "field 'relay' must be a domain name for relay type 3"},
+		{4, "relay.example.com.", "field 'relay-type' must be one of 0, 1, 2, 3"},
+	} {
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "AMTRELAY", "name": "invalid.example.com", "precedence": 10, "discovery-optional": false, "relay-type": test.relayType, "relay": test.relay,
+		})
+		if status != http.StatusBadRequest || response.Reason != test.reason {
+			t.Errorf("relay type %d with %q: expected %q, got %d %s", test.relayType, test.relay, test.reason, status, response.Reason)
+		}
+	}
+}
+
+func TestCreateRunsRegisteredValidators(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	// Block the benchmarking range, which other tests never use
+	_, reserved, _ := net.ParseCIDR("198.18.0.0/15")
+	util.RegisterValidator("A", func(name string, record db.Record) error {
+		for _, address := range record.(*db.A).Addresses {
+			if reserved.Contains(address) {
+				return util.ValidationError{Field: "hosts", Reason: "address " + address.String() + " is reserved"}
+			}
+		}
+		return nil
+	})
+
+	w, response := testRequestWithHeaders(t, AllRecordsHandler(database), "POST", "/api/records", token, nil, map[string]interface{}{
+		"type": "A", "name": "bench.example.com", "host": "198.18.0.1",
+	})
+	var body struct {
+		Details []util.ErrorDetail `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || response.Reason != "record rejected by validators" {
+		t.Errorf("expected the record to be rejected, got %d %s", w.Code, response.Reason)
+	} else if len(body.Details) != 1 || body.Details[0].Field != "hosts" || body.Details[0].Message != "address 198.18.0.1 is reserved" {
+		t.Errorf("expected the rejection as a detail, got %+v", body.Details)
+	} else if db.Get.A("bench.example.com.") != nil {
+		t.Error("expected the rejected record not to be written")
+	}
+
+	// Appending an address is checked along with those already held
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("expected an allowed address to be written, got %d %s", status, response.Reason)
+	}
+	if status, _ := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "198.19.255.1",
+	}); status != http.StatusBadRequest {
+		t.Errorf("expected an appended reserved address to be rejected, got %d", status)
+	}
+}
@@ -3,12 +3,12 @@ package records
 import (
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/viper"
 	"net/http"
 	"strings"
 )
 
-func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Set database into operations
 	db.Get.Db = database
 	db.Set.Db = database
@@ -49,61 +49,47 @@ func deleteRecord(w http.ResponseWriter, r *http.Request, path string, database
 	record := strings.ToLower(r.URL.Path[len(path):])
 
 	// Check if allowed
-	if allowed, err := db.EvaluateRole(user.Role, record, database); err != nil {
-		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+	if !checkRole(w, r, user, record, "delete", database) {
 		return
-	} else if !allowed {
-		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to create record")
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(record, r.URL.Query().Get("type"), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
 		return
 	}
 
-	switch r.URL.Query().Get("type") {
-	case "A":
-		err = db.Delete.A(record)
-	case "AAAA":
-		err = db.Delete.AAAA(record)
-	case "CNAME":
-		err = db.Delete.CNAME(record)
-	case "MX":
-		err = db.Delete.MX(record)
-	case "LOC":
-		err = db.Delete.LOC(record)
-	case "SRV":
-		err = db.Delete.SRV(record)
-	case "SPF":
-		err = db.Delete.SPF(record)
-	case "TXT":
-		err = db.Delete.TXT(record)
-	case "NS":
-		err = db.Delete.NS(record)
-	case "CAA":
-		err = db.Delete.CAA(record)
-	case "PTR":
-		err = db.Delete.PTR(record)
-	case "CERT":
-		err = db.Delete.CERT(record)
-	case "DNSKEY":
-		err = db.Delete.DNSKEY(record)
-	case "DS":
-		err = db.Delete.DS(record)
-	case "NAPTR":
-		err = db.Delete.NAPTR(record)
-	case "SMIMEA":
-		err = db.Delete.SMIMEA(record)
-	case "SSHFP":
-		err = db.Delete.SSHFP(record)
-	case "TLSA":
-		err = db.Delete.TLSA(record)
-	case "URI":
-		err = db.Delete.URI(record)
-	default:
-		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
+	// Keep the record in the trash for a while unless configured not to
+	recordType := strings.ToUpper(r.URL.Query().Get("type"))
+	if !util.StringInArray(recordType, db.RecordTypes) {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI, CSYNC, AMTRELAY, HINFO, CDNSKEY, SVCB, HTTPS, SOA")
 		return
 	}
 
+	// Record the change in the audit log along with the delete
+	deleter := db.Delete.WithAudit(db.NewAuditEntry(user.Username, "delete", record, recordType))
+	if viper.GetDuration("records.trash-retention") > 0 {
+		err = deleter.Trash(record, recordType)
+	} else {
+		err = deleter.Record(record, recordType)
+	}
+
 	if err != nil {
 		util.Responses.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
+
+	// Free the record from its owner's quota
+	if err := db.DeleteMetadata(record, recordType, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete record metadata: "+err.Error())
+		return
+	}
+
 	util.Responses.Success(w)
 }
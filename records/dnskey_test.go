@@ -0,0 +1,44 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestCreateValidatesDNSKEY(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	for _, recordType := range []string{"DNSKEY", "CDNSKEY"} {
+		for _, test := range []struct {
+			flags, protocol float64
+			status          int
+		}{
+			{256, 3, http.StatusOK},
+			{257, 3, http.StatusOK},
+			{257, 1, http.StatusBadRequest},
+			{256 | 0x8000, 3, http.StatusBadRequest},
+		} {
+			body := map[string]interface{}{"type": recordType, "name": "example.com", "flags": test.flags, "protocol": test.protocol, "algorithm": 13, "public-key": "AwEAAQ=="}
+			status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body)
+			if status != test.status {
+				t.Errorf("%s flags %v protocol %v: expected %d, got %d: %s", recordType, test.flags, test.protocol, test.status, status, response.Reason)
+			}
+		}
+	}
+}
+
+func TestUpdateValidatesCDNSKEY(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.CDNSKEY("example.com", 257, 3, 13, "AwEAAQ=="); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/example.com", token, map[string]interface{}{"type": "CDNSKEY", "protocol": 1})
+	if status != http.StatusBadRequest || response.Reason != "field 'protocol' must be 3" {
+		t.Fatalf("expected 400 for the protocol, got %d: %s", status, response.Reason)
+	}
+	if record := db.Get.CDNSKEY("example.com."); record == nil || record.Protocol != 3 {
+		t.Fatalf("expected the stored key to be unchanged, got %+v", record)
+	}
+}
@@ -0,0 +1,113 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestUpdateIfMatch(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	w, _ := testRequestWithHeaders(t, single, "GET", "/api/records/www.example.com?type=A", token, nil, nil)
+	stale := w.Header().Get("ETag")
+	if stale == "" {
+		t.Fatal("expected an ETag when reading")
+	}
+
+	// Another admin changes the record in the meantime
+	if w, response := testRequestWithHeaders(t, single, "PUT", "/api/records/www.example.com", token, nil, map[string]interface{}{"type": "A", "host": "192.0.2.2"}); w.Code != http.StatusOK {
+		t.Fatalf("failed to update record unconditionally: %d %s", w.Code, response.Reason)
+	}
+
+	w, response := testRequestWithHeaders(t, single, "PUT", "/api/records/www.example.com", token, map[string]string{"If-Match": stale}, map[string]interface{}{"type": "A", "host": "192.0.2.3"})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status 412 with a stale ETag, got %d", w.Code)
+	} else if response.Reason != "record has changed since it was read" {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+	fresh := w.Header().Get("ETag")
+	if fresh == "" || fresh == stale {
+		t.Fatalf("expected the current ETag with the failure, got %q", fresh)
+	}
+	if record := db.Get.A("www.example.com."); record.Hosts()[0] != "192.0.2.2" {
+		t.Errorf("expected the record to be left unchanged, got %v", record.Hosts())
+	}
+
+	// Retrying with the fresh ETag succeeds and returns the ETag of the new
+	// value for the next update
+	w, response = testRequestWithHeaders(t, single, "PUT", "/api/records/www.example.com", token, map[string]string{"If-Match": fresh}, map[string]interface{}{"type": "A", "host": "192.0.2.3"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got %d %s", w.Code, response.Reason)
+	}
+	if record := db.Get.A("www.example.com."); record.Hosts()[0] != "192.0.2.3" {
+		t.Errorf("expected 192.0.2.3 after the retry, got %v", record.Hosts())
+	}
+	if written, _ := testRequestWithHeaders(t, single, "GET", "/api/records/www.example.com?type=A", token, nil, nil); w.Header().Get("ETag") != written.Header().Get("ETag") {
+		t.Errorf("expected the update to return the ETag of the new value, got %q", w.Header().Get("ETag"))
+	}
+}
+
+func TestStaleUpdateReturnsCurrentValue(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "MX", "name": "example.com", "priority": 10, "host": "mail.example.com.",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	w, _ := testRequestWithHeaders(t, single, "GET", "/api/records/example.com?type=MX", token, nil, nil)
+	stale := w.Header().Get("ETag")
+
+	if status, response := testRequest(t, single, "PUT", "/api/records/example.com", token, map[string]interface{}{"type": "MX", "priority": 20}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+
+	w, response := testRequestWithHeaders(t, single, "PUT", "/api/records/example.com", token, map[string]string{"If-Match": stale}, map[string]interface{}{"type": "MX", "priority": 30})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status 412 with a stale ETag, got %d", w.Code)
+	}
+
+	// The current value comes back along with what changed since it was read
+	var conflict struct {
+		ETag   string `json:"etag"`
+		Record struct {
+			Priority uint16 `json:"priority"`
+			Host     string `json:"host"`
+		} `json:"record"`
+		Changes map[string]db.FieldChange `json:"changes"`
+	}
+	if err := json.Unmarshal(response.Data, &conflict); err != nil {
+		t.Fatal(err)
+	}
+	if conflict.ETag != w.Header().Get("ETag") || conflict.Record.Priority != 20 || conflict.Record.Host != "mail.example.com." {
+		t.Errorf("expected the current record with priority 20, got %+v", conflict)
+	}
+	if change, ok := conflict.Changes["priority"]; len(conflict.Changes) != 1 || !ok || change.Before != 10.0 || change.After != 20.0 {
+		t.Errorf("expected priority to have changed from 10 to 20, got %+v", conflict.Changes)
+	}
+}
+
+func TestStaleUpdateRequiresAccess(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if err := db.CreateRole("user", "", "", "", []string{"txt.example.com"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current value is not disclosed to roles which may not change it
+	w, response := testRequestWithHeaders(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]string{"If-Match": `"stale"`}, map[string]interface{}{"type": "A", "host": "192.0.2.2"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d %s", w.Code, response.Reason)
+	} else if len(response.Data) != 0 || w.Header().Get("ETag") != "" {
+		t.Errorf("expected nothing about the record in the response, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,35 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+)
+
+// Check that the role of a user may change a record, writing a 403 response
+// naming the action if not
+// Admins may pass ?explain=true&role=<role> to make the request as that role
+// and have a denial include the rule that decided it
+func checkRole(w http.ResponseWriter, r *http.Request, user db.User, name, action string, database *db.Database) bool {
+	role := user.Role
+	explain := user.Role == "admin" && r.URL.Query().Get("explain") == "true"
+	if explain && r.URL.Query().Get("role") != "" {
+		role = r.URL.Query().Get("role")
+	}
+
+	decision, err := db.ExplainRole(role, name, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return false
+	} else if decision.Allowed {
+		return true
+	}
+
+	reason := "role '" + role + "' is not allowed to " + action + " record"
+	if explain {
+		util.Responses.ErrorWithData(w, http.StatusForbidden, reason, decision)
+	} else {
+		util.Responses.Error(w, http.StatusForbidden, reason)
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestExplainDenial(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.CreateRole("restricted", "", "", "", []string{"*.example.com"}, []string{"secret.example.com"}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	body := map[string]interface{}{"type": "A", "name": "secret.example.com", "host": "192.0.2.1"}
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records?explain=true&role=restricted", token, body)
+	if status != http.StatusForbidden || response.Reason != "role 'restricted' is not allowed to create record" {
+		t.Fatalf("expected 403 for the role, got %d: %s", status, response.Reason)
+	}
+
+	var decision db.RoleDecision
+	if err := json.Unmarshal(response.Data, &decision); err != nil {
+		t.Fatalf("expected the decision in the response, got %s: %v", response.Data, err)
+	}
+	if decision.Role != "restricted" || decision.Rule != "secret.example.com" || decision.Source != "deny-names" {
+		t.Fatalf("unexpected decision %+v", decision)
+	}
+}
+
+func TestExplainIsAdminOnly(t *testing.T) {
+	database, token := testDatabase(t, "restricted")
+	if err := db.CreateRole("restricted", "", "", "", []string{"*.example.com"}, []string{"secret.example.com"}, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("secret.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		handler http.HandlerFunc
+		method  string
+		url     string
+		body    map[string]interface{}
+		action  string
+	}{
+		{AllRecordsHandler(database), "POST", "/api/records?explain=true", map[string]interface{}{"type": "A", "name": "secret.example.com", "host": "192.0.2.1"}, "create"},
+		{SingleRecordHandler("/api/records/", database), "PUT", "/api/records/secret.example.com?explain=true", map[string]interface{}{"type": "A", "host": "192.0.2.2"}, "update"},
+		{SingleRecordHandler("/api/records/", database), "DELETE", "/api/records/secret.example.com?type=A&explain=true", nil, "delete"},
+	} {
+		status, response := testRequest(t, test.handler, test.method, test.url, token, test.body)
+		if status != http.StatusForbidden || response.Reason != "role 'restricted' is not allowed to "+test.action+" record" {
+			t.Errorf("%s: expected 403, got %d: %s", test.method, status, response.Reason)
+		} else if len(response.Data) != 0 {
+			t.Errorf("%s: expected no explanation for a non-admin, got %s", test.method, response.Data)
+		}
+	}
+}
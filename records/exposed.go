@@ -1,13 +1,13 @@
 package records
 
 import (
+	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 )
 
 // Handle requests for methods regarding the entirety of the records
-func AllRecordsHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func AllRecordsHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
@@ -24,7 +24,7 @@ func AllRecordsHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request)
 }
 
 // Handle requests for methods regarding singular records
-func SingleRecordHandler(path string, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func SingleRecordHandler(path string, db *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
@@ -42,3 +42,203 @@ func SingleRecordHandler(path string, db *bolt.DB) func(w http.ResponseWriter, r
 		}
 	}
 }
+
+// Handle requests for methods regarding multiple records at once
+// Other methods are requests for the record named "batch" under path
+func BatchRecordsHandler(path string, db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			batch(w, r, db)
+			return
+		default:
+			SingleRecordHandler(path, db)(w, r)
+			return
+		}
+	}
+}
+
+// Handle requests exporting every change made to a record
+func JournalHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			journal(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for managing ACME DNS-01 challenges
+func AcmeHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			acmeChallenge(w, r, db)
+			return
+		case "DELETE":
+			acmeCleanup(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for swapping the values of two records
+func SwapRecordsHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			swap(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests importing records in bulk
+func ImportRecordsHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			importRecords(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests locking records against changes
+func LockRecordHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			lock(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests opening transactions
+func TransactionsHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			openTransaction(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests for the groups records inherit a TTL from
+func GroupsHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "POST":
+			changeGroups(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests regarding a specific group
+func GroupHandler(path string, db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "DELETE":
+			changeGroup(w, r, path, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests regarding a specific transaction
+func TransactionHandler(path string, db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "POST", "DELETE":
+			changeTransaction(w, r, path, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests listing deleted records
+func TrashHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			listTrash(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests restoring deleted records
+func RestoreRecordHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			restore(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests listing the previous values of a record
+func HistoryHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			history(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests reverting a record to a previous value
+func RevertRecordHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			revert(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+)
+
+// Handle listing groups and creating or changing a group
+func changeGroups(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "GET" && r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Method == "POST" && r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Method == "POST" && r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if r.Method == "GET" {
+		all, err := db.ListGroups(database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve groups: "+err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, all)
+		return
+	}
+
+	// Only admins may change groups as they affect records of every user
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exists, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"name", "ttl", "description"}, map[string]map[string]string{
+		"name":        {"type": "string", "required": "true"},
+		"ttl":         {"type": "uint32", "required": "true"},
+		"description": {"type": "string", "required": "false"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := strings.ToLower(body["name"].(string))
+	if strings.Contains(name, "/") {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'name' must not contain '/'")
+		return
+	}
+
+	g := db.Group{TTL: uint32(body["ttl"].(float64))}
+	if util.Exists(body, "description") {
+		g.Description = body["description"].(string)
+	}
+	operation := "update"
+	if _, exists, err := db.GetGroup(name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve group: "+err.Error())
+		return
+	} else if !exists {
+		operation = "create"
+	}
+	if err := db.Audited(db.NewAuditEntry(user.Username, operation, name, "group"), func(tx *bolt.Tx) error {
+		return db.SetGroupTx(name, g, tx)
+	}, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write group to database: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
+
+// Handle reading a group with its members or deleting it
+func changeGroup(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "GET" && r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "group must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name := strings.ToLower(r.URL.Path[len(path):])
+	g, exists, err := db.GetGroup(name, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve group: "+err.Error())
+		return
+	} else if !exists {
+		util.Responses.Error(w, http.StatusNotFound, "specified group does not exist")
+		return
+	}
+
+	if r.Method == "GET" {
+		members, err := db.GroupMembers(name, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve group members: "+err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, map[string]interface{}{"name": name, "description": g.Description, "ttl": g.TTL, "members": members})
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Members go back to the configured TTL
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	if err := db.Audited(db.NewAuditEntry(user.Username, "delete", name, "group"), func(tx *bolt.Tx) error {
+		return db.DeleteGroupTx(name, tx)
+	}, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete group: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
+
+// Parse the group a record body puts the record in, an empty group removes
+// it from its group
+// Returns whether the body sets a group and false if it is invalid, having
+// written the response
+func groupFromBody(w http.ResponseWriter, body map[string]interface{}, database *db.Database) (string, bool, bool) {
+	if !util.Exists(body, "group") {
+		return "", false, true
+	} else if !util.Types.String(body["group"]) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'group' must be a string")
+		return "", false, false
+	}
+
+	name := strings.ToLower(body["group"].(string))
+	if name == "" {
+		return "", true, true
+	}
+
+	if _, exists, err := db.GetGroup(name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve group: "+err.Error())
+		return "", false, false
+	} else if !exists {
+		util.Responses.Error(w, http.StatusBadRequest, "specified group does not exist")
+		return "", false, false
+	}
+	return name, true, true
+}
@@ -0,0 +1,78 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+func TestGroupTTLInheritance(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("dns.ttl", 300)
+	t.Cleanup(func() { viper.Set("dns.ttl", 0) })
+	setGroup := func(ttl int) {
+		t.Helper()
+		if status, response := testRequest(t, GroupsHandler(database), "POST", "/api/records/groups", token, map[string]interface{}{"name": "web", "ttl": ttl}); status != http.StatusOK {
+			t.Fatalf("failed to set group: %d %s", status, response.Reason)
+		}
+	}
+	served := func() map[string]uint32 {
+		ttls := map[string]uint32{}
+		for _, name := range []string{"www.example.com.", "api.example.com.", "pinned.example.com.", "other.example.com."} {
+			ttls[name] = util.RecordTTL(name, name, dns.TypeA)
+		}
+		return ttls
+	}
+
+	setGroup(60)
+	for _, body := range []map[string]interface{}{
+		{"name": "www.example.com", "host": "192.0.2.1", "group": "web"},
+		{"name": "api.example.com", "host": "192.0.2.2", "group": "web"},
+		{"name": "pinned.example.com", "host": "192.0.2.3", "group": "web", "ttl": 30},
+		{"name": "other.example.com", "host": "192.0.2.4"},
+	} {
+		body["type"] = "A"
+		if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body); status != http.StatusOK {
+			t.Fatalf("failed to create %s: %d %s", body["name"], status, response.Reason)
+		}
+	}
+
+	// Changing the group's TTL moves every inheriting member with it
+	for _, ttl := range []int{60, 120} {
+		setGroup(ttl)
+		expected := map[string]uint32{"www.example.com.": uint32(ttl), "api.example.com.": uint32(ttl), "pinned.example.com.": 30, "other.example.com.": 300}
+		for name, ttl := range served() {
+			if ttl != expected[name] {
+				t.Errorf("%s: expected a served ttl of %d, got %d", name, expected[name], ttl)
+			}
+		}
+	}
+
+	status, response := testRequest(t, GroupHandler("/api/records/groups/", database), "GET", "/api/records/groups/web", token, nil)
+	var group struct {
+		TTL     uint32           `json:"ttl"`
+		Members []db.GroupMember `json:"members"`
+	}
+	if status != http.StatusOK {
+		t.Fatalf("failed to read group: %d %s", status, response.Reason)
+	} else if err := json.Unmarshal(response.Data, &group); err != nil {
+		t.Fatal(err)
+	} else if group.TTL != 120 || len(group.Members) != 3 {
+		t.Errorf("expected a ttl of 120 with 3 members, got %+v", group)
+	}
+
+	// Deleting the group sends its members back to the configured TTL
+	if status, response := testRequest(t, GroupHandler("/api/records/groups/", database), "DELETE", "/api/records/groups/web", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete group: %d %s", status, response.Reason)
+	}
+	expected := map[string]uint32{"www.example.com.": 300, "api.example.com.": 300, "pinned.example.com.": 30, "other.example.com.": 300}
+	for name, ttl := range served() {
+		if ttl != expected[name] {
+			t.Errorf("%s: expected a served ttl of %d after deleting the group, got %d", name, expected[name], ttl)
+		}
+	}
+}
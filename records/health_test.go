@@ -0,0 +1,56 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestHealthCheckField(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+
+	tests := []struct {
+		check  interface{}
+		reason string
+	}{
+		{"tcp", "field 'health-check' must be an object"},
+		{map[string]interface{}{"protocol": "icmp", "port": 80}, "field 'health-check': protocol must be one of 'tcp' or 'http'"},
+		{map[string]interface{}{"protocol": "tcp", "port": 0}, "field 'health-check': port must not be 0"},
+		{map[string]interface{}{"protocol": "tcp", "port": 80, "path": "/"}, "field 'health-check': path is only supported for HTTP checks"},
+		{map[string]interface{}{"protocol": "http", "port": 80, "path": "health"}, "field 'health-check': path must start with '/'"},
+	}
+	for _, test := range tests {
+		if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "A", "name": "www.example.com", "host": "192.0.2.1", "health-check": test.check,
+		}); status != http.StatusBadRequest || response.Reason != test.reason {
+			t.Errorf("%v: expected 400 %q, got %d %q", test.check, test.reason, status, response.Reason)
+		}
+	}
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1", "health-check": map[string]interface{}{"protocol": "http", "port": 8080},
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	_, response := testRequest(t, single, "GET", "/api/records/www.example.com?type=A", token, nil)
+	var record struct {
+		HealthCheck *db.HealthCheck `json:"health-check"`
+	}
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if record.HealthCheck == nil || record.HealthCheck.Protocol != "http" || record.HealthCheck.Port != 8080 || record.HealthCheck.Path != "/" {
+		t.Errorf("expected an HTTP check of / on port 8080, got %+v", record.HealthCheck)
+	}
+
+	// Null removes the check
+	if status, response := testRequest(t, single, "PUT", "/api/records/www.example.com", token, map[string]interface{}{"type": "A", "health-check": nil}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+	if metadata, err := db.GetMetadata("www.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	} else if metadata.HealthCheck != nil {
+		t.Errorf("expected the check to be removed, got %+v", metadata.HealthCheck)
+	}
+}
@@ -0,0 +1,93 @@
+package records
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database holding a user of the given role, returning a token
+// for the user
+func testDatabase(t *testing.T, role string) (*db.Database, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	user := db.NewUser("Test", "test", "", role)
+	if err := user.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return database, token
+}
+
+// Serve a single zone for the duration of a test
+func testZone(t *testing.T, zone string) {
+	t.Helper()
+	viper.Set("dns.zones", []string{zone})
+	t.Cleanup(func() { viper.Set("dns.zones", []string{}) })
+}
+
+// The decoded body of a response
+type testResponse struct {
+	Status   string          `json:"status"`
+	Reason   string          `json:"reason"`
+	Data     json.RawMessage `json:"data"`
+	Warnings []string        `json:"warnings"`
+}
+
+// Send a request with a JSON body to a handler, returning the status code and
+// decoded response
+func testRequest(t *testing.T, handler http.HandlerFunc, method, url, token string, body interface{}) (int, testResponse) {
+	t.Helper()
+	w, response := testRequestWithHeaders(t, handler, method, url, token, nil, body)
+	return w.Code, response
+}
+
+// Send a request with extra headers and a JSON body to a handler, returning
+// the recorded and decoded response
+func testRequestWithHeaders(t *testing.T, handler http.HandlerFunc, method, url, token string, headers map[string]string, body interface{}) (*httptest.ResponseRecorder, testResponse) {
+	t.Helper()
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+	r := httptest.NewRequest(method, url, bytes.NewReader(encoded))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", token)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var response testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return w, response
+}
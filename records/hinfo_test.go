@@ -0,0 +1,52 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestHINFO(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+
+	// Both fields are required on create
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "HINFO", "name": "host.example.com", "cpu": "ARM64",
+	}); status != http.StatusBadRequest || response.Reason != "field 'os' is required" {
+		t.Errorf("expected 'os' to be required, got %d %q", status, response.Reason)
+	}
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "HINFO", "name": "host.example.com", "cpu": "ARM64", "os": "Linux",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.HINFO("host.example.com."); record == nil || record.CPU != "ARM64" || record.OS != "Linux" {
+		t.Fatalf("expected ARM64 and Linux to be stored, got %+v", record)
+	}
+
+	// Updating only the OS keeps the CPU
+	if status, response := testRequest(t, single, "PUT", "/api/records/host.example.com", token, map[string]interface{}{
+		"type": "HINFO", "os": "FreeBSD",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.HINFO("host.example.com."); record == nil || record.CPU != "ARM64" || record.OS != "FreeBSD" {
+		t.Errorf("expected ARM64 and FreeBSD after the update, got %+v", record)
+	}
+
+	if status, response := testRequest(t, single, "DELETE", "/api/records/host.example.com?type=HINFO", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.HINFO("host.example.com."); record != nil {
+		t.Errorf("expected the record to be deleted, got %+v", record)
+	}
+
+	// Updating a missing record is rejected
+	if status, response := testRequest(t, single, "PUT", "/api/records/host.example.com", token, map[string]interface{}{
+		"type": "HINFO", "os": "Linux",
+	}); status != http.StatusBadRequest || response.Reason != "specified record does not exist" {
+		t.Errorf("expected the missing record to be rejected, got %d %q", status, response.Reason)
+	}
+}
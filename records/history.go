@@ -0,0 +1,192 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handle listing the previous values of a record
+func history(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("name") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'name' is required")
+		return
+	} else if r.URL.Query().Get("type") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(r.URL.Query().Get("name")), ".")
+	recordType := strings.ToUpper(r.URL.Query().Get("type"))
+
+	// Check if allowed
+	if decision, err := db.ExplainRole(user.Role, name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !decision.Allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to access record")
+		return
+	}
+
+	versions, err := db.GetHistory(name, recordType, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record history: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, versions)
+}
+
+// Handle writing a previous value of a record back as its current value
+func revert(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Validate body by decoding json, checking fields exists, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"name", "type", "version"}, map[string]map[string]string{
+		"name":    {"type": "string", "required": "true"},
+		"type":    {"type": "string", "required": "true"},
+		"version": {"type": "uint32", "required": "true"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(body["name"].(string)), ".")
+	recordType := strings.ToUpper(body["type"].(string))
+
+	// Check if allowed
+	if decision, err := db.ExplainRole(user.Role, name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !decision.Allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to modify record")
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(name, recordType, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
+		return
+	}
+
+	// Deleted records are brought back through the trash instead
+	if db.Get.Record(name+".", recordType) == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		return
+	}
+
+	versions, err := db.GetHistory(name, recordType, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record history: "+err.Error())
+		return
+	}
+
+	// Find the requested version among those kept
+	var record db.Record
+	for _, version := range versions {
+		if version.Version == int(body["version"].(float64)) {
+			if record, err = version.Decode(recordType); err != nil {
+				util.Responses.Error(w, http.StatusInternalServerError, "failed to decode record version: "+err.Error())
+				return
+			}
+		}
+	}
+	if record == nil {
+		util.Responses.Error(w, http.StatusNotFound, "specified version does not exist")
+		return
+	}
+
+	// Record the change in the audit log and history along with the write,
+	// refusing versions whose alias now leads back to the record
+	var chain []string
+	setter := db.Set.WithAudit(db.NewAuditEntry(user.Username, "update", name, recordType))
+	if err := database.Update(func(tx *bolt.Tx) error {
+		if err := setter.WithTx(tx).Record(name, record); err != nil {
+			return err
+		} else if recordType == "CNAME" {
+			if chain = cnameLoop(name, tx); chain != nil {
+				return errCNAMELoop
+			}
+		}
+		return nil
+	}); err == errCNAMELoop {
+		util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, err.Error(), map[string][]string{"chain": chain})
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+		return
+	}
+
+	if err := db.TouchMetadata(name, recordType, database); err != nil {
+		log.Printf("Failed to mark record '%s' as modified: %v", name, err)
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,82 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+func TestHistoryAndRevert(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.history-size", 10)
+	t.Cleanup(func() { viper.Set("records.history-size", 0) })
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	for _, host := range []string{"192.0.2.2", "192.0.2.3"} {
+		if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+			"type": "A", "host": host,
+		}); status != http.StatusOK {
+			t.Fatalf("failed to update record: %d %s", status, response.Reason)
+		}
+	}
+
+	status, response := testRequest(t, HistoryHandler(database), "GET", "/api/records/history?name=www.example.com&type=A", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to list history: %d %s", status, response.Reason)
+	}
+	var versions []db.RecordVersion
+	if err := json.Unmarshal(response.Data, &versions); err != nil {
+		t.Fatal(err)
+	} else if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %d", len(versions))
+	}
+	for i, host := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		record, err := versions[i].Decode("A")
+		if err != nil {
+			t.Fatal(err)
+		} else if hosts := record.(*db.A).Hosts(); versions[i].Version != i+1 || len(hosts) != 1 || hosts[0] != host {
+			t.Errorf("expected version %d to hold %s, got %d %v", i+1, host, versions[i].Version, hosts)
+		} else if versions[i].Username != "test" || versions[i].Time == 0 {
+			t.Errorf("expected version %d to record its author and time, got %+v", i+1, versions[i])
+		}
+	}
+
+	// Reverting writes the version back as a new one
+	if status, response := testRequest(t, RevertRecordHandler(database), "POST", "/api/records/revert", token, map[string]interface{}{
+		"name": "www.example.com", "type": "A", "version": 2,
+	}); status != http.StatusOK {
+		t.Fatalf("failed to revert record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.A("www.example.com."); record == nil || len(record.Hosts()) != 1 || record.Hosts()[0] != "192.0.2.2" {
+		t.Errorf("expected 192.0.2.2 after reverting, got %v", record)
+	}
+	if versions, err := db.GetHistory("www.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	} else if len(versions) != 4 || versions[3].Version != 4 {
+		t.Errorf("expected the revert recorded as version 4, got %+v", versions)
+	}
+}
+
+func TestHistoryIsBounded(t *testing.T) {
+	database, _ := testDatabase(t, "admin")
+	viper.Set("records.history-size", 2)
+	t.Cleanup(func() { viper.Set("records.history-size", 0) })
+
+	for _, host := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		if err := db.Set.WithAudit(db.NewAuditEntry("test", "update", "www.example.com", "A")).ReplaceA("www.example.com", []string{host}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if versions, err := db.GetHistory("www.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	} else if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 3 {
+		t.Errorf("expected only versions 2 and 3 kept, got %+v", versions)
+	}
+}
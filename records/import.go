@@ -0,0 +1,322 @@
+package records
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A row of an import along with its outcome
+type importRow struct {
+	Row    int    `json:"row"`
+	Name   string `json:"name,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Advisory about a valid row, such as its TTL being clamped
+	Warning string `json:"warning,omitempty"`
+
+	record db.Record
+	ttl    uint32
+}
+
+// Handle importing records from CSV or TSV with the columns name, ttl, class, type, and rdata,
+// or from an RFC 1035 master file
+func importRecords(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	contentType := strings.Split(r.Header.Get("Content-Type"), ";")[0]
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if contentType != "text/csv" && contentType != "text/tab-separated-values" && contentType != "text/dns" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type CSV, TSV, or DNS")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Parse all rows before writing anything
+	var rows []*importRow
+	if contentType == "text/dns" {
+		if rows, err = parseZoneFile(r.Body); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "failed to parse body: "+err.Error())
+			return
+		}
+	} else {
+		reader := csv.NewReader(r.Body)
+		reader.FieldsPerRecord = -1
+		if contentType == "text/tab-separated-values" {
+			reader.Comma = '\t'
+		}
+		for i := 1; ; i++ {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, "failed to parse body: "+err.Error())
+				return
+			}
+
+			// Skip the header row
+			if i == 1 && strings.EqualFold(strings.TrimSpace(fields[0]), "name") {
+				continue
+			}
+
+			rows = append(rows, parseImportRow(i, fields))
+		}
+	}
+	valid := true
+	for _, row := range rows {
+		valid = valid && row.Status == "valid"
+
+		// TTLs above the maximum of the zone are lowered to it
+		row.ttl, row.Warning = util.LimitTTL(row.Name, row.ttl)
+	}
+
+	// Atomic imports only write if every row is valid
+	atomic := r.URL.Query().Get("atomic") == "true"
+	if atomic && !valid {
+		util.Responses.ErrorWithData(w, http.StatusBadRequest, "invalid rows in import", rows)
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be overwritten by anyone
+	for _, row := range rows {
+		if row.Status != "valid" {
+			continue
+		} else if locked, err := isLocked(row.Name, row.Type, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+			return
+		} else if locked {
+			row.Status = "error"
+			row.Error = "record is locked"
+			valid = false
+		}
+	}
+	if atomic && !valid {
+		util.Responses.ErrorWithData(w, http.StatusLocked, "locked records in import", rows)
+		return
+	}
+
+	// Rows repeating the name of an A record add to its addresses
+	merged := map[string]*db.A{}
+	for _, row := range rows {
+		a, ok := row.record.(*db.A)
+		if !ok || row.Status != "valid" {
+			continue
+		} else if first, ok := merged[row.Name]; ok {
+			first.Addresses = append(first.Addresses, a.Addresses...)
+			row.record = first
+		} else {
+			merged[row.Name] = a
+		}
+	}
+
+	// Admins are not limited by the quota
+	quota := viper.GetInt("records.quota")
+	if user.Role == "admin" {
+		quota = 0
+	}
+
+	// Claim each record and write its TTL in the same transaction as the
+	// record, rejecting aliases that would form a loop
+	usage := 0
+	var chain []string
+	metadata := func(row *importRow) func(tx *bolt.Tx) error {
+		return func(tx *bolt.Tx) (err error) {
+			if _, usage, err = db.ClaimRecordTx(row.Name, row.Type, user.Username, quota, tx); err != nil {
+				return err
+			} else if row.Type == "CNAME" {
+				if chain = cnameLoop(row.Name, tx); chain != nil {
+					return errCNAMELoop
+				}
+			}
+			return db.UpdateMetadataTx(row.Name, row.Type, func(m *db.Metadata) {
+				m.Modified = time.Now().Unix()
+				m.TTL = row.ttl
+			}, tx)
+		}
+	}
+
+	if atomic {
+		if err := database.Update(func(tx *bolt.Tx) error {
+			for _, row := range rows {
+				if err := db.Set.WithTx(tx).WithAudit(db.NewAuditEntry(user.Username, "create", row.Name, row.Type)).WithMetadata(metadata(row)).Record(row.Name, row.record); err == db.ErrQuotaExceeded || err == errCNAMELoop {
+					return err
+				} else if err != nil {
+					return fmt.Errorf("row %d: %v", row.Row, err)
+				}
+			}
+			return nil
+		}); err == db.ErrQuotaExceeded {
+			util.Responses.ErrorWithData(w, http.StatusForbidden, err.Error(), map[string]int{"usage": usage, "quota": quota})
+			return
+		} else if err == errCNAMELoop {
+			util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, err.Error(), map[string][]string{"chain": chain})
+			return
+		} else if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write records to database: "+err.Error())
+			return
+		}
+		for _, row := range rows {
+			row.Status = "created"
+		}
+	} else {
+		for _, row := range rows {
+			if row.Status != "valid" {
+				continue
+			} else if err := db.Set.WithAudit(db.NewAuditEntry(user.Username, "create", row.Name, row.Type)).WithMetadata(metadata(row)).Record(row.Name, row.record); err == db.ErrQuotaExceeded {
+				row.Status = "error"
+				row.Error = err.Error()
+				continue
+			} else if err == errCNAMELoop {
+				row.Status = "error"
+				row.Error = err.Error() + ": " + strings.Join(chain, " -> ")
+				continue
+			} else if err != nil {
+				row.Status = "error"
+				row.Error = "failed to write record to database: " + err.Error()
+				continue
+			}
+			row.Status = "created"
+		}
+	}
+
+	util.Responses.SuccessWithData(w, rows)
+}
+
+// Parse a single row of an import into a record
+func parseImportRow(i int, fields []string) *importRow {
+	row := &importRow{Row: i, Status: "error"}
+	if len(fields) < 5 {
+		row.Error = "row must have the columns name, ttl, class, type, and rdata"
+		return row
+	}
+	for j := range fields {
+		fields[j] = strings.TrimSpace(fields[j])
+	}
+
+	row.Name = strings.TrimSuffix(strings.ToLower(fields[0]), ".")
+	row.Type = strings.ToUpper(fields[3])
+	if row.Name == "" {
+		row.Error = "name must not be empty"
+		return row
+	} else if _, err := strconv.ParseUint(fields[1], 10, 32); err != nil && fields[1] != "" {
+		row.Error = "ttl must be an integer between 0 and 4294967295"
+		return row
+	} else if fields[2] != "" && !strings.EqualFold(fields[2], "IN") {
+		row.Error = "class must be IN"
+		return row
+	} else if !util.StringInArray(row.Type, db.RecordTypes) {
+		row.Error = "type must be one of: " + strings.Join(db.RecordTypes, ", ")
+		return row
+	}
+
+	// A blank TTL is left as 0 to serve the record with the configured TTL
+	ttl, _ := strconv.ParseUint(fields[1], 10, 32)
+	row.ttl = uint32(ttl)
+
+	// Keep text columns containing spaces as a single string, other types
+	// separate their fields with spaces
+	rdata := make([]string, len(fields)-4)
+	for j, field := range fields[4:] {
+		if (row.Type == "TXT" || row.Type == "SPF") && strings.ContainsAny(field, " \t") && !strings.HasPrefix(field, `"`) {
+			field = `"` + strings.ReplaceAll(field, `"`, `\"`) + `"`
+		}
+		rdata[j] = field
+	}
+
+	// Let the zone file parser handle type specific rdata
+	rr, err := dns.NewRR(dns.Fqdn(row.Name) + " IN " + row.Type + " " + strings.Join(rdata, " "))
+	if err != nil {
+		row.Error = "invalid rdata: " + err.Error()
+		return row
+	} else if rr == nil {
+		row.Error = "rdata must not be empty"
+		return row
+	}
+	return recordFromRR(row, rr)
+}
+
+// Parse the records of an RFC 1035 master file into rows of an import
+// Rows are numbered by the position of their record in the file
+func parseZoneFile(body io.Reader) ([]*importRow, error) {
+	var rows []*importRow
+	parser := dns.NewZoneParser(body, "", "")
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		row := &importRow{Row: len(rows) + 1, Status: "error"}
+		row.Name = strings.TrimSuffix(strings.ToLower(rr.Header().Name), ".")
+		row.Type = dns.TypeToString[rr.Header().Rrtype]
+		rows = append(rows, row)
+
+		if rr.Header().Class != dns.ClassINET {
+			row.Error = "class must be IN"
+			continue
+		} else if !util.StringInArray(row.Type, db.RecordTypes) {
+			row.Error = "type must be one of: " + strings.Join(db.RecordTypes, ", ")
+			continue
+		}
+
+		// Records with the configured TTL are served with it as it changes
+		if rr.Header().Ttl != uint32(viper.GetInt("dns.ttl")) {
+			row.ttl = rr.Header().Ttl
+		}
+		recordFromRR(row, rr)
+	}
+	return rows, parser.Err()
+}
+
+// Convert the parsed record of a row into the record to store
+func recordFromRR(row *importRow, rr dns.RR) *importRow {
+	var err error
+	if row.record, err = util.RRToRecord(rr); err != nil {
+		row.Error = err.Error()
+		return row
+	} else if rejections := util.ValidateRecord(row.Name, row.record); len(rejections) != 0 {
+		row.Error = rejections[0].Error()
+		return row
+	}
+	row.Status = "valid"
+	return row
+}
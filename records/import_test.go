@@ -0,0 +1,78 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	body := strings.Join([]string{
+		"name,ttl,class,type,rdata",
+		"www.example.com,300,IN,A,192.0.2.1",
+		"www.example.com,300,IN,A,192.0.2.2",
+		"example.com,,IN,MX,10 mail.example.com.",
+		`example.com,,IN,TXT,"v=spf1 include:example.net -all"`,
+		`example.com,,IN,CAA,"0 issue ""ca.example.net"""`,
+	}, "\n")
+	r := httptest.NewRequest("POST", "/api/records/import?atomic=true", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/csv")
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	ImportRecordsHandler(database)(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to import: %d %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data []importRow `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range response.Data {
+		if row.Status != "created" {
+			t.Errorf("row %d not created: %s", row.Row, row.Error)
+		}
+	}
+
+	if a := db.Get.A("www.example.com."); a == nil || len(a.Addresses) != 2 {
+		t.Errorf("expected both A records, got %+v", a)
+	}
+	if mx := db.Get.MX("example.com."); mx == nil || mx.Priority != 10 || mx.Host != "mail.example.com." {
+		t.Errorf("unexpected MX record: %+v", mx)
+	}
+	if txt := db.Get.TXT("example.com."); txt == nil || strings.Join(txt.Text, "") != "v=spf1 include:example.net -all" {
+		t.Errorf("unexpected TXT record: %+v", txt)
+	}
+	if caa := db.Get.CAA("example.com."); caa == nil || caa.Tag != "issue" || caa.Content != "ca.example.net" {
+		t.Errorf("unexpected CAA record: %+v", caa)
+	}
+
+	// The TTL and owner are written with each record
+	if metadata, err := db.GetMetadata("www.example.com", "A", database); err != nil || metadata.TTL != 300 || metadata.Owner != "test" {
+		t.Errorf("unexpected metadata: %+v %v", metadata, err)
+	}
+}
+
+func TestImportRejectsCNAMELoop(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	body := "a.example.com,,IN,CNAME,b.example.com.\nb.example.com,,IN,CNAME,a.example.com.\n"
+	r := httptest.NewRequest("POST", "/api/records/import?atomic=true", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/csv")
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	ImportRecordsHandler(database)(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected loop to be rejected, got %d %s", w.Code, w.Body.String())
+	} else if db.Get.CNAME("a.example.com.") != nil {
+		t.Error("records of a rejected import were written")
+	}
+}
@@ -0,0 +1,91 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handle exporting every change made to a record, oldest first
+func journal(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("name") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'name' is required")
+		return
+	} else if r.URL.Query().Get("type") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(r.URL.Query().Get("name")), ".")
+	recordType := strings.ToUpper(r.URL.Query().Get("type"))
+
+	// Check if allowed
+	if decision, err := db.ExplainRole(user.Role, name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !decision.Allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to access record")
+		return
+	}
+
+	changes, err := db.GetJournal(name, recordType, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record changes: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, changes)
+}
+
+// Get the fields of a record changed since a value matching an If-Match header
+// was written, according to the journal of the record
+// Returns nil if no value in the journal matches the header
+func changesSince(name, recordType, header string, current db.Record, database *db.Database) map[string]db.FieldChange {
+	changes, err := db.GetJournal(name, recordType, database)
+	if err != nil {
+		log.Printf("Failed to retrieve changes of '%s': %v", name, err)
+		return nil
+	}
+
+	after, err := json.Marshal(current)
+	if err != nil {
+		return nil
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		base := db.NewRecord(recordType)
+		if string(changes[i].After) == "null" || json.Unmarshal(changes[i].After, base) != nil || !util.ETagMatches(header, util.RecordETag(base)) {
+			continue
+		}
+
+		diff, err := db.FieldChanges(changes[i].After, after)
+		if err != nil {
+			return nil
+		}
+		return diff
+	}
+	return nil
+}
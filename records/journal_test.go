@@ -0,0 +1,104 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestJournalRecordsEveryChange(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "host": "192.0.2.2",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "DELETE", "/api/records/www.example.com?type=A", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+
+	status, response := testRequest(t, JournalHandler(database), "GET", "/api/records/journal?name=www.example.com&type=a", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to export journal: %d %s", status, response.Reason)
+	}
+	var changes []db.RecordChange
+	if err := json.Unmarshal(response.Data, &changes); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	for i, change := range changes {
+		if change.Username != "test" || change.Time == 0 {
+			t.Errorf("expected change %d to record its author and time, got %+v", i, change)
+		}
+	}
+
+	// A creation has nothing before it and a deletion nothing after it
+	if string(changes[0].Before) != "null" || string(changes[0].After) == "null" || changes[0].Changes != nil {
+		t.Errorf("expected the creation to hold only the new value, got %+v", changes[0])
+	}
+	if string(changes[2].Before) == "null" || string(changes[2].After) != "null" || changes[2].Changes != nil {
+		t.Errorf("expected the deletion to hold only the old value, got %+v", changes[2])
+	}
+
+	// The update holds both values and the fields that differ
+	if string(changes[1].Before) != string(changes[0].After) || string(changes[1].After) != string(changes[2].Before) {
+		t.Errorf("expected the update to chain the values around it, got %+v", changes[1])
+	}
+	if len(changes[1].Changes) != 1 {
+		t.Fatalf("expected one changed field, got %+v", changes[1].Changes)
+	}
+	for field, change := range changes[1].Changes {
+		before, _ := json.Marshal(change.Before)
+		after, _ := json.Marshal(change.After)
+		if string(before) == string(after) {
+			t.Errorf("expected field %s to differ, got %s and %s", field, before, after)
+		}
+	}
+}
+
+func TestJournalSkipsUnchangedWrites(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+
+	if changes, err := db.GetJournal("www.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 1 {
+		t.Errorf("expected only the creation journaled, got %+v", changes)
+	}
+}
+
+func TestJournalRequiresAccess(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if err := db.CreateRole("user", "", `^txt\.example\.com$`, "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, JournalHandler(database), "GET", "/api/records/journal?name=www.example.com&type=A", token, nil)
+	if status != http.StatusForbidden {
+		t.Errorf("expected 403, got %d %s", status, response.Reason)
+	} else if response.Reason != "role 'user' is not allowed to access record" {
+		t.Errorf("unexpected reason: %s", response.Reason)
+	}
+
+	if status, response := testRequest(t, JournalHandler(database), "GET", "/api/records/journal?name=txt.example.com&type=TXT", token, nil); status != http.StatusOK {
+		t.Errorf("expected 200 for an allowed record, got %d %s", status, response.Reason)
+	}
+}
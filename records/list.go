@@ -3,13 +3,15 @@ package records
 import (
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 	"strings"
 )
 
 // Handle the listing of all records
-func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func list(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
 	if r.Method != "GET" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -19,77 +21,66 @@ func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 	}
 
 	// Verify JWT in headers
-	_, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
 	if err != nil {
 		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
 		return
 	}
 
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	// List all records of a type if query parameter given
-	if len(r.URL.RawQuery) != 0 {
-		if _, ok := r.URL.Query()["type"]; !ok {
+	filters := r.URL.Query()
+	for _, param := range []string{"page", "per-page", "limit", "offset"} {
+		filters.Del(param)
+	}
+	types := db.RecordTypes
+	if len(filters) != 0 {
+		if _, ok := filters["type"]; !ok {
 			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' is required for type filtering")
 			return
 		}
 
-		var rawRecords []map[string]string
-		for _, record := range r.URL.Query()["type"] {
-			if err := database.View(func(tx *bolt.Tx) error {
-				return tx.Bucket([]byte(record)).ForEach(func(k, v []byte) error {
-					rawRecords = append(rawRecords, map[string]string{"name": strings.Split(string(k), "*")[0], "type": record})
-					return nil
-				})
-			}); err != nil {
-				util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve all records: "+err.Error())
+		types = nil
+		for _, recordType := range filters["type"] {
+			recordType = strings.ToUpper(recordType)
+			if !util.StringInArray(recordType, db.RecordTypes) {
+				util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: "+strings.Join(db.RecordTypes, ", "))
 				return
 			}
+			types = append(types, recordType)
 		}
+	}
 
-		// Remove duplicates from array
-		encountered := map[string]bool{}
-		var records []map[string]string
-		for _, v := range rawRecords {
-			if _, ok := encountered[v["name"]]; !ok {
-				encountered[v["name"]] = true
-				records = append(records, v)
-			}
-		}
-
-		// Return empty array if none
-		if records == nil {
-			util.Responses.SuccessWithData(w, []string{})
+	// Only list the records of names the role permits
+	records := []map[string]string{}
+	index := db.Get.Index()
+	for _, name := range db.Get.Names() {
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
 			return
+		} else if !allowed {
+			continue
 		}
-		util.Responses.SuccessWithData(w, records)
-		return
-	}
 
-	var rawRecords []map[string]string
-	for _, record := range []string{"A", "AAAA", "CNAME", "MX", "LOC", "SRV", "SPF", "TXT", "NS", "CAA", "PTR", "CERT", "DNSKEY", "DS", "NAPTR", "SMIMEA", "SSHFP", "TLSA", "URI"} {
-		if err := database.View(func(tx *bolt.Tx) error {
-			return tx.Bucket([]byte(record)).ForEach(func(k, v []byte) error {
-				rawRecords = append(rawRecords, map[string]string{"name": strings.Split(string(k), "*")[0], "type": record})
-				return nil
-			})
-		}); err != nil {
-			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve all records: "+err.Error())
-		}
-	}
-
-	// Remove duplicates from array
-	encountered := map[string]bool{}
-	var records []map[string]string
-	for _, v := range rawRecords {
-		if _, ok := encountered[v["name"]]; !ok {
-			encountered[v["name"]] = true
-			records = append(records, v)
+		for _, recordType := range index[name] {
+			if util.StringInArray(recordType, types) {
+				records = append(records, map[string]string{"name": name, "type": recordType})
+			}
 		}
 	}
 
-	// Return empty array if none
-	if records == nil {
-		util.Responses.SuccessWithData(w, []string{})
+	start, end, err := util.Paginate(w, r, len(records))
+	if err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	util.Responses.SuccessWithData(w, records)
+
+	// Always include the total so clients can build their own paging controls
+	util.Responses.SuccessWithData(w, map[string]interface{}{"total": len(records), "records": records[start:end]})
 }
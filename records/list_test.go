@@ -0,0 +1,85 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListAlwaysIncludesTotal(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		if err := db.Set.A(name, "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, url := range []string{"/api/records", "/api/records?per-page=2", "/api/records?limit=2&offset=1"} {
+		status, response := testRequest(t, AllRecordsHandler(database), "GET", url, token, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", url, status, response.Reason)
+		}
+
+		var data struct {
+			Total   int                 `json:"total"`
+			Records []map[string]string `json:"records"`
+		}
+		if err := json.Unmarshal(response.Data, &data); err != nil {
+			t.Fatalf("%s: unexpected data %s: %v", url, response.Data, err)
+		}
+		if data.Total != 3 {
+			t.Fatalf("%s: expected a total of 3, got %d", url, data.Total)
+		}
+		if url == "/api/records" && len(data.Records) != 3 || url != "/api/records" && len(data.Records) != 2 {
+			t.Fatalf("%s: unexpected page %v", url, data.Records)
+		}
+	}
+}
+
+// Get the targets of a Link header by relation
+func testLinks(header string) map[string]string {
+	links := map[string]string{}
+	for _, link := range strings.Split(header, ", ") {
+		parts := strings.SplitN(link, "; ", 2)
+		if len(parts) == 2 {
+			links[strings.TrimSuffix(strings.TrimPrefix(parts[1], `rel="`), `"`)] = strings.Trim(parts[0], "<>")
+		}
+	}
+	return links
+}
+
+func TestListLinksPages(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"} {
+		if err := db.Set.A(name, "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Follow the next links from the first page to the last
+	var pages []string
+	url := "/api/records?per-page=2"
+	for i := 0; url != "" && i < 5; i++ {
+		w, response := testRequestWithHeaders(t, AllRecordsHandler(database), "GET", url, token, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", url, w.Code, response.Reason)
+		}
+
+		links := testLinks(w.Header().Get("Link"))
+		if links["first"] != "/api/records?page=1&per-page=2" || links["last"] != "/api/records?page=3&per-page=2" {
+			t.Errorf("%s: expected links to the first and last pages, got %v", url, links)
+		}
+		if _, ok := links["prev"]; ok == (i == 0) {
+			t.Errorf("%s: expected a previous page only after the first, got %v", url, links)
+		}
+		pages = append(pages, url)
+		url = links["next"]
+	}
+
+	expected := []string{"/api/records?per-page=2", "/api/records?page=2&per-page=2", "/api/records?page=3&per-page=2"}
+	if strings.Join(pages, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected to traverse %v, got %v", expected, pages)
+	}
+}
@@ -0,0 +1,98 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+)
+
+// Check if a record is locked against changes
+func isLocked(name, recordType string, database *db.Database) (bool, error) {
+	metadata, err := db.GetMetadata(name, recordType, database)
+	return metadata.Locked, err
+}
+
+// Handle locking and unlocking a record against changes
+func lock(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exists, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"name", "type", "locked"}, map[string]map[string]string{
+		"name": {"type": "string", "required": "true"},
+		"type": {"type": "string", "required": "true"},
+		"locked": {"type": "bool", "required": "true"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(body["name"].(string)), ".")
+	recordType := strings.ToUpper(body["type"].(string))
+	if db.Get.Record(name+".", recordType) == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	operation := "unlock"
+	if body["locked"].(bool) {
+		operation = "lock"
+	}
+	if err := db.Audited(db.NewAuditEntry(user.Username, operation, name, recordType), func(tx *bolt.Tx) error {
+		return db.UpdateMetadataTx(name, recordType, func(m *db.Metadata) {
+			m.Locked = body["locked"].(bool)
+		}, tx)
+	}, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record metadata: "+err.Error())
+		return
+	}
+
+	util.Responses.Success(w)
+}
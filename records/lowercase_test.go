@@ -0,0 +1,54 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+func TestCreateLowercasesDomains(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.lowercase-domains", true)
+	t.Cleanup(func() { viper.Set("records.lowercase-domains", false) })
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "MX", "name": "example.com", "priority": 10, "host": "Mail.Example.COM.",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	// Stored canonically so it is served and exported the same way
+	if record := db.Get.MX("example.com."); record == nil || record.Host != "mail.example.com." {
+		t.Fatalf("expected the host stored as mail.example.com., got %+v", record)
+	}
+
+	// Reading shows the casing as written
+	status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=MX", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to read record: %d %s", status, response.Reason)
+	}
+	var record struct {
+		Host         string            `json:"host"`
+		OriginalCase map[string]string `json:"original-case"`
+	}
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if record.Host != "mail.example.com." || record.OriginalCase["host"] != "Mail.Example.COM." {
+		t.Errorf("expected the stored host with its original case, got %+v", record)
+	}
+}
+
+func TestCreateKeepsDomainCaseByDefault(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "MX", "name": "example.com", "priority": 10, "host": "Mail.Example.COM.",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.MX("example.com."); record == nil || record.Host != "Mail.Example.COM." {
+		t.Errorf("expected the host stored as written, got %+v", record)
+	}
+}
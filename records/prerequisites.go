@@ -0,0 +1,88 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Serializes record writes so prerequisites still hold when the write happens
+var writeLock sync.Mutex
+
+// A condition on the stored records that must hold before a write
+type prerequisite struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Exists *bool                  `json:"exists"`
+	Value  map[string]interface{} `json:"value"`
+}
+
+// Check if the prerequisite holds against the stored records
+// Without a type the prerequisite applies to the name as a whole
+func (p prerequisite) met() bool {
+	name := strings.TrimSuffix(strings.ToLower(p.Name), ".")
+	if p.Type == "" {
+		return db.Get.NameExists(name) == *p.Exists
+	}
+
+	record := db.Get.Record(name+".", strings.ToUpper(p.Type))
+	if !*p.Exists {
+		return record == nil
+	} else if p.Value != nil {
+		return util.RecordMatchesBody(record, p.Value)
+	}
+	return record != nil
+}
+
+// Verify the prerequisites of a write, if any were given in the body
+// Writes the error response and returns false if the write must not happen.
+// Callers must hold the write lock until the write completes.
+func checkPrerequisites(w http.ResponseWriter, body map[string]interface{}, role string, database *db.Database) bool {
+	if !util.Exists(body, "prerequisites") {
+		return true
+	}
+
+	// Decode list of prerequisites
+	var prerequisites []prerequisite
+	encoded, err := json.Marshal(body["prerequisites"])
+	if err == nil {
+		err = json.Unmarshal(encoded, &prerequisites)
+	}
+	if err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'prerequisites' must be an array of prerequisites")
+		return false
+	}
+
+	var failed []int
+	for i, p := range prerequisites {
+		if p.Name == "" || p.Exists == nil {
+			util.Responses.Error(w, http.StatusBadRequest, "prerequisites must have a name and whether the record exists")
+			return false
+		} else if p.Type != "" && !util.StringInArray(strings.ToUpper(p.Type), db.RecordTypes) {
+			util.Responses.Error(w, http.StatusBadRequest, "prerequisite type must be one of: "+strings.Join(db.RecordTypes, ", "))
+			return false
+		}
+
+		// Prerequisites must not reveal names the role cannot access
+		if allowed, err := db.EvaluateRole(role, strings.TrimSuffix(strings.ToLower(p.Name), "."), database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return false
+		} else if !allowed {
+			util.Responses.Error(w, http.StatusForbidden, "role '"+role+"' is not allowed to access prerequisite '"+p.Name+"'")
+			return false
+		}
+
+		if !p.met() {
+			failed = append(failed, i)
+		}
+	}
+
+	if len(failed) != 0 {
+		util.Responses.ErrorWithData(w, http.StatusPreconditionFailed, "prerequisites not met", map[string][]int{"failed": failed})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestPrerequisites(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	exists, missing := true, false
+
+	tests := []struct {
+		name          string
+		prerequisites []prerequisite
+		status        int
+	}{
+		{"existing record", []prerequisite{{Name: "www.example.com", Type: "A", Exists: &exists}}, http.StatusOK},
+		{"lowercase type", []prerequisite{{Name: "www.example.com", Type: "a", Exists: &exists}}, http.StatusOK},
+		{"matching value", []prerequisite{{Name: "www.example.com", Type: "a", Exists: &exists, Value: map[string]interface{}{"hosts": []interface{}{"192.0.2.1"}}}}, http.StatusOK},
+		{"missing record", []prerequisite{{Name: "mail.example.com", Type: "A", Exists: &missing}}, http.StatusOK},
+		{"record that does not exist", []prerequisite{{Name: "mail.example.com", Type: "A", Exists: &exists}}, http.StatusPreconditionFailed},
+		{"record that exists", []prerequisite{{Name: "www.example.com", Type: "a", Exists: &missing}}, http.StatusPreconditionFailed},
+		{"name", []prerequisite{{Name: "www.example.com", Exists: &exists}}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := map[string]interface{}{"type": "TXT", "name": "txt.example.com", "text": []string{test.name}, "prerequisites": test.prerequisites}
+			status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body)
+			if status != test.status {
+				t.Errorf("expected %d, got %d %s", test.status, status, response.Reason)
+			}
+		})
+	}
+}
+
+func TestPrerequisiteErrorIsValidJSON(t *testing.T) {
+	database, token := testDatabase(t, "user")
+	if err := db.CreateRole("user", "", "", "", []string{"txt.example.com"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	exists := true
+
+	// Names the role cannot access are echoed back in the reason, which must
+	// still decode as JSON
+	body := map[string]interface{}{"type": "TXT", "name": "txt.example.com", "text": []string{"a"}, "prerequisites": []prerequisite{{Name: `quoted"name`, Exists: &exists}}}
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, body)
+	if status != http.StatusForbidden {
+		t.Errorf("expected 403, got %d %s", status, response.Reason)
+	} else if response.Reason != `role 'user' is not allowed to access prerequisite 'quoted"name'` {
+		t.Errorf("unexpected reason: %s", response.Reason)
+	}
+}
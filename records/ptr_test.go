@@ -0,0 +1,45 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+func TestCreateNormalizesPTRTarget(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "PTR", "name": "1.2.0.192.in-addr.arpa", "domain": "Host.Example.com",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.PTR("1.2.0.192.in-addr.arpa."); record == nil || record.Domain != "host.example.com." {
+		t.Errorf("expected the target stored as host.example.com., got %+v", record)
+	}
+}
+
+func TestCreateRejectsPTROutsideReverseZonesWhenStrict(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.strict-ptr-owners", true)
+	t.Cleanup(func() { viper.Set("records.strict-ptr-owners", false) })
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "PTR", "name": "www.example.com", "domain": "host.example.com.",
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	} else if response.Reason != "PTR records must be within in-addr.arpa or ip6.arpa" {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+
+	for _, name := range []string{"1.2.0.192.in-addr.arpa", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"} {
+		if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": "PTR", "name": name, "domain": "host.example.com.",
+		}); status != http.StatusOK {
+			t.Errorf("expected %s to be accepted, got %d %s", name, status, response.Reason)
+		}
+	}
+}
@@ -1,15 +1,15 @@
 package records
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 	"strings"
 )
 
-func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func read(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Set database into operations
 	db.Get.Db = database
 	db.Set.Db = database
@@ -33,16 +33,42 @@ func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB
 	}
 
 	// Verify JWT in headers
-	_, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
 	if err != nil {
 		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
 		return
 	}
 
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	// Accounts for extra dot and all lowercase in DNS request
 	record := strings.ToLower(r.URL.Path[len(path):] + ".")
 	var response db.Record
 
+	// Every record at the name along with how many of each type there are
+	if r.URL.Query().Get("type") == "ANY" {
+		records := map[string]db.Record{}
+		summary := map[string]int{}
+		for _, recordType := range db.RecordTypes {
+			if stored := db.Get.Record(record, recordType); stored != nil {
+				records[recordType] = stored
+				summary[recordType] = recordCount(stored)
+			}
+		}
+		if len(records) == 0 {
+			util.Responses.Error(w, http.StatusBadRequest, "record does not exist")
+			return
+		}
+
+		util.Responses.SuccessWithData(w, map[string]interface{}{"records": records, "summary": summary})
+		return
+	}
+
 	switch r.URL.Query().Get("type") {
 	case "A":
 		response = db.Get.A(record)
@@ -82,8 +108,22 @@ func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB
 		response = db.Get.TLSA(record)
 	case "URI":
 		response = db.Get.URI(record)
+	case "CSYNC":
+		response = db.Get.CSYNC(record)
+	case "AMTRELAY":
+		response = db.Get.AMTRELAY(record)
+	case "HINFO":
+		response = db.Get.HINFO(record)
+	case "CDNSKEY":
+		response = db.Get.CDNSKEY(record)
+	case "SVCB":
+		response = db.Get.SVCB(record)
+	case "HTTPS":
+		response = db.Get.HTTPS(record)
+	case "SOA":
+		response = db.Get.SOA(record)
 	default:
-		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI, CSYNC, AMTRELAY, HINFO, CDNSKEY, SVCB, HTTPS, SOA")
 		return
 	}
 
@@ -93,5 +133,62 @@ func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB
 		return
 	}
 
+	// Let clients make conditional updates against this value
+	w.Header().Set("ETag", util.RecordETag(response))
+
+	metadata, err := db.GetMetadata(record[:len(record)-1], r.URL.Query().Get("type"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	}
+
+	// Include the record's own TTL and group, and notes only for admins
+	extra := map[string]interface{}{}
+	if metadata.TTL != 0 {
+		extra["ttl"] = metadata.TTL
+	}
+	if metadata.Group != "" {
+		extra["group"] = metadata.Group
+	}
+	if metadata.Preferred != "" {
+		extra["preferred"] = metadata.Preferred
+	}
+	if len(metadata.OriginalCase) != 0 {
+		extra["original-case"] = metadata.OriginalCase
+	}
+	if user.Role == "admin" && metadata.AdminNotes != "" {
+		extra["admin-notes"] = metadata.AdminNotes
+	}
+	if metadata.HealthCheck != nil {
+		extra["health-check"] = metadata.HealthCheck
+		if health, err := db.GetHealth(record[:len(record)-1], database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record health: "+err.Error())
+			return
+		} else if len(health) != 0 {
+			extra["health"] = health
+		}
+	}
+	if len(extra) != 0 {
+		var annotated map[string]interface{}
+		encoded, _ := json.Marshal(response)
+		if err := json.Unmarshal(encoded, &annotated); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to encode record: "+err.Error())
+			return
+		}
+		for key, value := range extra {
+			annotated[key] = value
+		}
+		util.Responses.SuccessWithData(w, annotated)
+		return
+	}
+
 	util.Responses.SuccessWithData(w, response)
 }
+
+// Get the number of resource records a stored record is served as
+func recordCount(record db.Record) int {
+	if a, ok := record.(*db.A); ok {
+		return len(a.Addresses)
+	}
+	return 1
+}
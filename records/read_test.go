@@ -0,0 +1,101 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestReadAnySummarizesTypes(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("www.example.com", "192.0.2.1", "192.0.2.2", "192.0.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("www.example.com", []string{"first", "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/www.example.com?type=ANY", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to read record: %d %s", status, response.Reason)
+	}
+
+	var data struct {
+		Records map[string]json.RawMessage `json:"records"`
+		Summary map[string]int             `json:"summary"`
+	}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Records) != 2 || data.Records["A"] == nil || data.Records["TXT"] == nil {
+		t.Errorf("expected the A and TXT records, got %v", data.Records)
+	}
+	if len(data.Summary) != 2 || data.Summary["A"] != 3 || data.Summary["TXT"] != 1 {
+		t.Errorf("expected 3 A and 1 TXT, got %v", data.Summary)
+	}
+}
+
+func TestAdminNotes(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.CreateRole("editors", "", ".*", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	editor := db.NewUser("Editor", "editor", "", "editors")
+	if err := editor.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	editorToken, err := db.NewToken(editor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := func(token string) (string, bool) {
+		t.Helper()
+		status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/www.example.com?type=A", token, nil)
+		if status != http.StatusOK {
+			t.Fatalf("failed to read record: %d %s", status, response.Reason)
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(response.Data, &record); err != nil {
+			t.Fatal(err)
+		}
+		value, ok := record["admin-notes"]
+		s, _ := value.(string)
+		return s, ok
+	}
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1", "admin-notes": "owned by web team",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if value, ok := notes(token); !ok || value != "owned by web team" {
+		t.Errorf("expected an admin to read the notes, got %q", value)
+	}
+	if value, ok := notes(editorToken); ok {
+		t.Errorf("expected the notes to be left out for other roles, got %q", value)
+	}
+
+	// Only admins may set notes
+	if status, _ := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", editorToken, map[string]interface{}{
+		"type": "A", "admin-notes": "taken over",
+	}); status != http.StatusForbidden {
+		t.Errorf("expected other roles to be forbidden from setting notes, got %d", status)
+	}
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "admin-notes": "see ticket 42",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to update notes: %d %s", status, response.Reason)
+	} else if value, _ := notes(token); value != "see ticket 42" {
+		t.Errorf("expected the updated notes, got %q", value)
+	}
+
+	// An empty string clears them
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "admin-notes": "",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to clear notes: %d %s", status, response.Reason)
+	} else if value, ok := notes(token); ok {
+		t.Errorf("expected the notes to be cleared, got %q", value)
+	}
+}
@@ -0,0 +1,126 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+)
+
+// Create SOA records for zones without one for the duration of a test
+func testAutoSOA(t *testing.T) {
+	t.Helper()
+	viper.Set("records.auto-soa.enabled", true)
+	viper.Set("records.auto-soa.nameserver", "ns1.example.net")
+	t.Cleanup(func() {
+		viper.Set("records.auto-soa.enabled", false)
+		viper.Set("records.auto-soa.nameserver", "")
+	})
+}
+
+func TestCreateMaterializesSOA(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	testAutoSOA(t)
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	soa := db.Get.SOA("example.com.")
+	if soa == nil {
+		t.Fatal("expected an SOA to be created for the zone")
+	} else if soa.Nameserver != "ns1.example.net." || soa.Mailbox != "hostmaster.example.com." || soa.Serial != 1 {
+		t.Errorf("unexpected SOA %+v", soa)
+	} else if soa.Refresh == 0 || soa.Retry == 0 || soa.Expire == 0 || soa.Minimum == 0 {
+		t.Errorf("expected the SOA to have timers, got %+v", soa)
+	}
+}
+
+func TestCreateKeepsExistingSOA(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	testAutoSOA(t)
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "admin.example.com.", 42, 7200, 900, 1209600, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	expected := db.SOA{Nameserver: "ns1.example.com.", Mailbox: "admin.example.com.", Serial: 42, Refresh: 7200, Retry: 900, Expire: 1209600, Minimum: 60}
+	if soa := db.Get.SOA("example.com."); soa == nil || *soa != expected {
+		t.Errorf("expected the SOA to be left as is, got %+v", soa)
+	}
+}
+
+func TestCreateOutsideZonesHasNoSOA(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testAutoSOA(t)
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if soa := db.Get.SOA("example.com."); soa != nil {
+		t.Errorf("expected no SOA without a served zone, got %+v", soa)
+	}
+}
+
+func TestUpdateBumpsSerial(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	viper.Set("records.bump-serial", true)
+	t.Cleanup(func() { viper.Set("records.bump-serial", false) })
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 10, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "host": "192.0.2.2",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to update record: %d %s", status, response.Reason)
+	}
+	if soa := db.Get.SOA("example.com."); soa == nil || soa.Serial != 11 {
+		t.Errorf("expected the serial to be bumped to 11, got %+v", soa)
+	}
+
+	// Deletes move the serial on too
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "DELETE", "/api/records/www.example.com?type=A", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+	if soa := db.Get.SOA("example.com."); soa == nil || soa.Serial != 12 {
+		t.Errorf("expected the serial to be bumped to 12, got %+v", soa)
+	}
+}
+
+func TestFailedWriteKeepsSerial(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testZone(t, "example.com")
+	viper.Set("records.bump-serial", true)
+	t.Cleanup(func() { viper.Set("records.bump-serial", false) })
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 10, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := testRequest(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/www.example.com", token, map[string]interface{}{
+		"type": "A", "host": "not an address",
+	}); status != http.StatusBadRequest {
+		t.Fatalf("expected the update to be rejected, got %d", status)
+	}
+	if soa := db.Get.SOA("example.com."); soa == nil || soa.Serial != 10 {
+		t.Errorf("expected the serial to stay at 10, got %+v", soa)
+	}
+}
@@ -0,0 +1,80 @@
+package records
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Build a base64 encoded ECHConfigList holding a single configuration
+func testECHConfigList(publicName string) []byte {
+	contents := []byte{0x01, 0x00, 0x20, 0x00, 0x20}
+	contents = append(contents, make([]byte, 32)...)
+	contents = append(contents, 0x00, 0x04, 0x00, 0x01, 0x00, 0x01, 0x00, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = append(contents, 0x00, 0x00)
+
+	config := append([]byte{0xfe, 0x0d, byte(len(contents) >> 8), byte(len(contents))}, contents...)
+	return append([]byte{byte(len(config) >> 8), byte(len(config))}, config...)
+}
+
+func TestCreateHTTPSWithECH(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	ech := base64.StdEncoding.EncodeToString(testECHConfigList("example.com"))
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "HTTPS", "name": "example.com", "priority": 1, "target": ".",
+		"params": map[string]string{"alpn": "h2,h3", "ech": ech},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+
+	_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=HTTPS", token, nil)
+	var record struct {
+		Priority uint16            `json:"priority"`
+		Target   string            `json:"target"`
+		Params   map[string]string `json:"params"`
+	}
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if record.Priority != 1 || record.Target != "." || record.Params["ech"] != ech || record.Params["alpn"] != "h2,h3" {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
+
+func TestCreateHTTPSRejectsTruncatedECH(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	list := testECHConfigList("example.com")
+	ech := base64.StdEncoding.EncodeToString(list[:len(list)-4])
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "HTTPS", "name": "example.com", "priority": 1, "target": ".",
+		"params": map[string]string{"ech": ech},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	} else if !strings.HasPrefix(response.Reason, "field 'params' has an invalid 'ech' parameter") {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+
+	if _, response := testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=HTTPS", token, nil); response.Reason != "record does not exist" {
+		t.Errorf("expected the record not to be stored, got %q", response.Reason)
+	}
+}
+
+func TestCreateSVCBAliasRejectsParams(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "SVCB", "name": "_dns.example.com", "priority": 0, "target": "svc.example.net",
+		"params": map[string]string{"alpn": "dot"},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	} else if response.Reason != "field 'params' must be empty for alias records with priority 0" {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+}
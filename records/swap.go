@@ -0,0 +1,151 @@
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handle exchanging the values of two records in one step
+func swap(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Decode the pair of records
+	var body struct {
+		Records []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if len(body.Records) != 2 {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'records' must be of length 2")
+		return
+	}
+
+	first := strings.ToLower(body.Records[0].Name)
+	second := strings.ToLower(body.Records[1].Name)
+	recordType := strings.ToUpper(body.Records[0].Type)
+	if first == "" || second == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "records must have a name")
+		return
+	} else if recordType != strings.ToUpper(body.Records[1].Type) {
+		util.Responses.Error(w, http.StatusBadRequest, "records must be of the same type")
+		return
+	} else if !util.StringInArray(recordType, db.RecordTypes) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: "+strings.Join(db.RecordTypes, ", "))
+		return
+	}
+
+	// Check if allowed on both records
+	for _, name := range []string{first, second} {
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if !allowed {
+			util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to update record '"+name+"'")
+			return
+		}
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	for _, name := range []string{first, second} {
+		if locked, err := isLocked(name, recordType, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+			return
+		} else if locked {
+			util.Responses.Error(w, http.StatusLocked, "record '"+name+"' is locked")
+			return
+		}
+	}
+
+	// Exchange the values in a single transaction
+	errNotExist := fmt.Errorf("specified record does not exist")
+	var chain []string
+	if err := database.Update(func(tx *bolt.Tx) error {
+		getter := db.Get.WithTx(tx)
+		setter := db.Set.WithTx(tx)
+
+		a := getter.Record(first+".", recordType)
+		b := getter.Record(second+".", recordType)
+		if a == nil || b == nil {
+			return errNotExist
+		}
+
+		if err := setter.WithAudit(db.NewAuditEntry(user.Username, "update", first, recordType)).Record(first, b); err != nil {
+			return err
+		}
+		if err := setter.WithAudit(db.NewAuditEntry(user.Username, "update", second, recordType)).Record(second, a); err != nil {
+			return err
+		}
+
+		// Aliases must not lead back to either record
+		for _, name := range []string{first, second} {
+			if recordType != "CNAME" {
+				break
+			} else if chain = cnameLoop(name, tx); chain != nil {
+				return errCNAMELoop
+			}
+		}
+		return nil
+	}); err == errNotExist {
+		util.Responses.Error(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err == errCNAMELoop {
+		util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, err.Error(), map[string][]string{"chain": chain})
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write records to database: "+err.Error())
+		return
+	}
+
+	for _, name := range []string{first, second} {
+		if err := db.TouchMetadata(name, recordType, database); err != nil {
+			log.Printf("Failed to mark record '%s' as modified: %v", name, err)
+		}
+	}
+
+	util.Responses.Success(w)
+}
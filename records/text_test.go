@@ -0,0 +1,96 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Use the text normalization mode for the duration of a test
+func testTextNormalization(t *testing.T, mode string) {
+	t.Helper()
+	viper.Set("records.text-normalization", mode)
+	t.Cleanup(func() { viper.Set("records.text-normalization", "off") })
+}
+
+func TestCreateNormalizesTextWhenLenient(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testTextNormalization(t, "lenient")
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "TXT", "name": "example.com", "text": []string{"v=spf1 -all  "},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	} else if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "surrounding whitespace") {
+		t.Errorf("expected a warning about the whitespace, got %v", response.Warnings)
+	}
+
+	_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=TXT", token, nil)
+	var record struct {
+		Text []string `json:"text"`
+	}
+	if err := json.Unmarshal(response.Data, &record); err != nil {
+		t.Fatal(err)
+	} else if len(record.Text) != 1 || record.Text[0] != "v=spf1 -all" {
+		t.Errorf("expected the trimmed text to be stored, got %q", record.Text)
+	}
+}
+
+func TestCreateRejectsTextWhenStrict(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	testTextNormalization(t, "strict")
+
+	status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "TXT", "name": "example.com", "text": []string{"v=spf1 -all  "},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	} else if response.Reason != "text string 0 has surrounding whitespace" {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+
+	if _, response := testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/example.com?type=TXT", token, nil); response.Reason != "record does not exist" {
+		t.Errorf("expected the record not to be stored, got %q", response.Reason)
+	}
+}
+
+func TestTextKeepsSubmittedOrder(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	submitted := []string{"token-c=3", "token-a=1", "token-b=2"}
+
+	for _, recordType := range []string{"TXT", "SPF"} {
+		status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+			"type": recordType, "name": "verify.example.com", "text": submitted,
+		})
+		if status != http.StatusOK {
+			t.Fatalf("%s: failed to create record: %d %s", recordType, status, response.Reason)
+		}
+
+		_, response = testRequest(t, SingleRecordHandler("/api/records/", database), "GET", "/api/records/verify.example.com?type="+recordType, token, nil)
+		var record struct {
+			Text []string `json:"text"`
+		}
+		if err := json.Unmarshal(response.Data, &record); err != nil {
+			t.Fatal(err)
+		} else if strings.Join(record.Text, " ") != strings.Join(submitted, " ") {
+			t.Errorf("%s: expected %q in submitted order, got %q", recordType, submitted, record.Text)
+		}
+	}
+
+	// Nothing but the submitted strings is stored
+	if err := database.View(func(tx *bolt.Tx) error {
+		var stored []string
+		if err := json.Unmarshal(tx.Bucket([]byte("TXT")).Get([]byte("verify.example.com")), &stored); err != nil {
+			return err
+		} else if strings.Join(stored, " ") != strings.Join(submitted, " ") {
+			t.Errorf("expected exactly %q to be stored, got %q", submitted, stored)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,333 @@
+package records
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record changes staged to be applied together
+type transaction struct {
+	Owner      string           `json:"owner"`
+	Expires    int64            `json:"expires"`
+	Operations []*transactionOp `json:"operations"`
+}
+
+// A single staged change, records are given in zone file presentation format
+type transactionOp struct {
+	Op    string `json:"op"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	RData string `json:"rdata,omitempty"`
+	TTL   uint32 `json:"ttl,omitempty"`
+
+	record db.Record
+}
+
+// Open transactions by their token, they only live as long as the process
+var transactions = struct {
+	sync.Mutex
+	open map[string]*transaction
+}{open: map[string]*transaction{}}
+
+// Get an open transaction belonging to a user, removing it if it has expired
+func getTransaction(id, username string) *transaction {
+	transactions.Lock()
+	defer transactions.Unlock()
+
+	t, ok := transactions.open[id]
+	if !ok || t.Owner != username {
+		return nil
+	} else if time.Now().Unix() > t.Expires {
+		delete(transactions.open, id)
+		return nil
+	}
+	return t
+}
+
+// Handle opening a transaction
+func openTransaction(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Generate token
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to generate transaction token: "+err.Error())
+		return
+	}
+	id := hex.EncodeToString(raw)
+	expires := time.Now().Add(viper.GetDuration("records.transaction-ttl")).Unix()
+
+	transactions.Lock()
+	defer transactions.Unlock()
+
+	// Drop transactions that were never committed or aborted
+	for key, t := range transactions.open {
+		if time.Now().Unix() > t.Expires {
+			delete(transactions.open, key)
+		}
+	}
+	transactions.open[id] = &transaction{Owner: user.Username, Expires: expires, Operations: []*transactionOp{}}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{"token": id, "expires": expires})
+}
+
+// Handle staging to, committing, aborting, and viewing a transaction
+func changeTransaction(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type and headers
+	id := r.URL.Path[len(path):]
+	commit := strings.HasSuffix(id, "/commit")
+	id = strings.TrimSuffix(id, "/commit")
+	if r.Method != "GET" && r.Method != "POST" && r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(id) == 0 || strings.Contains(id, "/") {
+		util.Responses.Error(w, http.StatusBadRequest, "transaction token must be specified in path")
+		return
+	} else if commit && r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Transactions of other users are treated as unknown
+	t := getTransaction(id, user.Username)
+	if t == nil {
+		util.Responses.Error(w, http.StatusNotFound, "transaction does not exist or has expired")
+		return
+	}
+
+	switch {
+	case r.Method == "GET":
+		transactions.Lock()
+		defer transactions.Unlock()
+		util.Responses.SuccessWithData(w, t)
+	case r.Method == "DELETE":
+		transactions.Lock()
+		delete(transactions.open, id)
+		transactions.Unlock()
+		util.Responses.Success(w)
+	case commit:
+		// A transaction can only be committed once, even if it fails
+		transactions.Lock()
+		delete(transactions.open, id)
+		operations := t.Operations
+		transactions.Unlock()
+		commitTransaction(w, operations, user, database)
+	default:
+		stageOperation(w, r, t, user, database)
+	}
+}
+
+// Validate a change and add it to a transaction
+func stageOperation(w http.ResponseWriter, r *http.Request, t *transaction, user db.User, database *db.Database) {
+	if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	}
+
+	op := &transactionOp{}
+	if err := json.NewDecoder(r.Body).Decode(op); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	op.Op = strings.ToLower(op.Op)
+	op.Name = strings.TrimSuffix(strings.ToLower(op.Name), ".")
+	op.Type = strings.ToUpper(op.Type)
+
+	if op.Op != "set" && op.Op != "delete" {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'op' must be one of: set, delete")
+		return
+	} else if op.Name == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'name' is required")
+		return
+	} else if !util.StringInArray(op.Type, db.RecordTypes) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: "+strings.Join(db.RecordTypes, ", "))
+		return
+	}
+
+	// Check if allowed
+	if allowed, err := db.EvaluateRole(user.Role, op.Name, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+		return
+	} else if !allowed {
+		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to modify record")
+		return
+	}
+
+	// Let the zone file parser handle type specific rdata
+	if op.Op == "set" {
+		rr, err := dns.NewRR(dns.Fqdn(op.Name) + " IN " + op.Type + " " + op.RData)
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "invalid rdata: "+err.Error())
+			return
+		} else if rr == nil {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'rdata' is required")
+			return
+		} else if op.record, err = util.RRToRecord(rr); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		} else if rejections := util.ValidateRecord(op.Name, op.record); len(rejections) != 0 {
+			util.Responses.ErrorWithDetails(w, http.StatusBadRequest, "record rejected by validators", util.RejectionDetails(rejections))
+			return
+		}
+	}
+
+	transactions.Lock()
+	defer transactions.Unlock()
+	t.Operations = append(t.Operations, op)
+
+	util.Responses.SuccessWithData(w, map[string]int{"operation": len(t.Operations) - 1})
+}
+
+// Apply every staged change in a single database transaction
+func commitTransaction(w http.ResponseWriter, operations []*transactionOp, user db.User, database *db.Database) {
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	// Locked records cannot be changed by anyone
+	for i, op := range operations {
+		if locked, err := isLocked(op.Name, op.Type, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+			return
+		} else if locked {
+			util.Responses.ErrorWithData(w, http.StatusLocked, "record is locked", map[string]int{"operation": i})
+			return
+		}
+	}
+
+	// Admins are not limited by the quota
+	quota := viper.GetInt("records.quota")
+	if user.Role == "admin" {
+		quota = 0
+	}
+
+	// Nothing is written if any operation fails, including the ownership and
+	// metadata of the changed records
+	var chain []string
+	if err := database.Update(func(tx *bolt.Tx) error {
+		getter := db.Get.WithTx(tx)
+		setter := db.Set.WithTx(tx)
+		deleter := db.Delete.WithTx(tx)
+		for i, op := range operations {
+			if op.Op == "set" {
+				operation := "update"
+				if getter.Record(op.Name+".", op.Type) == nil {
+					operation = "create"
+				}
+				if err := setter.WithAudit(db.NewAuditEntry(user.Username, operation, op.Name, op.Type)).WithMetadata(func(tx *bolt.Tx) error {
+					if _, _, err := db.ClaimRecordTx(op.Name, op.Type, user.Username, quota, tx); err != nil {
+						return err
+					}
+					return db.UpdateMetadataTx(op.Name, op.Type, func(m *db.Metadata) {
+						m.Modified = time.Now().Unix()
+						m.TTL = op.TTL
+					}, tx)
+				}).Record(op.Name, op.record); err != nil {
+					return fmt.Errorf("operation %d: %v", i, err)
+				}
+				continue
+			} else if getter.Record(op.Name+".", op.Type) == nil {
+				return fmt.Errorf("operation %d: record does not exist", i)
+			}
+
+			// Keep deleted records in the trash for a while unless configured not to
+			audited := deleter.WithAudit(db.NewAuditEntry(user.Username, "delete", op.Name, op.Type))
+			if viper.GetDuration("records.trash-retention") > 0 {
+				if err := audited.Trash(op.Name, op.Type); err != nil {
+					return fmt.Errorf("operation %d: %v", i, err)
+				}
+			} else if err := audited.Record(op.Name, op.Type); err != nil {
+				return fmt.Errorf("operation %d: %v", i, err)
+			}
+			if err := db.DeleteMetadataTx(op.Name, op.Type, tx); err != nil {
+				return fmt.Errorf("operation %d: %v", i, err)
+			}
+		}
+
+		// Aliases must not form a loop once every operation is applied
+		for _, op := range operations {
+			if op.Op != "set" || op.Type != "CNAME" {
+				continue
+			} else if chain = cnameLoop(op.Name, tx); chain != nil {
+				return errCNAMELoop
+			}
+		}
+		return nil
+	}); err == errCNAMELoop {
+		util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, err.Error(), map[string][]string{"chain": chain})
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusConflict, "failed to commit transaction: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]int{"applied": len(operations)})
+}
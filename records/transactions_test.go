@@ -0,0 +1,129 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Open a transaction, returning its token
+func testTransaction(t *testing.T, database *db.Database, token string) string {
+	t.Helper()
+	status, response := testRequest(t, TransactionsHandler(database), "POST", "/api/records/transactions", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to open transaction: %d %s", status, response.Reason)
+	}
+	var data struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data.Token
+}
+
+func TestTransactionCommit(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.transaction-ttl", time.Hour)
+	viper.Set("records.trash-retention", time.Hour)
+	t.Cleanup(func() { viper.Set("records.trash-retention", 0) })
+	handler := TransactionHandler("/api/records/transactions/", database)
+
+	if err := db.Set.TXT("old.example.com", []string{"gone"}); err != nil {
+		t.Fatal(err)
+	}
+
+	id := testTransaction(t, database, token)
+	for _, op := range []map[string]interface{}{
+		{"op": "set", "name": "www.example.com", "type": "A", "rdata": "192.0.2.1", "ttl": 300},
+		{"op": "set", "name": "example.com", "type": "MX", "rdata": "10 mail.example.com."},
+		{"op": "delete", "name": "old.example.com", "type": "TXT"},
+	} {
+		if status, response := testRequest(t, handler, "POST", "/api/records/transactions/"+id, token, op); status != http.StatusOK {
+			t.Fatalf("failed to stage operation: %d %s", status, response.Reason)
+		}
+	}
+
+	// Nothing is applied until the transaction is committed
+	if db.Get.A("www.example.com.") != nil {
+		t.Fatal("staged record written before commit")
+	}
+	if status, response := testRequest(t, handler, "POST", "/api/records/transactions/"+id+"/commit", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to commit: %d %s", status, response.Reason)
+	}
+
+	if db.Get.A("www.example.com.") == nil || db.Get.MX("example.com.") == nil {
+		t.Error("committed records not written")
+	}
+	if metadata, err := db.GetMetadata("www.example.com", "A", database); err != nil || metadata.TTL != 300 || metadata.Owner != "test" {
+		t.Errorf("unexpected metadata: %+v %v", metadata, err)
+	}
+
+	// Deleted records go to the trash
+	if db.Get.TXT("old.example.com.") != nil {
+		t.Error("deleted record still stored")
+	} else if trashed, err := db.ListTrash(database); err != nil || len(trashed) != 1 || trashed[0].Name != "old.example.com" {
+		t.Errorf("deleted record not in the trash: %+v %v", trashed, err)
+	}
+
+	// A transaction is gone once committed
+	if status, _ := testRequest(t, handler, "POST", "/api/records/transactions/"+id+"/commit", token, nil); status != http.StatusNotFound {
+		t.Errorf("expected committed transaction to be gone, got %d", status)
+	}
+}
+
+func TestTransactionAbort(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.transaction-ttl", time.Hour)
+	handler := TransactionHandler("/api/records/transactions/", database)
+
+	id := testTransaction(t, database, token)
+	if status, response := testRequest(t, handler, "POST", "/api/records/transactions/"+id, token, map[string]interface{}{"op": "set", "name": "www.example.com", "type": "A", "rdata": "192.0.2.1"}); status != http.StatusOK {
+		t.Fatalf("failed to stage operation: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, handler, "DELETE", "/api/records/transactions/"+id, token, nil); status != http.StatusOK {
+		t.Fatalf("failed to abort: %d %s", status, response.Reason)
+	}
+
+	if status, _ := testRequest(t, handler, "POST", "/api/records/transactions/"+id+"/commit", token, nil); status != http.StatusNotFound {
+		t.Errorf("expected aborted transaction to be gone, got %d", status)
+	} else if db.Get.A("www.example.com.") != nil {
+		t.Error("record of aborted transaction written")
+	}
+}
+
+func TestTransactionExpired(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.transaction-ttl", -time.Second)
+	handler := TransactionHandler("/api/records/transactions/", database)
+
+	id := testTransaction(t, database, token)
+	if status, _ := testRequest(t, handler, "POST", "/api/records/transactions/"+id, token, map[string]interface{}{"op": "set", "name": "www.example.com", "type": "A", "rdata": "192.0.2.1"}); status != http.StatusNotFound {
+		t.Errorf("expected expired transaction to be rejected, got %d", status)
+	}
+}
+
+func TestTransactionRejectsCNAMELoop(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.transaction-ttl", time.Hour)
+	handler := TransactionHandler("/api/records/transactions/", database)
+
+	id := testTransaction(t, database, token)
+	for _, op := range []map[string]interface{}{
+		{"op": "set", "name": "a.example.com", "type": "CNAME", "rdata": "b.example.com."},
+		{"op": "set", "name": "b.example.com", "type": "CNAME", "rdata": "a.example.com."},
+	} {
+		if status, response := testRequest(t, handler, "POST", "/api/records/transactions/"+id, token, op); status != http.StatusOK {
+			t.Fatalf("failed to stage operation: %d %s", status, response.Reason)
+		}
+	}
+
+	if status, _ := testRequest(t, handler, "POST", "/api/records/transactions/"+id+"/commit", token, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected loop to be rejected, got %d", status)
+	} else if db.Get.CNAME("a.example.com.") != nil {
+		t.Error("records of a rejected transaction written")
+	}
+}
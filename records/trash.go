@@ -0,0 +1,147 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"net/http"
+)
+
+// Handle listing the deleted records that can still be restored
+func listTrash(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	trash, err := db.ListTrash(database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve deleted records: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, trash)
+}
+
+// Handle moving a deleted record back out of the trash
+func restore(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+	db.Set.Db = database
+	db.Delete.Db = database
+
+	// Validate initial request with request type, body exists, and content type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exists, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"id"}, map[string]map[string]string{
+		"id": {"type": "string", "required": "true"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	trashed, exists, err := db.GetTrashed(body["id"].(string), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve deleted record: "+err.Error())
+		return
+	} else if !exists {
+		util.Responses.Error(w, http.StatusNotFound, db.ErrNotTrashed.Error())
+		return
+	}
+
+	// Record the change in the audit log along with the write, refusing
+	// aliases that now lead back to the record
+	var chain []string
+	setter := db.Set.WithAudit(db.NewAuditEntry(user.Username, "restore", trashed.Name, trashed.Type))
+	if err := database.Update(func(tx *bolt.Tx) error {
+		if err := setter.WithTx(tx).Restore(trashed.ID); err != nil {
+			return err
+		} else if trashed.Type == "CNAME" {
+			if chain = cnameLoop(trashed.Name, tx); chain != nil {
+				return errCNAMELoop
+			}
+		}
+		return nil
+	}); err == db.ErrRecordExists {
+		util.Responses.Error(w, http.StatusConflict, err.Error())
+		return
+	} else if err == errCNAMELoop {
+		util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, err.Error(), map[string][]string{"chain": chain})
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to restore record: "+err.Error())
+		return
+	}
+
+	if err := db.TouchMetadata(trashed.Name, trashed.Type, database); err != nil {
+		log.Printf("Failed to mark record '%s' as modified: %v", trashed.Name, err)
+	}
+
+	util.Responses.SuccessWithData(w, map[string]string{"name": trashed.Name, "type": trashed.Type})
+}
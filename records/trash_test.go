@@ -0,0 +1,190 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Create and delete an A record through the API while deleted records are
+// kept in the trash
+func testTrashRecord(t *testing.T, database *db.Database, token, name string) {
+	t.Helper()
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": name, "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, SingleRecordHandler("/api/records/", database), "DELETE", "/api/records/"+name+"?type=A", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+}
+
+func TestRestoreDeletedRecord(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.trash-retention", time.Hour)
+	t.Cleanup(func() { viper.Set("records.trash-retention", 0) })
+
+	testTrashRecord(t, database, token, "www.example.com")
+	if db.Get.A("www.example.com.") != nil {
+		t.Fatal("expected the deleted record to stop being served")
+	}
+
+	trash, err := db.ListTrash(database)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(trash) != 1 || trash[0].Name != "www.example.com" || trash[0].Type != "A" {
+		t.Fatalf("expected the record in the trash, got %+v", trash)
+	}
+
+	if err := db.Set.Restore(trash[0].ID); err != nil {
+		t.Fatalf("failed to restore record: %v", err)
+	}
+	if record := db.Get.A("www.example.com."); record == nil || len(record.Hosts()) != 1 || record.Hosts()[0] != "192.0.2.1" {
+		t.Errorf("expected the record to be restored, got %+v", record)
+	}
+	if metadata, err := db.GetMetadata("www.example.com", "A", database); err != nil || metadata.Owner != "test" {
+		t.Errorf("expected the record's metadata to be restored, got %+v %v", metadata, err)
+	}
+	if trash, err := db.ListTrash(database); err != nil || len(trash) != 0 {
+		t.Errorf("expected the trash to be empty, got %+v %v", trash, err)
+	}
+}
+
+func TestPurgeOnlyExpiredTombstones(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.trash-retention", time.Hour)
+	t.Cleanup(func() { viper.Set("records.trash-retention", 0) })
+
+	testTrashRecord(t, database, token, "old.example.com")
+	testTrashRecord(t, database, token, "new.example.com")
+
+	// Age one of the tombstones past the retention period
+	trash, err := db.ListTrash(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old, recent db.TrashedRecord
+	for _, trashed := range trash {
+		if trashed.Name == "old.example.com" {
+			old = trashed
+		} else {
+			recent = trashed
+		}
+	}
+	old.Deleted = time.Now().Add(-2 * time.Hour).Unix()
+	if err := database.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(old)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("deleted")).Put([]byte(old.ID), data)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if purged, err := db.PurgeTrash(time.Now().Add(-viper.GetDuration("records.trash-retention")), database); err != nil {
+		t.Fatal(err)
+	} else if purged != 1 {
+		t.Fatalf("expected 1 record purged, got %d", purged)
+	}
+
+	// The purge is kept in the record's journal and the audit log
+	changes, err := db.GetJournal("old.example.com", "A", database)
+	if err != nil {
+		t.Fatal(err)
+	} else if last := changes[len(changes)-1]; last.Operation != "purge" || last.Username != "system" || last.Before == nil {
+		t.Errorf("expected a purge by the system in the journal, got %+v", last)
+	}
+
+	if entries, err := db.QueryAudit(db.AuditFilter{Username: "system"}, database); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 || entries[0].Operation != "purge" || entries[0].Resource != "old.example.com" || entries[0].Type != "A" {
+		t.Errorf("expected a purge of old.example.com in the audit log, got %+v", entries)
+	}
+
+	// Restoring the purged record fails cleanly, the newer one still restores
+	if err := db.Set.Restore(old.ID); err != db.ErrNotTrashed {
+		t.Errorf("expected restoring a purged record to fail, got %v", err)
+	}
+	if err := db.Set.Restore(recent.ID); err != nil {
+		t.Fatalf("failed to restore record: %v", err)
+	}
+	if db.Get.A("new.example.com.") == nil {
+		t.Error("expected the newer record to be restored")
+	}
+	if db.Get.A("old.example.com.") != nil {
+		t.Error("expected the purged record to stay deleted")
+	}
+}
+
+// Get the records in the trash through the API
+func testTrash(t *testing.T, database *db.Database, token string) []db.TrashedRecord {
+	t.Helper()
+	status, response := testRequest(t, TrashHandler(database), "GET", "/api/records/trash", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to list trash: %d %s", status, response.Reason)
+	}
+
+	var trash []db.TrashedRecord
+	if err := json.Unmarshal(response.Data, &trash); err != nil {
+		t.Fatal(err)
+	}
+	return trash
+}
+
+func TestDeleteThenRestore(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	viper.Set("records.trash-retention", time.Hour)
+	t.Cleanup(func() { viper.Set("records.trash-retention", 0) })
+	single := SingleRecordHandler("/api/records/", database)
+
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.1",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create record: %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, single, "DELETE", "/api/records/www.example.com?type=A", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+	if db.Get.A("www.example.com.") != nil {
+		t.Fatal("expected the record to be gone once deleted")
+	}
+
+	trash := testTrash(t, database, token)
+	if len(trash) != 1 || trash[0].Name != "www.example.com" || trash[0].Type != "A" {
+		t.Fatalf("expected the record in the trash, got %+v", trash)
+	}
+
+	// The name can be recreated while the older version sits in the trash
+	if status, response := testRequest(t, AllRecordsHandler(database), "POST", "/api/records", token, map[string]interface{}{
+		"type": "A", "name": "www.example.com", "host": "192.0.2.2",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to recreate record: %d %s", status, response.Reason)
+	}
+	if status, _ := testRequest(t, RestoreRecordHandler(database), "POST", "/api/records/restore", token, map[string]string{"id": trash[0].ID}); status != http.StatusConflict {
+		t.Fatalf("expected 409 restoring over a live record, got %d", status)
+	}
+
+	// Deleting it again keeps both versions apart
+	if status, response := testRequest(t, single, "DELETE", "/api/records/www.example.com?type=A", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete record: %d %s", status, response.Reason)
+	}
+	if trash := testTrash(t, database, token); len(trash) != 2 {
+		t.Fatalf("expected both versions in the trash, got %+v", trash)
+	}
+
+	if status, response := testRequest(t, RestoreRecordHandler(database), "POST", "/api/records/restore", token, map[string]string{"id": trash[0].ID}); status != http.StatusOK {
+		t.Fatalf("failed to restore record: %d %s", status, response.Reason)
+	}
+	if record := db.Get.A("www.example.com."); record == nil || len(record.Hosts()) != 1 || record.Hosts()[0] != "192.0.2.1" {
+		t.Errorf("expected the first version to be restored, got %v", record)
+	}
+	if trash := testTrash(t, database, token); len(trash) != 1 {
+		t.Errorf("expected only the newer version left in the trash, got %+v", trash)
+	}
+}
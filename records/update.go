@@ -4,14 +4,16 @@ import (
 	"encoding/json"
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
 	bolt "go.etcd.io/bbolt"
 	"net"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // Handle the updating of records
-func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func update(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Set database into operations
 	db.Get.Db = database
 	db.Set.Db = database
@@ -51,11 +53,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 	recordName := strings.ToLower(r.URL.Path[len(path):])
 
 	// Check if allowed
-	if allowed, err := db.EvaluateRole(user.Role, recordName, database); err != nil {
-		util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
-		return
-	} else if !allowed {
-		util.Responses.Error(w, http.StatusForbidden, "role '"+user.Role+"' is not allowed to create record")
+	if !checkRole(w, r, user, recordName, "update", database) {
 		return
 	}
 
@@ -69,8 +67,158 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		return
 	}
 
+	// Check prerequisites and keep them from changing until the record is written
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	if !checkPrerequisites(w, body, user.Role, database) {
+		return
+	}
+
+	// Locked records cannot be changed by anyone
+	if locked, err := isLocked(recordName, body["type"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+		return
+	} else if locked {
+		util.Responses.Error(w, http.StatusLocked, "record is locked")
+		return
+	}
+
+	// Keep the current value to check the update against
+	recordType := strings.ToUpper(body["type"].(string))
+	previous := db.Get.Record(recordName+".", recordType)
+
+	// Parse the clients the record is restricted to
+	var sources []string
+	if util.Exists(body, "sources") {
+		if sources, err = util.ParseSources(body["sources"]); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Only admins may annotate records
+	notes, annotated := "", util.Exists(body, "admin-notes")
+	if annotated && user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin' to set field 'admin-notes'")
+		return
+	} else if annotated && !util.Types.String(body["admin-notes"]) {
+		util.Responses.Error(w, http.StatusBadRequest, "field 'admin-notes' must be a string")
+		return
+	} else if annotated {
+		notes = body["admin-notes"].(string)
+	}
+
+	// Advisories about the record, returned once it is written
+	var warnings []string
+
+	// Parse the TTL to serve the record with, 0 uses the configured TTL
+	var ttl uint32
+	hasTTL := util.Exists(body, "ttl")
+	if hasTTL {
+		if err, _ := util.ValidateBody(body, []string{"ttl"}, map[string]map[string]string{"ttl": {"type": "uint32", "required": "true"}}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		ttl = uint32(body["ttl"].(float64))
+
+		// TTLs above the maximum of the zone are lowered to it
+		var warning string
+		if ttl, warning = util.LimitTTL(recordName, ttl); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	// Parse the group to inherit a TTL from
+	group, grouped, ok := groupFromBody(w, body, database)
+	if !ok {
+		return
+	}
+
+	// Parse the check addresses must pass to be answered with
+	check, checked, validationErr := healthCheckFromBody(body, recordType)
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Parse the address to list first in answers
+	preferred, prefers, validationErr := preferredFromBody(body, recordType)
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Refuse to overwrite changes the client has not seen, returning the
+	// current value to reconcile against along with the fields changed since
+	// the value the client read, when it is still in the journal
+	if previous != nil && !util.ETagMatches(r.Header.Get("If-Match"), util.RecordETag(previous)) {
+		conflict := map[string]interface{}{"etag": util.RecordETag(previous), "record": previous}
+		if changes := changesSince(recordName, recordType, r.Header.Get("If-Match"), previous, database); changes != nil {
+			conflict["changes"] = changes
+		}
+		w.Header().Set("ETag", util.RecordETag(previous))
+		util.Responses.ErrorWithData(w, http.StatusPreconditionFailed, "record has changed since it was read", conflict)
+		return
+	}
+
+	// Apply custom policies, missing records and bodies failing to decode are rejected below
+	if proposed, err := util.ProposedRecord(recordType, previous, body); err == nil && previous != nil {
+		if rejections := util.ValidateRecord(recordName, proposed); len(rejections) != 0 {
+			util.Responses.ErrorWithDetails(w, http.StatusBadRequest, "record rejected by validators", util.RejectionDetails(rejections))
+			return
+		}
+	}
+
+	// Domain valued fields as written, kept when they are stored lowercased
+	originals := map[string]string{}
+	var rewritten []string
+	domain := func(field string) string {
+		rewritten = append(rewritten, field)
+		return domainFromBody(body, field, originals)
+	}
+
+	// Record the change in the audit log and write the metadata of the record
+	// in the same transaction as the record
+	setter := db.Set.WithAudit(db.NewAuditEntry(user.Username, "update", recordName, recordType)).WithMetadata(func(tx *bolt.Tx) error {
+		return db.UpdateMetadataTx(recordName, recordType, func(m *db.Metadata) {
+			m.Modified = time.Now().Unix()
+			if sources != nil {
+				m.AllowedSources = sources
+			}
+			if annotated {
+				m.AdminNotes = notes
+			}
+			if hasTTL {
+				m.TTL = ttl
+			}
+			if grouped {
+				m.Group = group
+			}
+			if checked {
+				m.HealthCheck = check
+			}
+			if prefers {
+				m.Preferred = preferred
+			}
+
+			// Fields written again drop the casing they were previously written with
+			for _, field := range rewritten {
+				delete(m.OriginalCase, field)
+			}
+			for field, value := range originals {
+				if m.OriginalCase == nil {
+					m.OriginalCase = map[string]string{}
+				}
+				m.OriginalCase[field] = value
+			}
+			if len(m.OriginalCase) == 0 {
+				m.OriginalCase = nil
+			}
+		}, tx)
+	})
+
 	// Parse out body by type
-	switch strings.ToUpper(body["type"].(string)) {
+	switch recordType {
 	case "A":
 		// Get original record from database
 		record := db.Get.A(recordName + ".")
@@ -79,20 +227,23 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			return
 		}
 
-		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"host"}, map[string]map[string]string{"host": {"type": "ipv4", "required": "false"}})
+		// Get valid values in body, replacing every address of the name
+		hosts, err := hostsFromBody(body, false)
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
+		} else if hosts == nil {
+			hosts = record.Hosts()
 		}
 
-		// Update values if they exist in the body
-		if valid["host"] {
-			record.Address = net.ParseIP(body["host"].(string))
+		// The preferred address must remain part of the record
+		if preferred != "" && !containsAddress(hosts, preferred) {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'preferred' must be one of the record's addresses")
+			return
 		}
 
 		// Write updated values to the database
-		if err := db.Set.A(recordName, record.Address.String()); err != nil {
+		if err := setter.ReplaceA(recordName, hosts); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -118,7 +269,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to the database
-		if err := db.Set.AAAA(recordName, record.Address.String()); err != nil {
+		if err := setter.AAAA(recordName, record.Address.String()); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -132,7 +283,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"type": "string", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"target"}, map[string]map[string]string{"target": {"type": "hostname", "required": "false"}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -140,11 +291,17 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 
 		// Update values if they exist in the body
 		if valid["target"] {
-			record.Target = body["target"].(string)
+			record.Target = domain("target")
+		}
+
+		// Aliases must not lead back to the record
+		if chain, loop := util.CNAMELoop(recordName, record.Target); loop {
+			util.Responses.ErrorWithData(w, http.StatusUnprocessableEntity, "record would form a CNAME loop", map[string][]string{"chain": chain})
+			return
 		}
 
 		// Write updated values to the database
-		if err := db.Set.CNAME(recordName, record.Target); err != nil {
+		if err := setter.CNAME(recordName, record.Target); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -158,7 +315,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"host", "priority"}, map[string]map[string]string{"host": {"type": "string", "required": "false"}, "priority": {"type": "uint16", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"host", "priority"}, map[string]map[string]string{"host": {"type": "hostname", "required": "false"}, "priority": {"type": "uint16", "required": "false"}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -166,14 +323,14 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 
 		// Update values if they exist in the body
 		if valid["host"] {
-			record.Host = body["host"].(string)
+			record.Host = domain("host")
 		}
 		if valid["priority"] {
 			record.Priority = uint16(body["priority"].(float64))
 		}
 
 		// Write updated values to the database
-		if err := db.Set.MX(recordName, record.Priority, record.Host); err != nil {
+		if err := setter.MX(recordName, record.Priority, record.Host); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -249,7 +406,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.LOC(recordName, record.Version, record.Size, record.HorizontalPrecision, record.VerticalPrecision, record.Altitude, record.LatDegrees, record.LatMinutes, record.LatSeconds, record.LatDirection, record.LongDegrees, record.LongMinutes, record.LongSeconds, record.LongDirection); err != nil {
+		if err := setter.LOC(recordName, record.Version, record.Size, record.HorizontalPrecision, record.VerticalPrecision, record.Altitude, record.LatDegrees, record.LatMinutes, record.LatSeconds, record.LatDirection, record.LongDegrees, record.LongMinutes, record.LongSeconds, record.LongDirection); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -267,7 +424,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			"priority": {"type": "uint16", "required": "false"},
 			"weight": {"type": "uint16", "required": "false"},
 			"port": {"type": "uint16", "required": "false"},
-			"target": {"type": "string", "required": "false"},
+			"target": {"type": "hostname", "required": "false"},
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
@@ -285,11 +442,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			record.Port = uint16(body["port"].(float64))
 		}
 		if valid["target"] {
-			record.Target = body["target"].(string)
+			record.Target = domain("target")
 		}
 
 		// Write updated values to database
-		if err := db.Set.SRV(recordName, record.Priority, record.Weight, record.Port, record.Target); err != nil {
+		if err := setter.SRV(recordName, record.Priority, record.Weight, record.Port, record.Target); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -303,7 +460,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false", "max": viper.GetString("records.max-text-strings")}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -312,11 +469,17 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		// Update values if they exist in body
 		if valid["text"] {
 			text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
+			text, normalized, err := util.NormalizeText(text)
+			if err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			warnings = append(warnings, normalized...)
 			record.Text = text
 		}
 
 		// Write updated values to database
-		if err := db.Set.SPF(recordName, record.Text); err != nil {
+		if err := setter.SPF(recordName, record.Text); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -330,7 +493,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"text"}, map[string]map[string]string{"text": {"type": "stringarray", "required": "false", "max": viper.GetString("records.max-text-strings")}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -339,11 +502,17 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		// Update values if they exist in body
 		if valid["text"] {
 			text, _ := util.ConvertArrayToString(body["text"].([]interface{}))
+			text, normalized, err := util.NormalizeText(text)
+			if err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			warnings = append(warnings, normalized...)
 			record.Text = text
 		}
 
 		// Write updated values to database
-		if err := db.Set.TXT(recordName, record.Text); err != nil {
+		if err := setter.TXT(recordName, record.Text); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -357,7 +526,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "string", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"nameserver"}, map[string]map[string]string{"nameserver": {"type": "hostname", "required": "false"}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -365,11 +534,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 
 		// Update values if they exist in body
 		if valid["nameserver"] {
-			record.Nameserver = body["nameserver"].(string)
+			record.Nameserver = domain("nameserver")
 		}
 
 		// Write updated values to database
-		if err := db.Set.NS(recordName, record.Nameserver); err != nil {
+		if err := setter.NS(recordName, record.Nameserver); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
 			return
 		}
@@ -386,6 +555,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		err, valid := util.ValidateBody(body, []string{"tag", "content"}, map[string]map[string]string{"tag": {"type": "string", "required": "false"}, "content": {"type": "string", "required": "false"}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -397,7 +567,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.CAA(recordName, record.Tag, record.Content); err != nil {
+		if err := setter.CAA(recordName, record.Tag, record.Content); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
 			return
 		}
@@ -411,18 +581,19 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Get valid values in body
-		err, valid := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "string", "required": "false"}})
+		err, valid := util.ValidateBody(body, []string{"domain"}, map[string]map[string]string{"domain": {"type": "hostname", "required": "false"}})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
 		if valid["domain"] {
-			record.Domain = body["domain"].(string)
+			record.Domain = util.NormalizePTRTarget(body["domain"].(string))
 		}
 
 		// Write updated values to database
-		if err := db.Set.PTR(recordName, record.Domain); err != nil {
+		if err := setter.PTR(recordName, record.Domain); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+ err.Error())
 			return
 		}
@@ -440,10 +611,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			"c-type": {"type": "uint16", "requried": "false"},
 			"key-tag": {"type": "uint16", "required": "false"},
 			"algorithm": {"type": "uint8", "required": "false"},
-			"certificate": {"type": "string", "required": "false"},
+			"certificate": {"type": "string", "required": "false", "pattern": util.PatternBase64, "format": "base64 encoded"},
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -461,7 +633,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.CERT(recordName, record.Type, record.KeyTag, record.Algorithm, record.Certificate); err != nil {
+		if err := setter.CERT(recordName, record.Type, record.KeyTag, record.Algorithm, record.Certificate); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -483,6 +655,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -499,8 +672,14 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			record.PublicKey = body["public-key"].(string)
 		}
 
+		// The key must remain well-formed whichever fields changed
+		if err := util.ValidateDNSKEY(record.Flags, record.Protocol); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Write updated values to database
-		if err := db.Set.DNSKEY(recordName, record.Flags, record.Protocol, record.Algorithm, record.PublicKey); err != nil {
+		if err := setter.DNSKEY(recordName, record.Flags, record.Protocol, record.Algorithm, record.PublicKey); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -518,10 +697,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			"key-tag": {"type": "uint16", "required": "false"},
 			"algorithm": {"type": "uint8", "required": "false"},
 			"digest-type": {"type": "uint8", "required": "false"},
-			"digest": {"type": "string", "required": "false"},
+			"digest": {"type": "string", "required": "false", "pattern": util.PatternHex, "format": "hex encoded"},
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -539,7 +719,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.DS(recordName, record.KeyTag, record.Algorithm, record.DigestType, record.Digest); err != nil {
+		if err := setter.DS(recordName, record.KeyTag, record.Algorithm, record.DigestType, record.Digest); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -563,6 +743,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -582,11 +763,11 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 			record.Regexp = body["regexp"].(string)
 		}
 		if valid["replacement"] {
-			record.Replacement = body["replacement"].(string)
+			record.Replacement = domain("replacement")
 		}
 
 		// Write updated values to database
-		if err := db.Set.NAPTR(recordName, record.Order, record.Preference, record.Flags, record.Service, record.Regexp, record.Replacement); err != nil {
+		if err := setter.NAPTR(recordName, record.Order, record.Preference, record.Flags, record.Service, record.Regexp, record.Replacement); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -608,6 +789,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -625,7 +807,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SMIMEA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
+		if err := setter.SMIMEA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -646,6 +828,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -660,7 +843,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.SSHFP(recordName, record.Algorithm, record.Type, record.Fingerprint); err != nil {
+		if err := setter.SSHFP(recordName, record.Algorithm, record.Type, record.Fingerprint); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -682,6 +865,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -699,7 +883,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.TLSA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
+		if err := setter.TLSA(recordName, record.Usage, record.Selector, record.MatchingType, record.Certificate); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
@@ -720,6 +904,7 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		})
 		if err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Update values if they exist in body
@@ -734,14 +919,297 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		}
 
 		// Write updated values to database
-		if err := db.Set.URI(recordName, record.Priority, record.Weight, record.Target); err != nil {
+		if err := setter.URI(recordName, record.Priority, record.Weight, record.Target); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "CSYNC":
+		// Get original record from database
+		record := db.Get.CSYNC(recordName + ".")
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"serial", "flags", "types"}, map[string]map[string]string{
+			"serial": {"type": "uint32", "required": "false"},
+			"flags": {"type": "uint16", "required": "false"},
+			"types": {"type": "stringarray", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in body
+		if valid["serial"] {
+			record.Serial = uint32(body["serial"].(float64))
+		}
+		if valid["flags"] {
+			record.Flags = uint16(body["flags"].(float64))
+			if err := util.ValidateCSYNCFlags(record.Flags); err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if valid["types"] {
+			types, err := util.ParseTypeBitmap(body["types"].([]interface{}))
+			if err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			record.Types = types
+		}
+
+		// Write updated values to database
+		if err := setter.CSYNC(recordName, record.Serial, record.Flags, record.Types); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "AMTRELAY":
+		// Get original record from database
+		record := db.Get.AMTRELAY(recordName + ".")
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"precedence", "discovery-optional", "relay-type", "relay"}, map[string]map[string]string{
+			"precedence": {"type": "uint8", "required": "false"},
+			"discovery-optional": {"type": "bool", "required": "false"},
+			"relay-type": {"type": "uint8", "required": "false"},
+			"relay": {"type": "string", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in body
+		if valid["precedence"] {
+			record.Precedence = uint8(body["precedence"].(float64))
+		}
+		if valid["discovery-optional"] {
+			record.Discovery = body["discovery-optional"].(bool)
+		}
+		if valid["relay-type"] {
+			record.RelayType = uint8(body["relay-type"].(float64))
+		}
+		if util.Exists(body, "relay") {
+			record.Relay = body["relay"].(string)
+		}
+
+		// The relay must still match its type
+		if err := util.ValidateAMTRELAY(record.RelayType, record.Relay); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Write updated values to database
+		if err := setter.AMTRELAY(recordName, record.Precedence, record.Discovery, record.RelayType, record.Relay); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "HINFO":
+		// Get original record from database
+		record := db.Get.HINFO(recordName + ".")
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"cpu", "os"}, map[string]map[string]string{
+			"cpu": {"type": "string", "required": "false"},
+			"os": {"type": "string", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in the body
+		if valid["cpu"] {
+			record.CPU = body["cpu"].(string)
+		}
+		if valid["os"] {
+			record.OS = body["os"].(string)
+		}
+
+		// Write updated values to the database
+		if err := setter.HINFO(recordName, record.CPU, record.OS); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "CDNSKEY":
+		// Get original record from database
+		record := db.Get.CDNSKEY(recordName + ".")
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"flags", "protocol", "algorithm", "public-key"}, map[string]map[string]string{
+			"flags": {"type": "uint16", "required": "false"},
+			"protocol": {"type": "uint8", "required": "false"},
+			"algorithm": {"type": "uint8", "required": "false"},
+			"public-key": {"type": "string", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in body
+		if valid["flags"] {
+			record.Flags = uint16(body["flags"].(float64))
+		}
+		if valid["protocol"] {
+			record.Protocol = uint8(body["protocol"].(float64))
+		}
+		if valid["algorithm"] {
+			record.Algorithm = uint8(body["algorithm"].(float64))
+		}
+		if valid["public-key"] {
+			record.PublicKey = body["public-key"].(string)
+		}
+
+		// The key must remain well-formed whichever fields changed
+		if err := util.ValidateDNSKEY(record.Flags, record.Protocol); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Write updated values to database
+		if err := setter.CDNSKEY(recordName, record.Flags, record.Protocol, record.Algorithm, record.PublicKey); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "SVCB", "HTTPS":
+		// Get original record from database
+		var record *db.SVCB
+		if recordType == "HTTPS" {
+			if https := db.Get.HTTPS(recordName + "."); https != nil {
+				record = &https.SVCB
+			}
+		} else {
+			record = db.Get.SVCB(recordName + ".")
+		}
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"priority", "target", "params"}, map[string]map[string]string{
+			"priority": {"type": "uint16", "required": "false"},
+			"target": {"type": "string", "required": "false"},
+			"params": {"type": "stringmap", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in body
+		if valid["priority"] {
+			record.Priority = uint16(body["priority"].(float64))
+		}
+		if valid["target"] {
+			record.Target = body["target"].(string)
+		}
+		if valid["params"] {
+			record.Params, _ = util.ConvertMapToString(body["params"].(map[string]interface{}))
+		}
+
+		// The record must remain well-formed whichever fields changed
+		if err := util.ValidateSVCB(record.Priority, record.Target, record.Params); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Write updated values to database
+		write := setter.SVCB
+		if recordType == "HTTPS" {
+			write = setter.HTTPS
+		}
+		if err := write(recordName, record.Priority, record.Target, record.Params); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
+			return
+		}
+
+	case "SOA":
+		// Get original record from database
+		record := db.Get.SOA(recordName + ".")
+		if util.RecordDoesNotExist(record) {
+			util.Responses.Error(w, http.StatusBadRequest, "specified record does not exist")
+			return
+		}
+
+		// Get valid values in body
+		err, valid := util.ValidateBody(body, []string{"nameserver", "mailbox", "serial", "refresh", "retry", "expire", "minimum"}, map[string]map[string]string{
+			"nameserver": {"type": "string", "required": "false"},
+			"mailbox": {"type": "string", "required": "false"},
+			"serial": {"type": "uint32", "required": "false"},
+			"refresh": {"type": "uint32", "required": "false"},
+			"retry": {"type": "uint32", "required": "false"},
+			"expire": {"type": "uint32", "required": "false"},
+			"minimum": {"type": "uint32", "required": "false"},
+		})
+		if err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Update values if they exist in body, incrementing the serial otherwise
+		if valid["nameserver"] {
+			record.Nameserver = domain("nameserver")
+		}
+		if valid["mailbox"] {
+			record.Mailbox = domain("mailbox")
+		}
+		record.Serial = 0
+		if valid["serial"] {
+			record.Serial = uint32(body["serial"].(float64))
+		}
+		if valid["refresh"] {
+			record.Refresh = uint32(body["refresh"].(float64))
+		}
+		if valid["retry"] {
+			record.Retry = uint32(body["retry"].(float64))
+		}
+		if valid["expire"] {
+			record.Expire = uint32(body["expire"].(float64))
+		}
+		if valid["minimum"] {
+			record.Minimum = uint32(body["minimum"].(float64))
+		}
+
+		// Write updated values to database
+		if err := setter.SOA(recordName, record.Nameserver, record.Mailbox, record.Serial, record.Refresh, record.Retry, record.Expire, record.Minimum); err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 			return
 		}
 	default:
-		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI")
+		util.Responses.Error(w, http.StatusBadRequest, "field 'type' must be on of: A, AAAA, CNAME, MX, LOC, SRV, SPF, TXT, NS, CAA, PTR, CERT, DNSKEY, DS, NAPTR, SMIMEA, SSHFP, TLSA, URI, CSYNC, AMTRELAY, HINFO, CDNSKEY, SVCB, HTTPS, SOA")
 		return
 	}
 
+	written := db.Get.Record(recordName+".", body["type"].(string))
+	if written != nil {
+		w.Header().Set("ETag", util.RecordETag(written))
+	}
+	if warnings := append(warnings, util.RecordWarnings(recordName, written)...); len(warnings) != 0 {
+		util.Responses.SuccessWithWarnings(w, warnings)
+		return
+	}
 	util.Responses.Success(w)
 }
@@ -0,0 +1,60 @@
+package records
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateRejectsInvalidBody(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+
+	tests := []struct {
+		recordType string
+		create     func(name string) error
+		invalid    map[string]interface{}
+	}{
+		{"CAA", func(name string) error { return db.Set.CAA(name, "issue", "ca.example.net") }, map[string]interface{}{"tag": 1}},
+		{"PTR", func(name string) error { return db.Set.PTR(name, "host.example.com.") }, map[string]interface{}{"domain": 1}},
+		{"CERT", func(name string) error { return db.Set.CERT(name, 1, 2, 3, "AAAA") }, map[string]interface{}{"key-tag": "two"}},
+		{"DNSKEY", func(name string) error { return db.Set.DNSKEY(name, 257, 3, 13, "AwEAAQ==") }, map[string]interface{}{"flags": "ksk"}},
+		{"DS", func(name string) error { return db.Set.DS(name, 1, 13, 2, "abcdef") }, map[string]interface{}{"digest-type": "sha256"}},
+		{"NAPTR", func(name string) error { return db.Set.NAPTR(name, 1, 2, "U", "E2U+sip", "", "sip.example.com.") }, map[string]interface{}{"order": "first"}},
+		{"SMIMEA", func(name string) error { return db.Set.SMIMEA(name, 3, 1, 1, "abcdef") }, map[string]interface{}{"usage": "ee"}},
+		{"SSHFP", func(name string) error { return db.Set.SSHFP(name, 4, 2, "abcdef") }, map[string]interface{}{"algorithm": "ed25519"}},
+		{"TLSA", func(name string) error { return db.Set.TLSA(name, 3, 1, 1, "abcdef") }, map[string]interface{}{"selector": "spki"}},
+		{"URI", func(name string) error { return db.Set.URI(name, 10, 1, "https://example.com/") }, map[string]interface{}{"weight": "heavy"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.recordType, func(t *testing.T) {
+			name := "invalid.example.com"
+			if err := test.create(name); err != nil {
+				t.Fatal(err)
+			}
+			before := db.Get.Record(name+".", test.recordType)
+			audited, err := db.QueryAudit(db.AuditFilter{}, database)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Two responses would not decode as a single JSON value
+			test.invalid["type"] = test.recordType
+			status, response := testRequest(t, single, "PUT", "/api/records/"+name, token, test.invalid)
+			if status != http.StatusBadRequest || response.Status != "error" {
+				t.Fatalf("expected a single 400 error, got %d: %+v", status, response)
+			}
+
+			if after := db.Get.Record(name+".", test.recordType); !reflect.DeepEqual(before, after) {
+				t.Errorf("expected the record to be unchanged, %+v became %+v", before, after)
+			}
+			if entries, err := db.QueryAudit(db.AuditFilter{}, database); err != nil {
+				t.Fatal(err)
+			} else if len(entries) != len(audited) {
+				t.Errorf("expected no write, the audit log grew by %d", len(entries)-len(audited))
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package records
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateKeepsTTL(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	records := AllRecordsHandler(database)
+	single := SingleRecordHandler("/api/records/", database)
+
+	tests := []struct {
+		name   string
+		create map[string]interface{}
+		update map[string]interface{}
+	}{
+		{"a.example.com", map[string]interface{}{"type": "A", "host": "192.0.2.1"}, map[string]interface{}{"type": "A", "host": "192.0.2.2"}},
+		{"cname.example.com", map[string]interface{}{"type": "CNAME", "target": "a.example.com"}, map[string]interface{}{"type": "CNAME", "target": "b.example.com"}},
+		{"mx.example.com", map[string]interface{}{"type": "MX", "priority": 10, "host": "mail.example.com"}, map[string]interface{}{"type": "MX", "priority": 20}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.create["type"].(string), func(t *testing.T) {
+			test.create["name"] = test.name
+			test.create["ttl"] = 300
+			if status, response := testRequest(t, records, "POST", "/api/records", token, test.create); status != http.StatusOK {
+				t.Fatalf("failed to create record: %d %s", status, response.Reason)
+			}
+
+			// Leaving out the TTL keeps the stored one
+			if status, response := testRequest(t, single, "PUT", "/api/records/"+test.name, token, test.update); status != http.StatusOK {
+				t.Fatalf("failed to update record: %d %s", status, response.Reason)
+			}
+
+			status, response := testRequest(t, single, "GET", "/api/records/"+test.name+"?type="+test.create["type"].(string), token, nil)
+			if status != http.StatusOK {
+				t.Fatalf("failed to read record: %d %s", status, response.Reason)
+			}
+			var record struct {
+				TTL uint32 `json:"ttl"`
+			}
+			if err := json.Unmarshal(response.Data, &record); err != nil {
+				t.Fatal(err)
+			} else if record.TTL != 300 {
+				t.Errorf("expected TTL of 300 to be kept, got %d", record.TTL)
+			}
+		})
+	}
+}
+
+func TestSwapRecords(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	if err := db.Set.A("blue.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("green.example.com", "192.0.2.2", "192.0.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("green.example.com", []string{"green"}); err != nil {
+		t.Fatal(err)
+	}
+	hosts := func(name string) string {
+		var addresses []string
+		for _, address := range db.Get.A(name).Addresses {
+			addresses = append(addresses, address.String())
+		}
+		return strings.Join(addresses, " ")
+	}
+
+	status, response := testRequest(t, SwapRecordsHandler(database), "POST", "/api/records/swap", token, map[string]interface{}{
+		"records": []map[string]string{{"name": "blue.example.com", "type": "A"}, {"name": "green.example.com", "type": "A"}},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to swap records: %d %s", status, response.Reason)
+	} else if blue, green := hosts("blue.example.com."), hosts("green.example.com."); blue != "192.0.2.2 192.0.2.3" || green != "192.0.2.1" {
+		t.Errorf("expected the addresses to be exchanged, got blue %s and green %s", blue, green)
+	}
+
+	// Nothing is written when either side cannot be swapped
+	for _, test := range []struct {
+		records []map[string]string
+		reason  string
+	}{
+		{[]map[string]string{{"name": "blue.example.com", "type": "A"}, {"name": "green.example.com", "type": "TXT"}}, "records must be of the same type"},
+		{[]map[string]string{{"name": "blue.example.com", "type": "A"}, {"name": "missing.example.com", "type": "A"}}, "specified record does not exist"},
+	} {
+		status, response := testRequest(t, SwapRecordsHandler(database), "POST", "/api/records/swap", token, map[string]interface{}{"records": test.records})
+		if status != http.StatusBadRequest || response.Reason != test.reason {
+			t.Errorf("expected %q, got %d %s", test.reason, status, response.Reason)
+		}
+	}
+	if blue := hosts("blue.example.com."); blue != "192.0.2.2 192.0.2.3" {
+		t.Errorf("expected a failed swap to leave the record unchanged, got %s", blue)
+	}
+}
+
+func TestSwapRecordsChecksBothNames(t *testing.T) {
+	database, token := testDatabase(t, "blue")
+	if err := db.CreateRole("blue", "", `^blue\.`, "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("blue.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("green.example.com", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testRequest(t, SwapRecordsHandler(database), "POST", "/api/records/swap", token, map[string]interface{}{
+		"records": []map[string]string{{"name": "blue.example.com", "type": "A"}, {"name": "green.example.com", "type": "A"}},
+	})
+	if status != http.StatusForbidden || !strings.Contains(response.Reason, "green.example.com") {
+		t.Errorf("expected the swap to be forbidden on green.example.com, got %d %s", status, response.Reason)
+	} else if record := db.Get.A("blue.example.com."); record == nil || record.Addresses[0].String() != "192.0.2.1" {
+		t.Errorf("expected the permitted record to be unchanged, got %v", record)
+	}
+}
+
+func TestLockedRecordRejectsChanges(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+	single := SingleRecordHandler("/api/records/", database)
+	if err := db.Set.A("example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	lock := func(locked bool) {
+		t.Helper()
+		if status, response := testRequest(t, LockRecordHandler(database), "POST", "/api/records/lock", token, map[string]interface{}{
+			"name": "example.com", "type": "A", "locked": locked,
+		}); status != http.StatusOK {
+			t.Fatalf("failed to set lock: %d %s", status, response.Reason)
+		}
+	}
+
+	// Locked records cannot be changed, even by admins
+	lock(true)
+	if status, response := testRequest(t, single, "PUT", "/api/records/example.com", token, map[string]interface{}{"type": "A", "host": "192.0.2.2"}); status != http.StatusLocked {
+		t.Errorf("expected the update to be rejected, got %d %s", status, response.Reason)
+	}
+	if status, response := testRequest(t, single, "DELETE", "/api/records/example.com?type=A", token, nil); status != http.StatusLocked {
+		t.Errorf("expected the delete to be rejected, got %d %s", status, response.Reason)
+	}
+	if record := db.Get.A("example.com."); record == nil || record.Addresses[0].String() != "192.0.2.1" {
+		t.Fatalf("expected the locked record to be unchanged, got %v", record)
+	}
+
+	// Unlocking restores editing
+	lock(false)
+	if status, response := testRequest(t, single, "PUT", "/api/records/example.com", token, map[string]interface{}{"type": "A", "host": "192.0.2.2"}); status != http.StatusOK {
+		t.Errorf("expected the update to succeed once unlocked, got %d %s", status, response.Reason)
+	} else if record := db.Get.A("example.com."); record == nil || record.Addresses[0].String() != "192.0.2.2" {
+		t.Errorf("expected the updated address, got %v", record)
+	}
+}
+
+func TestLockRequiresAdmin(t *testing.T) {
+	database, token := testDatabase(t, "editors")
+	if err := db.Set.A("example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := testRequest(t, LockRecordHandler(database), "POST", "/api/records/lock", token, map[string]interface{}{
+		"name": "example.com", "type": "A", "locked": true,
+	}); status != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", status)
+	}
+}
+
+func TestUpdateRejectionsCarryDetails(t *testing.T) {
+	database, token := testDatabase(t, "admin")
+
+	// Only applied to a name no other test writes
+	util.RegisterValidator("MX", func(name string, record db.Record) error {
+		if name == "policy.example.com" && record.(*db.MX).Priority >= 100 {
+			return util.ValidationError{Field: "priority", Reason: "must be below 100"}
+		}
+		return nil
+	})
+	util.RegisterValidator("MX", func(name string, record db.Record) error {
+		if name == "policy.example.com" && !strings.HasSuffix(record.(*db.MX).Host, ".example.com.") {
+			return util.ValidationError{Field: "host", Reason: "must be within example.com"}
+		}
+		return nil
+	})
+	if err := db.Set.MX("policy.example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	w, response := testRequestWithHeaders(t, SingleRecordHandler("/api/records/", database), "PUT", "/api/records/policy.example.com", token, nil, map[string]interface{}{
+		"type": "MX", "priority": 200, "host": "mail.example.org.",
+	})
+	var body struct {
+		Details []util.ErrorDetail `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	expected := []util.ErrorDetail{
+		{Field: "priority", Code: "rejected", Message: "must be below 100"},
+		{Field: "host", Code: "rejected", Message: "must be within example.com"},
+	}
+	if w.Code != http.StatusBadRequest || response.Reason != "record rejected by validators" {
+		t.Errorf("expected the update to be rejected, got %d %s", w.Code, response.Reason)
+	} else if !reflect.DeepEqual(body.Details, expected) {
+		t.Errorf("expected details %+v, got %+v", expected, body.Details)
+	}
+}
@@ -0,0 +1,69 @@
+package roles
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+)
+
+// List every role allowed to modify a record name
+func access(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("name") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'name' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Collect role names first, evaluating a role opens its own transaction
+	names := []string{"admin"}
+	if err := database.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("roles")).ForEach(func(k, v []byte) error {
+			names = append(names, string(k))
+			return nil
+		})
+	}); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve all roles: "+err.Error())
+		return
+	}
+
+	record := strings.TrimSuffix(strings.ToLower(r.URL.Query().Get("name")), ".")
+	allowed := []string{}
+	for _, name := range names {
+		if ok, err := db.EvaluateRole(name, record, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate role '"+name+"': "+err.Error())
+			return
+		} else if ok {
+			allowed = append(allowed, name)
+		}
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{"name": record, "roles": allowed})
+}
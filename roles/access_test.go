@@ -0,0 +1,66 @@
+package roles
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccessListsAllowedRoles(t *testing.T) {
+	database, token := testDatabase(t)
+	for _, role := range []struct {
+		name, allow, deny     string
+		allowNames, denyNames []string
+	}{
+		{"web", "", "", []string{"*.example.com"}, nil},
+		{"locked", "", "", []string{"*.example.com"}, []string{"www.example.com"}},
+		{"mail", `^mail\.`, "", nil, nil},
+		{"unrestricted", "", "", nil, nil},
+	} {
+		if err := db.CreateRole(role.name, "", role.allow, role.deny, role.allowNames, role.denyNames, database); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"www.example.com":  {"admin", "unrestricted", "web"},
+		"mail.example.com": {"admin", "locked", "mail", "unrestricted", "web"},
+		// Wildcards cover the names below the zone but not the zone itself
+		"example.com": {"admin", "unrestricted"},
+	}
+	for name, expected := range tests {
+		status, response := testRequest(t, AccessHandler(database), "GET", "/api/roles/access?name="+name, token, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s: failed to list roles: %d %s", name, status, response.Reason)
+		}
+
+		var access struct {
+			Name  string   `json:"name"`
+			Roles []string `json:"roles"`
+		}
+		if err := json.Unmarshal(response.Data, &access); err != nil {
+			t.Fatal(err)
+		} else if access.Name != name || strings.Join(access.Roles, " ") != strings.Join(expected, " ") {
+			t.Errorf("%s: expected roles %v, got %+v", name, expected, access)
+		}
+	}
+}
+
+func TestAccessRequiresAdmin(t *testing.T) {
+	database, _ := testDatabase(t)
+	testMembers(t, database, "web", "alice")
+	user, err := db.UserFromDatabase("alice", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := testRequest(t, AccessHandler(database), "GET", "/api/roles/access?name=www.example.com", token, nil); status != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", status)
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // Handle the creation of roles
-func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func create(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Validate initial request with request type, body exists, and content type
 	if r.Method != "POST" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -51,11 +51,13 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		util.Responses.Error(w, http.StatusBadRequest, "failed to decode  body: "+err.Error())
 		return
 	}
-	validationErr, valid := util.ValidateBody(body, []string{"name", "description", "allow", "deny"}, map[string]map[string]string{
+	validationErr, valid := util.ValidateBody(body, []string{"name", "description", "allow", "deny", "allow-names", "deny-names"}, map[string]map[string]string{
 		"name": {"type": "string", "required": "true"},
 		"description": {"type": "string", "required": "true"},
 		"allow": {"type": "string", "required": "false"},
 		"deny": {"type": "string", "required": "false"},
+		"allow-names": {"type": "stringarray", "required": "false"},
+		"deny-names": {"type": "stringarray", "required": "false"},
 	})
 	if validationErr != "" {
 		util.Responses.Error(w, http.StatusBadRequest, validationErr)
@@ -80,11 +82,31 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		body["deny"] = ""
 	}
 
+	// Name patterns are optional as well
+	var allowNames, denyNames []string
+	if valid["allow-names"] {
+		allowNames = namePatterns(body["allow-names"])
+	}
+	if valid["deny-names"] {
+		denyNames = namePatterns(body["deny-names"])
+	}
+
 	// Write role to database
-	if err := db.CreateRole(body["name"].(string), body["description"].(string), body["allow"].(string), body["deny"].(string), database); err != nil {
+	if err := db.Audited(db.NewAuditEntry(u.Username, "create", body["name"].(string), "role"), func(tx *bolt.Tx) error {
+		return db.CreateRoleTx(body["name"].(string), body["description"].(string), body["allow"].(string), body["deny"].(string), allowNames, denyNames, tx)
+	}, database); err != nil {
 		util.Responses.Error(w, http.StatusBadRequest, "failed to write role: "+err.Error())
 		return
 	}
 
 	util.Responses.Success(w)
 }
+
+// Get the patterns of a validated string array
+func namePatterns(value interface{}) []string {
+	var patterns []string
+	for _, pattern := range value.([]interface{}) {
+		patterns = append(patterns, pattern.(string))
+	}
+	return patterns
+}
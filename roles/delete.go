@@ -7,7 +7,7 @@ import (
 	"net/http"
 )
 
-func deleteRole(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func deleteRole(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Validate initial request with type, path, and header
 	if r.Method != "DELETE" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -40,11 +40,43 @@ func deleteRole(w http.ResponseWriter, r *http.Request, path string, database *b
 		return
 	}
 
-	// Delete role
-	if err := db.DeleteRole(r.URL.Path[len(path):], database); err != nil {
+	// Members may be moved to another role, which must exist
+	name := r.URL.Path[len(path):]
+	reassign := r.URL.Query().Get("reassign")
+	if reassign == name {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'reassign' must be a different role")
+		return
+	} else if reassign != "" && reassign != "admin" {
+		if role, err := db.GetRole(reassign, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve role: "+err.Error())
+			return
+		} else if role.Name == "" {
+			util.Responses.Error(w, http.StatusBadRequest, "role to reassign members to does not exist")
+			return
+		}
+	}
+
+	// Delete role, refusing while users hold it unless they are reassigned
+	// in the same transaction
+	var members []string
+	if err := db.Audited(db.NewAuditEntry(u.Username, "delete", name, "role"), func(tx *bolt.Tx) error {
+		if reassign != "" {
+			if err := db.ReassignRoleTx(name, reassign, tx); err != nil {
+				return err
+			}
+		} else if members, err = db.RoleMembersTx(name, tx); err != nil {
+			return err
+		} else if len(members) != 0 {
+			return db.ErrRoleInUse
+		}
+		return db.DeleteRoleTx(name, tx)
+	}, database); err == db.ErrRoleInUse {
+		util.Responses.ErrorWithData(w, http.StatusConflict, "role is still held by users, pass 'reassign' to move them to another role", map[string][]string{"members": members})
+		return
+	} else if err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete role: "+err.Error())
 		return
 	}
 
 	util.Responses.Success(w)
-}
\ No newline at end of file
+}
@@ -0,0 +1,78 @@
+package roles
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestDeleteRoleWithMembersBlocked(t *testing.T) {
+	database, token := testDatabase(t)
+	if err := db.CreateRole("editors", "", ".*", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	testMembers(t, database, "editors", "alice")
+
+	status, response := testRequest(t, SingleRoleHandler("/api/roles/", database), "DELETE", "/api/roles/editors", token, nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", status)
+	} else if string(response.Data) != `{"members":["alice"]}` {
+		t.Errorf("expected alice listed as a member, got %s", response.Data)
+	}
+
+	if role, err := db.GetRole("editors", database); err != nil {
+		t.Fatal(err)
+	} else if role.Name != "editors" {
+		t.Error("expected the role to be kept")
+	}
+}
+
+func TestDeleteRoleReassigningMembers(t *testing.T) {
+	database, token := testDatabase(t)
+	if err := db.CreateRole("editors", "", ".*", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateRole("viewers", "", "", ".*", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	testMembers(t, database, "editors", "alice", "bob")
+
+	if status, response := testRequest(t, SingleRoleHandler("/api/roles/", database), "DELETE", "/api/roles/editors?reassign=viewers", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete role: %d %s", status, response.Reason)
+	}
+
+	if role, err := db.GetRole("editors", database); err != nil {
+		t.Fatal(err)
+	} else if role.Name != "" {
+		t.Error("expected the role to be deleted")
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if user, err := db.UserFromDatabase(username, database); err != nil {
+			t.Fatal(err)
+		} else if user.Role != "viewers" {
+			t.Errorf("expected %s to be moved to viewers, got %s", username, user.Role)
+		}
+	}
+
+	// Members can't be moved to a role that doesn't exist
+	testMembers(t, database, "viewers", "carol")
+	if status, _ := testRequest(t, SingleRoleHandler("/api/roles/", database), "DELETE", "/api/roles/viewers?reassign=missing", token, nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 reassigning to a missing role, got %d", status)
+	}
+}
+
+func TestDeleteUnusedRole(t *testing.T) {
+	database, token := testDatabase(t)
+	if err := db.CreateRole("unused", "", "", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, response := testRequest(t, SingleRoleHandler("/api/roles/", database), "DELETE", "/api/roles/unused", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete role: %d %s", status, response.Reason)
+	}
+	if role, err := db.GetRole("unused", database); err != nil {
+		t.Fatal(err)
+	} else if role.Name != "" {
+		t.Error("expected the role to be deleted")
+	}
+}
@@ -1,13 +1,13 @@
 package roles
 
 import (
+	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 )
 
 // Handle requests regarding roles
-func AllRolesHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func AllRolesHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
@@ -24,7 +24,7 @@ func AllRolesHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
 }
 
 // Handle requests for methods regarding singlar roles
-func SingleRoleHandler(path string, db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func SingleRoleHandler(path string, db *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
@@ -42,3 +42,31 @@ func SingleRoleHandler(path string, db *bolt.DB) func(w http.ResponseWriter, r *
 		}
 	}
 }
+
+// Handle requests previewing changes to a role
+func PreviewRoleHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			preview(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests listing the roles with access to a record
+func AccessHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			access(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package roles
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database holding an admin, returning a token for the admin
+func testDatabase(t *testing.T) (*db.Database, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+
+	admin := db.NewUser("Admin", "admin", "", "admin")
+	if err := admin.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := db.NewToken(admin, database)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return database, token
+}
+
+// Create users holding a role
+func testMembers(t *testing.T, database *db.Database, role string, usernames ...string) {
+	t.Helper()
+	for _, username := range usernames {
+		user := db.NewUser(username, username, "", role)
+		if err := user.Encode(database); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+}
+
+// The decoded body of a response
+type testResponse struct {
+	Status string          `json:"status"`
+	Reason string          `json:"reason"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Send a request with a JSON body to a handler, returning the status code and
+// decoded response
+func testRequest(t *testing.T, handler http.HandlerFunc, method, url, token string, body interface{}) (int, testResponse) {
+	t.Helper()
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+	r := httptest.NewRequest(method, url, bytes.NewReader(encoded))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", token)
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var response testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, response
+}
@@ -8,7 +8,14 @@ import (
 	"net/http"
 )
 
-func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+// A role along with the number of users holding it
+type listedRole struct {
+	db.Role
+	Members int `json:"members"`
+}
+
+// Handle listing every role, or a single role by its exact name
+func list(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Validate initial request with type and headers
 	if r.Method != "GET" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -38,22 +45,42 @@ func list(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
-	// Get from database
-	var roles []db.Role
+	// Get roles and count their members from the same snapshot
+	name := r.URL.Query().Get("name")
+	roles := []listedRole{}
 	if err := database.View(func(tx *bolt.Tx) error {
+		members := map[string]int{}
+		if err := tx.Bucket([]byte("users")).ForEach(func(k, v []byte) error {
+			var user db.User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return err
+			}
+			members[user.Role]++
+			return nil
+		}); err != nil {
+			return err
+		}
+
 		return tx.Bucket([]byte("roles")).ForEach(func(k, v []byte) error {
 			var role db.Role
 
 			if err := json.Unmarshal(v, &role); err != nil {
 				return err
+			} else if name != "" && role.Name != name {
+				return nil
 			}
 
-			roles = append(roles, role)
+			roles = append(roles, listedRole{Role: role, Members: members[role.Name]})
 
 			return nil
 		})
 	}); err != nil {
-		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve all records: "+err.Error())
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve all roles: "+err.Error())
+		return
+	}
+
+	if name != "" && len(roles) == 0 {
+		util.Responses.Error(w, http.StatusNotFound, "specified role does not exist")
 		return
 	}
 
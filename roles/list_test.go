@@ -0,0 +1,83 @@
+package roles
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+// List roles through the API, failing the test on an error
+func testList(t *testing.T, database *db.Database, token, url string) []listedRole {
+	t.Helper()
+	status, response := testRequest(t, AllRolesHandler(database), "GET", url, token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to list roles: %d %s", status, response.Reason)
+	}
+
+	var roles []listedRole
+	if err := json.Unmarshal(response.Data, &roles); err != nil {
+		t.Fatal(err)
+	}
+	return roles
+}
+
+func TestListNoRoles(t *testing.T) {
+	database, token := testDatabase(t)
+	if roles := testList(t, database, token, "/api/roles"); len(roles) != 0 {
+		t.Errorf("expected no roles, got %+v", roles)
+	}
+}
+
+func TestListRolesWithMembers(t *testing.T) {
+	database, token := testDatabase(t)
+	if err := db.CreateRole("editors", "Edit records", ".*", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateRole("viewers", "Read records", "", ".*", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateRole("unused", "", "", "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	testMembers(t, database, "editors", "alice", "bob")
+	testMembers(t, database, "viewers", "carol")
+
+	roles := testList(t, database, token, "/api/roles")
+	expected := map[string]int{"editors": 2, "viewers": 1, "unused": 0}
+	if len(roles) != len(expected) {
+		t.Fatalf("expected %d roles, got %+v", len(expected), roles)
+	}
+	for _, role := range roles {
+		if members, ok := expected[role.Name]; !ok || role.Members != members {
+			t.Errorf("expected %d members of %s, got %d", members, role.Name, role.Members)
+		}
+		if role.Name == "editors" && (role.Description != "Edit records" || role.Allow != ".*") {
+			t.Errorf("expected the description and filters of editors, got %+v", role)
+		}
+	}
+
+	// Looking up a single role by its exact name
+	if roles := testList(t, database, token, "/api/roles?name=editors"); len(roles) != 1 || roles[0].Name != "editors" || roles[0].Members != 2 {
+		t.Errorf("expected only editors, got %+v", roles)
+	}
+	if status, _ := testRequest(t, AllRolesHandler(database), "GET", "/api/roles?name=edit", token, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a partial name, got %d", status)
+	}
+}
+
+func TestListRolesRequiresAdmin(t *testing.T) {
+	database, _ := testDatabase(t)
+	user := db.NewUser("User", "user", "", "editors")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := testRequest(t, AllRolesHandler(database), "GET", "/api/roles", token, nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", status)
+	}
+}
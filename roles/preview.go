@@ -0,0 +1,166 @@
+package roles
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"regexp"
+)
+
+// Preview which names users of a role would gain or lose with new rules
+func preview(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check user role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"name", "allow", "deny", "allow-names", "deny-names", "names"}, map[string]map[string]string{
+		"name": {"type": "string", "required": "true"},
+		"allow": {"type": "string", "required": "false"},
+		"deny": {"type": "string", "required": "false"},
+		"allow-names": {"type": "stringarray", "required": "false"},
+		"deny-names": {"type": "stringarray", "required": "false"},
+		"names": {"type": "stringarray", "required": "false"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	// Get current role from database
+	current, err := db.GetRole(body["name"].(string), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve role: "+err.Error())
+		return
+	} else if current.Name == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "specified role does not exist")
+		return
+	}
+
+	// Assemble proposed role, keeping rules that are not changed
+	proposed := *current
+	if valid["allow"] {
+		proposed.Allow = body["allow"].(string)
+	}
+	if valid["deny"] {
+		proposed.Deny = body["deny"].(string)
+	}
+	if valid["allow-names"] {
+		proposed.AllowNames = namePatterns(body["allow-names"])
+	}
+	if valid["deny-names"] {
+		proposed.DenyNames = namePatterns(body["deny-names"])
+	}
+	if _, err := regexp.Compile(proposed.Allow); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid regular expression for allow")
+		return
+	} else if _, err := regexp.Compile(proposed.Deny); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "invalid regular expression for deny")
+		return
+	}
+	for _, pattern := range append(append([]string{}, proposed.AllowNames...), proposed.DenyNames...) {
+		if err := db.ValidateNamePattern(pattern); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Sample against the given names or every stored name
+	names := db.Get.Names()
+	if valid["names"] {
+		names, _ = util.ConvertArrayToString(body["names"].([]interface{}))
+	}
+
+	// Compute the change for each name
+	gained := []string{}
+	lost := []string{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		before, err := current.Explain(name)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		}
+		after, err := proposed.Explain(name)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		}
+
+		if !before.Allowed && after.Allowed {
+			gained = append(gained, name)
+		} else if before.Allowed && !after.Allowed {
+			lost = append(lost, name)
+		}
+	}
+
+	// Find users holding the role
+	affected := []map[string]interface{}{}
+	if err := database.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("users")).ForEach(func(k, v []byte) error {
+			var user db.User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return err
+			}
+
+			if user.Role == current.Name && (len(gained) != 0 || len(lost) != 0) {
+				affected = append(affected, map[string]interface{}{"username": user.Username, "gained": gained, "lost": lost})
+			}
+			return nil
+		})
+	}); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve users: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{
+		"role":   current.Name,
+		"gained": gained,
+		"lost":   lost,
+		"users":  affected,
+	})
+}
@@ -0,0 +1,74 @@
+package roles
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPreviewReportsGainedAndLostNames(t *testing.T) {
+	database, token := testDatabase(t)
+	if err := db.CreateRole("editors", "Edit records", `^(www|mail)\.example\.com\.$`, "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	testMembers(t, database, "editors", "alice", "bob")
+	testMembers(t, database, "viewers", "carol")
+
+	status, response := testRequest(t, PreviewRoleHandler(database), "POST", "/api/roles/preview", token, map[string]interface{}{
+		"name":  "editors",
+		"allow": `^(www|api)\.example\.com\.$`,
+		"names": []string{"www.example.com.", "mail.example.com.", "api.example.com.", "ftp.example.com."},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("failed to preview role: %d %s", status, response.Reason)
+	}
+
+	var preview struct {
+		Gained []string `json:"gained"`
+		Lost   []string `json:"lost"`
+		Users  []struct {
+			Username string   `json:"username"`
+			Gained   []string `json:"gained"`
+			Lost     []string `json:"lost"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal(response.Data, &preview); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preview.Gained, []string{"api.example.com."}) {
+		t.Errorf("expected api.example.com. to be gained, got %v", preview.Gained)
+	}
+	if !reflect.DeepEqual(preview.Lost, []string{"mail.example.com."}) {
+		t.Errorf("expected mail.example.com. to be lost, got %v", preview.Lost)
+	}
+
+	users := map[string]bool{}
+	for _, user := range preview.Users {
+		users[user.Username] = true
+		if !reflect.DeepEqual(user.Lost, preview.Lost) || !reflect.DeepEqual(user.Gained, preview.Gained) {
+			t.Errorf("expected %s to share the role's changes, got %+v", user.Username, user)
+		}
+	}
+	if !reflect.DeepEqual(users, map[string]bool{"alice": true, "bob": true}) {
+		t.Errorf("expected only members of editors to be affected, got %v", users)
+	}
+
+	// The stored role is left untouched
+	if role, err := db.GetRole("editors", database); err != nil {
+		t.Fatal(err)
+	} else if role.Allow != `^(www|mail)\.example\.com\.$` {
+		t.Errorf("expected the role to be unchanged, got allow %q", role.Allow)
+	}
+}
+
+func TestPreviewUnknownRole(t *testing.T) {
+	database, token := testDatabase(t)
+	status, response := testRequest(t, PreviewRoleHandler(database), "POST", "/api/roles/preview", token, map[string]interface{}{
+		"name": "missing", "allow": ".*",
+	})
+	if status != http.StatusBadRequest || response.Reason != "specified role does not exist" {
+		t.Errorf("expected an unknown role to be rejected, got %d %s", status, response.Reason)
+	}
+}
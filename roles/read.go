@@ -3,11 +3,10 @@ package roles
 import (
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 )
 
-func read(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func read(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Validate initial request with type and header
 	if r.Method != "GET" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -8,7 +8,7 @@ import (
 	"net/http"
 )
 
-func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.DB) {
+func update(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
 	// Validate initial request with type, body exists, and headers
 	if r.Method != "PUT" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -50,10 +50,12 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
 		return
 	}
-	validationErr, valid := util.ValidateBody(body, []string{"description", "allow", "deny"}, map[string]map[string]string{
+	validationErr, valid := util.ValidateBody(body, []string{"description", "allow", "deny", "allow-names", "deny-names"}, map[string]map[string]string{
 		"description": {"type": "string", "required": "true"},
 		"allow": {"type": "string", "required": "true"},
 		"deny": {"type": "string", "required": "true"},
+		"allow-names": {"type": "stringarray", "required": "false"},
+		"deny-names": {"type": "stringarray", "required": "false"},
 	})
 	if validationErr != "" {
 		util.Responses.Error(w, http.StatusBadRequest, validationErr)
@@ -77,9 +79,17 @@ func update(w http.ResponseWriter, r *http.Request, path string, database *bolt.
 	if valid["deny"] {
 		role.Deny = body["deny"].(string)
 	}
+	if valid["allow-names"] {
+		role.AllowNames = namePatterns(body["allow-names"])
+	}
+	if valid["deny-names"] {
+		role.DenyNames = namePatterns(body["deny-names"])
+	}
 
 	// Save to database
-	if err := db.CreateRole(role.Name, role.Description, role.Allow, role.Deny, database); err != nil {
+	if err := db.Audited(db.NewAuditEntry(u.Username, "update", role.Name, "role"), func(tx *bolt.Tx) error {
+		return db.CreateRoleTx(role.Name, role.Description, role.Allow, role.Deny, role.AllowNames, role.DenyNames, tx)
+	}, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to write role to database: "+err.Error())
 		return
 	}
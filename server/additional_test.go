@@ -0,0 +1,46 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func TestAdditionalRecords(t *testing.T) {
+	_, udp, _ := testServer(t)
+	viper.Set("dns.additional-records", true)
+	t.Cleanup(func() { viper.Set("dns.additional-records", false) })
+
+	if err := db.Set.MX("example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("mail.example.com", "192.0.2.25"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.MX("out.example.com", 10, "mail.example.net."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("mail.example.net", "198.51.100.25"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The in-zone mail host's address primes the resolver
+	r := testQuery(t, "udp", udp, "example.com", dns.TypeMX)
+	if len(r.Answer) != 1 || len(r.Extra) != 1 {
+		t.Fatalf("expected one answer and one additional record, got %v", r)
+	} else if a, ok := r.Extra[0].(*dns.A); !ok || a.Hdr.Name != "mail.example.com." || a.A.String() != "192.0.2.25" {
+		t.Errorf("expected A 192.0.2.25 for mail.example.com., got %v", r.Extra[0])
+	}
+
+	// Out of zone hosts are left for the resolver to look up
+	r = testQuery(t, "udp", udp, "out.example.com", dns.TypeMX)
+	if len(r.Answer) != 1 {
+		t.Fatalf("expected one answer, got %v", r)
+	}
+	for _, rr := range r.Extra {
+		if rr.Header().Rrtype == dns.TypeA {
+			t.Errorf("expected no address for an out of zone host, got %v", rr)
+		}
+	}
+}
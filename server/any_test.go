@@ -0,0 +1,44 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func TestMinimalANY(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("www.example.com", []string{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("dns.minimal-any", true)
+	t.Cleanup(func() {
+		viper.Set("dns.minimal-any", false)
+		viper.Set("dns.minimal-any-types", false)
+	})
+
+	for _, test := range []struct {
+		types bool
+		os    string
+	}{
+		{false, ""},
+		{true, "A TXT"},
+	} {
+		viper.Set("dns.minimal-any-types", test.types)
+
+		r := testQuery(t, "udp", udp, "www.example.com", dns.TypeANY)
+		if r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 {
+			t.Fatalf("expected a single answer, got %v", r)
+		}
+		hinfo, ok := r.Answer[0].(*dns.HINFO)
+		if !ok {
+			t.Fatalf("expected an HINFO record, got %v", r.Answer[0])
+		} else if hinfo.Cpu != "RFC8482" || hinfo.Os != test.os {
+			t.Errorf("listing types %t: expected cpu 'RFC8482' and os '%s', got '%s' and '%s'", test.types, test.os, hinfo.Cpu, hinfo.Os)
+		}
+	}
+}
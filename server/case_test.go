@@ -0,0 +1,25 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+func TestQueryCasingEchoed(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resolvers randomizing the casing of names (DNS 0x20) expect it back
+	r := testQuery(t, "udp", udp, "WwW.ExAmple.com", dns.TypeA)
+	if len(r.Question) != 1 || r.Question[0].Name != "WwW.ExAmple.com." {
+		t.Errorf("expected the question with its casing, got %v", r.Question)
+	}
+	if len(r.Answer) != 1 {
+		t.Fatalf("expected one answer, got %v", r)
+	} else if a, ok := r.Answer[0].(*dns.A); !ok || a.Hdr.Name != "WwW.ExAmple.com." || a.A.String() != "192.0.2.1" {
+		t.Errorf("expected A 192.0.2.1 owned by WwW.ExAmple.com., got %v", r.Answer[0])
+	}
+}
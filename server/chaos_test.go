@@ -0,0 +1,28 @@
+package server
+
+import (
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func TestChaosIdentification(t *testing.T) {
+	_, udp, _ := testServer(t)
+	viper.Set("dns.chaos.version", "dns 1.2.3")
+	viper.Set("dns.chaos.hostname", "ns1")
+	t.Cleanup(func() {
+		viper.Set("dns.chaos.version", "")
+		viper.Set("dns.chaos.hostname", "")
+	})
+
+	for name, value := range map[string]string{"version.bind": "dns 1.2.3", "hostname.bind": "ns1"} {
+		r, _, _ := testExtendedError(t, udp, name, dns.ClassCHAOS)
+		if r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 {
+			t.Fatalf("%s: expected a single answer, got %v", name, r)
+		}
+		txt, ok := r.Answer[0].(*dns.TXT)
+		if !ok || txt.Hdr.Class != dns.ClassCHAOS || txt.Hdr.Name != name+"." || len(txt.Txt) != 1 || txt.Txt[0] != value {
+			t.Errorf("%s: expected CH TXT %q, got %v", name, value, r.Answer[0])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/base64"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Media type of wire format DNS messages (RFC 8484)
+const dnsMessageType = "application/dns-message"
+
+// Answer DNS queries over HTTPS (RFC 8484) through the same handler as
+// queries over UDP and TCP
+func DoHHandler(database *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	handler := &Handler{Db: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get the wire format query from the body or the query string
+		var packed []byte
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("dns") == "" {
+				util.Responses.Error(w, http.StatusBadRequest, "query parameter 'dns' is required")
+				return
+			}
+
+			var err error
+			if packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns")); err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, "query parameter 'dns' must be base64url encoded")
+				return
+			}
+
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != dnsMessageType {
+				util.Responses.Error(w, http.StatusUnsupportedMediaType, "body must be of type "+dnsMessageType)
+				return
+			}
+
+			var err error
+			if packed, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, dns.MaxMsgSize)); err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, "failed to read body: "+err.Error())
+				return
+			}
+
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		query := new(dns.Msg)
+		if err := query.Unpack(packed); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "failed to parse query: "+err.Error())
+			return
+		}
+
+		// Answer the query as if it was received over TCP from the client
+		writer := &dohWriter{remote: remoteAddr(r)}
+		handler.ServeDNS(writer, query)
+		if writer.msg == nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to answer query")
+			return
+		}
+
+		response, err := writer.msg.Pack()
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to encode response: "+err.Error())
+			return
+		}
+
+		// Let caches keep the response no longer than its records
+		w.Header().Set("Content-Type", dnsMessageType)
+		if ttl, ok := minimumTTL(writer.msg); ok {
+			w.Header().Set("Cache-Control", "max-age="+strconv.FormatUint(uint64(ttl), 10))
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(response)
+	}
+}
+
+// Get the lowest TTL of the records in a response
+// Returns false if the response holds no records to take it from
+func minimumTTL(msg *dns.Msg) (uint32, bool) {
+	var ttl uint32
+	found := false
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns} {
+		for _, rr := range section {
+			if !found || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+			found = true
+		}
+	}
+	return ttl, found
+}
+
+// Get the address of the client making a request
+func remoteAddr(r *http.Request) net.Addr {
+	host, port, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	p, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(host), Port: p}
+}
+
+// Captures the response of the handler to a query received over HTTPS
+type dohWriter struct {
+	remote net.Addr
+	msg    *dns.Msg
+}
+
+func (d *dohWriter) LocalAddr() net.Addr  { return &net.TCPAddr{} }
+func (d *dohWriter) RemoteAddr() net.Addr { return d.remote }
+
+func (d *dohWriter) WriteMsg(msg *dns.Msg) error {
+	d.msg = msg
+	return nil
+}
+
+func (d *dohWriter) Write(packed []byte) (int, error) {
+	msg := new(dns.Msg)
+	if err := msg.Unpack(packed); err != nil {
+		return 0, err
+	}
+	d.msg = msg
+	return len(packed), nil
+}
+
+func (d *dohWriter) Close() error        { return nil }
+func (d *dohWriter) TsigStatus() error   { return nil }
+func (d *dohWriter) TsigTimersOnly(bool) {}
+func (d *dohWriter) Hijack()             {}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Send a query over HTTPS with method, failing the test on a malformed answer
+func testDoH(t *testing.T, database *db.Database, method, name string, qtype uint16) (*httptest.ResponseRecorder, *dns.Msg) {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	packed, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r *http.Request
+	if method == "GET" {
+		r = httptest.NewRequest("GET", "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(packed), nil)
+	} else {
+		r = httptest.NewRequest("POST", "/dns-query", bytes.NewReader(packed))
+		r.Header.Set("Content-Type", dnsMessageType)
+	}
+	w := httptest.NewRecorder()
+	DoHHandler(database)(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	} else if w.Header().Get("Content-Type") != dnsMessageType {
+		t.Fatalf("expected content type %s, got %s", dnsMessageType, w.Header().Get("Content-Type"))
+	}
+
+	response := new(dns.Msg)
+	if err := response.Unpack(w.Body.Bytes()); err != nil {
+		t.Fatalf("failed to parse answer: %v", err)
+	} else if response.Id != m.Id || !response.Response {
+		t.Fatalf("expected a response to query %d, got %v", m.Id, response)
+	}
+	return w, response
+}
+
+func TestDoHAnswers(t *testing.T) {
+	database, _, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{"POST", "GET"} {
+		w, response := testDoH(t, database, method, "www.example.com", dns.TypeA)
+		if len(response.Answer) != 1 {
+			t.Fatalf("%s: expected one answer, got %v", method, response.Answer)
+		} else if a, ok := response.Answer[0].(*dns.A); !ok || a.A.String() != "192.0.2.1" || a.Hdr.Ttl != 300 {
+			t.Errorf("%s: expected A 192.0.2.1 with TTL 300, got %v", method, response.Answer[0])
+		}
+		if w.Header().Get("Cache-Control") != "max-age=300" {
+			t.Errorf("%s: expected Cache-Control max-age=300, got %q", method, w.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+func TestDoHNameError(t *testing.T) {
+	database, _, _ := testServer(t)
+
+	w, response := testDoH(t, database, "POST", "missing.example.com", dns.TypeA)
+	if response.Rcode != dns.RcodeNameError {
+		t.Errorf("expected NXDOMAIN, got %s", dns.RcodeToString[response.Rcode])
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("expected a Cache-Control header")
+	}
+}
+
+func TestDoHRejectsOtherContentTypes(t *testing.T) {
+	database, _, _ := testServer(t)
+
+	r := httptest.NewRequest("POST", "/dns-query", bytes.NewReader([]byte("query")))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	DoHHandler(database)(w, r)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status 415, got %d", w.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+// Send a query over EDNS, returning the extended error code of the response
+func testExtendedError(t *testing.T, address, name string, qclass uint16) (*dns.Msg, uint16, bool) {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+	m.Question[0].Qclass = qclass
+	m.SetEdns0(1232, false)
+
+	r, _, err := (&dns.Client{}).Exchange(m, address)
+	if err != nil {
+		t.Fatalf("failed to query %s: %v", name, err)
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if ede, ok := option.(*dns.EDNS0_EDE); ok {
+				return r, ede.InfoCode, true
+			}
+		}
+	}
+	return r, 0, false
+}
+
+func TestBlockedNameCarriesBlocked(t *testing.T) {
+	_, udp, _ := testServer(t)
+	viper.Set("dns.blocklist", []string{"Blocked.Example.com"})
+	t.Cleanup(func() { viper.Set("dns.blocklist", nil) })
+
+	for _, name := range []string{"blocked.example.com", "www.blocked.example.com"} {
+		r, code, ok := testExtendedError(t, udp, name, dns.ClassINET)
+		if r.Rcode != dns.RcodeNameError || !ok || code != dns.ExtendedErrorCodeBlocked {
+			t.Errorf("%s: expected NXDOMAIN with the Blocked code, got %s with code %d", name, dns.RcodeToString[r.Rcode], code)
+		}
+	}
+
+	if _, _, ok := testExtendedError(t, udp, "notblocked.example.com", dns.ClassINET); ok {
+		t.Error("expected no extended error for a name that is not blocked")
+	}
+}
+
+func TestRefusedIdentificationCarriesProhibited(t *testing.T) {
+	_, udp, _ := testServer(t)
+	viper.Set("dns.chaos.version", "")
+
+	r, code, ok := testExtendedError(t, udp, "version.bind", dns.ClassCHAOS)
+	if r.Rcode != dns.RcodeRefused || !ok || code != dns.ExtendedErrorCodeProhibited {
+		t.Errorf("expected REFUSED with the Prohibited code, got %s with code %d", dns.RcodeToString[r.Rcode], code)
+	}
+}
+
+func TestMaintenanceZoneCarriesNotReady(t *testing.T) {
+	database, udp, _ := testServer(t)
+	viper.Set("dns.zones", []string{"example.com", "example.org"})
+	if err := db.Set.TXT("www.example.com", []string{"maintained"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("www.example.org", []string{"serving"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{Maintenance: true}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, code, ok := testExtendedError(t, udp, "www.example.com", dns.ClassINET); r.Rcode != dns.RcodeServerFailure || !ok || code != dns.ExtendedErrorCodeNotReady {
+		t.Errorf("expected SERVFAIL with the Not Ready code, got %s with code %d", dns.RcodeToString[r.Rcode], code)
+	}
+	if r, _, ok := testExtendedError(t, udp, "www.example.org", dns.ClassINET); r.Rcode != dns.RcodeSuccess || ok || len(r.Answer) != 1 {
+		t.Errorf("expected other zones to keep answering, got %v", r)
+	}
+
+	// Leaving maintenance serves the zone again
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{}, database); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _ := testExtendedError(t, udp, "www.example.com", dns.ClassINET); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 {
+		t.Errorf("expected the zone to answer after maintenance, got %v", r)
+	}
+}
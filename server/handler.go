@@ -0,0 +1,519 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Answers DNS queries from the records in a database
+type Handler struct {
+	Db *db.Database
+}
+
+func (h *Handler) ServeDNS(w dns.ResponseWriter, m *dns.Msg) {
+	// Set database into getter and setter
+	db.Get.Db = h.Db
+	db.Set.Db = h.Db
+
+	// Change records through dynamic updates
+	if m.Opcode == dns.OpcodeUpdate {
+		h.update(w, m)
+		return
+	}
+
+	// Transfer whole zones to secondaries
+	if len(m.Question) == 1 && (m.Question[0].Qtype == dns.TypeAXFR || m.Question[0].Qtype == dns.TypeIXFR) {
+		h.transfer(w, m)
+		return
+	}
+
+	// Time request for logging
+	start := time.Now()
+
+	// Assemble response
+	r := new(dns.Msg)
+	r.SetReply(m)
+	r.Authoritative = true
+
+	// Only claim recursion when it would be done for this query
+	recursive := !viper.GetBool("dns.authoritative-only") && len(viper.GetStringSlice("dns.upstream")) != 0 && m.RecursionDesired
+	r.RecursionAvailable = recursive
+
+	// Iterate over all questions
+	nodata := false
+	for _, q := range r.Question {
+		var recordFound bool
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: q.Qclass, Ttl: util.ServedTTL(q.Name)}
+
+		// Answer server identification queries in the CHAOS class
+		if q.Qclass == dns.ClassCHAOS {
+			if value := util.ChaosValue(q.Name); value != "" && (q.Qtype == dns.TypeTXT || q.Qtype == dns.TypeANY) {
+				r.Answer = append(r.Answer, &dns.TXT{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS}, Txt: []string{value}})
+			} else {
+				r.Rcode = dns.RcodeRefused
+				util.SetExtendedError(m, r, dns.ExtendedErrorCodeProhibited, "server identification is disabled")
+			}
+			continue
+		}
+
+		// Deny blocked names whether or not they are within the served zones
+		if util.Blocked(q.Name) {
+			r.Rcode = dns.RcodeNameError
+			util.SetExtendedError(m, r, dns.ExtendedErrorCodeBlocked, "name is blocked")
+			continue
+		}
+
+		// Answer for zones served as a secondary from their last transfer,
+		// until it expires without the primary being reached
+		if zone := util.SecondaryZoneFor(q.Name); zone != "" {
+			answer, soa, exists, current := util.SecondaryAnswer(zone, q)
+			if !current {
+				r.Rcode = dns.RcodeServerFailure
+				util.SetExtendedError(m, r, dns.ExtendedErrorCodeNotAuthoritative, "zone has expired on this secondary")
+			} else if len(answer) != 0 {
+				r.Answer = append(r.Answer, answer...)
+			} else {
+				nodata = exists
+				r.Ns = append(r.Ns, soa)
+			}
+			continue
+		}
+
+		// Refuse queries outside of the served zones when authoritative only
+		if viper.GetBool("dns.authoritative-only") && db.ZoneFor(q.Name) == "" {
+			r.Rcode = dns.RcodeRefused
+			util.SetExtendedError(m, r, dns.ExtendedErrorCodeNotAuthoritative, "name is outside of the served zones")
+			continue
+		}
+
+		// Fail queries for zones under maintenance
+		if zone := db.ZoneFor(q.Name); zone != "" {
+			if settings, err := db.GetZoneSettings(zone, h.Db); err != nil {
+				log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+			} else if settings.Maintenance {
+				r.Rcode = dns.RcodeServerFailure
+				util.SetExtendedError(m, r, dns.ExtendedErrorCodeNotReady, "zone is under maintenance")
+				continue
+			}
+		}
+
+		// Find the name holding the records, which may be a wildcard
+		source := util.WildcardSource(q.Name)
+
+		// Hold back records still within the propagation delay
+		qtype := q.Qtype
+		staged := util.Staged(source, qtype)
+		if staged {
+			qtype = dns.TypeNone
+		}
+
+		// Hide records restricted to other clients, the name itself remains visible
+		if !util.SourceAllowed(source, qtype, w.RemoteAddr()) {
+			nodata = true
+			continue
+		}
+		hdr.Ttl = util.RecordTTL(q.Name, source, qtype)
+
+		// Do different things based on record type
+		switch qtype {
+		case dns.TypeA:
+			record := db.Get.A(source)
+			if record != nil {
+				recordFound = true
+				for _, address := range util.OrderAddresses(source, util.HealthyAddresses(source, record.Addresses)) {
+					r.Answer = append(r.Answer, &dns.A{Hdr: hdr, A: address})
+				}
+			}
+		case dns.TypeAAAA:
+			record :=  db.Get.AAAA(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.AAAA{Hdr: hdr, AAAA: record.Address})
+			}
+		case dns.TypeCNAME:
+			record :=  db.Get.CNAME(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.CNAME{Hdr: hdr, Target: record.Target})
+			}
+		case dns.TypeMX:
+			record :=  db.Get.MX(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.MX{Hdr: hdr, Preference: record.Priority, Mx: record.Host})
+			}
+		case dns.TypeLOC:
+			record :=  db.Get.LOC(source)
+			if record != nil {
+				recordFound = true
+				locString, vers := record.ToParsable()
+				r.Answer = append(r.Answer, util.ParseLOCString(locString, vers, hdr))
+			}
+		case dns.TypeSRV:
+			record :=  db.Get.SRV(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.SRV{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: record.Target})
+			}
+		case dns.TypeSPF:
+			record :=  db.Get.SPF(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.SPF{Hdr: hdr, Txt: record.Text})
+			}
+		case dns.TypeTXT:
+			record :=  db.Get.TXT(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.TXT{Hdr: hdr, Txt: record.Text})
+			}
+		case dns.TypeNS:
+			record :=  db.Get.NS(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.NS{Hdr: hdr, Ns: record.Nameserver})
+			}
+		case dns.TypeCAA:
+			record :=  db.Get.CAA(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.CAA{Hdr: hdr, Flag: record.Flag, Tag: record.Tag, Value: record.Content})
+			}
+		case dns.TypePTR:
+			record := db.Get.PTR(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.PTR{Hdr: hdr, Ptr: record.Domain})
+			}
+		case dns.TypeCERT:
+			record :=  db.Get.CERT(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.CERT{Hdr: hdr, Type: record.Type, KeyTag: record.KeyTag, Algorithm: record.Algorithm, Certificate: record.Certificate})
+			}
+		case dns.TypeDNSKEY:
+			// Signed zones publish the keys they are signed with
+			if keys := util.PublishedKeys(hdr, source); len(keys) != 0 {
+				recordFound = true
+				r.Answer = append(r.Answer, keys...)
+				break
+			}
+			record :=  db.Get.DNSKEY(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.DNSKEY{Hdr: hdr, Flags: record.Flags, Protocol: record.Protocol, Algorithm: record.Algorithm, PublicKey: record.PublicKey})
+			}
+		case dns.TypeDS:
+			record :=  db.Get.DS(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.DS{Hdr: hdr, KeyTag: record.KeyTag, Algorithm: record.Algorithm, DigestType: record.DigestType, Digest: record.Digest})
+			}
+		case dns.TypeNAPTR:
+			record :=  db.Get.NAPTR(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.NAPTR{Hdr: hdr, Order: record.Order, Preference: record.Preference, Flags: record.Flags, Service: record.Service, Regexp: record.Regexp, Replacement: record.Replacement})
+			}
+		case dns.TypeSMIMEA:
+			record :=  db.Get.SMIMEA(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.SMIMEA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+			}
+		case dns.TypeSSHFP:
+			record :=  db.Get.SSHFP(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.SSHFP{Hdr: hdr, Algorithm: record.Algorithm, Type: record.Type, FingerPrint: record.Fingerprint})
+			}
+		case dns.TypeTLSA:
+			record :=  db.Get.TLSA(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.TLSA{Hdr: hdr, Usage: record.Usage, Selector: record.Selector, MatchingType: record.MatchingType, Certificate: record.Certificate})
+			}
+		case dns.TypeURI:
+			record :=  db.Get.URI(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.URI{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Target: record.Target})
+			}
+		case dns.TypeAMTRELAY:
+			record :=  db.Get.AMTRELAY(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, util.AMTRELAYToRR(hdr, record))
+			}
+		case dns.TypeCSYNC:
+			record :=  db.Get.CSYNC(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.CSYNC{Hdr: hdr, Serial: record.Serial, Flags: record.Flags, TypeBitMap: util.TypeBitmap(record.Types)})
+			}
+		case dns.TypeHINFO:
+			record :=  db.Get.HINFO(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.HINFO{Hdr: hdr, Cpu: record.CPU, Os: record.OS})
+			}
+		case dns.TypeSVCB:
+			record := db.Get.SVCB(source)
+			if record != nil {
+				if rr := util.RecordToRR(hdr, record); rr != nil {
+					recordFound = true
+					r.Answer = append(r.Answer, rr)
+				}
+			}
+		case dns.TypeHTTPS:
+			record := db.Get.HTTPS(source)
+			if record != nil {
+				if rr := util.RecordToRR(hdr, record); rr != nil {
+					recordFound = true
+					r.Answer = append(r.Answer, rr)
+				}
+			}
+		case dns.TypeSOA:
+			record :=  db.Get.SOA(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.SOA{Hdr: hdr, Ns: record.Nameserver, Mbox: record.Mailbox, Serial: record.Serial, Refresh: record.Refresh, Retry: record.Retry, Expire: record.Expire, Minttl: record.Minimum})
+			}
+		case dns.TypeCDS:
+			if keys := util.ParentKeys(hdr, source); len(keys) != 0 {
+				recordFound = true
+				r.Answer = append(r.Answer, keys...)
+			}
+		case dns.TypeCDNSKEY:
+			if keys := util.ParentKeys(hdr, source); len(keys) != 0 {
+				recordFound = true
+				r.Answer = append(r.Answer, keys...)
+				break
+			}
+			record := db.Get.CDNSKEY(source)
+			if record != nil {
+				recordFound = true
+				r.Answer = append(r.Answer, &dns.CDNSKEY{DNSKEY: dns.DNSKEY{Hdr: hdr, Flags: record.Flags, Protocol: record.Protocol, Algorithm: record.Algorithm, PublicKey: record.PublicKey}})
+			}
+		case dns.TypeZONEMD:
+			if db.ZoneFor(source) == dns.Fqdn(strings.ToLower(source)) {
+				if record := util.StoredZONEMD(hdr, source); record != nil {
+					recordFound = true
+					r.Answer = append(r.Answer, record)
+				}
+			}
+		case dns.TypeANY:
+			// Answer with a single synthesized record to mitigate amplification (RFC 8482)
+			if viper.GetBool("dns.minimal-any") {
+				if db.Get.NameExists(strings.TrimSuffix(source, ".")) {
+					recordFound = true
+					hinfo := &dns.HINFO{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeHINFO, Class: q.Qclass, Ttl: hdr.Ttl}, Cpu: "RFC8482"}
+
+					// Enumerate the types the client could query for debugging
+					if viper.GetBool("dns.minimal-any-types") {
+						var present []string
+						for _, recordType := range db.RecordTypes {
+							rrtype := dns.StringToType[recordType]
+							if db.Get.Record(source, recordType) != nil && util.SourceAllowed(source, rrtype, w.RemoteAddr()) && !util.Staged(source, rrtype) {
+								present = append(present, recordType)
+							}
+						}
+						hinfo.Os = strings.Join(present, " ")
+					}
+					r.Answer = append(r.Answer, hinfo)
+				}
+				break
+			}
+
+			for _, recordType := range db.RecordTypes {
+				if record := db.Get.Record(source, recordType); record != nil {
+					rrHdr := hdr
+					rrHdr.Rrtype = dns.StringToType[recordType]
+					rrHdr.Ttl = util.RecordTTL(q.Name, source, rrHdr.Rrtype)
+					if rrs := util.RecordToRRs(rrHdr, record); len(rrs) != 0 && util.SourceAllowed(source, rrHdr.Rrtype, w.RemoteAddr()) && !util.Staged(source, rrHdr.Rrtype) {
+						recordFound = true
+						r.Answer = append(r.Answer, rrs...)
+					}
+				}
+			}
+		default:
+			recordFound = false
+		}
+
+		// Answer with the alias and its in-zone target when the name holds a CNAME
+		if !recordFound && qtype != dns.TypeNone && qtype != dns.TypeCNAME && viper.GetBool("dns.follow-cnames") {
+			if chain := util.FollowCNAME(q.Name, qtype, w.RemoteAddr()); len(chain) != 0 {
+				recordFound = true
+				r.Answer = append(r.Answer, chain...)
+			}
+		}
+
+		// A held back record is still answered for here rather than upstream,
+		// with no data if the name is otherwise served and no name if not
+		if !recordFound && staged {
+			if util.ServedBesides(source, q.Qtype) {
+				nodata = true
+			}
+			continue
+		}
+
+		// A name holding records of other types has no data rather than not existing
+		if !recordFound && viper.GetBool("dns.nodata-for-missing-types") && db.Get.NameExists(strings.TrimSuffix(source, ".")) {
+			nodata = true
+			continue
+		}
+
+		if !recordFound && recursive {
+			// Look up recursively
+			recursMsg := new(dns.Msg)
+			recursMsg.SetQuestion(dns.Fqdn(q.Name), q.Qtype)
+			recursMsg.SetEdns0(4096, true)
+			recursMsg.RecursionDesired = true
+
+			// Get random upstream resolver
+			rand.Seed(time.Now().UnixNano())
+			resolvers := viper.GetStringSlice("dns.upstream")
+
+			// Send new response
+			resp, err := dns.Exchange(recursMsg, resolvers[rand.Intn(len(resolvers))])
+			if err != nil {
+				r.Rcode = dns.RcodeServerFailure
+				util.SetExtendedError(m, r, dns.ExtendedErrorCodeNetworkError, "failed to reach upstream resolver")
+				continue
+			}
+
+			// Add new responses
+			if resp.Rcode != dns.RcodeSuccess {
+				r.Rcode = resp.Rcode
+			}
+			r.Answer = append(r.Answer, resp.Answer...)
+		}
+	}
+
+	// Prime the resolver's cache with the addresses of in-zone hosts
+	var additional []dns.RR
+	if viper.GetBool("dns.additional-records") {
+		additional = util.AdditionalRecords(r.Answer, w.RemoteAddr())
+	}
+
+	// Serve with the TTL requested by a trusted client when debugging
+	if ttl, ok := util.TTLOverride(m, w.RemoteAddr()); ok {
+		for _, rr := range append(r.Answer, additional...) {
+			rr.Header().Ttl = ttl
+		}
+	}
+	r.Extra = append(r.Extra, additional...)
+
+	// Throw error if no answers
+	if len(r.Answer) == 0 && r.Rcode == dns.RcodeSuccess && !nodata {
+		r.Rcode = dns.RcodeNameError
+	}
+
+	// Let resolvers cache negative answers within the served zones (RFC 2308)
+	if len(r.Answer) == 0 && (r.Rcode == dns.RcodeSuccess || r.Rcode == dns.RcodeNameError) && len(r.Question) != 0 {
+		if soa := util.NegativeSOA(r.Question[0].Name); soa != nil {
+			r.Ns = append(r.Ns, soa)
+		}
+	}
+
+	// Sign answers within signed zones for clients asking for DNSSEC records
+	util.SignResponse(m, r)
+
+	// Write response
+	if err := w.WriteMsg(r); err != nil {
+		log.Printf("Unable to send response: %v", err)
+	}
+
+	// Log to console
+	util.LogResponse(w, r, start)
+}
+
+// Records sent in each message of a transfer
+const transferChunkSize = 100
+
+// Answer an AXFR or IXFR query with the full zone
+// Incremental transfers are answered the same way, as allowed by RFC 1995
+// when the differences are not kept
+func (h *Handler) transfer(w dns.ResponseWriter, m *dns.Msg) {
+	start := time.Now()
+	r := new(dns.Msg)
+	r.SetReply(m)
+
+	// Refuse transfers to peers not allowed them, and ask for TCP over UDP
+	zone := db.ZoneFor(m.Question[0].Name)
+	if !util.TransferAllowed(w.RemoteAddr()) {
+		r.Rcode = dns.RcodeRefused
+		util.SetExtendedError(m, r, dns.ExtendedErrorCodeProhibited, "zone transfers are not allowed")
+	} else if zone == "" || zone != dns.Fqdn(strings.ToLower(m.Question[0].Name)) {
+		r.Rcode = dns.RcodeNotAuth
+		util.SetExtendedError(m, r, dns.ExtendedErrorCodeNotAuthoritative, "name is not the apex of a served zone")
+	} else if w.RemoteAddr().Network() != "tcp" {
+		r.Truncated = true
+	}
+	if r.Rcode != dns.RcodeSuccess || r.Truncated {
+		if err := w.WriteMsg(r); err != nil {
+			log.Printf("Unable to send response: %v", err)
+		}
+		util.LogResponse(w, r, start)
+		return
+	}
+
+	// Close the connection on peers beyond the limits, as they are likely to
+	// retry straight away on any answer
+	peer := util.TransferPeer(w.RemoteAddr())
+	if !util.Transfers.Acquire(peer) {
+		log.Printf("Refused transfer of '%s' to %s: too many concurrent transfers", zone, peer)
+		_ = w.Close()
+		return
+	}
+	defer util.Transfers.Release(peer)
+
+	rrs := util.TransferRecords(zone)
+	if rrs == nil {
+		r.Rcode = dns.RcodeServerFailure
+		util.SetExtendedError(m, r, dns.ExtendedErrorCodeOther, "zone has no SOA record")
+		if err := w.WriteMsg(r); err != nil {
+			log.Printf("Unable to send response: %v", err)
+		}
+		util.LogResponse(w, r, start)
+		return
+	}
+
+	ch := make(chan *dns.Envelope)
+	done := make(chan error)
+	go func() { done <- new(dns.Transfer).Out(w, m, ch) }()
+	for len(rrs) > 0 {
+		n := transferChunkSize
+		if n > len(rrs) {
+			n = len(rrs)
+		}
+		ch <- &dns.Envelope{RR: rrs[:n]}
+		rrs = rrs[n:]
+	}
+	close(ch)
+	if err := <-done; err != nil {
+		log.Printf("Failed to transfer '%s' to %s: %v", zone, peer, err)
+	}
+	log.Printf("Transferred '%s' to %s in %s", zone, peer, time.Since(start))
+}
+
+func queryDNS(q string, t uint16) ([]dns.RR, int) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(q), t)
+	msg.SetEdns0(4096, true)
+
+	c := new(dns.Client)
+	rand.Seed(time.Now().UnixNano())
+	resolvers := viper.GetStringSlice("dns.upstream")
+	in, _, err := c.Exchange(msg, resolvers[rand.Intn(len(resolvers))])
+	if err != nil {
+		return []dns.RR{}, dns.RcodeNameError
+	}
+	return in.Answer, dns.RcodeSuccess
+}
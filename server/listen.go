@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+)
+
+// Answer DNS queries over TCP on the configured address
+// Concurrent connections are limited to keep clients from exhausting them
+func ListenTCP(database *db.Database) error {
+	listener, err := net.Listen("tcp", viper.GetString("dns.host")+":"+viper.GetString("dns.port"))
+	if err != nil {
+		return err
+	}
+
+	// Limit concurrent connections
+	limited := util.NewLimitedListener(listener, viper.GetInt64("dns.max-tcp-connections"))
+	util.Metrics.Gauge("dns-tcp-connections", limited.Active)
+	util.Metrics.Gauge("dns-transfers", util.Transfers.Active)
+	util.Metrics.Gauge("dns-transfer-peers", util.Transfers.Peers)
+
+	tcp := &dns.Server{Listener: limited, Net: "tcp", Handler: &Handler{Db: database}, TsigSecret: util.TSIGSecrets(), MsgAcceptFunc: util.AcceptMsg}
+	return tcp.ActivateAndServe()
+}
+
+// Answer DNS queries over UDP on the configured address
+func ListenUDP(database *db.Database) error {
+	udp := &dns.Server{Addr: viper.GetString("dns.host") + ":" + viper.GetString("dns.port"), Net: "udp", Handler: &Handler{Db: database}, TsigSecret: util.TSIGSecrets(), MsgAcceptFunc: util.AcceptMsg}
+	return udp.ListenAndServe()
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+func TestPreferredAddressListedFirst(t *testing.T) {
+	database, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1", "192.0.2.2", "192.0.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateMetadata("www.example.com", "A", func(m *db.Metadata) { m.Preferred = "192.0.2.2" }, database); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		r := testQuery(t, "udp", udp, "www.example.com", dns.TypeA)
+		if len(r.Answer) != 3 {
+			t.Fatalf("expected all 3 addresses, got %v", r.Answer)
+		}
+
+		seen := map[string]bool{}
+		for _, rr := range r.Answer {
+			seen[rr.(*dns.A).A.String()] = true
+		}
+		if first := r.Answer[0].(*dns.A).A.String(); first != "192.0.2.2" {
+			t.Fatalf("query %d: expected 192.0.2.2 first, got %s", i, first)
+		} else if !seen["192.0.2.1"] || !seen["192.0.2.3"] {
+			t.Fatalf("query %d: expected the other addresses to follow, got %v", i, r.Answer)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"testing"
+)
+
+func TestAuthoritativeOnlyNeverSetsRA(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := testQuery(t, "udp", udp, "www.unrelated.org", dns.TypeA); r.Rcode != dns.RcodeRefused || r.RecursionAvailable {
+		t.Errorf("expected REFUSED without RA outside of the zones, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "www.example.com", dns.TypeA); r.Rcode != dns.RcodeSuccess || r.RecursionAvailable {
+		t.Errorf("expected an answer without RA within the zones, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "missing.example.com", dns.TypeA); r.Rcode != dns.RcodeNameError || r.RecursionAvailable {
+		t.Errorf("expected NXDOMAIN without RA within the zones, got %v", r)
+	}
+}
+
+func TestRecursionAvailableMatchesRecursion(t *testing.T) {
+	_, udp, _ := testServer(t)
+	testUpstream(t)
+
+	// Recursion is done, and offered, when the client desires it
+	r := testQuery(t, "udp", udp, "www.unrelated.org", dns.TypeA)
+	if r.Rcode != dns.RcodeSuccess || !r.RecursionAvailable || len(r.Answer) != 1 || !r.Answer[0].(*dns.A).A.Equal(net.ParseIP("203.0.113.9")) {
+		t.Errorf("expected a recursive answer with RA, got %v", r)
+	}
+
+	// Without RD nothing is asked upstream
+	m := new(dns.Msg)
+	m.SetQuestion("www.unrelated.org.", dns.TypeA)
+	m.RecursionDesired = false
+	r, _, err := (&dns.Client{}).Exchange(m, udp)
+	if err != nil {
+		t.Fatal(err)
+	} else if r.RecursionAvailable || len(r.Answer) != 0 {
+		t.Errorf("expected no recursion without RD, got %v", r)
+	}
+
+	// Nor without any upstream resolvers
+	viper.Set("dns.upstream", nil)
+	if r := testQuery(t, "udp", udp, "www.unrelated.org", dns.TypeA); r.RecursionAvailable || len(r.Answer) != 0 {
+		t.Errorf("expected no recursion without upstream resolvers, got %v", r)
+	}
+}
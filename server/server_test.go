@@ -0,0 +1,300 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Serve a fresh database over UDP and TCP on local ports, returning their
+// addresses
+func testServer(t *testing.T) (*db.Database, string, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("dns.zones", []string{"example.com"})
+	viper.Set("dns.authoritative-only", true)
+	viper.Set("dns.ttl", 300)
+	t.Cleanup(func() {
+		viper.Set("dns.zones", nil)
+		viper.Set("dns.authoritative-only", false)
+	})
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, server := range []*dns.Server{
+		{PacketConn: conn, Net: "udp", Handler: &Handler{Db: database}, TsigSecret: util.TSIGSecrets(), MsgAcceptFunc: util.AcceptMsg},
+		{Listener: listener, Net: "tcp", Handler: &Handler{Db: database}, TsigSecret: util.TSIGSecrets(), MsgAcceptFunc: util.AcceptMsg},
+	} {
+		server := server
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = server.ActivateAndServe() }()
+		<-started
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+	return database, conn.LocalAddr().String(), listener.Addr().String()
+}
+
+// Send a query to a listener, failing the test if it cannot be answered
+func testQuery(t *testing.T, network, address, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+
+	client := &dns.Client{Net: network}
+	r, _, err := client.Exchange(m, address)
+	if err != nil {
+		t.Fatalf("failed to query %s over %s: %v", name, network, err)
+	}
+	return r
+}
+
+func TestServeRecords(t *testing.T) {
+	database, udp, tcp := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.AAAA("www.example.com", "2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.MX("example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("example.com", []string{"v=spf1 -all"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.CSYNC("example.com", 2024010101, 3, []string{"A", "NS"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records with their own TTL are served with it
+	if err := db.UpdateMetadata("www.example.com", "AAAA", func(m *db.Metadata) { m.TTL = 60 }, database); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		qtype uint16
+		ttl   uint32
+		check func(rr dns.RR) bool
+	}{
+		{"www.example.com", dns.TypeA, 300, func(rr dns.RR) bool { return rr.(*dns.A).A.Equal(net.ParseIP("192.0.2.1")) }},
+		{"www.example.com", dns.TypeAAAA, 60, func(rr dns.RR) bool { return rr.(*dns.AAAA).AAAA.Equal(net.ParseIP("2001:db8::1")) }},
+		{"example.com", dns.TypeMX, 300, func(rr dns.RR) bool {
+			return rr.(*dns.MX).Preference == 10 && rr.(*dns.MX).Mx == "mail.example.com."
+		}},
+		{"example.com", dns.TypeTXT, 300, func(rr dns.RR) bool { return rr.(*dns.TXT).Txt[0] == "v=spf1 -all" }},
+		{"example.com", dns.TypeCSYNC, 300, func(rr dns.RR) bool {
+			csync := rr.(*dns.CSYNC)
+			return csync.Serial == 2024010101 && csync.Flags == 3 && len(csync.TypeBitMap) == 2 &&
+				csync.TypeBitMap[0] == dns.TypeA && csync.TypeBitMap[1] == dns.TypeNS
+		}},
+	}
+	for _, network := range []string{"udp", "tcp"} {
+		address := map[string]string{"udp": udp, "tcp": tcp}[network]
+		for _, test := range tests {
+			r := testQuery(t, network, address, test.name, test.qtype)
+			if r.Rcode != dns.RcodeSuccess || !r.Authoritative || len(r.Answer) != 1 {
+				t.Fatalf("%s %s over %s: unexpected response %v", test.name, dns.TypeToString[test.qtype], network, r)
+			}
+
+			rr := r.Answer[0]
+			if rr.Header().Rrtype != test.qtype || !test.check(rr) {
+				t.Errorf("%s %s over %s: unexpected answer %v", test.name, dns.TypeToString[test.qtype], network, rr)
+			} else if rr.Header().Ttl != test.ttl {
+				t.Errorf("%s %s over %s: expected a ttl of %d, got %d", test.name, dns.TypeToString[test.qtype], network, test.ttl, rr.Header().Ttl)
+			}
+		}
+	}
+}
+
+func TestServeNegativeAnswers(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("dns.nodata-for-missing-types", true)
+	t.Cleanup(func() { viper.Set("dns.nodata-for-missing-types", false) })
+
+	if r := testQuery(t, "udp", udp, "missing.example.com", dns.TypeA); r.Rcode != dns.RcodeNameError || len(r.Answer) != 0 {
+		t.Errorf("expected NXDOMAIN for a missing name, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "www.example.com", dns.TypeMX); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 0 {
+		t.Errorf("expected NODATA for a missing type, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "www.other.com", dns.TypeA); r.Rcode != dns.RcodeRefused {
+		t.Errorf("expected REFUSED outside of the served zones, got %v", r)
+	}
+}
+
+func TestServeRestrictedSources(t *testing.T) {
+	database, udp, _ := testServer(t)
+	if err := db.Set.A("internal.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.AAAA("internal.example.com", "2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("internal.example.com", []string{"visible"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries arrive from 127.0.0.1
+	if err := db.UpdateMetadata("internal.example.com", "A", func(m *db.Metadata) { m.AllowedSources = []string{"127.0.0.0/8"} }, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateMetadata("internal.example.com", "AAAA", func(m *db.Metadata) { m.AllowedSources = []string{"10.0.0.0/8"} }, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := testQuery(t, "udp", udp, "internal.example.com", dns.TypeA); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 {
+		t.Errorf("expected the A record to be served to an in-range client, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "internal.example.com", dns.TypeAAAA); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 0 {
+		t.Errorf("expected NODATA for the AAAA record restricted to another range, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "internal.example.com", dns.TypeTXT); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 {
+		t.Errorf("expected the unrestricted TXT record to remain visible, got %v", r)
+	}
+
+	// ANY only lists what the client may see
+	r := testQuery(t, "udp", udp, "internal.example.com", dns.TypeANY)
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype == dns.TypeAAAA {
+			t.Errorf("expected ANY to leave out the restricted AAAA record, got %v", r)
+		}
+	}
+}
+
+func TestServeCNAMEChain(t *testing.T) {
+	_, udp, _ := testServer(t)
+	viper.Set("dns.follow-cnames", true)
+	t.Cleanup(func() { viper.Set("dns.follow-cnames", false) })
+	if err := db.Set.A("host.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.CNAME("www.example.com", "host.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.CNAME("alias.example.com", "www.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.CNAME("external.example.com", "www.example.org."); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		chain []string
+	}{
+		{"www.example.com", []string{"www.example.com.\tCNAME", "host.example.com.\tA"}},
+		{"alias.example.com", []string{"alias.example.com.\tCNAME", "www.example.com.\tCNAME", "host.example.com.\tA"}},
+		// Targets outside of the zone are left to the resolver
+		{"external.example.com", []string{"external.example.com.\tCNAME"}},
+	}
+	for _, test := range tests {
+		r := testQuery(t, "udp", udp, test.name, dns.TypeA)
+		var chain []string
+		for _, rr := range r.Answer {
+			chain = append(chain, rr.Header().Name+"\t"+dns.TypeToString[rr.Header().Rrtype])
+		}
+		if r.Rcode != dns.RcodeSuccess || strings.Join(chain, ", ") != strings.Join(test.chain, ", ") {
+			t.Errorf("%s: expected %v, got %v", test.name, test.chain, r)
+		}
+	}
+}
+
+func TestServeTTLOverride(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("dns.debug.ttl-override.option", 65001)
+	t.Cleanup(func() {
+		viper.Set("dns.debug.ttl-override.enabled", false)
+		viper.Set("dns.debug.ttl-override.sources", nil)
+	})
+
+	// Query with the override option asking for a TTL of 5
+	query := func() uint32 {
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.com.", dns.TypeA)
+		m.SetEdns0(1232, false)
+		opt := m.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0, 0, 0, 5}})
+
+		r, _, err := (&dns.Client{}).Exchange(m, udp)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(r.Answer) != 1 {
+			t.Fatalf("expected one answer, got %v", r)
+		}
+		return r.Answer[0].Header().Ttl
+	}
+
+	tests := []struct {
+		enabled bool
+		sources []string
+		ttl     uint32
+	}{
+		{true, []string{"127.0.0.0/8"}, 5},
+		{true, []string{"10.0.0.0/8"}, 300},
+		{false, []string{"127.0.0.0/8"}, 300},
+	}
+	for _, test := range tests {
+		viper.Set("dns.debug.ttl-override.enabled", test.enabled)
+		viper.Set("dns.debug.ttl-override.sources", test.sources)
+		if ttl := query(); ttl != test.ttl {
+			t.Errorf("enabled %t for %v: expected a ttl of %d, got %d", test.enabled, test.sources, test.ttl, ttl)
+		}
+	}
+
+	// Queries without the option are served the stored TTL
+	viper.Set("dns.debug.ttl-override.enabled", true)
+	viper.Set("dns.debug.ttl-override.sources", []string{"127.0.0.0/8"})
+	if r := testQuery(t, "udp", udp, "www.example.com", dns.TypeA); len(r.Answer) != 1 || r.Answer[0].Header().Ttl != 300 {
+		t.Errorf("expected the stored TTL without the option, got %v", r)
+	}
+}
+
+func TestServeTextInStoredOrder(t *testing.T) {
+	_, udp, tcp := testServer(t)
+	submitted := []string{"token-c=3", "token-a=1", "token-b=2"}
+	if err := db.Set.TXT("verify.example.com", submitted); err != nil {
+		t.Fatal(err)
+	}
+
+	for network, address := range map[string]string{"udp": udp, "tcp": tcp} {
+		r := testQuery(t, network, address, "verify.example.com", dns.TypeTXT)
+		if len(r.Answer) != 1 {
+			t.Fatalf("%s: expected one answer, got %v", network, r)
+		}
+
+		// The client unpacks the strings in the order they were sent
+		if txt := r.Answer[0].(*dns.TXT).Txt; strings.Join(txt, " ") != strings.Join(submitted, " ") {
+			t.Errorf("%s: expected %q in stored order, got %q", network, submitted, txt)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+// Send a query asking for DNSSEC records, failing the test if it cannot be
+// answered
+func testSignedQuery(t *testing.T, address, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.SetEdns0(4096, true)
+
+	r, _, err := new(dns.Client).Exchange(m, address)
+	if err != nil {
+		t.Fatalf("failed to query %s: %v", name, err)
+	}
+	return r
+}
+
+// Add a new zone signing key to example.com
+func testSigningKey(t *testing.T, database *db.Database) db.SigningKey {
+	t.Helper()
+	key, err := util.GenerateSigningKey("example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err = db.AddSigningKey("example.com.", key, database); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// Check that the records of a type in an answer are signed by a key
+func testSignedBy(t *testing.T, r *dns.Msg, rrtype uint16, key db.SigningKey) {
+	t.Helper()
+	var set []dns.RR
+	var sig *dns.RRSIG
+	for _, rr := range r.Answer {
+		if s, ok := rr.(*dns.RRSIG); ok && s.TypeCovered == rrtype {
+			sig = s
+		} else if rr.Header().Rrtype == rrtype {
+			set = append(set, rr)
+		}
+	}
+	if len(set) == 0 || sig == nil {
+		t.Fatalf("expected signed %s records, got %v", dns.TypeToString[rrtype], r.Answer)
+	} else if sig.KeyTag != key.KeyTag {
+		t.Fatalf("expected %s records signed by key %d, got %d", dns.TypeToString[rrtype], key.KeyTag, sig.KeyTag)
+	}
+
+	dnskey := &dns.DNSKEY{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, Flags: key.Flags, Protocol: 3, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+	if err := sig.Verify(dnskey, set); err != nil {
+		t.Errorf("signature over %s records does not verify: %v", dns.TypeToString[rrtype], err)
+	}
+}
+
+// Get the key tags of the DNSKEY records in an answer
+func testPublishedTags(r *dns.Msg) map[uint16]bool {
+	tags := map[uint16]bool{}
+	for _, rr := range r.Answer {
+		if key, ok := rr.(*dns.DNSKEY); ok {
+			tags[key.KeyTag()] = true
+		}
+	}
+	return tags
+}
+
+func TestSigningKeyRollover(t *testing.T) {
+	database, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{Signed: true}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	current := testSigningKey(t, database)
+	if _, err := db.TransitionSigningKey("example.com.", current.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next key is published ahead of use
+	next := testSigningKey(t, database)
+	r := testSignedQuery(t, udp, "example.com", dns.TypeDNSKEY)
+	if tags := testPublishedTags(r); len(tags) != 2 || !tags[current.KeyTag] || !tags[next.KeyTag] {
+		t.Fatalf("expected keys %d and %d to be published, got %v", current.KeyTag, next.KeyTag, r.Answer)
+	}
+	testSignedBy(t, r, dns.TypeDNSKEY, current)
+	testSignedBy(t, testSignedQuery(t, udp, "www.example.com", dns.TypeA), dns.TypeA, current)
+
+	// Activating the next key stops signing with the current one, which stays
+	// published for signatures resolvers still hold
+	if _, err := db.TransitionSigningKey("example.com.", next.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := db.GetSigningKeys("example.com.", false, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if key.KeyTag == current.KeyTag && key.State != db.KeyInactive {
+			t.Fatalf("expected key %d to be inactive, got %+v", current.KeyTag, key)
+		}
+	}
+	r = testSignedQuery(t, udp, "example.com", dns.TypeDNSKEY)
+	if tags := testPublishedTags(r); len(tags) != 2 || !tags[current.KeyTag] || !tags[next.KeyTag] {
+		t.Fatalf("expected keys %d and %d to be published, got %v", current.KeyTag, next.KeyTag, r.Answer)
+	}
+	testSignedBy(t, testSignedQuery(t, udp, "www.example.com", dns.TypeA), dns.TypeA, next)
+
+	// Retiring the replaced key once cached signatures expired unpublishes it
+	if _, err := db.TransitionSigningKey("example.com.", current.KeyTag, db.KeyRetired, database); err != nil {
+		t.Fatal(err)
+	}
+	r = testSignedQuery(t, udp, "example.com", dns.TypeDNSKEY)
+	if tags := testPublishedTags(r); len(tags) != 1 || !tags[next.KeyTag] {
+		t.Fatalf("expected only key %d to be published, got %v", next.KeyTag, r.Answer)
+	}
+	testSignedBy(t, r, dns.TypeDNSKEY, next)
+
+	// Clients not asking for DNSSEC records get none
+	for _, rr := range testQuery(t, "udp", udp, "www.example.com", dns.TypeA).Answer {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			t.Errorf("expected no signatures without the DO bit, got %v", rr)
+		}
+	}
+}
+
+func TestUnsignedZoneServesStoredKey(t *testing.T) {
+	database, udp, _ := testServer(t)
+	if err := db.Set.DNSKEY("example.com", 257, 3, 13, "AwEAAc1+Ek4BqbXy0OC/KU4ERW0hJ3BtAjYBL57rLcRYIcOCMBEZ0U1eIpRIrKf1FAgG6IyAuaBbMKeutYnTmwSNoUc="); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys of a zone are only served once it is signed
+	key := testSigningKey(t, database)
+	if _, err := db.TransitionSigningKey("example.com.", key.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+	r := testSignedQuery(t, udp, "example.com", dns.TypeDNSKEY)
+	if len(r.Answer) != 1 || r.Answer[0].(*dns.DNSKEY).Flags != 257 {
+		t.Fatalf("expected the stored key alone, got %v", r.Answer)
+	}
+}
+
+// Get the NSEC records in the authority section of an answer, checking each
+// is signed by a key
+func testDenial(t *testing.T, r *dns.Msg, key db.SigningKey) map[string]*dns.NSEC {
+	t.Helper()
+	dnskey := &dns.DNSKEY{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, Flags: key.Flags, Protocol: 3, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+
+	records := map[string]*dns.NSEC{}
+	for _, rr := range r.Ns {
+		if nsec, ok := rr.(*dns.NSEC); ok {
+			records[nsec.Hdr.Name] = nsec
+		}
+	}
+	for _, rr := range r.Ns {
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == dns.TypeNSEC {
+			if nsec := records[sig.Hdr.Name]; nsec == nil {
+				t.Errorf("unexpected signature over NSEC records of %s", sig.Hdr.Name)
+			} else if err := sig.Verify(dnskey, []dns.RR{nsec}); err != nil {
+				t.Errorf("signature over the NSEC record of %s does not verify: %v", sig.Hdr.Name, err)
+			}
+		}
+	}
+	return records
+}
+
+func TestSignedNegativeAnswers(t *testing.T) {
+	database, udp, _ := testServer(t)
+	if err := db.Set.SOA("example.com", "ns.example.com.", "hostmaster.example.com.", 1, 3600, 600, 604800, 300); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"mail.example.com", "www.example.com"} {
+		if err := db.Set.A(name, "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{Signed: true}, database); err != nil {
+		t.Fatal(err)
+	}
+	key := testSigningKey(t, database)
+	if _, err := db.TransitionSigningKey("example.com.", key.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing name is covered, as is the wildcard that could have answered
+	r := testSignedQuery(t, udp, "missing.example.com", dns.TypeA)
+	if r.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN, got %v", r)
+	}
+	denial := testDenial(t, r, key)
+	if nsec := denial["mail.example.com."]; nsec == nil || nsec.NextDomain != "www.example.com." {
+		t.Errorf("expected mail.example.com. to www.example.com. covering the name, got %v", r.Ns)
+	}
+	if nsec := denial["example.com."]; nsec == nil || nsec.NextDomain != "mail.example.com." {
+		t.Errorf("expected example.com. to mail.example.com. covering the wildcard, got %v", r.Ns)
+	}
+
+	// A missing type is shown absent from the name's own NSEC
+	r = testSignedQuery(t, udp, "www.example.com", dns.TypeAAAA)
+	if r.Rcode != dns.RcodeSuccess || len(r.Answer) != 0 {
+		t.Fatalf("expected NODATA, got %v", r)
+	}
+	nsec := testDenial(t, r, key)["www.example.com."]
+	if nsec == nil || nsec.NextDomain != "example.com." {
+		t.Fatalf("expected the NSEC of www.example.com. wrapping to the apex, got %v", r.Ns)
+	}
+	for _, rrtype := range nsec.TypeBitMap {
+		if rrtype == dns.TypeAAAA {
+			t.Errorf("expected AAAA to be absent from %v", nsec)
+		}
+	}
+}
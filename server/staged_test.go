@@ -0,0 +1,82 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"testing"
+	"time"
+)
+
+// Start an upstream resolver answering every A query with the same address,
+// and have the server recurse to it
+func testUpstream(t *testing.T) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+		r := new(dns.Msg)
+		r.SetReply(m)
+		r.RecursionAvailable = true
+		if m.Question[0].Qtype == dns.TypeA {
+			r.Answer = append(r.Answer, &dns.A{Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60}, A: net.ParseIP("203.0.113.9")})
+		}
+		_ = w.WriteMsg(r)
+	})
+	server := &dns.Server{PacketConn: conn, Net: "udp", Handler: handler}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	viper.Set("dns.authoritative-only", false)
+	viper.Set("dns.upstream", []string{conn.LocalAddr().String()})
+	t.Cleanup(func() { viper.Set("dns.upstream", nil) })
+}
+
+func TestStagedRecordsAreHeldBack(t *testing.T) {
+	database, udp, _ := testServer(t)
+	testUpstream(t)
+	viper.Set("dns.propagation-delay", time.Hour)
+	t.Cleanup(func() { viper.Set("dns.propagation-delay", 0) })
+
+	// A record just written is staged, along with its name
+	stage := func(name string, qtype uint16, modified time.Time) {
+		if err := db.UpdateMetadata(name, dns.TypeToString[qtype], func(m *db.Metadata) { m.Modified = modified.Unix() }, database); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Set.A("new.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	stage("new.example.com", dns.TypeA, time.Now())
+
+	// Another name holds a served TXT record alongside a staged A record
+	if err := db.Set.TXT("mixed.example.com", []string{"served"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("mixed.example.com", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+	stage("mixed.example.com", dns.TypeA, time.Now())
+
+	// Staged names are answered authoritatively instead of from upstream
+	if r := testQuery(t, "udp", udp, "new.example.com", dns.TypeA); r.Rcode != dns.RcodeNameError || len(r.Answer) != 0 || !r.Authoritative {
+		t.Errorf("expected an authoritative NXDOMAIN for a staged name, got %v", r)
+	}
+	if r := testQuery(t, "udp", udp, "mixed.example.com", dns.TypeA); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 0 {
+		t.Errorf("expected NODATA for a staged type at a served name, got %v", r)
+	}
+
+	// Once the delay has passed the record is served
+	stage("new.example.com", dns.TypeA, time.Now().Add(-2*time.Hour))
+	r := testQuery(t, "udp", udp, "new.example.com", dns.TypeA)
+	if r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 || !r.Answer[0].(*dns.A).A.Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("expected the record to be served after the delay, got %v", r)
+	}
+}
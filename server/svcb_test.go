@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+func TestMissingServiceBindingRecords(t *testing.T) {
+	_, udp, _ := testServer(t)
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Names without the record are answered instead of bringing the server down
+	for _, qtype := range []uint16{dns.TypeSVCB, dns.TypeHTTPS} {
+		if r := testQuery(t, "udp", udp, "missing.example.com", qtype); r.Rcode != dns.RcodeNameError || len(r.Answer) != 0 {
+			t.Errorf("%s: expected NXDOMAIN for a missing name, got %v", dns.TypeToString[qtype], r)
+		}
+		if r := testQuery(t, "udp", udp, "www.example.com", qtype); r.Rcode == dns.RcodeServerFailure || len(r.Answer) != 0 {
+			t.Errorf("%s: expected no answer for a name holding other types, got %v", dns.TypeToString[qtype], r)
+		}
+	}
+}
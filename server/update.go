@@ -0,0 +1,90 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Answer a dynamic update (RFC 2136), making its changes if the update policy
+// of the zone grants every one of them to the updater
+func (h *Handler) update(w dns.ResponseWriter, m *dns.Msg) {
+	start := time.Now()
+	r := new(dns.Msg)
+	r.SetReply(m)
+	r.Rcode = h.applyUpdate(w, m)
+
+	// Sign the response with the key the update was signed with
+	if tsig := m.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		r.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+
+	if err := w.WriteMsg(r); err != nil {
+		log.Printf("Unable to send response: %v", err)
+	}
+	util.LogResponse(w, r, start)
+}
+
+// Check and make the changes of a dynamic update
+// Returns the response code to answer with
+func (h *Handler) applyUpdate(w dns.ResponseWriter, m *dns.Msg) int {
+	// The zone section names a single zone
+	if len(m.Question) != 1 || m.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError
+	}
+	zone := dns.Fqdn(strings.ToLower(m.Question[0].Name))
+	if db.ZoneFor(zone) != zone {
+		return dns.RcodeNotAuth
+	}
+
+	// Identify the updater by its key, if signed, and its address
+	key := ""
+	if tsig := m.IsTsig(); tsig != nil {
+		if w.TsigStatus() != nil {
+			return dns.RcodeNotAuth
+		}
+		key = tsig.Hdr.Name
+	}
+	ip := net.ParseIP(util.TransferPeer(w.RemoteAddr()))
+	updater := "update:" + util.TransferPeer(w.RemoteAddr())
+	if key != "" {
+		updater = "update:" + strings.TrimSuffix(key, ".")
+	}
+
+	settings, err := db.GetZoneSettings(zone, h.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+		return dns.RcodeServerFailure
+	}
+
+	if rcode := util.UpdatePrerequisites(zone, m.Answer); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	// Every change must be granted before any is made
+	for _, rr := range m.Ns {
+		hdr := rr.Header()
+		if !dns.IsSubDomain(zone, strings.ToLower(hdr.Name)) {
+			return dns.RcodeNotZone
+		} else if hdr.Class != dns.ClassINET && hdr.Class != dns.ClassANY && hdr.Class != dns.ClassNONE {
+			return dns.RcodeFormatError
+		} else if recordType := util.UpdateType(rr); recordType != "ANY" && !util.StringInArray(recordType, db.RecordTypes) {
+			return dns.RcodeNotImplemented
+		} else if !settings.UpdateAllowed(key, ip, hdr.Name, recordType) {
+			log.Printf("Refused update of %s records of '%s' by %s", recordType, hdr.Name, updater)
+			return dns.RcodeRefused
+		}
+	}
+
+	for _, rr := range m.Ns {
+		if err := util.ApplyUpdate(zone, updater, rr, h.Db); err != nil {
+			log.Printf("Failed to update %s records of '%s': %v", util.UpdateType(rr), rr.Header().Name, err)
+			return dns.RcodeServerFailure
+		}
+	}
+	return dns.RcodeSuccess
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+// Send a dynamic update of example.com, signed with a key if given, returning
+// the response code
+func testUpdate(t *testing.T, address, key string, insert, remove []dns.RR) int {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetUpdate("example.com.")
+	if len(insert) != 0 {
+		m.Insert(insert)
+	}
+	if len(remove) != 0 {
+		m.RemoveRRset(remove)
+	}
+
+	client := &dns.Client{}
+	if key != "" {
+		client.TsigSecret = map[string]string{key: viper.GetStringMapString("dns.tsig-keys")[key]}
+		m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	r, _, err := client.Exchange(m, address)
+	if err != nil {
+		t.Fatalf("failed to send update: %v", err)
+	}
+	return r.Rcode
+}
+
+// Parse a record for an update
+func testRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+// Grant updates of example.com by its policy
+func testUpdatePolicy(t *testing.T, database *db.Database, grants ...db.UpdateGrant) {
+	t.Helper()
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{UpdatePolicy: grants}, database); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateWithinPolicy(t *testing.T) {
+	database, udp, _ := testServer(t)
+	testUpdatePolicy(t, database, db.UpdateGrant{Source: "127.0.0.1/32", Name: "*.dyn.example.com", Types: []string{"A"}})
+
+	if rcode := testUpdate(t, udp, "", []dns.RR{testRR(t, "host.dyn.example.com. 300 IN A 192.0.2.10")}, nil); rcode != dns.RcodeSuccess {
+		t.Fatalf("expected the granted update to succeed, got %s", dns.RcodeToString[rcode])
+	}
+	if r := testQuery(t, "udp", udp, "host.dyn.example.com", dns.TypeA); len(r.Answer) != 1 || r.Answer[0].(*dns.A).A.String() != "192.0.2.10" {
+		t.Fatalf("expected A 192.0.2.10 to be served, got %v", r)
+	}
+	if changes, err := db.GetJournal("host.dyn.example.com", "A", database); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 1 || changes[0].Username != "update:127.0.0.1" {
+		t.Errorf("expected the update to be journaled as made by its source, got %+v", changes)
+	}
+
+	// Names and types outside of the grant are refused, without changing anything
+	for _, rr := range []dns.RR{
+		testRR(t, "www.example.com. 300 IN A 192.0.2.11"),
+		testRR(t, "host.dyn.example.com. 300 IN TXT \"text\""),
+	} {
+		if rcode := testUpdate(t, udp, "", []dns.RR{testRR(t, "other.dyn.example.com. 300 IN A 192.0.2.12"), rr}, nil); rcode != dns.RcodeRefused {
+			t.Errorf("expected an update of %v to be refused, got %s", rr, dns.RcodeToString[rcode])
+		}
+	}
+	if db.Get.NameExists("www.example.com") || db.Get.NameExists("other.dyn.example.com") || db.Get.TXT("host.dyn.example.com.") != nil {
+		t.Error("expected refused updates to leave the zone unchanged")
+	}
+
+	// Deleting within the grant is allowed too
+	if rcode := testUpdate(t, udp, "", nil, []dns.RR{testRR(t, "host.dyn.example.com. 0 IN A 0.0.0.0")}); rcode != dns.RcodeSuccess {
+		t.Fatalf("expected the granted delete to succeed, got %s", dns.RcodeToString[rcode])
+	} else if db.Get.A("host.dyn.example.com.") != nil {
+		t.Error("expected the record to be deleted")
+	}
+}
+
+func TestUpdateRefusedWithoutPolicy(t *testing.T) {
+	_, udp, _ := testServer(t)
+
+	if rcode := testUpdate(t, udp, "", []dns.RR{testRR(t, "host.dyn.example.com. 300 IN A 192.0.2.10")}, nil); rcode != dns.RcodeRefused {
+		t.Errorf("expected updates to be refused without a policy, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestUpdateGrantedToKey(t *testing.T) {
+	viper.Set("dns.tsig-keys", map[string]string{"updater.": "c2VjcmV0LWtleS1mb3ItdGVzdGluZw=="})
+	t.Cleanup(func() { viper.Set("dns.tsig-keys", map[string]string{}) })
+	database, udp, _ := testServer(t)
+	testUpdatePolicy(t, database, db.UpdateGrant{Key: "updater.", Name: "*.dyn.example.com", Types: []string{"A"}})
+
+	rr := testRR(t, "host.dyn.example.com. 300 IN A 192.0.2.10")
+	if rcode := testUpdate(t, udp, "", []dns.RR{rr}, nil); rcode != dns.RcodeRefused {
+		t.Errorf("expected an unsigned update to be refused, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := testUpdate(t, udp, "updater.", []dns.RR{rr}, nil); rcode != dns.RcodeSuccess {
+		t.Errorf("expected the signed update to succeed, got %s", dns.RcodeToString[rcode])
+	}
+}
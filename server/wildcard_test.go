@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+func TestWildcardPrecedence(t *testing.T) {
+	_, udp, _ := testServer(t)
+	for name, address := range map[string]string{
+		"*.example.com":      "192.0.2.1",
+		"www.example.com":    "192.0.2.2",
+		"*.sub.example.com":  "192.0.2.3",
+		"host.b.example.com": "192.0.2.4",
+	} {
+		if err := db.Set.A(name, address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name, address, reason string
+	}{
+		{"www.example.com", "192.0.2.2", "an existing name beats the wildcard"},
+		{"other.example.com", "192.0.2.1", "a missing name is synthesized from the wildcard"},
+		{"host.sub.example.com", "192.0.2.3", "the wildcard below the closest encloser is used"},
+		{"deep.host.sub.example.com", "192.0.2.3", "the closest encloser is the deepest existing name"},
+		{"b.example.com", "", "an empty non-terminal is not covered by the wildcard above it"},
+		{"other.b.example.com", "", "an empty non-terminal blocks the wildcard above it"},
+	} {
+		r := testQuery(t, "udp", udp, test.name, dns.TypeA)
+		if test.address == "" {
+			if len(r.Answer) != 0 {
+				t.Errorf("%s: expected no answer as %s, got %v", test.name, test.reason, r.Answer)
+			}
+			continue
+		}
+		if len(r.Answer) != 1 {
+			t.Errorf("%s: expected one answer as %s, got %v", test.name, test.reason, r)
+		} else if a, ok := r.Answer[0].(*dns.A); !ok || a.A.String() != test.address || a.Hdr.Name != test.name+"." {
+			t.Errorf("%s: expected A %s as %s, got %v", test.name, test.address, test.reason, r.Answer[0])
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+	"strconv"
+)
+
+// Handle listing the changes made by the requesting user, so users can review
+// their own activity without access to the whole audit log
+func activity(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Time range as unix timestamps, either end may be left open
+	var from, to int64
+	if r.URL.Query().Get("from") != "" {
+		if from, err = strconv.ParseInt(r.URL.Query().Get("from"), 10, 64); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'from' must be a unix timestamp")
+			return
+		}
+	}
+	if r.URL.Query().Get("to") != "" {
+		if to, err = strconv.ParseInt(r.URL.Query().Get("to"), 10, 64); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'to' must be a unix timestamp")
+			return
+		}
+	}
+
+	// Page through the entries, all of them without a limit
+	offset, limit := 0, 0
+	if r.URL.Query().Get("offset") != "" {
+		if offset, err = strconv.Atoi(r.URL.Query().Get("offset")); err != nil || offset < 0 {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'offset' must be a non-negative integer")
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") != "" {
+		if limit, err = strconv.Atoi(r.URL.Query().Get("limit")); err != nil || limit < 1 {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'limit' must be a positive integer")
+			return
+		}
+	}
+
+	// Only ever the entries of the user, whatever their role
+	entries, err := db.QueryAudit(db.AuditFilter{From: from, To: to, Username: u.Username}, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve audit log: "+err.Error())
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(entries)))
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	entries = entries[offset:]
+	if limit != 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+
+	util.Responses.SuccessWithData(w, entries)
+}
@@ -0,0 +1,107 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/admin"
+	"github.com/iznotek/dns/db"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Decode a list of audit entries from a response
+func testEntries(t *testing.T, response testResponse) []db.AuditEntry {
+	t.Helper()
+	var entries []db.AuditEntry
+	if err := json.Unmarshal(response.Data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestActivityOnlyHoldsOwnEntries(t *testing.T) {
+	database, adminToken := testDatabase(t)
+
+	user := db.NewUser("User", "user", "", "user")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	userToken, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for i, username := range []string{"user", "admin", "user", "other", "user"} {
+			entry := db.NewAuditEntry(username, "update", "www.example.com", "A")
+			entry.Time = int64(100 * (i + 1))
+			if err := db.WriteAudit(entry, tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user sees their own entries only, a page at a time
+	var times []int64
+	for _, offset := range []string{"0", "2"} {
+		status, response := testExchange(t, ActivityHandler(database), "GET", "/api/users/activity?limit=2&offset="+offset, userToken, nil)
+		if status != http.StatusOK {
+			t.Fatalf("failed to list activity: %d %s", status, response.Reason)
+		}
+		for _, entry := range testEntries(t, response) {
+			if entry.Username != "user" {
+				t.Errorf("expected only entries of the user, got %+v", entry)
+			}
+			times = append(times, entry.Time)
+		}
+	}
+	if len(times) != 3 || times[0] != 100 || times[1] != 300 || times[2] != 500 {
+		t.Errorf("expected the entries at 100, 300 and 500, got %v", times)
+	}
+
+	// Admins see their own activity here too, and everyone's in the audit log
+	if status, response := testExchange(t, ActivityHandler(database), "GET", "/api/users/activity", adminToken, nil); status != http.StatusOK {
+		t.Fatalf("failed to list activity: %d %s", status, response.Reason)
+	} else if entries := testEntries(t, response); len(entries) != 1 || entries[0].Username != "admin" {
+		t.Errorf("expected only the admin's entry, got %+v", entries)
+	}
+	if status, response := testExchange(t, admin.AuditHandler(database), "GET", "/api/audit", adminToken, nil); status != http.StatusOK {
+		t.Fatalf("failed to list audit log: %d %s", status, response.Reason)
+	} else if entries := testEntries(t, response); len(entries) != 5 {
+		t.Errorf("expected every entry in the audit log, got %+v", entries)
+	}
+
+	// The user is still kept out of the audit log
+	if status, response := testExchange(t, admin.AuditHandler(database), "GET", "/api/audit", userToken, nil); status != http.StatusForbidden || !strings.Contains(response.Reason, "admin") {
+		t.Errorf("expected 403 listing the audit log, got %d %s", status, response.Reason)
+	}
+}
+
+func TestUserChangesAudited(t *testing.T) {
+	database, token := testDatabase(t)
+
+	if status := testRequest(t, AllUsersHandler(database), "POST", "/api/users", token, map[string]string{
+		"name": "New", "username": "new", "password": "correct horse battery staple", "role": "user",
+	}); status != http.StatusOK {
+		t.Fatalf("failed to create user: %d", status)
+	}
+	if status := testRequest(t, AllUsersHandler(database), "DELETE", "/api/users?user=new", token, nil); status != http.StatusOK {
+		t.Fatalf("failed to delete user: %d", status)
+	}
+
+	entries, err := db.QueryAudit(db.AuditFilter{Resource: "new"}, database)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 2 || entries[0].Operation != "create" || entries[1].Operation != "delete" {
+		t.Fatalf("expected the user's creation and deletion in the audit log, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Username != "admin" || entry.Type != "user" {
+			t.Errorf("expected a change to a user by the admin, got %+v", entry)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"gopkg.in/hlandau/passlib.v1"
+	"net/http"
+	"testing"
+)
+
+// Check the last entry written to the audit log for a user
+func expectAudit(t *testing.T, database *db.Database, username, operation, resource string) {
+	t.Helper()
+	entries, err := db.QueryAudit(db.AuditFilter{Resource: resource, Type: "user"}, database)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) == 0 {
+		t.Fatalf("expected an audit entry for '%s'", resource)
+	}
+
+	last := entries[len(entries)-1]
+	if last.Username != username || last.Operation != operation || last.Resource != resource {
+		t.Fatalf("unexpected audit entry %+v", last)
+	}
+}
+
+func TestRotateAndResetAreAudited(t *testing.T) {
+	database, token := testDatabase(t)
+
+	// A hash not made with the current scheme is flagged
+	outdated := db.NewUser("Outdated", "outdated", "outdated-hash", "admin")
+	if err := outdated.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	if status := testRequest(t, RotateHandler(database), "POST", "/api/users/rotate", token, nil); status != http.StatusOK {
+		t.Fatalf("expected 200 rotating, got %d", status)
+	}
+	expectAudit(t, database, "admin", "flag", "outdated")
+
+	// Logging in with a new password clears the flag
+	u, err := db.UserFromDatabase("outdated", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.ResetRequired {
+		t.Fatal("expected the user to be flagged")
+	}
+	if u.Password, err = passlib.Hash("old-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+
+	status := testRequest(t, Login(database), "POST", "/api/users/login", "", map[string]string{
+		"username":     "outdated",
+		"password":     "old-password",
+		"new-password": "A-much-longer-new-password-1",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 logging in, got %d", status)
+	}
+	expectAudit(t, database, "outdated", "reset-password", "outdated")
+}
@@ -10,7 +10,7 @@ import (
 	"net/http"
 )
 
-func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func create(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Validate initial request with request, body exists, and content-type
 	if r.Method != "POST" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -73,6 +73,12 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		return
 	}
 
+	// Enforce the password policy before hashing
+	if err := util.CurrentPasswordPolicy().Check(body["password"].(string)); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Hash password
 	hash, err := passlib.Hash(body["password"].(string))
 	if err != nil {
@@ -82,7 +88,7 @@ func create(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 
 	// Write to database
 	u := db.NewUser(body["name"].(string), body["username"].(string), hash, body["role"].(string))
-	if err := u.Encode(database); err != nil {
+	if err := db.Audited(db.NewAuditEntry(user.Username, "create", u.Username, "user"), u.EncodeTx, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to write to database: "+err.Error())
 		return
 	}
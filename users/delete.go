@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-func deleteUser(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func deleteUser(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Check request type and headers
 	if r.Method != "DELETE" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -39,10 +39,13 @@ func deleteUser(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		username = r.URL.Query().Get("user")
 	}
 
-	// Delete user
-	if err := database.Update(func(tx *bolt.Tx) error {
+	// Delete user along with their refresh tokens
+	if err := db.Audited(db.NewAuditEntry(u.Username, "delete", username, "user"), func(tx *bolt.Tx) error {
+		if _, err := db.RevokeRefreshTokensTx(username, tx); err != nil {
+			return err
+		}
 		return tx.Bucket([]byte("users")).Delete([]byte(username))
-	}); err != nil {
+	}, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete user from database: "+err.Error())
 		return
 	}
@@ -1,13 +1,13 @@
 package users
 
 import (
+	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
-	bolt "go.etcd.io/bbolt"
 	"net/http"
 )
 
 // Handle requests for methods regarding specific users
-func AllUsersHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func AllUsersHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
@@ -28,3 +28,76 @@ func AllUsersHandler(db *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// Handle requests requiring password resets from users with outdated hashes
+func RotateHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			rotate(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests revoking login tokens
+func TokensHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "DELETE":
+			revokeTokens(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests exchanging or revoking refresh tokens
+func RefreshHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			refresh(w, r, db)
+			return
+		case "DELETE":
+			revokeRefresh(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests listing the changes made by the requesting user
+func ActivityHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			activity(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
+
+// Handle requests managing the webhook subscriptions of the requesting user
+func WebhooksHandler(db *db.Database) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "POST", "DELETE":
+			webhooks(w, r, db)
+			return
+		default:
+			util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+	}
+}
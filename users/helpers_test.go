@@ -0,0 +1,104 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"gopkg.in/hlandau/passlib.v1"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database holding an admin, returning a token for the admin
+func testDatabase(t *testing.T) (*db.Database, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+	viper.Set("http.refresh-token-ttl", 24*time.Hour)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+
+	admin := db.NewUser("Admin", "admin", "", "admin")
+	if err := admin.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := db.NewToken(admin, database)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return database, token
+}
+
+// Send a request with a JSON body to a handler, returning the status code
+func testRequest(t *testing.T, handler http.HandlerFunc, method, url, token string, body interface{}) int {
+	t.Helper()
+	status, _ := testExchange(t, handler, method, url, token, body)
+	return status
+}
+
+// The decoded body of a response
+type testResponse struct {
+	Status string          `json:"status"`
+	Reason string          `json:"reason"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Send a request with a JSON body to a handler, returning the status code and
+// decoded response
+func testExchange(t *testing.T, handler http.HandlerFunc, method, url, token string, body interface{}) (int, testResponse) {
+	t.Helper()
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to encode body: %v", err)
+	}
+	r := httptest.NewRequest(method, url, bytes.NewReader(encoded))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var response testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, response
+}
+
+// Create a user with a password and log them in, returning their login and
+// refresh tokens
+func testLogin(t *testing.T, database *db.Database, username, password string) (string, string) {
+	t.Helper()
+	hash, err := passlib.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := db.NewUser(username, username, hash, "admin")
+	if err := user.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	status, response := testExchange(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": username, "password": password})
+	if status != http.StatusOK {
+		t.Fatalf("failed to log in: %d %s", status, response.Reason)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(response.Data, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	return tokens["token"], tokens["refresh-token"]
+}
@@ -4,12 +4,13 @@ import (
 	"encoding/json"
 	"github.com/iznotek/dns/db"
 	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
 	bolt "go.etcd.io/bbolt"
 	"gopkg.in/hlandau/passlib.v1"
 	"net/http"
 )
 
-func Login(database *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func Login(database *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Validate initial request with request type, body exists, and content-type
 		if r.Method != "POST" {
@@ -28,9 +29,10 @@ func Login(database *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
 			return
-		} else if err, _ := util.ValidateBody(body, []string{"username", "password"}, map[string]map[string]string{
+		} else if err, _ := util.ValidateBody(body, []string{"username", "password", "new-password"}, map[string]map[string]string{
 			"username": {"type": "string", "required": "true"},
 			"password": {"type": "string", "required": "true"},
+			"new-password": {"type": "string", "required": "false"},
 		}); err != "" {
 			util.Responses.Error(w, http.StatusBadRequest, err)
 			return
@@ -55,6 +57,36 @@ func Login(database *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Require a new password from flagged users before issuing a token
+		if u.ResetRequired && !util.Exists(body, "new-password") {
+			util.Responses.ErrorWithData(w, http.StatusForbidden, "password reset required", map[string]bool{"reset-required": true})
+			return
+		} else if u.ResetRequired {
+			if err := util.CurrentPasswordPolicy().Check(body["new-password"].(string)); err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			hash, err := passlib.Hash(body["new-password"].(string))
+			if err != nil {
+				util.Responses.Error(w, http.StatusInternalServerError, "failed to hash password: "+err.Error())
+				return
+			}
+			u.Password = hash
+			u.ResetRequired = false
+
+			// Sessions started with the old password are ended
+			if err := db.Audited(db.NewAuditEntry(u.Username, "reset-password", u.Username, "user"), func(tx *bolt.Tx) error {
+				if err := u.EncodeTx(tx); err != nil {
+					return err
+				}
+				return db.RevokeSessionsTx(u.Username, tx)
+			}, database); err != nil {
+				util.Responses.Error(w, http.StatusInternalServerError, "failed to write user to database: "+err.Error())
+				return
+			}
+		}
+
 		// Generate token
 		token, err := db.NewToken(u, database)
 		if err != nil {
@@ -62,6 +94,17 @@ func Login(database *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		util.Responses.SuccessWithData(w, map[string]string{"token": token})
+		// Issue a refresh token to resume the session once the token expires
+		if viper.GetDuration("http.refresh-token-ttl") <= 0 {
+			util.Responses.SuccessWithData(w, map[string]string{"token": token})
+			return
+		}
+		refreshToken, err := db.NewRefreshToken(u.Username, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to save refresh token to database: "+err.Error())
+			return
+		}
+
+		util.Responses.SuccessWithData(w, map[string]string{"token": token, "refresh-token": refreshToken})
 	}
 }
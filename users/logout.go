@@ -7,7 +7,7 @@ import (
 	"net/http"
 )
 
-func Logout(database *bolt.DB) func(w http.ResponseWriter, r *http.Request) {
+func Logout(database *db.Database) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Validate initial request with type and authorization
 		if r.Method != "GET" {
@@ -0,0 +1,49 @@
+package users
+
+import (
+	"github.com/iznotek/dns/util"
+	"net/http"
+	"testing"
+)
+
+// Enforce a policy for the duration of a test
+func testPasswordPolicy(t *testing.T, policy util.PasswordPolicy) {
+	t.Helper()
+	previous := util.CurrentPasswordPolicy
+	util.CurrentPasswordPolicy = func() util.PasswordPolicy { return policy }
+	t.Cleanup(func() { util.CurrentPasswordPolicy = previous })
+}
+
+func TestCreateEnforcesPasswordPolicy(t *testing.T) {
+	database, token := testDatabase(t)
+	testPasswordPolicy(t, util.PasswordPolicy{MinLength: 12, Classes: []string{"digit"}})
+
+	for password, expected := range map[string]int{
+		"short1":              http.StatusBadRequest,
+		"long-without-digits": http.StatusBadRequest,
+		"long-enough-with-1":  http.StatusOK,
+	} {
+		status := testRequest(t, AllUsersHandler(database), "POST", "/api/users", token, map[string]string{
+			"name": "Test", "username": "test-" + password, "password": password, "role": "admin",
+		})
+		if status != expected {
+			t.Errorf("expected %d creating with %q, got %d", expected, password, status)
+		}
+	}
+}
+
+func TestUpdateEnforcesPasswordPolicy(t *testing.T) {
+	database, token := testDatabase(t)
+
+	// Tightened, a short password is rejected
+	testPasswordPolicy(t, util.PasswordPolicy{MinLength: 8})
+	if status := testRequest(t, AllUsersHandler(database), "PUT", "/api/users", token, map[string]string{"password": "x1"}); status != http.StatusBadRequest {
+		t.Errorf("expected 400 with a tightened policy, got %d", status)
+	}
+
+	// Relaxed, the same password is accepted
+	testPasswordPolicy(t, util.PasswordPolicy{MinLength: 1})
+	if status := testRequest(t, AllUsersHandler(database), "PUT", "/api/users", token, map[string]string{"password": "x1"}); status != http.StatusOK {
+		t.Errorf("expected 200 with a relaxed policy, got %d", status)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"net/http"
 )
 
-func read(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func read(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Validate initial request with request type
 	if r.Method != "GET" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -57,6 +57,7 @@ func read(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 				userData["username"] = u.Username
 				userData["role"] = u.Role
 				userData["logins"] = u.Tokens
+				userData["reset-required"] = u.ResetRequired
 
 				users = append(users, userData)
 
@@ -67,7 +68,12 @@ func read(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 			return
 		}
 
-		util.Responses.SuccessWithData(w, users)
+		start, end, err := util.Paginate(w, r, len(users))
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, users[start:end])
 		return
 	}
 
@@ -84,6 +90,15 @@ func read(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 	userData["username"] = rawUser.Username
 	userData["role"] = rawUser.Role
 	userData["logins"] = rawUser.Tokens
+	userData["reset-required"] = rawUser.ResetRequired
+
+	// Add number of records owned towards the quota
+	usage, err := db.CountOwned(rawUser.Username, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to count owned records: "+err.Error())
+		return
+	}
+	userData["records"] = usage
 
 	// Return user data
 	util.Responses.SuccessWithData(w, userData)
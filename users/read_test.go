@@ -0,0 +1,44 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListUsersLinksPages(t *testing.T) {
+	database, token := testDatabase(t)
+	for _, username := range []string{"alice", "bob"} {
+		user := db.NewUser(username, username, "", "admin")
+		if err := user.Encode(database); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"/api/users?user=*&per-page=2":        {`</api/users?page=2&per-page=2&user=%2A>; rel="next"`, `</api/users?page=2&per-page=2&user=%2A>; rel="last"`},
+		"/api/users?user=*&page=2&per-page=2": {`</api/users?page=1&per-page=2&user=%2A>; rel="prev"`, `</api/users?page=2&per-page=2&user=%2A>; rel="last"`},
+	}
+	for url, expected := range tests {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		AllUsersHandler(database)(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", url, w.Code, w.Body.String())
+		}
+
+		link := w.Header().Get("Link")
+		for _, target := range expected {
+			if !strings.Contains(link, target) {
+				t.Errorf("%s: expected %s in %q", url, target, link)
+			}
+		}
+		// The last page has nothing after it
+		if strings.Contains(url, "&page=2") && strings.Contains(link, `rel="next"`) {
+			t.Errorf("%s: expected no next page, got %q", url, link)
+		}
+	}
+}
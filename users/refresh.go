@@ -0,0 +1,100 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+)
+
+// Exchange a refresh token for a new login token, rotating the refresh token
+func refresh(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type, body exists, and content-type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"refresh-token"}, map[string]map[string]string{
+		"refresh-token": {"type": "string", "required": "true"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	username, rotated, err := db.RotateRefreshToken(body["refresh-token"].(string), database)
+	if err == db.ErrInvalidRefreshToken {
+		util.Responses.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	} else if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to rotate refresh token: "+err.Error())
+		return
+	}
+
+	// The user may have been deleted or flagged since logging in
+	u, err := db.UserFromDatabase(username, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, db.ErrInvalidRefreshToken.Error())
+		return
+	} else if u.ResetRequired {
+		util.Responses.ErrorWithData(w, http.StatusForbidden, "password reset required", map[string]bool{"reset-required": true})
+		return
+	}
+
+	// Generate token
+	token, err := db.NewToken(u, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to save token to database: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]string{"token": token, "refresh-token": rotated})
+}
+
+// Revoke a single refresh token, ending the session it belongs to
+func revokeRefresh(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type, body exists, and content-type
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	} else if err, _ := util.ValidateBody(body, []string{"refresh-token"}, map[string]map[string]string{
+		"refresh-token": {"type": "string", "required": "true"},
+	}); err != "" {
+		util.Responses.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if exists, err := db.RevokeRefreshToken(body["refresh-token"].(string), database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete refresh token: "+err.Error())
+		return
+	} else if !exists {
+		util.Responses.Error(w, http.StatusNotFound, "specified refresh token does not exist")
+		return
+	}
+
+	util.Responses.Success(w)
+}
@@ -0,0 +1,81 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+// Exchange a refresh token, returning the status and the issued tokens
+func testRefresh(t *testing.T, database *db.Database, refreshToken string) (int, map[string]string) {
+	t.Helper()
+	status, response := testExchange(t, RefreshHandler(database), "POST", "/api/users/refresh", "", map[string]string{"refresh-token": refreshToken})
+
+	tokens := map[string]string{}
+	if status == http.StatusOK {
+		if err := json.Unmarshal(response.Data, &tokens); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status, tokens
+}
+
+func TestRefresh(t *testing.T) {
+	database, _ := testDatabase(t)
+	_, refreshToken := testLogin(t, database, "alice", "Correct-Horse-9")
+	if refreshToken == "" {
+		t.Fatal("expected a refresh token when logging in")
+	}
+
+	status, tokens := testRefresh(t, database, refreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 refreshing, got %d", status)
+	} else if tokens["refresh-token"] == "" || tokens["refresh-token"] == refreshToken {
+		t.Errorf("expected a rotated refresh token, got %q", tokens["refresh-token"])
+	}
+
+	// The new login token authenticates
+	token, err := db.TokenFromString(tokens["token"], database)
+	if err != nil {
+		t.Fatalf("expected the new token to authenticate: %v", err)
+	} else if user, err := db.UserFromToken(token, database); err != nil || user.Username != "alice" {
+		t.Errorf("expected the token to belong to alice, got %v %v", user, err)
+	}
+}
+
+func TestRefreshRejectsRotatedToken(t *testing.T) {
+	database, _ := testDatabase(t)
+	_, refreshToken := testLogin(t, database, "alice", "Correct-Horse-9")
+
+	status, tokens := testRefresh(t, database, refreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 refreshing, got %d", status)
+	}
+
+	// Reusing the rotated out token is rejected while its replacement works
+	if status, _ := testRefresh(t, database, refreshToken); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 reusing a rotated token, got %d", status)
+	}
+	if status, _ := testRefresh(t, database, tokens["refresh-token"]); status != http.StatusOK {
+		t.Errorf("expected 200 with the rotated token, got %d", status)
+	}
+}
+
+func TestRefreshRevoked(t *testing.T) {
+	database, _ := testDatabase(t)
+	_, refreshToken := testLogin(t, database, "alice", "Correct-Horse-9")
+	_, other := testLogin(t, database, "bob", "Correct-Horse-9")
+
+	if status := testRequest(t, RefreshHandler(database), "DELETE", "/api/users/refresh", "", map[string]string{"refresh-token": refreshToken}); status != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d", status)
+	}
+	if status, _ := testRefresh(t, database, refreshToken); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a revoked token, got %d", status)
+	}
+
+	// Tokens are revoked individually
+	if status, _ := testRefresh(t, database, other); status != http.StatusOK {
+		t.Errorf("expected 200 with another user's token, got %d", status)
+	}
+}
@@ -0,0 +1,70 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/hlandau/passlib.v1"
+	"net/http"
+)
+
+// Flag all users whose password hash does not use the current scheme
+func rotate(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	// Each flagged user gets an entry in the audit log
+	var flagged []string
+	if err := database.Update(func(tx *bolt.Tx) (err error) {
+		if flagged, err = db.FlagUsersTx(compliant, tx); err != nil {
+			return err
+		}
+		for _, username := range flagged {
+			if err := db.WriteAudit(db.NewAuditEntry(u.Username, "flag", username, "user"), tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to flag users: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string][]string{"flagged": flagged})
+}
+
+// Check if a hash was made with the preferred scheme and its current parameters
+func compliant(hash string) bool {
+	if len(passlib.DefaultSchemes) == 0 {
+		return true
+	}
+
+	scheme := passlib.DefaultSchemes[0]
+	return scheme.SupportsStub(hash) && !scheme.NeedsUpdate(hash)
+}
@@ -0,0 +1,88 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"gopkg.in/hlandau/passlib.v1"
+	"net/http"
+	"testing"
+)
+
+func TestRotateFlagsOutdatedHashes(t *testing.T) {
+	database, token := testDatabase(t)
+	testLogin(t, database, "current", "current-password")
+
+	// A bcrypt hash left over from before the current scheme
+	legacy := db.NewUser("Legacy", "legacy", "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", "admin")
+	if err := legacy.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := testExchange(t, RotateHandler(database), "POST", "/api/users/rotate", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to rotate users: %d %s", status, response.Reason)
+	}
+	var data struct {
+		Flagged []string `json:"flagged"`
+	}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	flagged := map[string]bool{}
+	for _, username := range data.Flagged {
+		flagged[username] = true
+	}
+	if !flagged["legacy"] || flagged["current"] {
+		t.Errorf("expected only outdated hashes to be flagged, got %v", data.Flagged)
+	}
+
+	if u, err := db.UserFromDatabase("legacy", database); err != nil {
+		t.Fatal(err)
+	} else if !u.ResetRequired {
+		t.Error("expected the legacy user to require a reset")
+	}
+	if u, err := db.UserFromDatabase("current", database); err != nil {
+		t.Fatal(err)
+	} else if u.ResetRequired {
+		t.Error("expected the current user to be left alone")
+	}
+}
+
+func TestLoginRequiresReset(t *testing.T) {
+	database, _ := testDatabase(t)
+	testPasswordPolicy(t, util.PasswordPolicy{MinLength: 8})
+	hash, err := passlib.Hash("old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := db.NewUser("Flagged", "flagged", hash, "admin")
+	user.ResetRequired = true
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logging in without a new password is refused
+	status, response := testExchange(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": "flagged", "password": "old-password"})
+	if status != http.StatusForbidden || response.Reason != "password reset required" {
+		t.Fatalf("expected the login to require a reset, got %d %s", status, response.Reason)
+	}
+
+	// The new password must follow the policy
+	if status := testRequest(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": "flagged", "password": "old-password", "new-password": "short"}); status != http.StatusBadRequest {
+		t.Errorf("expected a new password breaking the policy to be rejected, got %d", status)
+	}
+
+	// Sending one clears the flag
+	if status := testRequest(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": "flagged", "password": "old-password", "new-password": "new-password"}); status != http.StatusOK {
+		t.Fatalf("expected the login to reset the password, got %d", status)
+	}
+	if u, err := db.UserFromDatabase("flagged", database); err != nil {
+		t.Fatal(err)
+	} else if u.ResetRequired {
+		t.Error("expected the flag to be cleared")
+	}
+	if status := testRequest(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": "flagged", "password": "new-password"}); status != http.StatusOK {
+		t.Errorf("expected to log in with the new password, got %d", status)
+	}
+}
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+)
+
+// Revoke the token used for the request, or every token of a user
+func revokeTokens(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type
+	if r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Admins may end the sessions of another user
+	username := r.URL.Query().Get("user")
+	if username != "" && u.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	} else if username != "" {
+		if _, err := db.UserFromDatabase(username, database); err != nil {
+			util.Responses.Error(w, http.StatusNotFound, "specified user does not exist")
+			return
+		}
+	}
+
+	// Only revoke the token used when not ending every session
+	if username == "" && r.URL.Query().Get("all") != "true" {
+		if err := database.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("tokens")).Delete([]byte(token.Header["kid"].(string)))
+		}); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to delete token: "+err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, map[string]int{"revoked": 1})
+		return
+	}
+
+	if username == "" {
+		username = u.Username
+	}
+	revoked, err := db.RevokeTokens(username, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete tokens: "+err.Error())
+		return
+	}
+	refreshRevoked, err := db.RevokeRefreshTokens(username, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to delete refresh tokens: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]int{"revoked": revoked, "refresh-revoked": refreshRevoked})
+}
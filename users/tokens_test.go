@@ -0,0 +1,115 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+// Log in an existing user again, returning their login and refresh tokens
+func testRelogin(t *testing.T, database *db.Database, username, password string) (string, string) {
+	t.Helper()
+	status, response := testExchange(t, Login(database), "POST", "/api/users/login", "", map[string]string{"username": username, "password": password})
+	if status != http.StatusOK {
+		t.Fatalf("failed to log in: %d %s", status, response.Reason)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(response.Data, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	return tokens["token"], tokens["refresh-token"]
+}
+
+// Check if a token still authenticates
+func testAuthenticates(database *db.Database, token string) bool {
+	_, err := db.TokenFromString(token, database)
+	return err == nil
+}
+
+func TestRevokeSingleToken(t *testing.T) {
+	database, _ := testDatabase(t)
+	first, _ := testLogin(t, database, "alice", "Correct-Horse-9")
+	second, refresh := testRelogin(t, database, "alice", "Correct-Horse-9")
+
+	if status := testRequest(t, TokensHandler(database), "DELETE", "/api/users/tokens", first, nil); status != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d", status)
+	}
+	if testAuthenticates(database, first) {
+		t.Error("expected the revoked token to fail authentication")
+	}
+
+	// Other sessions of the user continue
+	if !testAuthenticates(database, second) {
+		t.Error("expected the other token to still authenticate")
+	}
+	if status, _ := testRefresh(t, database, refresh); status != http.StatusOK {
+		t.Errorf("expected the other session to still refresh, got %d", status)
+	}
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	database, _ := testDatabase(t)
+	first, firstRefresh := testLogin(t, database, "alice", "Correct-Horse-9")
+	second, secondRefresh := testRelogin(t, database, "alice", "Correct-Horse-9")
+	other, _ := testLogin(t, database, "bob", "Correct-Horse-9")
+
+	status, response := testExchange(t, TokensHandler(database), "DELETE", "/api/users/tokens?all=true", second, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d %s", status, response.Reason)
+	}
+	var counts map[string]int
+	if err := json.Unmarshal(response.Data, &counts); err != nil {
+		t.Fatal(err)
+	} else if counts["revoked"] != 2 || counts["refresh-revoked"] != 2 {
+		t.Errorf("expected both sessions to be revoked, got %v", counts)
+	}
+
+	for _, token := range []string{first, second} {
+		if testAuthenticates(database, token) {
+			t.Error("expected every token of the user to fail authentication")
+		}
+	}
+	for _, refresh := range []string{firstRefresh, secondRefresh} {
+		if status, _ := testRefresh(t, database, refresh); status != http.StatusUnauthorized {
+			t.Errorf("expected 401 refreshing a revoked session, got %d", status)
+		}
+	}
+
+	// Other users are not logged out
+	if !testAuthenticates(database, other) {
+		t.Error("expected another user's token to still authenticate")
+	}
+}
+
+func TestRevokeOtherUserTokens(t *testing.T) {
+	database, admin := testDatabase(t)
+	alice, _ := testLogin(t, database, "alice", "Correct-Horse-9")
+	bob, _ := testLogin(t, database, "bob", "Correct-Horse-9")
+
+	// Only admins may end another user's sessions
+	user := db.NewUser("Carol", "carol", "", "editors")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	carol, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := testRequest(t, TokensHandler(database), "DELETE", "/api/users/tokens?user=alice", carol, nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", status)
+	}
+	if status := testRequest(t, TokensHandler(database), "DELETE", "/api/users/tokens?user=nobody", admin, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", status)
+	}
+
+	if status := testRequest(t, TokensHandler(database), "DELETE", "/api/users/tokens?user=alice", admin, nil); status != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d", status)
+	}
+	if testAuthenticates(database, alice) {
+		t.Error("expected alice's token to fail authentication")
+	}
+	if !testAuthenticates(database, bob) || !testAuthenticates(database, admin) {
+		t.Error("expected the other users' tokens to still authenticate")
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"net/http"
 )
 
-func update(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
+func update(w http.ResponseWriter, r *http.Request, database *db.Database) {
 	// Validate initial request with request type, body exist, and content-type
 	if r.Method != "PUT" {
 		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -74,19 +74,34 @@ func update(w http.ResponseWriter, r *http.Request, database *bolt.DB) {
 		u.Name = body["name"].(string)
 	}
 	if valid["password"] {
+		if err := util.CurrentPasswordPolicy().Check(body["password"].(string)); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		hash, err := passlib.Hash(body["password"].(string))
 		if err != nil {
 			util.Responses.Error(w, http.StatusInternalServerError, "failed to hash password: "+err.Error())
 			return
 		}
 		u.Password = hash
+		u.ResetRequired = false
 	}
 	if valid["role"] && tokenUser.Role == "admin" {
 		u.Role = body["role"].(string)
 	}
 
 	// Write updates to database
-	if err := u.Encode(database); err != nil {
+	// Changing the password ends every session of the user, including the
+	// one making the change, so they must log in again everywhere
+	if err := db.Audited(db.NewAuditEntry(tokenUser.Username, "update", u.Username, "user"), func(tx *bolt.Tx) error {
+		if err := u.EncodeTx(tx); err != nil {
+			return err
+		} else if valid["password"] {
+			return db.RevokeSessionsTx(u.Username, tx)
+		}
+		return nil
+	}, database); err != nil {
 		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record to database: "+err.Error())
 		return
 	}
@@ -0,0 +1,42 @@
+package users
+
+import (
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"testing"
+)
+
+func TestPasswordChangeRevokesTokens(t *testing.T) {
+	database, _ := testDatabase(t)
+	token, refreshToken := testLogin(t, database, "alice", "Correct-Horse-9")
+	if _, err := db.TokenFromString(token, database); err != nil {
+		t.Fatalf("expected the token to authenticate before the change: %v", err)
+	}
+
+	if status := testRequest(t, AllUsersHandler(database), "PUT", "/api/users", token, map[string]string{"password": "Battery-Staple-7"}); status != http.StatusOK {
+		t.Fatalf("expected 200 changing the password, got %d", status)
+	}
+
+	if _, err := db.TokenFromString(token, database); err == nil {
+		t.Error("expected the old token to no longer authenticate")
+	}
+	if status, _ := testRefresh(t, database, refreshToken); status != http.StatusUnauthorized {
+		t.Errorf("expected the refresh token to be revoked, got %d", status)
+	}
+}
+
+func TestAdminPasswordChangeRevokesTargetTokens(t *testing.T) {
+	database, adminToken := testDatabase(t)
+	token, _ := testLogin(t, database, "alice", "Correct-Horse-9")
+
+	if status := testRequest(t, AllUsersHandler(database), "PUT", "/api/users?user=alice", adminToken, map[string]string{"password": "Battery-Staple-7"}); status != http.StatusOK {
+		t.Fatalf("expected 200 changing the password, got %d", status)
+	}
+
+	if _, err := db.TokenFromString(token, database); err == nil {
+		t.Error("expected the target's token to no longer authenticate")
+	}
+	if _, err := db.TokenFromString(adminToken, database); err != nil {
+		t.Errorf("expected the admin's own token to keep working: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+	"net/url"
+)
+
+// A subscription along with the deliveries still waiting to be made to it
+type subscriptionState struct {
+	db.Subscription
+	Pending []db.Delivery `json:"pending"`
+}
+
+// Handle listing, adding and removing the webhook subscriptions of the
+// requesting user, who is only notified about names their role allows
+func webhooks(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with request type
+	if r.Method != "GET" && r.Method != "POST" && r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	u, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		subscriptions, err := db.ListSubscriptions(u.Username, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve subscriptions: "+err.Error())
+			return
+		}
+		pending, err := db.PendingDeliveries(database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve deliveries: "+err.Error())
+			return
+		}
+
+		states := []subscriptionState{}
+		for _, s := range subscriptions {
+			deliveries := pending[s.ID]
+			if deliveries == nil {
+				deliveries = []db.Delivery{}
+			}
+			states = append(states, subscriptionState{Subscription: s, Pending: deliveries})
+		}
+		util.Responses.SuccessWithData(w, states)
+
+	case "POST":
+		if r.Body == nil {
+			util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+			return
+		} else if r.Header.Get("Content-Type") != "application/json" {
+			util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+			return
+		}
+
+		// Validate body by decoding json, checking fields exist, and checking field type
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+			return
+		} else if err, _ := util.ValidateBody(body, []string{"url", "events", "pattern"}, map[string]map[string]string{
+			"url": {"type": "string", "required": "true"},
+			"events": {"type": "stringarray", "required": "true"},
+			"pattern": {"type": "string", "required": "true"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if target, err := url.Parse(body["url"].(string)); err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'url' must be an http or https URL")
+			return
+		}
+		events, _ := util.ConvertArrayToString(body["events"].([]interface{}))
+		for _, event := range events {
+			if !util.StringInArray(event, []string{db.EventCreated, db.EventUpdated, db.EventDeleted}) {
+				util.Responses.Error(w, http.StatusBadRequest, "event '"+event+"' must be one of: created, updated, deleted")
+				return
+			}
+		}
+		if err := db.ValidateNamePattern(body["pattern"].(string)); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		subscription, err := db.CreateSubscription(db.Subscription{Username: u.Username, URL: body["url"].(string), Events: events, Pattern: body["pattern"].(string)}, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write subscription: "+err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, subscription)
+
+	case "DELETE":
+		if r.URL.Query().Get("id") == "" {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'id' is required")
+			return
+		}
+
+		// Users may only remove their own subscriptions
+		if err := db.DeleteSubscription(r.URL.Query().Get("id"), u.Username, database); err == db.ErrSubscriptionNotFound {
+			util.Responses.Error(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to delete subscription: "+err.Error())
+			return
+		}
+		util.Responses.Success(w)
+	}
+}
@@ -0,0 +1,117 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhooksOnlyDeliverMatchingEvents(t *testing.T) {
+	database, _ := testDatabase(t)
+	viper.Set("http.webhooks.timeout", time.Second)
+	viper.Set("http.webhooks.retry-interval", time.Hour)
+	viper.Set("http.webhooks.max-attempts", 10)
+
+	if err := db.CreateRole("team", "", "", "", []string{"*.example.com"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	user := db.NewUser("User", "user", "", "team")
+	if err := user.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var received []util.WebhookEvent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event util.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		lock.Lock()
+		received = append(received, event)
+		lock.Unlock()
+	}))
+	t.Cleanup(receiver.Close)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	for _, target := range []string{receiver.URL, failing.URL} {
+		if status, response := testExchange(t, WebhooksHandler(database), "POST", "/api/users/webhooks", token, map[string]interface{}{
+			"url": target, "events": []string{"created", "deleted"}, "pattern": "*.team.example.com",
+		}); status != http.StatusOK {
+			t.Fatalf("failed to subscribe: %d %s", status, response.Reason)
+		}
+	}
+
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for _, change := range [][2]string{
+			{"create", "www.team.example.com"},
+			{"create", "www.other.example.com"},
+			{"update", "www.team.example.com"},
+			{"delete", "www.team.example.com"},
+		} {
+			if err := db.WriteAudit(db.NewAuditEntry("admin", change[0], change[1], "A"), tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run delivers one event per subscription
+	for i := 0; i < 3; i++ {
+		util.DeliverWebhooks(database)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected two events, got %+v", received)
+	} else if received[0].Event != "created" || received[0].Name != "www.team.example.com" {
+		t.Errorf("expected the creation of www.team.example.com first, got %+v", received[0])
+	} else if received[1].Event != "deleted" || received[1].Name != "www.team.example.com" {
+		t.Errorf("expected the deletion of www.team.example.com second, got %+v", received[1])
+	}
+
+	// The failing endpoint keeps its own events waiting for a retry
+	status, response := testExchange(t, WebhooksHandler(database), "GET", "/api/users/webhooks", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to list subscriptions: %d %s", status, response.Reason)
+	}
+	var subscriptions []subscriptionState
+	if err := json.Unmarshal(response.Data, &subscriptions); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range subscriptions {
+		if s.URL == receiver.URL && len(s.Pending) != 0 {
+			t.Errorf("expected nothing pending for the working endpoint, got %+v", s.Pending)
+		} else if s.URL == failing.URL && (len(s.Pending) != 2 || s.Pending[0].Attempts != 1 || s.Pending[0].LastError == "") {
+			t.Errorf("expected two pending events after one failed attempt, got %+v", s.Pending)
+		}
+	}
+}
+
+func TestWebhooksRejectUnknownEvents(t *testing.T) {
+	database, token := testDatabase(t)
+
+	status, response := testExchange(t, WebhooksHandler(database), "POST", "/api/users/webhooks", token, map[string]interface{}{
+		"url": "https://example.com/hook", "events": []string{"renamed"}, "pattern": "*.example.com",
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	} else if response.Reason != "event 'renamed' must be one of: created, updated, deleted" {
+		t.Errorf("unexpected reason %q", response.Reason)
+	}
+}
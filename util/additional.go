@@ -0,0 +1,53 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+)
+
+// Get the address records of the in-zone hosts named by MX, NS, and SRV
+// records in an answer, sparing resolvers the queries that would follow
+func AdditionalRecords(answer []dns.RR, addr net.Addr) []dns.RR {
+	var extra []dns.RR
+	seen := map[string]bool{}
+	for _, rr := range answer {
+		var target string
+		switch record := rr.(type) {
+		case *dns.MX:
+			target = record.Mx
+		case *dns.NS:
+			target = record.Ns
+		case *dns.SRV:
+			target = record.Target
+		default:
+			continue
+		}
+
+		// Only hosts within the zone of the answer are known to be current
+		target = dns.Fqdn(strings.ToLower(target))
+		if seen[target] || target == "." {
+			continue
+		}
+		seen[target] = true
+		if zone := db.ZoneFor(rr.Header().Name); zone == "" || db.ZoneFor(target) != zone {
+			continue
+		}
+
+		source := WildcardSource(target)
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			if Staged(source, qtype) || !SourceAllowed(source, qtype, addr) {
+				continue
+			}
+
+			record := db.Get.Record(source, dns.TypeToString[qtype])
+			if record == nil {
+				continue
+			}
+			hdr := dns.RR_Header{Name: target, Rrtype: qtype, Class: dns.ClassINET, Ttl: RecordTTL(target, source, qtype)}
+			extra = append(extra, RecordToRRs(hdr, record)...)
+		}
+	}
+	return extra
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"net"
+)
+
+// Check that the relay of an AMTRELAY record matches its type as defined in RFC 8777
+func ValidateAMTRELAY(relayType uint8, relay string) error {
+	switch relayType {
+	case dns.AMTRELAYNone:
+		if relay != "" {
+			return fmt.Errorf("field 'relay' must be empty for relay type 0")
+		}
+	case dns.AMTRELAYIPv4:
+		if ip := net.ParseIP(relay); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("field 'relay' must be an IPv4 address for relay type 1")
+		}
+	case dns.AMTRELAYIPv6:
+		if ip := net.ParseIP(relay); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("field 'relay' must be an IPv6 address for relay type 2")
+		}
+	case dns.AMTRELAYHost:
+		if _, ok := dns.IsDomainName(relay); !ok || relay == "" {
+			return fmt.Errorf("field 'relay' must be a domain name for relay type 3")
+		}
+	default:
+		return fmt.Errorf("field 'relay-type' must be one of 0, 1, 2, 3")
+	}
+	return nil
+}
+
+// Convert a stored AMTRELAY record into its wire representation
+// The discovery optional flag is carried in the high bit of the type
+func AMTRELAYToRR(hdr dns.RR_Header, record *db.AMTRELAY) *dns.AMTRELAY {
+	rr := &dns.AMTRELAY{Hdr: hdr, Precedence: record.Precedence, GatewayType: record.RelayType}
+	if record.Discovery {
+		rr.GatewayType |= 0x80
+	}
+
+	switch record.RelayType {
+	case dns.AMTRELAYIPv4, dns.AMTRELAYIPv6:
+		rr.GatewayAddr = net.ParseIP(record.Relay)
+	case dns.AMTRELAYHost:
+		rr.GatewayHost = dns.Fqdn(record.Relay)
+	}
+	return rr
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"sort"
+	"strings"
+)
+
+// Convert a list of record type names into a sorted and de-duplicated bitmap
+// Returns an error if any of the types are unknown
+func ParseTypeBitmap(types []interface{}) ([]string, error) {
+	codes := map[uint16]bool{}
+	for _, t := range types {
+		name, ok := t.(string)
+		if !ok {
+			return nil, fmt.Errorf("field 'types' must be an array of strings")
+		}
+
+		code, ok := dns.StringToType[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("field 'types' contains unknown record type '%s'", name)
+		}
+		codes[code] = true
+	}
+
+	// Bitmaps are encoded in ascending order of type
+	var sorted []int
+	for code := range codes {
+		sorted = append(sorted, int(code))
+	}
+	sort.Ints(sorted)
+
+	var bitmap []string
+	for _, code := range sorted {
+		bitmap = append(bitmap, dns.TypeToString[uint16(code)])
+	}
+	return bitmap, nil
+}
+
+// Convert a stored list of record type names into their type codes
+func TypeBitmap(types []string) []uint16 {
+	var bitmap []uint16
+	for _, t := range types {
+		if code, ok := dns.StringToType[t]; ok {
+			bitmap = append(bitmap, code)
+		}
+	}
+	return bitmap
+}
+
+// Check that only the flags defined in RFC 7477 are set on a CSYNC record
+func ValidateCSYNCFlags(flags uint16) error {
+	// Only the immediate (0x1) and soaminimum (0x2) bits are defined
+	if flags&^0x3 != 0 {
+		return fmt.Errorf("field 'flags' may only contain the immediate (1) and soaminimum (2) bits")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// Check if a name is on the configured blocklist, either listed itself or
+// below a listed name
+func Blocked(name string) bool {
+	name = dns.Fqdn(strings.ToLower(name))
+	for _, blocked := range viper.GetStringSlice("dns.blocklist") {
+		if dns.IsSubDomain(dns.Fqdn(strings.ToLower(blocked)), name) {
+			return true
+		}
+	}
+	return false
+}
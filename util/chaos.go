@@ -0,0 +1,18 @@
+package util
+
+import (
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// Get the configured answer for a CHAOS class server identification query
+// Returns an empty string if the query is unknown or the answer is suppressed
+func ChaosValue(qname string) string {
+	switch strings.ToLower(qname) {
+	case "version.bind.", "version.server.":
+		return viper.GetString("dns.chaos.version")
+	case "hostname.bind.", "id.server.":
+		return viper.GetString("dns.chaos.hostname")
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"net"
+	"strings"
+)
+
+// Longest chain of aliases followed for a single question
+const maxCNAMEChain = 8
+
+// Follow the CNAME held by a name to the records of the requested type
+// Aliases are only followed while the target stays within the zone of the
+// query, the CNAME pointing out of the zone is the last record returned.
+func FollowCNAME(qname string, qtype uint16, addr net.Addr) []dns.RR {
+	zone := db.ZoneFor(qname)
+
+	var answer []dns.RR
+	seen := map[string]bool{}
+	name := qname
+	for i := 0; i < maxCNAMEChain && !seen[strings.ToLower(name)]; i++ {
+		seen[strings.ToLower(name)] = true
+		source := WildcardSource(name)
+		hdr := dns.RR_Header{Name: name, Class: dns.ClassINET}
+
+		// Stop at the first target holding the requested records
+		if i > 0 && !Staged(source, qtype) && SourceAllowed(source, qtype, addr) {
+			if record := db.Get.Record(source, dns.TypeToString[qtype]); record != nil {
+				hdr.Rrtype = qtype
+				hdr.Ttl = RecordTTL(name, source, qtype)
+				if rrs := RecordToRRs(hdr, record); len(rrs) != 0 {
+					return append(answer, rrs...)
+				}
+			}
+		}
+
+		cname := db.Get.CNAME(source)
+		if cname == nil || Staged(source, dns.TypeCNAME) || !SourceAllowed(source, dns.TypeCNAME, addr) {
+			break
+		}
+		hdr.Rrtype = dns.TypeCNAME
+		hdr.Ttl = RecordTTL(name, source, dns.TypeCNAME)
+		answer = append(answer, &dns.CNAME{Hdr: hdr, Target: cname.Target})
+
+		// Never follow aliases across zone boundaries
+		name = dns.Fqdn(cname.Target)
+		if zone == "" || db.ZoneFor(name) != zone {
+			break
+		}
+	}
+
+	return answer
+}
+
+// Check whether pointing a name at a target would form a loop of aliases
+// Only aliases within the zone of the name are followed, or every alias when
+// no zones are configured, returning the chain of names leading back to it if
+// there is a loop.
+func CNAMELoop(name, target string) ([]string, bool) {
+	return CNAMELoopTx(name, target, nil)
+}
+
+// Check for a loop of aliases against the records of an open transaction,
+// such as one holding changes that are not committed yet
+func CNAMELoopTx(name, target string, tx *bolt.Tx) ([]string, bool) {
+	getter := db.Get.WithTx(tx)
+	name = strings.ToLower(dns.Fqdn(name))
+	zone := db.ZoneFor(name)
+
+	chain := []string{name}
+	seen := map[string]bool{}
+	current := strings.ToLower(dns.Fqdn(target))
+	for !seen[current] {
+		chain = append(chain, current)
+		if current == name {
+			return chain, true
+		} else if db.ZoneFor(current) != zone {
+			return nil, false
+		}
+		seen[current] = true
+
+		cname := getter.CNAME(current)
+		if cname == nil {
+			return nil, false
+		}
+		current = strings.ToLower(dns.Fqdn(cname.Target))
+	}
+
+	// Loops not passing through the name are left to the resolver's limits
+	return nil, false
+}
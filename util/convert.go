@@ -0,0 +1,176 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"net"
+	"reflect"
+)
+
+// Check whether a record is missing, including the typed nil pointers the
+// getters return for names without the record
+func nilRecord(record db.Record) bool {
+	if record == nil {
+		return true
+	}
+	value := reflect.ValueOf(record)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// Convert a stored record into the wire representation of its set
+// A records may hold several addresses, every other type a single record
+func RecordToRRs(hdr dns.RR_Header, record db.Record) []dns.RR {
+	if nilRecord(record) {
+		return nil
+	} else if a, ok := record.(*db.A); ok {
+		rrs := make([]dns.RR, 0, len(a.Addresses))
+		for _, address := range a.Addresses {
+			rrs = append(rrs, &dns.A{Hdr: hdr, A: address})
+		}
+		return rrs
+	}
+
+	if rr := RecordToRR(hdr, record); rr != nil {
+		return []dns.RR{rr}
+	}
+	return nil
+}
+
+// Convert a stored record into its wire representation
+// Returns nil if the record cannot be represented, A records holding
+// several addresses are converted with RecordToRRs
+func RecordToRR(hdr dns.RR_Header, record db.Record) dns.RR {
+	if nilRecord(record) {
+		return nil
+	}
+
+	switch r := record.(type) {
+	case *db.AAAA:
+		return &dns.AAAA{Hdr: hdr, AAAA: r.Address}
+	case *db.CNAME:
+		return &dns.CNAME{Hdr: hdr, Target: r.Target}
+	case *db.MX:
+		return &dns.MX{Hdr: hdr, Preference: r.Priority, Mx: r.Host}
+	case *db.LOC:
+		locString, vers := r.ToParsable()
+		if loc := ParseLOCString(locString, vers, hdr); loc != nil {
+			return loc
+		}
+	case *db.SRV:
+		return &dns.SRV{Hdr: hdr, Priority: r.Priority, Weight: r.Weight, Port: r.Port, Target: r.Target}
+	case *db.SPF:
+		return &dns.SPF{Hdr: hdr, Txt: r.Text}
+	case *db.TXT:
+		return &dns.TXT{Hdr: hdr, Txt: r.Text}
+	case *db.NS:
+		return &dns.NS{Hdr: hdr, Ns: r.Nameserver}
+	case *db.CAA:
+		return &dns.CAA{Hdr: hdr, Flag: r.Flag, Tag: r.Tag, Value: r.Content}
+	case *db.PTR:
+		return &dns.PTR{Hdr: hdr, Ptr: r.Domain}
+	case *db.CERT:
+		return &dns.CERT{Hdr: hdr, Type: r.Type, KeyTag: r.KeyTag, Algorithm: r.Algorithm, Certificate: r.Certificate}
+	case *db.DNSKEY:
+		return &dns.DNSKEY{Hdr: hdr, Flags: r.Flags, Protocol: r.Protocol, Algorithm: r.Algorithm, PublicKey: r.PublicKey}
+	case *db.DS:
+		return &dns.DS{Hdr: hdr, KeyTag: r.KeyTag, Algorithm: r.Algorithm, DigestType: r.DigestType, Digest: r.Digest}
+	case *db.NAPTR:
+		return &dns.NAPTR{Hdr: hdr, Order: r.Order, Preference: r.Preference, Flags: r.Flags, Service: r.Service, Regexp: r.Regexp, Replacement: r.Replacement}
+	case *db.SMIMEA:
+		return &dns.SMIMEA{Hdr: hdr, Usage: r.Usage, Selector: r.Selector, MatchingType: r.MatchingType, Certificate: r.Certificate}
+	case *db.SSHFP:
+		return &dns.SSHFP{Hdr: hdr, Algorithm: r.Algorithm, Type: r.Type, FingerPrint: r.Fingerprint}
+	case *db.TLSA:
+		return &dns.TLSA{Hdr: hdr, Usage: r.Usage, Selector: r.Selector, MatchingType: r.MatchingType, Certificate: r.Certificate}
+	case *db.URI:
+		return &dns.URI{Hdr: hdr, Priority: r.Priority, Weight: r.Weight, Target: r.Target}
+	case *db.AMTRELAY:
+		return AMTRELAYToRR(hdr, r)
+	case *db.CSYNC:
+		return &dns.CSYNC{Hdr: hdr, Serial: r.Serial, Flags: r.Flags, TypeBitMap: TypeBitmap(r.Types)}
+	case *db.HINFO:
+		return &dns.HINFO{Hdr: hdr, Cpu: r.CPU, Os: r.OS}
+	case *db.CDNSKEY:
+		return &dns.CDNSKEY{DNSKEY: dns.DNSKEY{Hdr: hdr, Flags: r.Flags, Protocol: r.Protocol, Algorithm: r.Algorithm, PublicKey: r.PublicKey}}
+	case *db.SVCB:
+		return SVCBToRR(hdr, *r)
+	case *db.HTTPS:
+		return SVCBToRR(hdr, r.SVCB)
+	case *db.SOA:
+		return &dns.SOA{Hdr: hdr, Ns: r.Nameserver, Mbox: r.Mailbox, Serial: r.Serial, Refresh: r.Refresh, Retry: r.Retry, Expire: r.Expire, Minttl: r.Minimum}
+	}
+	return nil
+}
+
+// Convert a parsed resource record into the stored representation
+// Returns an error for record types which cannot be stored
+func RRToRecord(rr dns.RR) (db.Record, error) {
+	switch r := rr.(type) {
+	case *dns.A:
+		return &db.A{Addresses: []net.IP{r.A}}, nil
+	case *dns.AAAA:
+		return &db.AAAA{Address: r.AAAA}, nil
+	case *dns.CNAME:
+		return &db.CNAME{Target: r.Target}, nil
+	case *dns.MX:
+		return &db.MX{Host: r.Mx, Priority: r.Preference}, nil
+	case *dns.SRV:
+		return &db.SRV{Priority: r.Priority, Weight: r.Weight, Port: r.Port, Target: r.Target}, nil
+	case *dns.SPF:
+		return &db.SPF{Text: r.Txt}, nil
+	case *dns.TXT:
+		return &db.TXT{Text: r.Txt}, nil
+	case *dns.NS:
+		return &db.NS{Nameserver: r.Ns}, nil
+	case *dns.CAA:
+		return &db.CAA{Flag: r.Flag, Tag: r.Tag, Content: r.Value}, nil
+	case *dns.PTR:
+		return &db.PTR{Domain: r.Ptr}, nil
+	case *dns.CERT:
+		return &db.CERT{Type: r.Type, KeyTag: r.KeyTag, Algorithm: r.Algorithm, Certificate: r.Certificate}, nil
+	case *dns.DNSKEY:
+		return &db.DNSKEY{Flags: r.Flags, Protocol: r.Protocol, Algorithm: r.Algorithm, PublicKey: r.PublicKey}, nil
+	case *dns.DS:
+		return &db.DS{KeyTag: r.KeyTag, Algorithm: r.Algorithm, DigestType: r.DigestType, Digest: r.Digest}, nil
+	case *dns.NAPTR:
+		return &db.NAPTR{Order: r.Order, Preference: r.Preference, Flags: r.Flags, Service: r.Service, Regexp: r.Regexp, Replacement: r.Replacement}, nil
+	case *dns.SMIMEA:
+		return &db.SMIMEA{Usage: r.Usage, Selector: r.Selector, MatchingType: r.MatchingType, Certificate: r.Certificate}, nil
+	case *dns.SSHFP:
+		return &db.SSHFP{Algorithm: r.Algorithm, Type: r.Type, Fingerprint: r.FingerPrint}, nil
+	case *dns.TLSA:
+		return &db.TLSA{Usage: r.Usage, Selector: r.Selector, MatchingType: r.MatchingType, Certificate: r.Certificate}, nil
+	case *dns.URI:
+		return &db.URI{Priority: r.Priority, Weight: r.Weight, Target: r.Target}, nil
+	case *dns.CSYNC:
+		var types []string
+		for _, t := range r.TypeBitMap {
+			types = append(types, dns.TypeToString[t])
+		}
+		return &db.CSYNC{Serial: r.Serial, Flags: r.Flags, Types: types}, nil
+	case *dns.AMTRELAY:
+		relay := r.GatewayHost
+		if r.GatewayAddr != nil {
+			relay = r.GatewayAddr.String()
+		}
+		return &db.AMTRELAY{Precedence: r.Precedence, Discovery: r.GatewayType&0x80 != 0, RelayType: r.GatewayType &^ 0x80, Relay: relay}, nil
+	case *dns.HINFO:
+		return &db.HINFO{CPU: r.Cpu, OS: r.Os}, nil
+	case *dns.CDNSKEY:
+		return &db.CDNSKEY{Flags: r.Flags, Protocol: r.Protocol, Algorithm: r.Algorithm, PublicKey: r.PublicKey}, nil
+	case *dns.SVCB:
+		record := svcbFromRR(r)
+		return &record, nil
+	case *dns.HTTPS:
+		return &db.HTTPS{SVCB: svcbFromRR(&r.SVCB)}, nil
+	case *dns.SOA:
+		return &db.SOA{Nameserver: r.Ns, Mailbox: r.Mbox, Serial: r.Serial, Refresh: r.Refresh, Retry: r.Retry, Expire: r.Expire, Minimum: r.Minttl}, nil
+	}
+	return nil, fmt.Errorf("records of type %s cannot be stored", dns.TypeToString[rr.Header().Rrtype])
+}
+
+// Get the presentation format of a record's data without its header
+func RData(rr dns.RR) string {
+	return rr.String()[len(rr.Header().String()):]
+}
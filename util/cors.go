@@ -0,0 +1,22 @@
+package util
+
+import (
+	"github.com/rs/cors"
+	"github.com/spf13/viper"
+	"net/http"
+)
+
+// Build the CORS middleware for the API from the configured origins
+// Preflight requests are answered directly, and requests from origins not
+// in the allowlist are passed through without any CORS headers
+func CORS() *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins: viper.GetStringSlice("http.cors.allowed-origins"),
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "If-Match", "X-Request-ID"},
+		// Let browsers read the headers used for concurrency control, rate
+		// limiting, and correlating requests with the logs
+		ExposedHeaders: []string{"ETag", "Retry-After", "X-Request-ID"},
+		MaxAge:         viper.GetInt("http.cors.max-age"),
+	})
+}
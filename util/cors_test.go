@@ -0,0 +1,70 @@
+package util
+
+import (
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Wrap a handler responding with no content in the configured middleware
+func testCORS(t *testing.T) http.Handler {
+	t.Helper()
+	viper.Set("http.cors.allowed-origins", []string{"https://dashboard.example.com"})
+	viper.Set("http.cors.max-age", 600)
+	t.Cleanup(func() { viper.Set("http.cors.allowed-origins", nil) })
+
+	return CORS().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := testCORS(t)
+
+	r := httptest.NewRequest("OPTIONS", "/api/records", nil)
+	r.Header.Set("Origin", "https://dashboard.example.com")
+	r.Header.Set("Access-Control-Request-Method", "PUT")
+	r.Header.Set("Access-Control-Request-Headers", "authorization,content-type")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "https://dashboard.example.com" {
+		t.Errorf("expected the origin to be echoed, got %q", origin)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PUT") {
+		t.Errorf("expected PUT to be allowed, got %q", methods)
+	}
+	headers := strings.ToLower(w.Header().Get("Access-Control-Allow-Headers"))
+	if !strings.Contains(headers, "authorization") || !strings.Contains(headers, "content-type") {
+		t.Errorf("expected Authorization and Content-Type to be allowed, got %q", headers)
+	}
+	if maxAge := w.Header().Get("Access-Control-Max-Age"); maxAge != "600" {
+		t.Errorf("expected a max age of 600, got %q", maxAge)
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	handler := testCORS(t)
+
+	for origin, allowed := range map[string]bool{
+		"https://dashboard.example.com": true,
+		"https://evil.example.net":      false,
+	} {
+		r := httptest.NewRequest("GET", "/api/records", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		// Disallowed origins are served without CORS headers rather than refused
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: expected the request to be served, got %d", origin, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); allowed && got != origin {
+			t.Errorf("%s: expected the origin to be echoed, got %q", origin, got)
+		} else if !allowed && got != "" {
+			t.Errorf("%s: expected no CORS headers, got %q", origin, got)
+		}
+	}
+}
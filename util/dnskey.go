@@ -0,0 +1,34 @@
+package util
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+)
+
+// Flag combinations of a usable key: a zone signing key or key signing key,
+// either of which may be revoked as defined in RFC 5011
+var validDNSKEYFlags = []uint16{
+	dns.ZONE,
+	dns.ZONE | dns.SEP,
+	dns.ZONE | dns.REVOKE,
+	dns.ZONE | dns.SEP | dns.REVOKE,
+}
+
+// Check that the flags and protocol of a DNSKEY or CDNSKEY are well-formed as
+// defined in RFC 4034, unless experimental values are allowed
+func ValidateDNSKEY(flags uint16, protocol uint8) error {
+	if viper.GetBool("records.allow-experimental-dnskey") {
+		return nil
+	}
+
+	if protocol != 3 {
+		return fmt.Errorf("field 'protocol' must be 3")
+	}
+	for _, valid := range validDNSKEYFlags {
+		if flags == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("field 'flags' must be one of 256 (ZSK), 257 (KSK), 384 (revoked ZSK), 385 (revoked KSK)")
+}
@@ -0,0 +1,17 @@
+package util
+
+import (
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// Apply the configured case policy to a domain valued field such as a CNAME
+// target or MX host
+// Returns whether the value was changed
+func NormalizeDomain(domain string) (string, bool) {
+	if !viper.GetBool("records.lowercase-domains") {
+		return domain, false
+	}
+	lowered := strings.ToLower(domain)
+	return lowered, lowered != domain
+}
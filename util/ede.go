@@ -0,0 +1,21 @@
+package util
+
+import "github.com/miekg/dns"
+
+// Attach an Extended DNS Error (RFC 8914) to a response
+// Only done when the query used EDNS, as otherwise no OPT record may be sent
+func SetExtendedError(req, resp *dns.Msg, code uint16, text string) {
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil {
+		return
+	}
+
+	// Add OPT record to response if not present
+	opt := resp.IsEdns0()
+	if opt == nil {
+		resp.SetEdns0(reqOpt.UDPSize(), reqOpt.Do())
+		opt = resp.IsEdns0()
+	}
+
+	opt.Option = append(opt.Option, &dns.EDNS0_EDE{InfoCode: code, ExtraText: text})
+}
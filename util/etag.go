@@ -0,0 +1,36 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"strings"
+)
+
+// Get the entity tag identifying the stored value of a record
+func RecordETag(record db.Record) string {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(encoded)
+	return "\"" + hex.EncodeToString(sum[:16]) + "\""
+}
+
+// Check if an If-Match header allows changing a record with the given tag
+// An empty header places no condition on the change
+func ETagMatches(header, etag string) bool {
+	if header == "" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check every address of the records with a health check, keeping the results
+// for answers to leave out unhealthy addresses
+func CheckHealth(database *db.Database) {
+	checks, err := db.ListHealthChecks(database)
+	if err != nil {
+		log.Printf("Failed to retrieve health checks: %v", err)
+		return
+	}
+
+	for name, check := range checks {
+		record := db.Get.A(name + ".")
+		if record == nil {
+			continue
+		}
+
+		// Check the addresses of a record at the same time so a slow one
+		// doesn't hold up the others
+		var lock sync.Mutex
+		var wg sync.WaitGroup
+		health := map[string]db.AddressHealth{}
+		for _, address := range record.Addresses {
+			wg.Add(1)
+			go func(address net.IP) {
+				defer wg.Done()
+				healthy := checkAddress(address, check)
+				lock.Lock()
+				health[address.String()] = db.AddressHealth{Healthy: healthy, Checked: time.Now().Unix()}
+				lock.Unlock()
+			}(address)
+		}
+		wg.Wait()
+
+		if err := db.SetHealth(name, health, database); err != nil {
+			log.Printf("Failed to write health of '%s': %v", name, err)
+		}
+	}
+}
+
+// Check whether an address passes a health check
+func checkAddress(address net.IP, check db.HealthCheck) bool {
+	timeout := viper.GetDuration("records.health-check.timeout")
+	target := net.JoinHostPort(address.String(), strconv.Itoa(int(check.Port)))
+
+	if check.Protocol != "http" {
+		conn, err := net.DialTimeout("tcp", target, timeout)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	// Redirects are answers too, and following them would check another host
+	client := &http.Client{
+		Timeout:       timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	response, err := client.Get("http://" + target + check.Path)
+	if err != nil {
+		return false
+	}
+	_ = response.Body.Close()
+	return response.StatusCode < http.StatusBadRequest
+}
+
+// Leave out the addresses of a name which failed their last health check,
+// keeping every address when none passed so the name still resolves
+func HealthyAddresses(name string, addresses []net.IP) []net.IP {
+	health, err := db.GetHealth(strings.TrimSuffix(name, "."), db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve health of '%s': %v", name, err)
+		return addresses
+	}
+
+	// Addresses not checked yet are given the benefit of the doubt
+	healthy := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		if h, ok := health[address.String()]; !ok || h.Healthy {
+			healthy = append(healthy, address)
+		}
+	}
+	if len(healthy) == 0 {
+		return addresses
+	}
+	return healthy
+}
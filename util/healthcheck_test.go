@@ -0,0 +1,87 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Get the addresses of a name that are answered with, sorted
+func testHealthyAddresses(t *testing.T, name string) []string {
+	t.Helper()
+	record := db.Get.A(name + ".")
+	if record == nil {
+		t.Fatalf("expected an A record for %s", name)
+	}
+	var addresses []string
+	for _, address := range HealthyAddresses(name+".", record.Addresses) {
+		addresses = append(addresses, address.String())
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func TestUnhealthyAddressesWithheld(t *testing.T) {
+	database := testSignedZone(t)
+	viper.Set("records.health-check.timeout", time.Second)
+	t.Cleanup(func() { viper.Set("records.health-check.timeout", 5*time.Second) })
+
+	// Only the first address accepts connections on the checked port
+	healthy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = healthy.Close() })
+	port := healthy.Addr().(*net.TCPAddr).Port
+
+	if err := db.Set.A("www.example.com", "127.0.0.1", "127.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateMetadata("www.example.com", "A", func(m *db.Metadata) {
+		m.HealthCheck = &db.HealthCheck{Protocol: "tcp", Port: uint16(port)}
+	}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	// Addresses are answered with until they are checked
+	if addresses := testHealthyAddresses(t, "www.example.com"); len(addresses) != 2 {
+		t.Fatalf("expected both addresses before checking, got %v", addresses)
+	}
+
+	CheckHealth(database)
+	if addresses := testHealthyAddresses(t, "www.example.com"); len(addresses) != 1 || addresses[0] != "127.0.0.1" {
+		t.Fatalf("expected only the healthy address, got %v", addresses)
+	}
+	if health, err := db.GetHealth("www.example.com", database); err != nil {
+		t.Fatal(err)
+	} else if !health["127.0.0.1"].Healthy || health["127.0.0.2"].Healthy || health["127.0.0.2"].Checked == 0 {
+		t.Errorf("expected 127.0.0.2 to be recorded as unhealthy, got %+v", health)
+	}
+
+	// The address is answered with again once it recovers
+	recovered, err := net.Listen("tcp", "127.0.0.2:"+strconv.Itoa(port))
+	if err != nil {
+		t.Skipf("cannot listen on 127.0.0.2: %v", err)
+	}
+	CheckHealth(database)
+	if addresses := testHealthyAddresses(t, "www.example.com"); len(addresses) != 2 {
+		t.Fatalf("expected both addresses once recovered, got %v", addresses)
+	}
+
+	// Every address is answered with when none pass
+	_ = recovered.Close()
+	_ = healthy.Close()
+	CheckHealth(database)
+	if health, err := db.GetHealth("www.example.com", database); err != nil {
+		t.Fatal(err)
+	} else if health["127.0.0.1"].Healthy || health["127.0.0.2"].Healthy {
+		t.Fatalf("expected both addresses to be unhealthy, got %+v", health)
+	}
+	if addresses := testHealthyAddresses(t, "www.example.com"); len(addresses) != 2 {
+		t.Errorf("expected both addresses when none are healthy, got %v", addresses)
+	}
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// A public key in JWK form (RFC 8037)
+type JWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+}
+
+// The protected header of a detached JWS
+type jwsHeader struct {
+	Alg   string `json:"alg"`
+	KeyID string `json:"kid"`
+}
+
+// Get the identifier of a key, the start of a hash of it
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Get a public key in the form it is published in
+func PublicJWK(key ed25519.PublicKey) JWK {
+	return JWK{KeyType: "OKP", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key), KeyID: KeyID(key), Use: "sig", Alg: "EdDSA"}
+}
+
+// Sign a payload with a JWS leaving the payload out (RFC 7515 appendix F), so
+// it can be sent alongside the payload unchanged
+func SignDetached(payload []byte, key ed25519.PrivateKey) (string, error) {
+	header, err := json.Marshal(jwsHeader{Alg: "EdDSA", KeyID: KeyID(key.Public().(ed25519.PublicKey))})
+	if err != nil {
+		return "", err
+	}
+
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	signature := ed25519.Sign(key, []byte(protected+"."+base64.RawURLEncoding.EncodeToString(payload)))
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Check a detached JWS made by SignDetached against the payload it was sent
+// with
+func VerifyDetached(jws string, payload []byte, key ed25519.PublicKey) error {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New("signature must be a detached JWS")
+	}
+
+	encoded, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return errors.New("failed to decode signature header: " + err.Error())
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(encoded, &header); err != nil {
+		return errors.New("failed to decode signature header: " + err.Error())
+	} else if header.Alg != "EdDSA" {
+		return errors.New("unsupported signature algorithm '" + header.Alg + "'")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errors.New("failed to decode signature: " + err.Error())
+	} else if !ed25519.Verify(key, []byte(parts[0]+"."+base64.RawURLEncoding.EncodeToString(payload)), signature) {
+		return errors.New("signature does not match the payload")
+	}
+	return nil
+}
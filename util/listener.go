@@ -0,0 +1,55 @@
+package util
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Listener that closes connections beyond a maximum number of active connections
+type LimitedListener struct {
+	net.Listener
+	max    int64
+	active int64
+}
+
+// Wrap a listener with a connection limit, a limit of 0 or less disables it
+func NewLimitedListener(listener net.Listener, max int64) *LimitedListener {
+	return &LimitedListener{Listener: listener, max: max}
+}
+
+// Accept the next connection within the limit
+func (l *LimitedListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		// Refuse the connection if over the limit
+		if active := atomic.AddInt64(&l.active, 1); l.max > 0 && active > l.max {
+			atomic.AddInt64(&l.active, -1)
+			_ = conn.Close()
+			continue
+		}
+
+		return &limitedConn{Conn: conn, listener: l}, nil
+	}
+}
+
+// Get the number of currently active connections
+func (l *LimitedListener) Active() int64 {
+	return atomic.LoadInt64(&l.active)
+}
+
+// Connection that frees its slot in the listener when closed
+type limitedConn struct {
+	net.Conn
+	listener *LimitedListener
+	once     sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(&c.listener.active, -1) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Wait for a gauge to reach a value, failing the test if it doesn't in time
+func testGauge(t *testing.T, name string, want int64) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if Metrics.Snapshot()[name] == want {
+			return
+		}
+	}
+	t.Fatalf("expected gauge %s to be %d, got %d", name, want, Metrics.Snapshot()[name])
+}
+
+func TestLimitedListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited := NewLimitedListener(listener, 2)
+	t.Cleanup(func() { _ = limited.Close() })
+	Metrics.Gauge("test-connections", limited.Active)
+
+	accepted := make(chan net.Conn, 3)
+	go func() {
+		for {
+			conn, err := limited.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	dial := func() net.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	// Connections within the limit are served and counted
+	dial()
+	dial()
+	testGauge(t, "test-connections", 2)
+
+	// The connection past the limit is closed straight away
+	extra := dial()
+	_ = extra.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := extra.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection past the limit to be closed, read succeeded")
+	} else if e, ok := err.(net.Error); ok && e.Timeout() {
+		t.Fatalf("expected the connection past the limit to be closed, got %v", err)
+	}
+	testGauge(t, "test-connections", 2)
+
+	// Closing a connection frees its slot for the next one
+	_ = (<-accepted).Close()
+	testGauge(t, "test-connections", 1)
+	dial()
+	testGauge(t, "test-connections", 2)
+}
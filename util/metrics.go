@@ -0,0 +1,28 @@
+package util
+
+import "sync"
+
+var Metrics = metrics{mutex: &sync.RWMutex{}, gauges: map[string]func() int64{}}
+type metrics struct {
+	mutex  *sync.RWMutex
+	gauges map[string]func() int64
+}
+
+// Register a gauge to be read on every snapshot
+func (m metrics) Gauge(name string, value func() int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gauges[name] = value
+}
+
+// Get the current value of all gauges
+func (m metrics) Snapshot() map[string]int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	snapshot := make(map[string]int64)
+	for name, value := range m.gauges {
+		snapshot[name] = value()
+	}
+	return snapshot
+}
@@ -1,19 +1,28 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/iznotek/dns/db"
+	"log"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
 )
 
+// Offset to start the next set of rotated addresses at
+var rotation uint32
+
 // Check if a value exists within a map
 func Exists(m map[string]interface{}, key string) bool {
 	_, ok := m[key]
 	return ok
 }
 
-// Convert []interface to []string)
+// Convert []interface to []string, keeping the order of the elements
 func ConvertArrayToString(iarr []interface{}) ([]string, error) {
-	strings := make([]string, len(iarr))
+	strings := make([]string, 0, len(iarr))
 
 	for _, v := range iarr {
 		if s, ok := v.(string); !ok {
@@ -26,6 +35,21 @@ func ConvertArrayToString(iarr []interface{}) ([]string, error) {
 	return strings, nil
 }
 
+// Convert an object of interfaces to a map of strings
+func ConvertMapToString(imap map[string]interface{}) (map[string]string, error) {
+	strings := make(map[string]string, len(imap))
+
+	for k, v := range imap {
+		if s, ok := v.(string); !ok {
+			return map[string]string{}, fmt.Errorf("not all values are strings")
+		} else {
+			strings[k] = s
+		}
+	}
+
+	return strings, nil
+}
+
 // Remove duplicates from array
 func RemoveDuplicates(arr []string) []string {
 	// Get all elements, harmlessly overwrite if already exists
@@ -48,6 +72,35 @@ func RecordDoesNotExist(r db.Record) bool {
 	return fmt.Sprintf("%v", r) == "<nil>"
 }
 
+// Check if a stored record holds exactly the values of a request body
+func RecordMatchesBody(r db.Record, body map[string]interface{}) bool {
+	if RecordDoesNotExist(r) {
+		return false
+	}
+
+	// Round trip through JSON to get the same representation as the body
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		return false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return false
+	}
+
+	// A records may be given a single host in place of the set of hosts
+	if _, ok := r.(*db.A); ok && !Exists(body, "hosts") && body["host"] != nil {
+		body = map[string]interface{}{"hosts": []interface{}{body["host"]}}
+	}
+
+	for key, value := range fields {
+		if !reflect.DeepEqual(value, body[key]) {
+			return false
+		}
+	}
+	return true
+}
+
 // Check if value in array
 func StringInArray(val string, list []string) bool {
 	for _, el := range list {
@@ -57,3 +110,33 @@ func StringInArray(val string, list []string) bool {
 	}
 	return false
 }
+
+// Rotate a set of addresses to start at the next one on each call,
+// spreading clients that use the first address across all of them
+func RoundRobin(addresses []net.IP) []net.IP {
+	if len(addresses) < 2 {
+		return addresses
+	}
+
+	offset := int(atomic.AddUint32(&rotation, 1) % uint32(len(addresses)))
+	return append(append([]net.IP{}, addresses[offset:]...), addresses[:offset]...)
+}
+
+// Order the addresses of a name for an answer, listing the preferred address
+// first for clients that only use the first one and rotating the others
+func OrderAddresses(source string, addresses []net.IP) []net.IP {
+	metadata, err := db.GetMetadata(strings.TrimSuffix(source, "."), "A", db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve metadata for '%s': %v", source, err)
+		return RoundRobin(addresses)
+	}
+
+	preferred := net.ParseIP(metadata.Preferred)
+	for i, address := range addresses {
+		if preferred != nil && address.Equal(preferred) {
+			others := append(append([]net.IP{}, addresses[:i]...), addresses[i+1:]...)
+			return append([]net.IP{address}, RoundRobin(others)...)
+		}
+	}
+	return RoundRobin(addresses)
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"encoding/binary"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+)
+
+// Get the TTL a trusted client asked answers to be served with
+// The TTL is carried as a 32-bit integer in a local EDNS option, which is
+// only honored when enabled and the client is within the allowed sources.
+func TTLOverride(m *dns.Msg, addr net.Addr) (uint32, bool) {
+	if !viper.GetBool("dns.debug.ttl-override.enabled") {
+		return 0, false
+	}
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		return 0, false
+	}
+
+	// Find the override option
+	code := uint16(viper.GetUint32("dns.debug.ttl-override.option"))
+	var data []byte
+	for _, o := range opt.Option {
+		if local, ok := o.(*dns.EDNS0_LOCAL); ok && local.Code == code {
+			data = local.Data
+		}
+	}
+	if len(data) != 4 {
+		return 0, false
+	}
+
+	// Only allow trusted clients
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		return 0, false
+	}
+	for _, source := range viper.GetStringSlice("dns.debug.ttl-override.sources") {
+		if _, network, err := net.ParseCIDR(source); err == nil && network.Contains(ip) {
+			return binary.BigEndian.Uint32(data), true
+		}
+	}
+	return 0, false
+}
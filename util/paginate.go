@@ -0,0 +1,117 @@
+package util
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Get the bounds of the requested page of a list from the 'page' and
+// 'per-page', or 'limit' and 'offset', query parameters, and link to the
+// other pages with an RFC 8288 Link header. The whole list is one page when
+// neither 'per-page' nor 'limit' is given.
+func Paginate(w http.ResponseWriter, r *http.Request, total int) (int, int, error) {
+	query := r.URL.Query()
+	if query.Get("limit") != "" || query.Get("offset") != "" {
+		return paginateOffset(w, r, total)
+	} else if query.Get("per-page") == "" {
+		return 0, total, nil
+	}
+
+	perPage, err := strconv.Atoi(query.Get("per-page"))
+	if err != nil || perPage < 1 {
+		return 0, 0, errors.New("query parameter 'per-page' must be a positive integer")
+	}
+	page := 1
+	if query.Get("page") != "" {
+		if page, err = strconv.Atoi(query.Get("page")); err != nil || page < 1 {
+			return 0, 0, errors.New("query parameter 'page' must be a positive integer")
+		}
+	}
+
+	// An empty list still has a single, empty page
+	last := (total + perPage - 1) / perPage
+	if last == 0 {
+		last = 1
+	}
+
+	// Link to the surrounding pages with the same query
+	link := func(p int, rel string) string {
+		query.Set("page", strconv.Itoa(p))
+		return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="` + rel + `"`
+	}
+	links := []string{link(1, "first")}
+	if page > 1 && page <= last {
+		links = append(links, link(page-1, "prev"))
+	}
+	if page < last {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end, nil
+}
+
+// Get the bounds of a slice of a list from the 'limit' and 'offset' query
+// parameters, linking to the surrounding slices when a limit is given
+func paginateOffset(w http.ResponseWriter, r *http.Request, total int) (int, int, error) {
+	query := r.URL.Query()
+
+	offset := 0
+	if query.Get("offset") != "" {
+		var err error
+		if offset, err = strconv.Atoi(query.Get("offset")); err != nil || offset < 0 {
+			return 0, 0, errors.New("query parameter 'offset' must be a non-negative integer")
+		}
+	}
+	if offset > total {
+		offset = total
+	}
+	if query.Get("limit") == "" {
+		return offset, total, nil
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		return 0, 0, errors.New("query parameter 'limit' must be a positive integer")
+	}
+
+	// Link to the surrounding slices with the same query
+	link := func(o int, rel string) string {
+		query.Set("offset", strconv.Itoa(o))
+		return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="` + rel + `"`
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / limit * limit
+	}
+	links := []string{link(0, "first")}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if offset+limit < total {
+		links = append(links, link(offset+limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end, nil
+}
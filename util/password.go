@@ -0,0 +1,72 @@
+package util
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"strings"
+	"unicode"
+)
+
+// Passwords rejected regardless of the configured rules
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "111111", "abc123",
+	"password", "password1", "passw0rd", "qwerty", "qwerty123", "letmein",
+	"welcome", "admin", "changeme", "iloveyou", "monkey", "dragon",
+}
+
+// Rules a new password must follow
+type PasswordPolicy struct {
+	MinLength int
+	// Character classes of which at least one character is required, any
+	// of: lower, upper, digit, symbol
+	Classes []string
+	// Passwords to reject in addition to the common ones
+	Denied []string
+}
+
+// Get the password policy to enforce, replaceable to tighten or relax it
+var CurrentPasswordPolicy = PasswordPolicyFromConfig
+
+// Get the password policy from the configuration
+func PasswordPolicyFromConfig() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength: viper.GetInt("http.password-policy.min-length"),
+		Classes:   viper.GetStringSlice("http.password-policy.classes"),
+		Denied:    viper.GetStringSlice("http.password-policy.denied"),
+	}
+}
+
+// Check a password against the policy, naming the rule it breaks
+func (p PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	for _, class := range p.Classes {
+		var matches func(r rune) bool
+		switch class {
+		case "lower":
+			matches = unicode.IsLower
+		case "upper":
+			matches = unicode.IsUpper
+		case "digit":
+			matches = unicode.IsDigit
+		case "symbol":
+			matches = func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) }
+		default:
+			continue
+		}
+
+		if strings.IndexFunc(password, matches) == -1 {
+			return fmt.Errorf("password must contain at least one %s character", class)
+		}
+	}
+
+	for _, denied := range append(append([]string{}, commonPasswords...), p.Denied...) {
+		if strings.EqualFold(password, denied) {
+			return fmt.Errorf("password is too common")
+		}
+	}
+
+	return nil
+}
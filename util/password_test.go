@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, Classes: []string{"lower", "upper", "digit", "symbol"}, Denied: []string{"Company-Name-2024"}}
+
+	cases := []struct {
+		password string
+		reason   string
+	}{
+		{"Sh0rt!", "password must be at least 10 characters long"},
+		{"NO-LOWER-CASE-1", "password must contain at least one lower character"},
+		{"no-upper-case-1", "password must contain at least one upper character"},
+		{"No-Digits-Here", "password must contain at least one digit character"},
+		{"NoSymbolsHere1", "password must contain at least one symbol character"},
+		{"Company-Name-2024", "password is too common"},
+		{"Correct-Horse-9", ""},
+	}
+	for _, c := range cases {
+		err := policy.Check(c.password)
+		if c.reason == "" && err != nil {
+			t.Errorf("expected %q to be accepted, got %v", c.password, err)
+		} else if c.reason != "" && (err == nil || err.Error() != c.reason) {
+			t.Errorf("expected %q to be rejected with %q, got %v", c.password, c.reason, err)
+		}
+	}
+}
+
+func TestPasswordPolicyRejectsCommonPasswords(t *testing.T) {
+	if err := (PasswordPolicy{MinLength: 6}).Check("Password1"); err == nil || err.Error() != "password is too common" {
+		t.Errorf("expected a common password to be rejected, got %v", err)
+	}
+}
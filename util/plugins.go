@@ -0,0 +1,119 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"strings"
+	"sync"
+)
+
+// Checks a record against a custom policy before it is written
+// Returning a ValidationError identifies the offending field
+type RecordValidator func(name string, record db.Record) error
+
+// A rejection of a record by a validator
+type ValidationError struct {
+	Type   string `json:"type"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Field != "" {
+		return "field '" + e.Field + "': " + e.Reason
+	}
+	return e.Reason
+}
+
+// Convert rejections into the details of an error response
+func RejectionDetails(rejections []ValidationError) []ErrorDetail {
+	details := make([]ErrorDetail, 0, len(rejections))
+	for _, rejection := range rejections {
+		details = append(details, ErrorDetail{Field: rejection.Field, Code: "rejected", Message: rejection.Reason})
+	}
+	return details
+}
+
+var validators = struct {
+	sync.RWMutex
+	byType map[string][]RecordValidator
+}{byType: map[string][]RecordValidator{}}
+
+// Register a validator for records of a type, run after the built-in
+// validation on creates, updates, and imports
+func RegisterValidator(recordType string, validator RecordValidator) {
+	validators.Lock()
+	defer validators.Unlock()
+
+	recordType = strings.ToUpper(recordType)
+	validators.byType[recordType] = append(validators.byType[recordType], validator)
+}
+
+// Run the validators registered for a record's type
+// Returns the rejections of every validator that failed
+func ValidateRecord(name string, record db.Record) []ValidationError {
+	if RecordDoesNotExist(record) {
+		return nil
+	}
+
+	validators.RLock()
+	defer validators.RUnlock()
+
+	var rejections []ValidationError
+	for _, validator := range validators.byType[record.Name()] {
+		err := validator(name, record)
+		if err == nil {
+			continue
+		}
+
+		rejection, ok := err.(ValidationError)
+		if !ok {
+			rejection = ValidationError{Reason: err.Error()}
+		}
+		rejection.Type = record.Name()
+		rejections = append(rejections, rejection)
+	}
+	return rejections
+}
+
+// Get the record a request body would result in, taking its fields over
+// the stored record if there is one
+func ProposedRecord(recordType string, stored db.Record, body map[string]interface{}) (db.Record, error) {
+	base := stored
+	if RecordDoesNotExist(base) {
+		base = db.NewRecord(recordType)
+	}
+	if base == nil {
+		return nil, fmt.Errorf("unsupported record type '%s'", recordType)
+	}
+
+	// Round trip through JSON so body fields line up with the record's
+	encoded, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for key := range fields {
+		if value, ok := body[key]; ok {
+			fields[key] = value
+		}
+	}
+
+	// A records may be given a single host in place of the set of hosts
+	if _, ok := body["hosts"]; !ok && strings.EqualFold(recordType, "A") && body["host"] != nil {
+		fields["hosts"] = []interface{}{body["host"]}
+	}
+
+	if encoded, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	record := db.NewRecord(recordType)
+	if err := json.Unmarshal(encoded, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
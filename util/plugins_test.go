@@ -0,0 +1,53 @@
+package util
+
+import (
+	"errors"
+	"github.com/iznotek/dns/db"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRecordRunsRegisteredValidators(t *testing.T) {
+	// Registered for a type no other test validates
+	var order []string
+	RegisterValidator("hinfo", func(name string, record db.Record) error {
+		order = append(order, "first")
+		if record.(*db.HINFO).CPU == "" {
+			return ValidationError{Field: "cpu", Reason: "must be set"}
+		}
+		return nil
+	})
+	RegisterValidator("HINFO", func(name string, record db.Record) error {
+		order = append(order, "second")
+		if name == "blocked.example.com" {
+			return errors.New("name is reserved")
+		}
+		return nil
+	})
+
+	if rejections := ValidateRecord("host.example.com", &db.HINFO{CPU: "x86", OS: "Linux"}); len(rejections) != 0 {
+		t.Errorf("expected a valid record to pass, got %v", rejections)
+	}
+
+	// Every validator runs in the order registered, plain errors have no field
+	order = nil
+	rejections := ValidateRecord("blocked.example.com", &db.HINFO{OS: "Linux"})
+	expected := []ValidationError{
+		{Type: "HINFO", Field: "cpu", Reason: "must be set"},
+		{Type: "HINFO", Reason: "name is reserved"},
+	}
+	if !reflect.DeepEqual(order, []string{"first", "second"}) {
+		t.Errorf("expected validators to run in the order registered, got %v", order)
+	}
+	if !reflect.DeepEqual(rejections, expected) {
+		t.Errorf("expected rejections %+v, got %+v", expected, rejections)
+	}
+	if details := RejectionDetails(rejections); len(details) != 2 || details[0].Field != "cpu" || details[1].Message != "name is reserved" {
+		t.Errorf("expected a detail for each rejection, got %+v", details)
+	}
+
+	// Other types are not affected
+	if rejections := ValidateRecord("blocked.example.com", &db.TXT{Text: []string{"ok"}}); len(rejections) != 0 {
+		t.Errorf("expected other types to pass, got %v", rejections)
+	}
+}
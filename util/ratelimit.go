@@ -0,0 +1,121 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens available to a single client, refilled continuously
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  int
+}
+
+// Token buckets of API clients by username or address
+type rateLimiter struct {
+	sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+var limiter = &rateLimiter{buckets: map[string]*tokenBucket{}}
+
+// Take a token from the bucket of a client
+// Returns how long until a token is available if the bucket is empty
+func (l *rateLimiter) take(key string, rate float64, burst int) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.rate, bucket.burst = rate, burst
+
+	// Refill for the time passed since the last request
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / rate * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// Drop buckets that have refilled completely as they are no different from new ones
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate >= float64(bucket.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Limit the rate of API requests of each user, or of each address for
+// requests without a valid token
+// Admins use their own limits, where a rate of 0 exempts them.
+func RateLimit(next http.Handler, database *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The frontend's static files are not limited
+		if !viper.GetBool("http.rate-limit.enabled") || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, admin := rateLimitKey(r, database)
+		rate, burst := viper.GetFloat64("http.rate-limit.rate"), viper.GetInt("http.rate-limit.burst")
+		if admin {
+			rate, burst = viper.GetFloat64("http.rate-limit.admin-rate"), viper.GetInt("http.rate-limit.admin-burst")
+		}
+		if rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		} else if burst < 1 {
+			// Without a burst no request could ever be let through
+			burst = int(math.Ceil(rate))
+		}
+
+		if wait, ok := limiter.take(key, rate, burst); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			Responses.Error(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Get the client a request is counted against and whether it is an admin
+func rateLimitKey(r *http.Request, database *db.Database) (string, bool) {
+	if r.Header.Get("Authorization") != "" {
+		if token, err := db.TokenFromString(r.Header.Get("Authorization"), database); err == nil {
+			if user, err := db.UserFromToken(token, database); err == nil {
+				return "user:" + user.Username, user.Role == "admin"
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "address:" + host, false
+}
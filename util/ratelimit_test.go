@@ -0,0 +1,99 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Configure the rate limit for the duration of a test
+func testRateLimit(t *testing.T, rate float64, burst int, adminRate float64, adminBurst int) {
+	t.Helper()
+	viper.Set("http.rate-limit.enabled", true)
+	viper.Set("http.rate-limit.rate", rate)
+	viper.Set("http.rate-limit.burst", burst)
+	viper.Set("http.rate-limit.admin-rate", adminRate)
+	viper.Set("http.rate-limit.admin-burst", adminBurst)
+	limiter = &rateLimiter{buckets: map[string]*tokenBucket{}}
+	t.Cleanup(func() { viper.Set("http.rate-limit.enabled", false) })
+}
+
+// Send a request through the rate limiter from an address
+func limitedRequest(handler http.Handler, address, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/api/records", nil)
+	r.RemoteAddr = address
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitRecovers(t *testing.T) {
+	testRateLimit(t, 20, 2, 0, 0)
+	handler := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+
+	for i := 0; i < 2; i++ {
+		if w := limitedRequest(handler, "192.0.2.1:1234", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst got %d", i, w.Code)
+		}
+	}
+
+	w := limitedRequest(handler, "192.0.2.1:1234", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request over the limit to get 429, got %d", w.Code)
+	} else if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 {
+		t.Errorf("expected a Retry-After of at least a second, got %q", w.Header().Get("Retry-After"))
+	}
+
+	// Other clients have buckets of their own
+	if w := limitedRequest(handler, "192.0.2.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("request of another address got %d", w.Code)
+	}
+
+	// A token is refilled every 50ms
+	time.Sleep(60 * time.Millisecond)
+	if w := limitedRequest(handler, "192.0.2.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("expected limit to recover, got %d", w.Code)
+	}
+}
+
+func TestRateLimitWithoutBurst(t *testing.T) {
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := db.Setup(database); err != nil {
+		t.Fatal(err)
+	}
+	admin := db.NewUser("Admin", "admin", "", "admin")
+	if err := admin.Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	token, err := db.NewToken(admin, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Admins have a rate of their own but no burst configured
+	testRateLimit(t, 1, 1, 2, 0)
+	handler := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), database)
+
+	for i := 0; i < 2; i++ {
+		if w := limitedRequest(handler, "192.0.2.1:1234", token); w.Code != http.StatusOK {
+			t.Fatalf("admin request %d got %d", i, w.Code)
+		}
+	}
+	if w := limitedRequest(handler, "192.0.2.1:1234", token); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected admin over the limit to get 429, got %d", w.Code)
+	}
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gorilla/handlers"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+type contextKey string
+
+const requestIDKey contextKey = "request-id"
+
+// Where request log lines are written, replaceable to capture them
+var requestLogOutput io.Writer = os.Stdout
+
+// A single API request as logged in the JSON format
+type requestLogLine struct {
+	Time      string  `json:"time"`
+	RequestID string  `json:"request-id"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	Latency   float64 `json:"latency-ms"`
+	Username  string  `json:"username,omitempty"`
+}
+
+// Response writer remembering the status it was written with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Get the ID of a request, empty if it passed through no request logging
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey).(string)
+	return id
+}
+
+// Give each request an ID, honoring one sent by the client, and echo it in
+// the X-Request-ID header so errors can be correlated with the logs
+// With the JSON log format, a line is logged for every request once served.
+func RequestLog(next http.Handler, database *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+
+		if viper.GetString("http.log-format") != "json" {
+			return
+		}
+		line := requestLogLine{
+			Time:      start.Format(time.RFC3339),
+			RequestID: id,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    recorder.status,
+			Latency:   float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if r.Header.Get("Authorization") != "" {
+			if token, err := db.TokenFromString(r.Header.Get("Authorization"), database); err == nil {
+				if user, err := db.UserFromToken(token, database); err == nil {
+					line.Username = user.Username
+				}
+			}
+		}
+
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			log.Printf("Failed to encode request log: %v", err)
+			return
+		}
+		if _, err := requestLogOutput.Write(append(encoded, '\n')); err != nil {
+			log.Printf("Failed to write request log: %v", err)
+		}
+	})
+}
+
+// Log a route in the common log format unless requests are logged as JSON
+func AccessLog(h http.Handler) http.Handler {
+	if viper.GetString("http.log-format") == "json" {
+		return h
+	}
+	return handlers.LoggingHandler(os.Stdout, h)
+}
+
+// Generate a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// IDs from clients are echoed in headers and responses, so only short IDs
+// made of safe characters are honored
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
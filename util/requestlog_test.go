@@ -0,0 +1,74 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Serve a failing request through the request log in the JSON format,
+// returning the response and the logged line
+func testRequestLog(t *testing.T, id string) (*httptest.ResponseRecorder, requestLogLine) {
+	t.Helper()
+	viper.Set("http.log-format", "json")
+	t.Cleanup(func() { viper.Set("http.log-format", "") })
+
+	var output bytes.Buffer
+	previous := requestLogOutput
+	requestLogOutput = &output
+	t.Cleanup(func() { requestLogOutput = previous })
+
+	handler := RequestLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Responses.Error(w, http.StatusBadRequest, "record must be specified in path")
+	}), nil)
+
+	r := httptest.NewRequest("GET", "/api/records/", nil)
+	if id != "" {
+		r.Header.Set("X-Request-ID", id)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var line requestLogLine
+	if err := json.Unmarshal(output.Bytes(), &line); err != nil {
+		t.Fatalf("invalid log line %q: %v", output.String(), err)
+	}
+	return w, line
+}
+
+func TestRequestLogCorrelatesID(t *testing.T) {
+	w, line := testRequestLog(t, "")
+
+	id := w.Header().Get("X-Request-ID")
+	if id == "" {
+		t.Fatal("expected a generated request ID in the response header")
+	} else if line.RequestID != id {
+		t.Errorf("expected the logged ID %q to match the header %q", line.RequestID, id)
+	}
+	if line.Method != "GET" || line.Path != "/api/records/" || line.Status != http.StatusBadRequest {
+		t.Errorf("unexpected log line %+v", line)
+	}
+
+	var body struct {
+		RequestID string `json:"request-id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	} else if body.RequestID != id {
+		t.Errorf("expected the error to carry the ID %q, got %q", id, body.RequestID)
+	}
+}
+
+func TestRequestLogHonorsInboundID(t *testing.T) {
+	if w, line := testRequestLog(t, "client-id-1"); w.Header().Get("X-Request-ID") != "client-id-1" || line.RequestID != "client-id-1" {
+		t.Errorf("expected the inbound ID to be used, got header %q and log %q", w.Header().Get("X-Request-ID"), line.RequestID)
+	}
+
+	// Unsafe IDs are replaced rather than echoed
+	if w, _ := testRequestLog(t, "bad id\""); w.Header().Get("X-Request-ID") == "bad id\"" {
+		t.Error("expected an unsafe inbound ID to be replaced")
+	}
+}
@@ -35,11 +35,90 @@ func (r responses) SuccessWithData(w http.ResponseWriter, data interface{}) {
 	}
 }
 
+// Return a success with warnings about the submitted data
+func (r responses) SuccessWithWarnings(w http.ResponseWriter, warnings []string) {
+	// Encode to JSON
+	encoded, err := json.Marshal(warnings)
+	if err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"status": "success", "warnings": %s}`, string(encoded)))); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
 // Return error with reason
 func (r responses) Error(w http.ResponseWriter, status int, reason string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if _, err := w.Write([]byte(fmt.Sprintf(`{"status": "error", "reason": "%s"}`, reason))); err != nil {
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"status": "error", "reason": %s%s}`, quote(reason), requestIDField(w)))); err != nil {
 		log.Printf("Failed to write responses: %v", err)
 	}
 }
+
+// Return error with reason and additional data
+func (r responses) ErrorWithData(w http.ResponseWriter, status int, reason string, data interface{}) {
+	// Encode to JSON
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"status": "error", "reason": %s, "data": %s%s}`, quote(reason), string(encoded), requestIDField(w)))); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// A structured detail of an error, such as a single failed field
+type ErrorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Return error with reason and a detail for each individual failure
+func (r responses) ErrorWithDetails(w http.ResponseWriter, status int, reason string, details []ErrorDetail) {
+	// Encode to JSON
+	encoded, err := json.Marshal(struct {
+		Status    string        `json:"status"`
+		Reason    string        `json:"reason"`
+		Details   []ErrorDetail `json:"details"`
+		RequestID string        `json:"request-id,omitempty"`
+	}{"error", reason, details, w.Header().Get("X-Request-ID")})
+	if err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(encoded); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// Get the request ID field to add to an error, if the request has an ID
+// IDs only contain characters that need no escaping in JSON
+func requestIDField(w http.ResponseWriter) string {
+	if id := w.Header().Get("X-Request-ID"); id != "" {
+		return fmt.Sprintf(`, "request-id": "%s"`, id)
+	}
+	return ""
+}
+
+// Encode a string as JSON, as reasons may carry quotes from parser errors or
+// names submitted by users
+func quote(s string) string {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("Failed to encode string: %v", err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorEscapesReason(t *testing.T) {
+	reasons := []string{
+		`failed to parse body: dns: bad MX Pref: "ten" at line: 1:25`,
+		"name 'back\\slash' contains\na newline",
+	}
+
+	for _, reason := range reasons {
+		w := httptest.NewRecorder()
+		Responses.Error(w, http.StatusBadRequest, reason)
+
+		var body struct {
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON for reason %q: %v", reason, err)
+		} else if body.Reason != reason {
+			t.Errorf("expected reason %q, got %q", reason, body.Reason)
+		}
+	}
+}
+
+func TestErrorWithDataEscapesReason(t *testing.T) {
+	w := httptest.NewRecorder()
+	Responses.ErrorWithData(w, http.StatusBadRequest, `record "a" is invalid`, []int{1})
+
+	var body struct {
+		Reason string `json:"reason"`
+		Data   []int  `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	} else if body.Reason != `record "a" is invalid` || len(body.Data) != 1 {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestErrorWithDetails(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "abc")
+	Responses.ErrorWithDetails(w, http.StatusBadRequest, "record rejected by validators", []ErrorDetail{
+		{Field: "host", Code: "rejected", Message: "must be within the zone"},
+		{Field: "priority", Code: "rejected", Message: "must be below 100"},
+	})
+
+	var body struct {
+		Status    string        `json:"status"`
+		Reason    string        `json:"reason"`
+		Details   []ErrorDetail `json:"details"`
+		RequestID string        `json:"request-id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || body.Status != "error" || body.Reason != "record rejected by validators" || body.RequestID != "abc" {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	} else if len(body.Details) != 2 || body.Details[0].Field != "host" || body.Details[1].Message != "must be below 100" {
+		t.Errorf("expected a detail for each field, got %+v", body.Details)
+	}
+
+	// Simple errors keep their shape
+	w = httptest.NewRecorder()
+	Responses.Error(w, http.StatusBadRequest, "body must be present")
+	var simple map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &simple); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	} else if _, ok := simple["details"]; ok {
+		t.Errorf("expected no details on a simple error, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Normalize the target of a PTR record to a lowercase fully qualified name
+func NormalizePTRTarget(domain string) string {
+	domain = strings.ToLower(domain)
+	if !strings.HasSuffix(domain, ".") {
+		domain += "."
+	}
+	return domain
+}
+
+// Check if a name lies within the reverse mapping trees, made up of decimal
+// octets under in-addr.arpa or hexadecimal nibbles under ip6.arpa
+func IsReverseName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	if prefix := strings.TrimSuffix(name, "in-addr.arpa"); prefix != name {
+		labels := strings.Split(strings.TrimSuffix(prefix, "."), ".")
+		if prefix == "" {
+			return true
+		} else if !strings.HasSuffix(prefix, ".") || len(labels) > 4 {
+			return false
+		}
+		for _, label := range labels {
+			if octet, err := strconv.Atoi(label); err != nil || octet < 0 || octet > 255 || strconv.Itoa(octet) != label {
+				return false
+			}
+		}
+		return true
+	}
+
+	if prefix := strings.TrimSuffix(name, "ip6.arpa"); prefix != name {
+		labels := strings.Split(strings.TrimSuffix(prefix, "."), ".")
+		if prefix == "" {
+			return true
+		} else if !strings.HasSuffix(prefix, ".") || len(labels) > 32 {
+			return false
+		}
+		for _, label := range labels {
+			if len(label) != 1 || !strings.Contains("0123456789abcdef", label) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"log"
+	"strings"
+	"time"
+)
+
+// Get the zones served as a secondary, with the address of their primary
+func SecondaryZones() map[string]string {
+	zones := map[string]string{}
+	for zone, primary := range viper.GetStringMapString("dns.secondary.primaries") {
+		zones[dns.Fqdn(strings.ToLower(zone))] = primary
+	}
+	return zones
+}
+
+// Get the closest zone served as a secondary a name belongs to
+// Returns an empty string if the name is outside of all of them
+func SecondaryZoneFor(name string) string {
+	name = dns.Fqdn(strings.ToLower(name))
+
+	zone := ""
+	for origin := range SecondaryZones() {
+		if dns.IsSubDomain(origin, name) && len(origin) > len(zone) {
+			zone = origin
+		}
+	}
+	return zone
+}
+
+// Transfer a zone from its primary, keeping the copy to answer from
+// A failed transfer leaves the last copy in place
+func TransferSecondary(zone, primary string, database *db.Database) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	cached, err := db.GetSecondaryZone(zone, database)
+	if err != nil {
+		return err
+	}
+	cached.Attempted = time.Now().Unix()
+
+	records, err := transferIn(zone, primary)
+	if err != nil {
+		if saveErr := db.SetSecondaryZone(zone, cached, database); saveErr != nil {
+			log.Printf("Failed to record transfer attempt of '%s': %v", zone, saveErr)
+		}
+		return err
+	}
+
+	cached.Records = records
+	cached.Transferred = cached.Attempted
+	return db.SetSecondaryZone(zone, cached, database)
+}
+
+// Get the records of a zone from its primary in presentation format, SOA first
+func transferIn(zone, primary string) ([]string, error) {
+	m := new(dns.Msg)
+	m.SetAxfr(zone)
+
+	envelopes, err := new(dns.Transfer).In(m, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []string
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		for _, rr := range envelope.RR {
+			records = append(records, rr.String())
+		}
+	}
+
+	// The transfer ends with the SOA it started with
+	if len(records) < 2 || records[0] != records[len(records)-1] {
+		return nil, fmt.Errorf("transfer of '%s' is incomplete", zone)
+	} else if soa, err := dns.NewRR(records[0]); err != nil || soa.Header().Rrtype != dns.TypeSOA {
+		return nil, fmt.Errorf("transfer of '%s' does not start with an SOA record", zone)
+	}
+	return records[:len(records)-1], nil
+}
+
+// Transfer the zones served as a secondary that are due for it: once the
+// refresh interval of their SOA has passed since the last transfer, or the
+// retry interval since a failed attempt
+func RefreshSecondaries(database *db.Database) {
+	now := time.Now().Unix()
+	for zone, primary := range SecondaryZones() {
+		cached, err := db.GetSecondaryZone(zone, database)
+		if err != nil {
+			log.Printf("Failed to retrieve secondary zone '%s': %v", zone, err)
+			continue
+		}
+
+		if soa := secondarySOA(cached); soa != nil {
+			if cached.Attempted > cached.Transferred && now < cached.Attempted+int64(soa.Retry) {
+				continue
+			} else if cached.Attempted <= cached.Transferred && now < cached.Transferred+int64(soa.Refresh) {
+				continue
+			}
+		}
+
+		if err := TransferSecondary(zone, primary, database); err != nil {
+			log.Printf("Failed to transfer '%s' from %s: %v", zone, primary, err)
+		}
+	}
+}
+
+// Get the SOA a zone was last transferred with, nil if it never was
+func secondarySOA(cached db.SecondaryZone) *dns.SOA {
+	if len(cached.Records) == 0 {
+		return nil
+	}
+	rr, err := dns.NewRR(cached.Records[0])
+	if err != nil {
+		return nil
+	}
+	soa, _ := rr.(*dns.SOA)
+	return soa
+}
+
+// Answer a question within a zone served as a secondary from its last
+// transfer, along with the SOA of the zone for negative answers
+// The copy stops being current once the expire interval of its SOA has passed
+// since the last transfer, or if the zone was never transferred
+func SecondaryAnswer(zone string, q dns.Question) (answer []dns.RR, soa dns.RR, exists bool, current bool) {
+	cached, err := db.GetSecondaryZone(zone, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve secondary zone '%s': %v", zone, err)
+		return nil, nil, false, false
+	}
+	expire := secondarySOA(cached)
+	if expire == nil || time.Now().Unix() > cached.Transferred+int64(expire.Expire) {
+		return nil, nil, false, false
+	}
+
+	for _, record := range cached.Records {
+		rr, err := dns.NewRR(record)
+		if err != nil || !strings.EqualFold(rr.Header().Name, q.Name) {
+			continue
+		}
+		exists = true
+		if rr.Header().Rrtype == q.Qtype || q.Qtype == dns.TypeANY {
+			rr.Header().Name = q.Name
+			answer = append(answer, rr)
+		}
+	}
+	return answer, expire, exists, true
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"testing"
+	"time"
+)
+
+// Serve transfers of example.org over TCP as its primary, returning the
+// address and a function stopping the server
+func testPrimary(t *testing.T) (string, func()) {
+	t.Helper()
+	zone := []dns.RR{}
+	for _, record := range []string{
+		"example.org. 300 IN SOA ns1.example.org. hostmaster.example.org. 7 3600 600 86400 300",
+		"example.org. 300 IN NS ns1.example.org.",
+		"www.example.org. 300 IN A 192.0.2.1",
+	} {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zone = append(zone, rr)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &dns.Server{Listener: listener, Net: "tcp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+		ch := make(chan *dns.Envelope)
+		done := make(chan error)
+		go func() { done <- new(dns.Transfer).Out(w, m, ch) }()
+		ch <- &dns.Envelope{RR: append(zone, zone[0])}
+		close(ch)
+		<-done
+	})}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+
+	stop := func() { _ = server.Shutdown() }
+	t.Cleanup(stop)
+	return listener.Addr().String(), stop
+}
+
+func TestSecondaryServesUntilExpired(t *testing.T) {
+	database := testSignedZone(t)
+	primary, stop := testPrimary(t)
+	viper.Set("dns.secondary.primaries", map[string]string{"example.org": primary})
+	t.Cleanup(func() { viper.Set("dns.secondary.primaries", map[string]string{}) })
+
+	www := dns.Question{Name: "www.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}
+	if zone := SecondaryZoneFor(www.Name); zone != "example.org." {
+		t.Fatalf("expected www.example.org. to be within example.org., got '%s'", zone)
+	}
+
+	// Nothing is answered before the first transfer
+	if _, _, _, current := SecondaryAnswer("example.org.", www); current {
+		t.Fatal("expected the zone not to be current before it is transferred")
+	}
+
+	RefreshSecondaries(database)
+	answer, _, _, current := SecondaryAnswer("example.org.", www)
+	if !current || len(answer) != 1 || answer[0].(*dns.A).A.String() != "192.0.2.1" {
+		t.Fatalf("expected A 192.0.2.1 from the transferred zone, got %v", answer)
+	}
+	missing := dns.Question{Name: "missing.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}
+	if answer, soa, exists, _ := SecondaryAnswer("example.org.", missing); len(answer) != 0 || exists || soa == nil {
+		t.Errorf("expected no such name along with the zone's SOA, got %v %v", answer, soa)
+	}
+
+	// The copy is still answered from within the expire interval while the
+	// primary is down
+	stop()
+	if err := TransferSecondary("example.org", primary, database); err == nil {
+		t.Fatal("expected the transfer from a stopped primary to fail")
+	}
+	if answer, _, _, current := SecondaryAnswer("example.org.", www); !current || len(answer) != 1 {
+		t.Fatalf("expected the cached zone to be answered within its expire interval, got %v", answer)
+	}
+
+	// Once the expire interval has passed since the last transfer it is not
+	cached, err := db.GetSecondaryZone("example.org.", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached.Transferred = time.Now().Add(-86401 * time.Second).Unix()
+	if err := db.SetSecondaryZone("example.org.", cached, database); err != nil {
+		t.Fatal(err)
+	}
+	if answer, _, _, current := SecondaryAnswer("example.org.", www); current || len(answer) != 0 {
+		t.Errorf("expected the zone to stop being answered once expired, got %v", answer)
+	}
+}
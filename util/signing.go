@@ -0,0 +1,312 @@
+package util
+
+import (
+	"crypto"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Generate a new key for a zone, a key signing key if ksk is set and a zone
+// signing key otherwise
+func GenerateSigningKey(zone string, ksk bool) (db.SigningKey, error) {
+	dnskey := &dns.DNSKEY{Hdr: dns.RR_Header{Name: dns.Fqdn(strings.ToLower(zone)), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, Flags: dns.ZONE, Protocol: 3, Algorithm: dns.ECDSAP256SHA256}
+	if ksk {
+		dnskey.Flags |= dns.SEP
+	}
+
+	private, err := dnskey.Generate(256)
+	if err != nil {
+		return db.SigningKey{}, err
+	}
+	return db.SigningKey{KeyTag: dnskey.KeyTag(), Flags: dnskey.Flags, Algorithm: dnskey.Algorithm, PublicKey: dnskey.PublicKey, PrivateKey: dnskey.PrivateKeyString(private)}, nil
+}
+
+// Get the keys of a zone if it is signed and name is its apex
+func apexSigningKeys(name string) (string, []db.SigningKey) {
+	zone := db.ZoneFor(name)
+	if zone == "" || zone != dns.Fqdn(strings.ToLower(name)) {
+		return "", nil
+	}
+
+	settings, err := db.GetZoneSettings(zone, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+		return "", nil
+	} else if !settings.Signed {
+		return "", nil
+	}
+
+	keys, err := db.GetSigningKeys(zone, false, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve signing keys for zone '%s': %v", zone, err)
+		return "", nil
+	}
+	return zone, keys
+}
+
+// Build the DNSKEY record of a signing key
+func signingKeyRR(hdr dns.RR_Header, key db.SigningKey) *dns.DNSKEY {
+	return &dns.DNSKEY{Hdr: hdr, Flags: key.Flags, Protocol: 3, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+}
+
+// Get the DNSKEY records of a signed zone's apex: its pre-published keys,
+// so resolvers learn them before they are used, its active keys, and the
+// keys they replaced until those are retired
+// Returns nil if name is not the apex of a signed zone
+func PublishedKeys(hdr dns.RR_Header, name string) []dns.RR {
+	_, keys := apexSigningKeys(name)
+
+	var rrs []dns.RR
+	for _, key := range keys {
+		if key.State == db.KeyPrePublished || key.State == db.KeyActive || key.State == db.KeyInactive {
+			rrs = append(rrs, signingKeyRR(hdr, key))
+		}
+	}
+	return rrs
+}
+
+// Get the CDS or CDNSKEY records asking the parent of a signed zone to
+// delegate to its active key signing keys (RFC 7344)
+// Returns nil if name is not the apex of a signed zone
+func ParentKeys(hdr dns.RR_Header, name string) []dns.RR {
+	zone, keys := apexSigningKeys(name)
+
+	var rrs []dns.RR
+	for _, key := range keys {
+		if key.State != db.KeyActive || key.Flags&dns.SEP == 0 {
+			continue
+		}
+
+		dnskey := signingKeyRR(dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, key)
+		if hdr.Rrtype == dns.TypeCDNSKEY {
+			rrs = append(rrs, &dns.CDNSKEY{DNSKEY: *signingKeyRR(hdr, key)})
+		} else if ds := dnskey.ToDS(dns.SHA256); ds != nil {
+			rrs = append(rrs, &dns.CDS{DS: dns.DS{Hdr: hdr, KeyTag: ds.KeyTag, Algorithm: ds.Algorithm, DigestType: ds.DigestType, Digest: ds.Digest}})
+		}
+	}
+	return rrs
+}
+
+// Sign the answer and authority sections of a response with the active keys
+// of the zone it is for, when the zone is signed and the query asked for
+// DNSSEC records
+// The DNSKEY set is signed with the key signing key, everything else with the
+// zone signing key. Either is used for both if the zone has only one
+func SignResponse(req, resp *dns.Msg) {
+	if opt := req.IsEdns0(); opt == nil || !opt.Do() || len(resp.Question) == 0 {
+		return
+	}
+
+	zone := db.ZoneFor(resp.Question[0].Name)
+	if zone == "" {
+		return
+	} else if settings, err := db.GetZoneSettings(zone, db.Get.Db); err != nil || !settings.Signed {
+		return
+	}
+
+	keys, err := db.GetSigningKeys(zone, true, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve signing keys for zone '%s': %v", zone, err)
+		return
+	}
+
+	// Find the active key of each kind
+	var zsk, ksk *db.SigningKey
+	for i := range keys {
+		if keys[i].State != db.KeyActive {
+			continue
+		} else if keys[i].Flags&dns.SEP != 0 {
+			ksk = &keys[i]
+		} else {
+			zsk = &keys[i]
+		}
+	}
+	if zsk == nil {
+		zsk = ksk
+	} else if ksk == nil {
+		ksk = zsk
+	}
+	if zsk == nil {
+		return
+	}
+
+	// Let the client know DNSSEC records are included
+	if opt := resp.IsEdns0(); opt != nil {
+		opt.SetDo()
+	} else {
+		resp.SetEdns0(req.IsEdns0().UDPSize(), true)
+	}
+
+	// Prove negative answers with NSEC records, which are signed with the rest
+	// of the authority section
+	if len(resp.Answer) == 0 && (resp.Rcode == dns.RcodeNameError || resp.Rcode == dns.RcodeSuccess) {
+		// An existing name can't be proven missing, so it gets a NODATA answer
+		// whether or not those are configured for missing types
+		if resp.Rcode == dns.RcodeNameError && db.Get.NameExists(strings.TrimSuffix(strings.ToLower(resp.Question[0].Name), ".")) {
+			resp.Rcode = dns.RcodeSuccess
+		}
+		resp.Ns = append(resp.Ns, denialOfExistence(resp.Question[0].Name, zone, resp)...)
+	}
+
+	resp.Answer = signSection(resp.Answer, zone, zsk, ksk)
+	resp.Ns = signSection(resp.Ns, zone, zsk, ksk)
+}
+
+// Compare two names in the canonical DNS order (RFC 4034 section 6.1), label
+// by label starting from the root
+func canonicalLess(a, b string) bool {
+	aLabels := dns.SplitDomainName(strings.ToLower(a))
+	bLabels := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(aLabels)-1, len(bLabels)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if aLabels[i] != bLabels[j] {
+			return aLabels[i] < bLabels[j]
+		}
+	}
+	return len(aLabels) < len(bLabels)
+}
+
+// Build the NSEC records proving a negative answer for a name within a
+// signed zone (RFC 4035 section 3.1.3)
+// Each name holding records gets an NSEC pointing to the next one in
+// canonical order, listing the types it holds. A missing name is proven by the
+// NSEC covering it along with the one covering the wildcard that could have
+// synthesized it, and a missing type by the NSEC of the name itself
+// Zones without an SOA can't give negative answers a validator accepts, so
+// they get no NSEC records either
+func denialOfExistence(qname, zone string, resp *dns.Msg) []dns.RR {
+	var ttl uint32
+	found := false
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			ttl, found = soa.Hdr.Ttl, true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	// Every name of the zone holding records with the types it holds, the
+	// apex always being the first
+	types := map[string][]uint16{zone: {dns.TypeSOA, dns.TypeDNSKEY}}
+	for name, recordTypes := range db.Get.Index() {
+		name = dns.Fqdn(name)
+		if !dns.IsSubDomain(zone, name) {
+			continue
+		}
+		for _, recordType := range recordTypes {
+			if rrtype, ok := dns.StringToType[recordType]; ok {
+				types[name] = append(types[name], rrtype)
+			}
+		}
+	}
+	if len(ParentKeys(dns.RR_Header{Name: zone, Rrtype: dns.TypeCDS, Class: dns.ClassINET}, zone)) != 0 {
+		types[zone] = append(types[zone], dns.TypeCDS, dns.TypeCDNSKEY)
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	// The NSEC owned by a name, or by the closest name before it
+	nsec := func(name string) dns.RR {
+		i := sort.Search(len(names), func(i int) bool { return canonicalLess(name, names[i]) }) - 1
+		if i < 0 {
+			i = 0
+		}
+		owner, next := names[i], names[(i+1)%len(names)]
+
+		bitmap := append([]uint16{dns.TypeRRSIG, dns.TypeNSEC}, types[owner]...)
+		sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+		return &dns.NSEC{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl}, NextDomain: next, TypeBitMap: bitmap}
+	}
+
+	qname = dns.Fqdn(strings.ToLower(qname))
+	proof := []dns.RR{nsec(qname)}
+	if resp.Rcode == dns.RcodeNameError {
+		// Names only exist below the closest encloser as a wildcard could
+		encloser := qname
+		for encloser != zone && !db.Get.NameExists(strings.TrimSuffix(encloser, ".")) {
+			encloser = strings.SplitN(encloser, ".", 2)[1]
+		}
+		proof = append(proof, nsec("*."+encloser))
+	} else if source := WildcardSource(qname); source != qname {
+		// The wildcard answering for the name lacks the type
+		proof = append(proof, nsec(source))
+	}
+
+	// Both proofs may come from the same record
+	if len(proof) == 2 && proof[0].Header().Name == proof[1].Header().Name {
+		proof = proof[:1]
+	}
+	return proof
+}
+
+// Add a signature after every set of records within a section
+func signSection(section []dns.RR, zone string, zsk, ksk *db.SigningKey) []dns.RR {
+	validity := viper.GetDuration("dns.signature-validity")
+	now := time.Now()
+
+	var signed []dns.RR
+	for i := 0; i < len(section); {
+		// Sets are made of consecutive records with the same name and type
+		hdr := section[i].Header()
+		j := i + 1
+		for j < len(section) && section[j].Header().Rrtype == hdr.Rrtype && strings.EqualFold(section[j].Header().Name, hdr.Name) {
+			j++
+		}
+		set := section[i:j]
+		signed = append(signed, set...)
+		i = j
+
+		// Records from outside the zone are not ours to sign
+		if hdr.Rrtype == dns.TypeRRSIG || !dns.IsSubDomain(zone, strings.ToLower(hdr.Name)) {
+			continue
+		}
+
+		key := zsk
+		if hdr.Rrtype == dns.TypeDNSKEY {
+			key = ksk
+		}
+		if sig := signSet(set, zone, key, now, validity); sig != nil {
+			signed = append(signed, sig)
+		}
+	}
+	return signed
+}
+
+// Sign a set of records with a key
+// Returns nil if the key can't be used
+func signSet(set []dns.RR, zone string, key *db.SigningKey, now time.Time, validity time.Duration) dns.RR {
+	dnskey := signingKeyRR(dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, *key)
+	private, err := dnskey.NewPrivateKey(key.PrivateKey)
+	if err != nil {
+		log.Printf("Failed to read signing key %d of zone '%s': %v", key.KeyTag, zone, err)
+		return nil
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil
+	}
+
+	hdr := set[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: hdr.Name, Rrtype: dns.TypeRRSIG, Class: hdr.Class, Ttl: hdr.Ttl},
+		KeyTag:     key.KeyTag,
+		SignerName: zone,
+		Algorithm:  key.Algorithm,
+		// Allow for clocks running behind
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(validity).Unix()),
+	}
+	if err := sig.Sign(signer, set); err != nil {
+		log.Printf("Failed to sign %s records of '%s': %v", dns.TypeToString[hdr.Rrtype], hdr.Name, err)
+		return nil
+	}
+	return sig
+}
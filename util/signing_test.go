@@ -0,0 +1,130 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database serving a signed example.com
+func testSignedZone(t *testing.T) *db.Database {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("dns.zones", []string{"example.com"})
+	viper.Set("dns.signature-validity", time.Hour)
+	t.Cleanup(func() { viper.Set("dns.zones", []string{}) })
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{Signed: true}, database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// Add a new zone signing key to example.com
+func testSigningKey(t *testing.T, database *db.Database) db.SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey("example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err = db.AddSigningKey("example.com.", key, database); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// Sign an answer holding a single A record, as the responder does
+func testSignedAnswer(t *testing.T) *dns.Msg {
+	t.Helper()
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+	req.SetEdns0(4096, true)
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	rr, err := dns.NewRR("www.example.com. 3600 IN A 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Answer = append(resp.Answer, rr)
+
+	SignResponse(req, resp)
+	return resp
+}
+
+// Get the key tags of the keys published at the apex of example.com
+func testPublishedTags() map[uint16]bool {
+	tags := map[uint16]bool{}
+	for _, rr := range PublishedKeys(dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, "example.com.") {
+		tags[rr.(*dns.DNSKEY).KeyTag()] = true
+	}
+	return tags
+}
+
+func TestSigningKeyPrePublishThenActivate(t *testing.T) {
+	database := testSignedZone(t)
+
+	// A pre-published key is visible but signs nothing
+	key := testSigningKey(t, database)
+	if tags := testPublishedTags(); len(tags) != 1 || !tags[key.KeyTag] {
+		t.Fatalf("expected key %d to be published, got %v", key.KeyTag, tags)
+	}
+	if resp := testSignedAnswer(t); len(resp.Answer) != 1 {
+		t.Fatalf("expected no signature before a key is active, got %v", resp.Answer)
+	}
+
+	if _, err := db.TransitionSigningKey("example.com.", key.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next key is published alongside the active one, which signs
+	next := testSigningKey(t, database)
+	if tags := testPublishedTags(); len(tags) != 2 || !tags[key.KeyTag] || !tags[next.KeyTag] {
+		t.Fatalf("expected keys %d and %d to be published, got %v", key.KeyTag, next.KeyTag, tags)
+	}
+
+	resp := testSignedAnswer(t)
+	if len(resp.Answer) != 2 {
+		t.Fatalf("expected the answer and its signature, got %v", resp.Answer)
+	}
+	sig, ok := resp.Answer[1].(*dns.RRSIG)
+	if !ok || sig.KeyTag != key.KeyTag {
+		t.Fatalf("expected a signature by key %d, got %v", key.KeyTag, resp.Answer[1])
+	}
+	dnskey := &dns.DNSKEY{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, Flags: key.Flags, Protocol: 3, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+	if err := sig.Verify(dnskey, resp.Answer[:1]); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestUnsignedZoneHasNoKeys(t *testing.T) {
+	database := testSignedZone(t)
+	if err := db.SetZoneSettings("example.com.", db.ZoneSettings{}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	key := testSigningKey(t, database)
+	if _, err := db.TransitionSigningKey("example.com.", key.KeyTag, db.KeyActive, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if tags := testPublishedTags(); len(tags) != 0 {
+		t.Errorf("expected no keys published for an unsigned zone, got %v", tags)
+	}
+	if resp := testSignedAnswer(t); len(resp.Answer) != 1 {
+		t.Errorf("expected no signature for an unsigned zone, got %v", resp.Answer)
+	}
+}
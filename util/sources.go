@@ -0,0 +1,62 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"log"
+	"net"
+)
+
+// Parse a list of CIDRs a record may be served to
+// An empty list removes the restriction
+func ParseSources(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("field 'sources' must be an array of strings")
+	}
+
+	sources := []string{}
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("field 'sources' must be an array of strings")
+		}
+
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("field 'sources' must only contain CIDRs: %v", err)
+		}
+		sources = append(sources, network.String())
+	}
+	return sources, nil
+}
+
+// Check if a client may be served a record
+// Records without allowed sources are served to everyone
+func SourceAllowed(qname string, qtype uint16, addr net.Addr) bool {
+	metadata, err := db.GetMetadata(qname[:len(qname)-1], dns.TypeToString[qtype], db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve metadata for '%s': %v", qname, err)
+		return false
+	} else if len(metadata.AllowedSources) == 0 {
+		return true
+	}
+
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+
+	for _, source := range metadata.AllowedSources {
+		if _, network, err := net.ParseCIDR(source); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
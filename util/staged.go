@@ -0,0 +1,41 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"log"
+	"strings"
+	"time"
+)
+
+// Check if a record was created or changed too recently to be served
+func Staged(qname string, qtype uint16) bool {
+	delay := viper.GetDuration("dns.propagation-delay")
+	if delay <= 0 {
+		return false
+	}
+
+	metadata, err := db.GetMetadata(qname[:len(qname)-1], dns.TypeToString[qtype], db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve metadata for '%s': %v", qname, err)
+		return false
+	} else if metadata.Modified == 0 {
+		return false
+	}
+
+	return time.Since(time.Unix(metadata.Modified, 0)) < delay
+}
+
+// Check if a name has anything served besides its record of a type held back
+// by the propagation delay, in which case a query for it has no data rather
+// than no name
+func ServedBesides(qname string, qtype uint16) bool {
+	for _, recordType := range db.RecordTypes {
+		rrtype := dns.StringToType[recordType]
+		if rrtype != qtype && db.Get.Record(qname, recordType) != nil && !Staged(qname, rrtype) {
+			return true
+		}
+	}
+	return db.Get.HasDescendants(strings.TrimSuffix(qname, "."))
+}
@@ -0,0 +1,162 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version of the ECH configurations defined in draft-ietf-tls-esni, others are
+// skipped over as clients do
+const echConfigVersion = 0xfe0d
+
+// Build an SVCB or HTTPS record, whichever the header is for, from its stored
+// parts
+// Returns nil if the parameters cannot be parsed
+func SVCBToRR(hdr dns.RR_Header, record db.SVCB) dns.RR {
+	// Parameters are kept in presentation format, so parse the record as it
+	// would be written in a zone file
+	keys := make([]string, 0, len(record.Params))
+	for key := range record.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s %d %s %s %d %s", hdr.Name, hdr.Ttl, dns.ClassToString[hdr.Class], dns.TypeToString[hdr.Rrtype], record.Priority, dns.Fqdn(record.Target)))
+	for _, key := range keys {
+		builder.WriteString(" " + key)
+		if value := record.Params[key]; value != "" {
+			builder.WriteString("=" + strconv.Quote(value))
+		}
+	}
+
+	rr, err := dns.NewRR(builder.String())
+	if err != nil || rr == nil {
+		return nil
+	}
+	return rr
+}
+
+// Get the stored parts of an SVCB or HTTPS record
+func svcbFromRR(r *dns.SVCB) db.SVCB {
+	record := db.SVCB{Priority: r.Priority, Target: r.Target}
+	for _, kv := range r.Value {
+		if record.Params == nil {
+			record.Params = map[string]string{}
+		}
+		record.Params[kv.Key().String()] = kv.String()
+	}
+	return record
+}
+
+// Check that an SVCB or HTTPS record is well-formed as defined in RFC 9460,
+// including any Encrypted Client Hello configurations it carries
+func ValidateSVCB(priority uint16, target string, params map[string]string) error {
+	if reason := checkHostname(target); reason != "" {
+		return fmt.Errorf("field 'target' must be a hostname: %s", reason)
+	} else if priority == 0 && len(params) != 0 {
+		return fmt.Errorf("field 'params' must be empty for alias records with priority 0")
+	}
+
+	hdr := dns.RR_Header{Name: "validate.", Rrtype: dns.TypeSVCB, Class: dns.ClassINET}
+	if SVCBToRR(hdr, db.SVCB{Priority: priority, Target: target, Params: params}) == nil {
+		return fmt.Errorf("field 'params' must hold valid service parameters")
+	}
+
+	if ech, ok := params["ech"]; ok {
+		if err := ValidateECHConfigList(ech); err != nil {
+			return fmt.Errorf("field 'params' has an invalid 'ech' parameter: %v", err)
+		}
+	}
+	return nil
+}
+
+// Check that a base64 encoded ECHConfigList is well-formed, so clients are not
+// handed configurations they will fail to parse
+func ValidateECHConfigList(encoded string) error {
+	list, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("must be base64 encoded")
+	}
+
+	configs, rest, ok := echVector16(list)
+	if !ok || len(rest) != 0 {
+		return fmt.Errorf("list length does not match its contents")
+	} else if len(configs) == 0 {
+		return fmt.Errorf("list must hold at least one configuration")
+	}
+
+	for i := 0; len(configs) != 0; i++ {
+		if len(configs) < 2 {
+			return fmt.Errorf("configuration %d is truncated", i)
+		}
+		version := binary.BigEndian.Uint16(configs)
+
+		var contents []byte
+		if contents, configs, ok = echVector16(configs[2:]); !ok {
+			return fmt.Errorf("configuration %d is truncated", i)
+		} else if version != echConfigVersion {
+			continue
+		} else if err := validateECHConfigContents(contents); err != nil {
+			return fmt.Errorf("configuration %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Check the contents of a single ECHConfig of the supported version
+func validateECHConfigContents(contents []byte) error {
+	// The key configuration starts with its id and KEM
+	if len(contents) < 3 {
+		return fmt.Errorf("key configuration is truncated")
+	}
+	publicKey, rest, ok := echVector16(contents[3:])
+	if !ok {
+		return fmt.Errorf("public key is truncated")
+	} else if len(publicKey) == 0 {
+		return fmt.Errorf("public key must not be empty")
+	}
+
+	suites, rest, ok := echVector16(rest)
+	if !ok {
+		return fmt.Errorf("cipher suites are truncated")
+	} else if len(suites) == 0 || len(suites)%4 != 0 {
+		return fmt.Errorf("cipher suites must hold one or more pairs of KDF and AEAD")
+	}
+
+	// Maximum name length followed by the public name
+	if len(rest) < 2 {
+		return fmt.Errorf("public name is truncated")
+	}
+	nameLength := int(rest[1])
+	if nameLength == 0 {
+		return fmt.Errorf("public name must not be empty")
+	} else if len(rest) < 2+nameLength {
+		return fmt.Errorf("public name is truncated")
+	}
+
+	if _, rest, ok = echVector16(rest[2+nameLength:]); !ok {
+		return fmt.Errorf("extensions are truncated")
+	} else if len(rest) != 0 {
+		return fmt.Errorf("configuration length does not match its contents")
+	}
+	return nil
+}
+
+// Split a vector with a 16 bit length off the start of data
+func echVector16(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 2 {
+		return nil, nil, false
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+length {
+		return nil, nil, false
+	}
+	return data[2 : 2+length], data[2+length:], true
+}
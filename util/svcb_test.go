@@ -0,0 +1,47 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"testing"
+)
+
+func TestHTTPSToRR(t *testing.T) {
+	// A single ECH configuration for the public name example.com
+	ech := "AD7+DQA6AQAgACAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAEAAEAAQALZXhhbXBsZS5jb20AAA=="
+	if err := ValidateECHConfigList(ech); err != nil {
+		t.Fatalf("expected a valid ECH configuration list, got %v", err)
+	}
+
+	hdr := dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET, Ttl: 300}
+	rr := RecordToRR(hdr, &db.HTTPS{SVCB: db.SVCB{Priority: 1, Target: ".", Params: map[string]string{"alpn": "h2,h3", "port": "8443", "ech": ech}}})
+	https, ok := rr.(*dns.HTTPS)
+	if !ok || https.Priority != 1 || https.Target != "." || len(https.Value) != 3 {
+		t.Fatalf("expected HTTPS 1 . with three parameters, got %v", rr)
+	}
+	for _, kv := range https.Value {
+		if alpn, ok := kv.(*dns.SVCBAlpn); ok && (len(alpn.Alpn) != 2 || alpn.Alpn[1] != "h3") {
+			t.Errorf("expected alpn h2,h3, got %v", alpn)
+		} else if port, ok := kv.(*dns.SVCBPort); ok && port.Port != 8443 {
+			t.Errorf("expected port 8443, got %v", port)
+		} else if config, ok := kv.(*dns.SVCBECHConfig); ok && config.String() != ech {
+			t.Errorf("expected the stored ECH configuration, got %v", config)
+		}
+	}
+
+	// Converting back keeps the parameters in presentation format
+	record, err := RRToRecord(rr)
+	if err != nil {
+		t.Fatal(err)
+	} else if stored := record.(*db.HTTPS); stored.Params["alpn"] != "h2,h3" || stored.Params["ech"] != ech {
+		t.Errorf("expected the parameters to round trip, got %+v", stored)
+	}
+}
+
+func TestValidateSVCBTarget(t *testing.T) {
+	for target, valid := range map[string]bool{".": true, "svc.example.com.": true, "svc.example.com": true, "-svc.example.com.": false, "svc..example.com.": false} {
+		if err := ValidateSVCB(1, target, nil); (err == nil) != valid {
+			t.Errorf("expected target '%s' valid %v, got %v", target, valid, err)
+		}
+	}
+}
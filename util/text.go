@@ -0,0 +1,48 @@
+package util
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// Typographic quotes commonly pasted in from documents and chat clients
+var quoteReplacer = strings.NewReplacer(
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+)
+
+// Normalize the strings of a TXT or SPF record according to the configured
+// mode, where surrounding whitespace and typographic quotes are fixed with a
+// warning when lenient or rejected when strict
+// Returns the text to store and the warnings describing what was changed
+func NormalizeText(text []string) ([]string, []string, error) {
+	mode := viper.GetString("records.text-normalization")
+	if mode != "lenient" && mode != "strict" {
+		return text, nil, nil
+	}
+
+	normalized := make([]string, 0, len(text))
+	var warnings []string
+	for i, s := range text {
+		var changes []string
+		fixed := quoteReplacer.Replace(s)
+		if fixed != s {
+			changes = append(changes, "typographic quotes")
+		}
+		if trimmed := strings.TrimSpace(fixed); trimmed != fixed {
+			fixed = trimmed
+			changes = append(changes, "surrounding whitespace")
+		}
+
+		if len(changes) != 0 {
+			if mode == "strict" {
+				return nil, nil, fmt.Errorf("text string %d has %s", i, strings.Join(changes, " and "))
+			}
+			warnings = append(warnings, fmt.Sprintf("text string %d had %s, which were normalized", i, strings.Join(changes, " and ")))
+		}
+		normalized = append(normalized, fixed)
+	}
+
+	return normalized, warnings, nil
+}
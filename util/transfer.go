@@ -0,0 +1,130 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limits on concurrent outbound zone transfers, shared by all listeners
+var Transfers = &TransferLimiter{mutex: &sync.Mutex{}, peers: map[string]int64{}}
+
+// Counts active zone transfers overall and by peer, refusing those beyond the
+// configured limits
+type TransferLimiter struct {
+	mutex  *sync.Mutex
+	active int64
+	peers  map[string]int64
+}
+
+// Take a slot for a transfer to a peer
+// Returns false if the overall or per-peer limit is reached, a limit of 0 or
+// less disables it
+func (l *TransferLimiter) Acquire(peer string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if max := viper.GetInt64("dns.transfer.max-concurrent"); max > 0 && l.active >= max {
+		return false
+	} else if max := viper.GetInt64("dns.transfer.max-per-peer"); max > 0 && l.peers[peer] >= max {
+		return false
+	}
+
+	l.active++
+	l.peers[peer]++
+	return true
+}
+
+// Free the slot of a completed transfer to a peer
+func (l *TransferLimiter) Release(peer string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.active--
+	if l.peers[peer]--; l.peers[peer] <= 0 {
+		delete(l.peers, peer)
+	}
+}
+
+// Get the number of active transfers
+func (l *TransferLimiter) Active() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.active
+}
+
+// Get the number of peers with active transfers
+func (l *TransferLimiter) Peers() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int64(len(l.peers))
+}
+
+// Get the address of a peer without its port, as counted by the limiter
+func TransferPeer(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+	return addr.String()
+}
+
+// Check if a peer may transfer zones
+func TransferAllowed(addr net.Addr) bool {
+	ip := net.ParseIP(TransferPeer(addr))
+	for _, source := range viper.GetStringSlice("dns.transfer.allow") {
+		if _, network, err := net.ParseCIDR(source); err == nil && ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the records of a zone in transfer order: the SOA, every other record
+// whose closest zone is this one, then the SOA again (RFC 5936)
+// Returns nil if the zone has no SOA record
+func TransferRecords(zone string) []dns.RR {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	soa := db.Get.SOA(zone)
+	if soa == nil {
+		return nil
+	}
+	first := &dns.SOA{
+		Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ServedTTL(zone)},
+		Ns:  soa.Nameserver, Mbox: soa.Mailbox, Serial: soa.Serial,
+		Refresh: soa.Refresh, Retry: soa.Retry, Expire: soa.Expire, Minttl: soa.Minimum,
+	}
+
+	var rrs []dns.RR
+	for name, types := range db.Get.Index() {
+		fqdn := dns.Fqdn(name)
+		if db.ZoneFor(fqdn) != zone {
+			continue
+		}
+		for _, recordType := range types {
+			record := db.Get.Record(fqdn, recordType)
+			if record == nil || recordType == "SOA" {
+				continue
+			}
+			rrtype := dns.StringToType[recordType]
+			hdr := dns.RR_Header{Name: strings.ToLower(fqdn), Rrtype: rrtype, Class: dns.ClassINET, Ttl: RecordTTL(fqdn, fqdn, rrtype)}
+			rrs = append(rrs, RecordToRRs(hdr, record)...)
+		}
+	}
+
+	// Keep a stable order by name and type
+	sort.SliceStable(rrs, func(i, j int) bool {
+		if rrs[i].Header().Name != rrs[j].Header().Name {
+			return rrs[i].Header().Name < rrs[j].Header().Name
+		}
+		return rrs[i].Header().Rrtype < rrs[j].Header().Rrtype
+	})
+
+	return append(append([]dns.RR{first}, rrs...), dns.Copy(first))
+}
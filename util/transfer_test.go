@@ -0,0 +1,102 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net"
+	"sync"
+	"testing"
+)
+
+// Set the transfer limits for the duration of a test
+func testTransferLimits(t *testing.T, concurrent, perPeer int) {
+	t.Helper()
+	viper.Set("dns.transfer.allow", []string{"127.0.0.0/8"})
+	viper.Set("dns.transfer.max-concurrent", concurrent)
+	viper.Set("dns.transfer.max-per-peer", perPeer)
+	t.Cleanup(func() {
+		viper.Set("dns.transfer.allow", []string{})
+		viper.Set("dns.transfer.max-concurrent", 10)
+		viper.Set("dns.transfer.max-per-peer", 2)
+	})
+}
+
+func TestTransferLimit(t *testing.T) {
+	testTransferLimits(t, 1, 0)
+	limiter := &TransferLimiter{mutex: &sync.Mutex{}, peers: map[string]int64{}}
+
+	if !limiter.Acquire("192.0.2.1") {
+		t.Fatal("expected a free slot")
+	}
+	if limiter.Acquire("192.0.2.2") {
+		t.Fatal("expected a transfer beyond the limit to be refused")
+	}
+
+	// Completing the transfer frees its slot
+	limiter.Release("192.0.2.1")
+	if limiter.Active() != 0 || limiter.Peers() != 0 {
+		t.Fatalf("expected no active transfers, got %d to %d peers", limiter.Active(), limiter.Peers())
+	}
+	if !limiter.Acquire("192.0.2.2") {
+		t.Error("expected the freed slot to be taken")
+	}
+}
+
+func TestTransferPerPeerLimit(t *testing.T) {
+	testTransferLimits(t, 10, 1)
+	limiter := &TransferLimiter{mutex: &sync.Mutex{}, peers: map[string]int64{}}
+
+	if !limiter.Acquire("192.0.2.1") {
+		t.Fatal("expected a free slot")
+	}
+	if limiter.Acquire("192.0.2.1") {
+		t.Error("expected a second transfer to the same peer to be refused")
+	}
+	if !limiter.Acquire("192.0.2.2") {
+		t.Error("expected another peer to get a slot")
+	}
+	if limiter.Active() != 2 || limiter.Peers() != 2 {
+		t.Errorf("expected 2 transfers to 2 peers, got %d to %d", limiter.Active(), limiter.Peers())
+	}
+}
+
+func TestTransferAllowed(t *testing.T) {
+	testTransferLimits(t, 10, 2)
+
+	if !TransferAllowed(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}) {
+		t.Error("expected a peer within the allowed range to be allowed")
+	}
+	if TransferAllowed(&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5353}) {
+		t.Error("expected a peer outside of the allowed ranges to be refused")
+	}
+}
+
+func TestTransferRecords(t *testing.T) {
+	testSignedZone(t)
+
+	if rrs := TransferRecords("example.com."); rrs != nil {
+		t.Fatalf("expected no records for a zone without an SOA, got %v", rrs)
+	}
+
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 7, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.NS("example.com", "ns1.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("www.example.org", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	rrs := TransferRecords("example.com.")
+	if len(rrs) != 4 || rrs[0].Header().Rrtype != dns.TypeSOA || rrs[3].Header().Rrtype != dns.TypeSOA {
+		t.Fatalf("expected the zone between two SOA records, got %v", rrs)
+	}
+	if rrs[1].Header().Rrtype != dns.TypeNS || rrs[2].Header().Name != "www.example.com." {
+		t.Errorf("expected the zone's records ordered by name, got %v", rrs)
+	}
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+)
+
+// Get a stable offset for a name within the configured jitter bound
+// Names hash to different offsets so records created together don't expire
+// from resolver caches at the same time, while each name's TTL stays constant
+func TTLOffset(name string) uint32 {
+	max := viper.GetUint32("dns.ttl-jitter")
+	if max == 0 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.ToLower(strings.TrimSuffix(name, "."))))
+	return hash.Sum32() % (max + 1)
+}
+
+// Get the TTL to serve records of a name with
+func ServedTTL(name string) uint32 {
+	return clampTTL(name, viper.GetUint32("dns.ttl")+TTLOffset(name))
+}
+
+// Get the TTL to serve a record with, using its own TTL if one was set and
+// otherwise the TTL of its group
+// The source is the name holding the record, which may be a wildcard
+func RecordTTL(name, source string, qtype uint16) uint32 {
+	if ttl := storedTTL(source, qtype); ttl != 0 {
+		return clampTTL(name, ttl)
+	}
+	return ServedTTL(name)
+}
+
+// Get the TTL stored for a record, falling back to the configured TTL
+// Unlike the served TTL it is not jittered or clamped, so it only changes
+// when the record, its group or the configuration does
+func StoredTTL(source string, qtype uint16) uint32 {
+	if ttl := storedTTL(source, qtype); ttl != 0 {
+		return ttl
+	}
+	return viper.GetUint32("dns.ttl")
+}
+
+// Get the TTL set on a record or its group, or 0 if neither has one
+func storedTTL(source string, qtype uint16) uint32 {
+	metadata, err := db.GetMetadata(strings.TrimSuffix(source, "."), dns.TypeToString[qtype], db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve metadata for '%s': %v", source, err)
+		return 0
+	} else if metadata.TTL != 0 || metadata.Group == "" {
+		return metadata.TTL
+	}
+
+	group, _, err := db.GetGroup(metadata.Group, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve group '%s': %v", metadata.Group, err)
+		return 0
+	}
+	return group.TTL
+}
+
+// Clamp a TTL being written for a record to the maximum set for the zone of
+// the name, returning a warning if it was lowered or is unusually high
+func LimitTTL(name string, ttl uint32) (uint32, string) {
+	if ttl == 0 {
+		return ttl, ""
+	}
+
+	if zone := db.ZoneFor(name); zone != "" {
+		settings, err := db.GetZoneSettings(zone, db.Get.Db)
+		if err != nil {
+			log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+		} else if settings.MaxTTL != 0 && ttl > settings.MaxTTL {
+			return settings.MaxTTL, fmt.Sprintf("ttl %d exceeds the maximum of %d for zone '%s' and was clamped", ttl, settings.MaxTTL, strings.TrimSuffix(zone, "."))
+		}
+	}
+
+	// Legal but long TTLs keep changes from reaching resolvers for days
+	if high := viper.GetUint32("records.high-ttl"); high != 0 && ttl > high {
+		return ttl, fmt.Sprintf("ttl %d is above %d, changes to the record may take a long time to reach resolvers", ttl, high)
+	}
+	return ttl, ""
+}
+
+// Clamp a TTL to the maximum set for the zone of the name, and to the
+// recovery TTL for a while after the zone leaves maintenance
+func clampTTL(name string, ttl uint32) uint32 {
+	zone := db.ZoneFor(name)
+	if zone == "" {
+		return ttl
+	}
+	settings, err := db.GetZoneSettings(zone, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+		return ttl
+	}
+
+	if settings.MaxTTL != 0 && ttl > settings.MaxTTL {
+		ttl = settings.MaxTTL
+	}
+
+	window := viper.GetDuration("dns.recovery.window")
+	recovering := settings.Recovered != 0 && time.Since(time.Unix(settings.Recovered, 0)) < window
+	if recovering && ttl > viper.GetUint32("dns.recovery.ttl") {
+		ttl = viper.GetUint32("dns.recovery.ttl")
+	}
+
+	return ttl
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+func TestTTLJitter(t *testing.T) {
+	viper.Set("dns.ttl", 300)
+	viper.Set("dns.ttl-jitter", 60)
+	t.Cleanup(func() { viper.Set("dns.ttl-jitter", 0) })
+
+	offsets := map[uint32]bool{}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("host%d.example.org.", i)
+		ttl := ServedTTL(name)
+		if ttl < 300 || ttl > 360 {
+			t.Fatalf("%s: expected a TTL between 300 and 360, got %d", name, ttl)
+		}
+
+		// Every query for a name gets the same TTL, whatever its casing
+		for _, query := range []string{name, "HOST" + name[4:], name[:len(name)-1]} {
+			if again := ServedTTL(query); again != ttl {
+				t.Fatalf("%s: expected the TTL to stay %d, got %d", query, ttl, again)
+			}
+		}
+		offsets[ttl-300] = true
+	}
+
+	// Names are spread across the bound
+	if len(offsets) < 10 {
+		t.Errorf("expected offsets to spread across names, got %v", offsets)
+	}
+
+	viper.Set("dns.ttl-jitter", 0)
+	if ttl := ServedTTL("host1.example.org."); ttl != 300 {
+		t.Errorf("expected no jitter once disabled, got %d", ttl)
+	}
+}
+
+func TestRecoveryTTL(t *testing.T) {
+	database := testZone(t, "example.com")
+	viper.Set("dns.recovery.ttl", 60)
+	viper.Set("dns.recovery.window", 10*time.Minute)
+	t.Cleanup(func() { viper.Set("dns.recovery.window", 0) })
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateMetadata("short.example.com", "A", func(m *db.Metadata) { m.TTL = 30 }, database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("short.example.com", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		recovered time.Time
+		www       uint32
+	}{
+		// Never in maintenance
+		{time.Time{}, 300},
+		// Just left maintenance
+		{time.Now(), 60},
+		// The window has passed
+		{time.Now().Add(-time.Hour), 300},
+	}
+	for _, test := range tests {
+		settings := db.ZoneSettings{}
+		if !test.recovered.IsZero() {
+			settings.Recovered = test.recovered.Unix()
+		}
+		if err := db.SetZoneSettings("example.com.", settings, database); err != nil {
+			t.Fatal(err)
+		}
+
+		if ttl := RecordTTL("www.example.com.", "www.example.com.", dns.TypeA); ttl != test.www {
+			t.Errorf("recovered at %v: expected a ttl of %d, got %d", test.recovered, test.www, ttl)
+		}
+		// TTLs already below the recovery TTL are kept
+		if ttl := RecordTTL("short.example.com.", "short.example.com.", dns.TypeA); ttl != 30 {
+			t.Errorf("recovered at %v: expected the record's own ttl of 30, got %d", test.recovered, ttl)
+		}
+	}
+}
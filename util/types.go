@@ -55,3 +55,26 @@ func (t types) StringArray(value interface{}) bool {
 
 	return true
 }
+
+// Check if value is an object of strings
+func (t types) StringMap(value interface{}) bool {
+	// Check if object
+	if _, ok := value.(map[string]interface{}); !ok {
+		return false
+	}
+
+	// Check each value
+	for _, v := range value.(map[string]interface{}) {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if value is a boolean
+func (t types) Bool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
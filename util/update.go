@@ -0,0 +1,183 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// Get the secrets of the TSIG keys messages may be signed with, by key name
+func TSIGSecrets() map[string]string {
+	secrets := map[string]string{}
+	for name, secret := range viper.GetStringMapString("dns.tsig-keys") {
+		secrets[dns.Fqdn(strings.ToLower(name))] = secret
+	}
+	return secrets
+}
+
+// Accept dynamic updates along with the messages accepted by default, whose
+// checks of the section counts don't apply to updates
+func AcceptMsg(dh dns.Header) dns.MsgAcceptAction {
+	const response = 1 << 15
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == dns.OpcodeUpdate && dh.Bits&response == 0 && dh.Qdcount == 1 {
+		return dns.MsgAccept
+	}
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// Get the type of the records a change in a dynamic update touches, ANY for
+// every type at its name
+func UpdateType(rr dns.RR) string {
+	if rr.Header().Rrtype == dns.TypeANY {
+		return "ANY"
+	}
+	return dns.TypeToString[rr.Header().Rrtype]
+}
+
+// Check the prerequisites of a dynamic update against the records of a zone
+// (RFC 2136, section 3.2)
+// Returns the response code to fail the update with, or success
+func UpdatePrerequisites(zone string, prerequisites []dns.RR) int {
+	// Sets of records that must exist as given, by name and type
+	expected := map[string][]dns.RR{}
+
+	for _, rr := range prerequisites {
+		hdr := rr.Header()
+		name := strings.TrimSuffix(strings.ToLower(hdr.Name), ".")
+		if !dns.IsSubDomain(zone, dns.Fqdn(name)) {
+			return dns.RcodeNotZone
+		}
+
+		switch hdr.Class {
+		case dns.ClassANY:
+			if hdr.Rrtype == dns.TypeANY && !db.Get.NameExists(name) {
+				return dns.RcodeNameError
+			} else if hdr.Rrtype != dns.TypeANY && db.Get.Record(name+".", UpdateType(rr)) == nil {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if hdr.Rrtype == dns.TypeANY && db.Get.NameExists(name) {
+				return dns.RcodeYXDomain
+			} else if hdr.Rrtype != dns.TypeANY && db.Get.Record(name+".", UpdateType(rr)) != nil {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			key := name + "*" + UpdateType(rr)
+			expected[key] = append(expected[key], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	// Sets given in full must match the stored records exactly
+	for key, rrs := range expected {
+		name, recordType := key[:strings.LastIndex(key, "*")], key[strings.LastIndex(key, "*")+1:]
+		record := db.Get.Record(name+".", recordType)
+		if record == nil {
+			return dns.RcodeNXRrset
+		}
+
+		hdr := dns.RR_Header{Name: name + ".", Rrtype: rrs[0].Header().Rrtype, Class: dns.ClassINET}
+		stored := RecordToRRs(hdr, record)
+		if len(stored) != len(rrs) {
+			return dns.RcodeNXRrset
+		}
+		for _, rr := range rrs {
+			found := false
+			for _, s := range stored {
+				found = found || dns.IsDuplicate(rr, s)
+			}
+			if !found {
+				return dns.RcodeNXRrset
+			}
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// Make a single change of a dynamic update to the records of a zone
+// (RFC 2136, section 3.4.2), journaled as made by the updater
+// The SOA and NS records at the apex are never deleted
+func ApplyUpdate(zone, updater string, rr dns.RR, database *db.Database) error {
+	hdr := rr.Header()
+	name := strings.TrimSuffix(strings.ToLower(hdr.Name), ".")
+	recordType := UpdateType(rr)
+	apex := dns.Fqdn(name) == zone
+	protected := func(recordType string) bool {
+		return apex && (recordType == "SOA" || recordType == "NS")
+	}
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		// Serials are managed by the server
+		if recordType == "SOA" {
+			return nil
+		}
+		record, err := RRToRecord(rr)
+		if err != nil {
+			return err
+		}
+
+		// Addresses are added to those of the name
+		setter := db.Set.WithAudit(db.NewAuditEntry(updater, "update", name, recordType))
+		if a, ok := rr.(*dns.A); ok {
+			return setter.A(name, a.A.String())
+		}
+		return setter.Record(name, record)
+
+	case dns.ClassANY:
+		types := []string{recordType}
+		if recordType == "ANY" {
+			types = db.Get.Index()[name]
+		}
+		for _, t := range types {
+			if protected(t) || db.Get.Record(name+".", t) == nil {
+				continue
+			}
+			if err := deleteUpdated(name, t, updater, database); err != nil {
+				return err
+			}
+		}
+
+	case dns.ClassNONE:
+		stored := db.Get.Record(name+".", recordType)
+		if stored == nil || protected(recordType) {
+			return nil
+		}
+
+		// Remove a single address, keeping the rest of the record
+		rrHdr := dns.RR_Header{Name: hdr.Name, Rrtype: hdr.Rrtype, Class: dns.ClassINET}
+		var kept []string
+		removed := false
+		for _, s := range RecordToRRs(rrHdr, stored) {
+			if dns.IsDuplicate(s, withClass(rr, dns.ClassINET)) {
+				removed = true
+			} else if a, ok := s.(*dns.A); ok {
+				kept = append(kept, a.A.String())
+			}
+		}
+		if !removed {
+			return nil
+		} else if len(kept) != 0 {
+			return db.Set.WithAudit(db.NewAuditEntry(updater, "update", name, recordType)).ReplaceA(name, kept)
+		}
+		return deleteUpdated(name, recordType, updater, database)
+	}
+	return nil
+}
+
+// Delete a record removed by a dynamic update along with its metadata
+func deleteUpdated(name, recordType, updater string, database *db.Database) error {
+	if err := db.Delete.WithAudit(db.NewAuditEntry(updater, "delete", name, recordType)).Record(name, recordType); err != nil {
+		return err
+	}
+	return db.DeleteMetadata(name, recordType, database)
+}
+
+// Copy a record with a different class
+func withClass(rr dns.RR, class uint16) dns.RR {
+	c := dns.Copy(rr)
+	c.Header().Class = class
+	return c
+}
@@ -2,10 +2,41 @@ package util
 
 import (
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// Patterns for common formats of string fields
+const (
+	PatternBase64 = `[A-Za-z0-9+/]+={0,2}`
+	PatternHex    = `[0-9A-Fa-f]+`
+)
+
+// Compiled patterns by their source
+var (
+	patterns     = make(map[string]*regexp.Regexp)
+	patternsLock sync.Mutex
+)
+
+// Compile a pattern anchored to match the full value, caching the result
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternsLock.Lock()
+	defer patternsLock.Unlock()
+
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	patterns[pattern] = re
+	return re, nil
+}
+
 // Validate a fields in a JSON request body
 // Returns a string to be used as an error or empty if no error
 func ValidateBody(body map[string]interface{}, keys []string, options map[string]map[string]string) (string, map[string]bool) {
@@ -57,6 +88,26 @@ func ValidateBody(body map[string]interface{}, keys []string, options map[string
 				}
 			}
 
+			// Check the full value against a pattern, described by its format if given
+			if pattern, ok := options[key]["pattern"]; ok {
+				if re, err := compilePattern(pattern); err != nil {
+					return "field '" + key + "' has an invalid pattern: " + err.Error(), valid
+				} else if !re.MatchString(body[key].(string)) && options[key]["format"] != "" {
+					return "field '" + key + "' must be " + options[key]["format"], valid
+				} else if !re.MatchString(body[key].(string)) {
+					return "field '" + key + "' must match the pattern " + pattern, valid
+				}
+			}
+
+		case "hostname":
+			if !Types.String(body[key]) {
+				return "field '" + key + "' must be a string", valid
+			} else if body[key].(string) == "" && options[key]["required"] == "false" {
+				continue
+			} else if reason := checkHostname(body[key].(string)); reason != "" {
+				return "field '" + key + "' must be a hostname: " + reason, valid
+			}
+
 		case "uint8":
 			if !Types.Uint8(body[key]) {
 				return "field '" + key + "' must be an integer between 0 and 255", valid
@@ -82,11 +133,26 @@ func ValidateBody(body map[string]interface{}, keys []string, options map[string
 				return "field '" + key + "' must be an integer between 0 and 4294967296", valid
 			}
 
+		case "bool":
+			if !Types.Bool(body[key]) {
+				return "field '" + key + "' must be a boolean", valid
+			}
+
 		case "stringarray":
 			if !Types.StringArray(body[key]) {
 				return "field '" + key + "' must be an array of strings", valid
-			} else if text, _ := ConvertArrayToString(body["text"].([]interface{})); len(text) < 1 {
+			} else if text, _ := ConvertArrayToString(body[key].([]interface{})); len(text) < 1 {
 				return "field '" + key + "' must be of at least length 1", valid
+			} else if maxString, ok := options[key]["max"]; ok && maxString != "" {
+				max, _ := strconv.Atoi(maxString)
+				if max > 0 && len(body[key].([]interface{})) > max {
+					return "field '" + key + "' must have at most " + maxString + " elements", valid
+				}
+			}
+
+		case "stringmap":
+			if !Types.StringMap(body[key]) {
+				return "field '" + key + "' must be an object of strings", valid
 			}
 		}
 
@@ -96,3 +162,36 @@ func ValidateBody(body map[string]interface{}, keys []string, options map[string
 
 	return "", valid
 }
+
+// Check that a name is a valid hostname with an optional trailing dot
+// The root name is accepted to allow targets denoting no service
+// Returns the reason the name is invalid or empty if it is valid
+func checkHostname(name string) string {
+	if name == "." {
+		return ""
+	}
+
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return "name must not be empty"
+	} else if len(name) > 253 {
+		return "name must be at most 253 characters long"
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return "labels must not be empty"
+		} else if len(label) > 63 {
+			return "label '" + label + "' must be at most 63 characters long"
+		} else if label[0] == '-' || label[len(label)-1] == '-' {
+			return "label '" + label + "' must not start or end with a hyphen"
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return "label '" + label + "' must only contain letters, digits, hyphens, and underscores"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBodyPattern(t *testing.T) {
+	cases := []struct {
+		value   string
+		options map[string]string
+		reason  string
+	}{
+		{"Zm9vYmFy", map[string]string{"pattern": PatternBase64}, ""},
+		{"not base64!", map[string]string{"pattern": PatternBase64}, "field 'value' must match the pattern " + PatternBase64},
+		{"not base64!", map[string]string{"pattern": PatternBase64, "format": "base64 encoded"}, "field 'value' must be base64 encoded"},
+		{"abcdef", map[string]string{"pattern": PatternHex}, ""},
+		{"abcdefg", map[string]string{"pattern": PatternHex}, "field 'value' must match the pattern " + PatternHex},
+	}
+
+	for _, c := range cases {
+		c.options["type"] = "string"
+		c.options["required"] = "true"
+		reason, _ := ValidateBody(map[string]interface{}{"value": c.value}, []string{"value"}, map[string]map[string]string{"value": c.options})
+		if reason != c.reason {
+			t.Errorf("expected %q for %q against %q, got %q", c.reason, c.value, c.options["pattern"], reason)
+		}
+	}
+}
+
+func TestValidateBodyInvalidPattern(t *testing.T) {
+	options := map[string]map[string]string{"value": {"type": "string", "required": "true", "pattern": "[a-z"}}
+	if reason, _ := ValidateBody(map[string]interface{}{"value": "abc"}, []string{"value"}, options); !strings.HasPrefix(reason, "field 'value' has an invalid pattern: ") {
+		t.Errorf("expected the invalid pattern to be reported, got %q", reason)
+	}
+}
+
+func TestValidateBodyPatternMatchesFullValue(t *testing.T) {
+	options := map[string]map[string]string{"value": {"type": "string", "required": "true", "pattern": "[a-z]+"}}
+	if reason, _ := ValidateBody(map[string]interface{}{"value": "abc123"}, []string{"value"}, options); reason == "" {
+		t.Error("expected a partial match to be rejected")
+	}
+}
+
+func TestCompilePatternCaches(t *testing.T) {
+	first, err := compilePattern("[0-9]+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := compilePattern("[0-9]+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected the compiled pattern to be reused")
+	}
+}
+
+func TestValidateBodyHostname(t *testing.T) {
+	label63 := strings.Repeat("a", 63)
+	name253 := strings.Join([]string{label63, label63, label63, strings.Repeat("b", 61)}, ".")
+	name254 := strings.Join([]string{label63, label63, label63, strings.Repeat("b", 62)}, ".")
+
+	cases := []struct {
+		value  string
+		reason string
+	}{
+		{"www.example.com", ""},
+		{"www.example.com.", ""},
+		{".", ""},
+		{"_sip._tcp.example.com", ""},
+		{"xn--bcher-kva.example", ""},
+		{label63 + ".example.com", ""},
+		{name253, ""},
+		{name253 + ".", ""},
+		{"a" + label63 + ".example.com", "label 'a" + label63 + "' must be at most 63 characters long"},
+		{name254, "name must be at most 253 characters long"},
+		{"www..example.com", "labels must not be empty"},
+		{"-www.example.com", "label '-www' must not start or end with a hyphen"},
+		{"http://foo", "label 'http://foo' must only contain letters, digits, hyphens, and underscores"},
+		{"", "name must not be empty"},
+	}
+
+	for _, c := range cases {
+		reason, _ := ValidateBody(map[string]interface{}{"target": c.value}, []string{"target"}, map[string]map[string]string{"target": {"type": "hostname", "required": "true"}})
+		expected := ""
+		if c.reason != "" {
+			expected = "field 'target' must be a hostname: " + c.reason
+		}
+		if reason != expected {
+			t.Errorf("expected %q for %q, got %q", expected, c.value, reason)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"strconv"
+	"strings"
+)
+
+// Text records longer than this may not fit in a plain UDP response
+const maxTextLength = 400
+
+// Get non-fatal advisories about a record that was written
+func RecordWarnings(name string, record db.Record) []string {
+	var warnings []string
+
+	switch r := record.(type) {
+	case *db.SRV:
+		warnings = append(warnings, CheckSRVTarget(r.Target)...)
+	case *db.MX:
+		if zone := db.ZoneFor(name); zone != "" && db.ZoneFor(r.Host) != zone {
+			warnings = append(warnings, "MX host '"+r.Host+"' is outside of zone '"+zone+"'")
+		}
+	case *db.TXT:
+		warnings = append(warnings, checkTextLength(r.Text)...)
+	case *db.SPF:
+		warnings = append(warnings, checkTextLength(r.Text)...)
+	}
+
+	return warnings
+}
+
+// Check if text strings are approaching the size limits of a response
+func checkTextLength(text []string) []string {
+	var warnings []string
+
+	total := 0
+	for _, s := range text {
+		if len(s) > 240 {
+			warnings = append(warnings, "text string of "+strconv.Itoa(len(s))+" bytes is close to the 255 byte limit")
+		}
+		total += len(s) + 1
+	}
+	if total > maxTextLength {
+		warnings = append(warnings, "text of "+strconv.Itoa(total)+" bytes may require TCP or EDNS to be served")
+	}
+
+	return warnings
+}
+
+// Check that an SRV target within a served zone resolves to address records directly
+// Per RFC 2782 the target must not be an alias, returns any warnings found
+func CheckSRVTarget(target string) []string {
+	if !viper.GetBool("records.check-srv-targets") {
+		return nil
+	}
+
+	target = dns.Fqdn(strings.ToLower(target))
+	if db.ZoneFor(target) == "" {
+		return nil
+	}
+
+	if db.Get.CNAME(target) != nil {
+		return []string{"SRV target '" + target + "' is a CNAME, it must point to A/AAAA records directly"}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+	"time"
+)
+
+// The body posted to a webhook for a change to a record
+type WebhookEvent struct {
+	Subscription string `json:"subscription"`
+	Event        string `json:"event"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Username     string `json:"username"`
+	Time         int64  `json:"time"`
+}
+
+// Deliver the oldest pending event of every subscription that is due
+// Subscriptions keep their own retry state, so a failing endpoint only delays
+// its own events, backing off further after each failed attempt
+func DeliverWebhooks(database *db.Database) {
+	pending, err := db.PendingDeliveries(database)
+	if err != nil {
+		log.Printf("Failed to retrieve pending webhook deliveries: %v", err)
+		return
+	}
+	subscriptions, err := db.ListSubscriptions("", database)
+	if err != nil {
+		log.Printf("Failed to retrieve webhook subscriptions: %v", err)
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, subscription := range subscriptions {
+		deliveries := pending[subscription.ID]
+		if len(deliveries) == 0 || deliveries[0].NextAttempt > now {
+			continue
+		}
+		delivery := deliveries[0]
+
+		// Users only hear of names their role still gives them access to
+		if allowed, err := webhookAllowed(subscription.Username, delivery.Name, database); err != nil {
+			log.Printf("Failed to check access of '%s' to '%s': %v", subscription.Username, delivery.Name, err)
+			continue
+		} else if !allowed {
+			if err := db.FinishDelivery(delivery, true, database); err != nil {
+				log.Printf("Failed to remove webhook delivery: %v", err)
+			}
+			continue
+		}
+
+		err := postWebhook(subscription.URL, WebhookEvent{Subscription: subscription.ID, Event: delivery.Event, Name: delivery.Name, Type: delivery.Type, Username: delivery.Username, Time: delivery.Time})
+		done := err == nil
+		if err != nil {
+			delivery.Attempts++
+			delivery.LastError = err.Error()
+			delivery.NextAttempt = now + int64(webhookBackoff(delivery.Attempts).Seconds())
+			if delivery.Attempts >= viper.GetInt("http.webhooks.max-attempts") {
+				log.Printf("Giving up on webhook delivery to '%s' after %d attempts: %v", subscription.URL, delivery.Attempts, err)
+				done = true
+			}
+		}
+		if err := db.FinishDelivery(delivery, done, database); err != nil {
+			log.Printf("Failed to write webhook delivery: %v", err)
+		}
+	}
+}
+
+// Check whether a user may still be notified about a name
+func webhookAllowed(username, name string, database *db.Database) (bool, error) {
+	user, err := db.UserFromDatabase(username, database)
+	if err != nil {
+		// Users removed since subscribing are not notified
+		return false, nil
+	} else if user.Role == "admin" {
+		return true, nil
+	}
+	return db.EvaluateRole(user.Role, name, database)
+}
+
+// Get how long to wait after a number of failed attempts, doubling each time
+func webhookBackoff(attempts int) time.Duration {
+	backoff := viper.GetDuration("http.webhooks.retry-interval")
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+// Post an event to a webhook, failing on any status other than success
+func postWebhook(url string, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: viper.GetDuration("http.webhooks.timeout")}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered with status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"strings"
+)
+
+// Get the name a query should be answered from following RFC 4592
+// The returned name is always lowercase to match the stored records
+//
+// An existing name always answers for itself, even if it is only an empty
+// non-terminal. Otherwise, only the wildcard directly below the closest
+// encloser may synthesize the answer, so a closer wildcard always beats a
+// farther one and wildcards never apply below an existing node.
+func WildcardSource(qname string) string {
+	name := strings.TrimSuffix(strings.ToLower(qname), ".")
+	if db.Get.NameExists(name) {
+		return name + "."
+	}
+
+	// Walk up the tree to find the closest encloser
+	labels := dns.SplitDomainName(name)
+	for i := 1; i < len(labels); i++ {
+		encloser := strings.Join(labels[i:], ".")
+		if !db.Get.NameExists(encloser) {
+			continue
+		}
+
+		if wildcard := "*." + encloser; db.Get.NameExists(wildcard) {
+			return wildcard + "."
+		}
+		return name + "."
+	}
+
+	return name + "."
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Digest schemes and algorithms of RFC 8976
+const (
+	zonemdSchemeSimple = 1
+	zonemdHashSHA384   = 1
+)
+
+// Compute the ZONEMD digest of a zone over the records as they are stored
+// TTLs are taken as stored rather than served, so jitter and clamping don't
+// change the digest. The zone must have an SOA record at its apex to take the
+// serial from
+func ZoneDigest(zone string) (*dns.ZONEMD, error) {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	soa := db.Get.SOA(zone)
+	if soa == nil {
+		return nil, fmt.Errorf("zone apex '%s' has no SOA record", zone)
+	}
+
+	// Collect every record whose closest zone is this one
+	var rrs []dns.RR
+	for name, types := range db.Get.Index() {
+		fqdn := dns.Fqdn(name)
+		if db.ZoneFor(fqdn) != zone {
+			continue
+		}
+		for _, recordType := range types {
+			record := db.Get.Record(fqdn, recordType)
+			if record == nil {
+				continue
+			}
+			rrtype := dns.StringToType[recordType]
+			hdr := dns.RR_Header{Name: strings.ToLower(fqdn), Rrtype: rrtype, Class: dns.ClassINET, Ttl: StoredTTL(fqdn, rrtype)}
+			for _, rr := range RecordToRRs(hdr, record) {
+				rrs = append(rrs, CanonicalRR(rr))
+			}
+		}
+	}
+
+	// Order by name and type, then by address within sets of A records
+	// as the only type a name holds several records of (RFC 4034)
+	sort.Slice(rrs, func(i, j int) bool {
+		if c := CompareNames(rrs[i].Header().Name, rrs[j].Header().Name); c != 0 {
+			return c < 0
+		} else if rrs[i].Header().Rrtype != rrs[j].Header().Rrtype {
+			return rrs[i].Header().Rrtype < rrs[j].Header().Rrtype
+		}
+
+		a, aOk := rrs[i].(*dns.A)
+		b, bOk := rrs[j].(*dns.A)
+		return aOk && bOk && bytes.Compare(a.A.To4(), b.A.To4()) < 0
+	})
+
+	hash := sha512.New384()
+	buf := make([]byte, dns.MaxMsgSize)
+	for _, rr := range rrs {
+		n, err := dns.PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s record of '%s': %v", dns.TypeToString[rr.Header().Rrtype], rr.Header().Name, err)
+		}
+		_, _ = hash.Write(buf[:n])
+	}
+
+	return &dns.ZONEMD{
+		Hdr:    dns.RR_Header{Name: zone, Rrtype: dns.TypeZONEMD, Class: dns.ClassINET, Ttl: StoredTTL(zone, dns.TypeSOA)},
+		Serial: soa.Serial,
+		Scheme: zonemdSchemeSimple,
+		Hash:   zonemdHashSHA384,
+		Digest: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Get the stored ZONEMD record of a zone apex
+// Returns nil if none is stored or the zone has changed since, whether or not
+// its serial was bumped
+func StoredZONEMD(hdr dns.RR_Header, zone string) dns.RR {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	settings, err := db.GetZoneSettings(zone, db.Get.Db)
+	if err != nil {
+		log.Printf("Failed to retrieve settings for zone '%s': %v", zone, err)
+		return nil
+	} else if settings.Digest == "" {
+		return nil
+	}
+
+	// Recompute to catch records changed without the serial moving on
+	current, err := ZoneDigest(zone)
+	if err != nil || current.Serial != settings.DigestSerial || current.Digest != settings.Digest {
+		return nil
+	}
+	return &dns.ZONEMD{Hdr: hdr, Serial: settings.DigestSerial, Scheme: zonemdSchemeSimple, Hash: zonemdHashSHA384, Digest: settings.Digest}
+}
+
+// Lowercase the names within a record as required for its canonical form
+func CanonicalRR(rr dns.RR) dns.RR {
+	name := func(s string) string {
+		return strings.ToLower(dns.Fqdn(s))
+	}
+
+	switch r := rr.(type) {
+	case *dns.NS:
+		r.Ns = name(r.Ns)
+	case *dns.CNAME:
+		r.Target = name(r.Target)
+	case *dns.SOA:
+		r.Ns = name(r.Ns)
+		r.Mbox = name(r.Mbox)
+	case *dns.PTR:
+		r.Ptr = name(r.Ptr)
+	case *dns.MX:
+		r.Mx = name(r.Mx)
+	case *dns.SRV:
+		r.Target = name(r.Target)
+	case *dns.NAPTR:
+		r.Replacement = name(r.Replacement)
+	}
+	return rr
+}
+
+// Compare two names in canonical order, label by label from the root
+func CompareNames(a, b string) int {
+	labelsA := dns.SplitDomainName(strings.ToLower(a))
+	labelsB := dns.SplitDomainName(strings.ToLower(b))
+
+	for i, j := len(labelsA)-1, len(labelsB)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(labelsA[i], labelsB[j]); c != 0 {
+			return c
+		}
+	}
+	return len(labelsA) - len(labelsB)
+}
@@ -0,0 +1,109 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database serving a single zone with an SOA at its apex
+func testZone(t *testing.T, zone string) *db.Database {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("dns.zones", []string{zone})
+	viper.Set("dns.ttl", 300)
+	t.Cleanup(func() { viper.Set("dns.zones", nil) })
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	if err := db.Set.SOA(zone, "ns1."+zone+".", "hostmaster."+zone+".", 1, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestZoneDigestIgnoresServedTTL(t *testing.T) {
+	database := testZone(t, "example.com")
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := ZoneDigest("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Jitter and a recovering zone change the served TTLs but not the stored ones
+	viper.Set("dns.ttl-jitter", 60)
+	viper.Set("dns.recovery.window", time.Hour)
+	viper.Set("dns.recovery.ttl", 30)
+	t.Cleanup(func() {
+		viper.Set("dns.ttl-jitter", 0)
+		viper.Set("dns.recovery.window", 0)
+	})
+	settings, err := db.GetZoneSettings("example.com.", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Recovered = time.Now().Unix()
+	if err := db.SetZoneSettings("example.com.", settings, database); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := RecordTTL("www.example.com.", "www.example.com.", dns.TypeA); ttl != 30 {
+		t.Fatalf("expected a served ttl of 30 while recovering, got %d", ttl)
+	}
+
+	after, err := ZoneDigest("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Digest != after.Digest {
+		t.Fatalf("digest changed with the served ttl: %s != %s", before.Digest, after.Digest)
+	}
+}
+
+func TestStoredZONEMDInvalidatedByChange(t *testing.T) {
+	database := testZone(t, "example.com")
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	zonemd, err := ZoneDigest("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := db.GetZoneSettings("example.com.", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Digest = zonemd.Digest
+	settings.DigestSerial = zonemd.Serial
+	if err := db.SetZoneSettings("example.com.", settings, database); err != nil {
+		t.Fatal(err)
+	}
+
+	hdr := dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeZONEMD, Class: dns.ClassINET, Ttl: 300}
+	if StoredZONEMD(hdr, "example.com") == nil {
+		t.Fatal("expected the stored digest to be served")
+	}
+
+	// Changing a record without bumping the serial makes the digest stale
+	if err := db.Set.A("www.example.com", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+	if StoredZONEMD(hdr, "example.com") != nil {
+		t.Fatal("expected a stale digest not to be served")
+	}
+}
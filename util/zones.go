@@ -0,0 +1,35 @@
+package util
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/miekg/dns"
+)
+
+// Get the SOA record of the zone a name belongs to for a negative answer
+// The TTL is the lower of the record's TTL and its minimum field (RFC 2308)
+// Returns nil if the name is outside of all zones or the zone has no SOA
+func NegativeSOA(name string) dns.RR {
+	zone := db.ZoneFor(name)
+	if zone == "" {
+		return nil
+	}
+	soa := db.Get.SOA(zone)
+	if soa == nil {
+		return nil
+	}
+
+	ttl := RecordTTL(zone, zone, dns.TypeSOA)
+	if soa.Minimum < ttl {
+		ttl = soa.Minimum
+	}
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      soa.Nameserver,
+		Mbox:    soa.Mailbox,
+		Serial:  soa.Serial,
+		Refresh: soa.Refresh,
+		Retry:   soa.Retry,
+		Expire:  soa.Expire,
+		Minttl:  soa.Minimum,
+	}
+}
@@ -0,0 +1,110 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A record ready to be inserted into a resolver's cache
+type cacheEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+	// Seconds the entry stays valid from when it was generated, which is the
+	// full TTL as answers come straight from the authoritative data
+	Remaining uint32 `json:"remaining"`
+	RData     string `json:"rdata"`
+}
+
+// Handle returning every permitted record of a zone as cache entries for
+// resolvers warming their caches
+func cache(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("zone") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'zone' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Query().Get("zone")))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	generated := time.Now().Unix()
+	entries := []cacheEntry{}
+	for name, types := range db.Get.Index() {
+		fqdn := dns.Fqdn(name)
+		if db.ZoneFor(fqdn) != zone {
+			continue
+		}
+
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if !allowed {
+			continue
+		}
+
+		for _, recordType := range types {
+			record := db.Get.Record(fqdn, recordType)
+			if record == nil {
+				continue
+			}
+
+			// Entries take the TTL the record is served with
+			rrtype := dns.StringToType[recordType]
+			ttl := util.RecordTTL(fqdn, fqdn, rrtype)
+			hdr := dns.RR_Header{Name: fqdn, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+			for _, rr := range util.RecordToRRs(hdr, record) {
+				entries = append(entries, cacheEntry{
+					Name:      fqdn,
+					Type:      recordType,
+					TTL:       ttl,
+					Remaining: ttl,
+					RData:     util.RData(rr),
+				})
+			}
+		}
+	}
+
+	// Keep a stable order so successive fetches can be compared
+	sort.SliceStable(entries, func(i, j int) bool {
+		if c := util.CompareNames(entries[i].Name, entries[j].Name); c != 0 {
+			return c < 0
+		} else if entries[i].Type != entries[j].Type {
+			return typeIndex(dns.StringToType[entries[i].Type]) < typeIndex(dns.StringToType[entries[j].Type])
+		}
+		return entries[i].RData < entries[j].RData
+	})
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{"zone": zone, "generated": generated, "entries": entries})
+}
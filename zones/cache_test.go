@@ -0,0 +1,72 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheEntries(t *testing.T) {
+	database, token := testDatabase(t, "restricted", "example.com")
+	viper.Set("dns.ttl", 300)
+	if err := db.CreateRole("restricted", "", "", "", []string{"example.com", "*.example.com"}, []string{"secret.example.com"}, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 1, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.MX("example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("mail.example.com", "192.0.2.25"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("mail.example.com", []string{"v=spf1 -all"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("secret.example.com", "192.0.2.99"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateMetadata("mail.example.com", "A", func(m *db.Metadata) { m.TTL = 60 }, database); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/api/zones/cache?zone=example.com", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	CacheHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data struct {
+			Zone    string       `json:"zone"`
+			Entries []cacheEntry `json:"entries"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]cacheEntry{
+		"example.com. SOA":      {TTL: 300, RData: "ns1.example.com. hostmaster.example.com. 1 3600 600 86400 300"},
+		"example.com. MX":       {TTL: 300, RData: "10 mail.example.com."},
+		"mail.example.com. A":   {TTL: 60, RData: "192.0.2.25"},
+		"mail.example.com. TXT": {TTL: 300, RData: "\"v=spf1 -all\""},
+	}
+	if len(response.Data.Entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), response.Data.Entries)
+	}
+	for _, entry := range response.Data.Entries {
+		want, ok := expected[entry.Name+" "+entry.Type]
+		if !ok {
+			t.Errorf("unexpected entry %+v", entry)
+		} else if entry.TTL != want.TTL || entry.Remaining != want.TTL || entry.RData != want.RData {
+			t.Errorf("expected %s %s to have TTL %d and rdata %q, got %+v", entry.Name, entry.Type, want.TTL, want.RData, entry)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http"
+	"strings"
+)
+
+// Handle computing, and optionally storing, the ZONEMD digest of a zone
+func digest(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" && r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Only admins may publish a digest
+	if r.Method == "POST" && user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Path[len(path):]))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	zonemd, err := util.ZoneDigest(zone)
+	if err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to compute zone digest: "+err.Error())
+		return
+	}
+
+	// Store the digest to be served at the zone apex
+	if r.Method == "POST" {
+		settings, err := db.GetZoneSettings(zone, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone settings: "+err.Error())
+			return
+		}
+		settings.Digest = zonemd.Digest
+		settings.DigestSerial = zonemd.Serial
+		if err := db.SetZoneSettings(zone, settings, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to write zone settings to database: "+err.Error())
+			return
+		}
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{
+		"zone":   zone,
+		"serial": zonemd.Serial,
+		"scheme": zonemd.Scheme,
+		"hash":   zonemd.Hash,
+		"digest": zonemd.Digest,
+	})
+}
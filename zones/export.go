@@ -0,0 +1,244 @@
+package zones
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A record of an exported zone along with the TTL it is stored with
+type exportedRR struct {
+	rr  dns.RR
+	ttl uint32
+}
+
+// A record of a zone exported as JSON, in presentation format
+type exportedEntry struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	TTL   uint32 `json:"ttl"`
+	RData string `json:"rdata"`
+}
+
+// Handle exporting a zone as an RFC 1035 master file, or as JSON or NDJSON,
+// optionally signed with a detached JWS
+func export(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.URL.Query().Get("zone") == "" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'zone' is required")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "zone" && format != "json" && format != "ndjson" {
+		util.Responses.Error(w, http.StatusBadRequest, "query parameter 'format' must be one of 'zone', 'json' or 'ndjson'")
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Query().Get("zone")))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	} else if db.Get.SOA(zone) == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "zone apex '"+zone+"' has no SOA record")
+		return
+	}
+
+	groups, err := db.ListGroups(database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve groups: "+err.Error())
+		return
+	}
+
+	// Collect every record whose closest zone is this one
+	var rrs []exportedRR
+	for name, types := range db.Get.Index() {
+		fqdn := dns.Fqdn(name)
+		if db.ZoneFor(fqdn) != zone {
+			continue
+		}
+
+		for _, recordType := range types {
+			record := db.Get.Record(fqdn, recordType)
+			if record == nil {
+				continue
+			}
+
+			// Records inheriting a TTL from their group are exported with it
+			metadata, err := db.GetMetadata(name, recordType, database)
+			if err != nil {
+				log.Printf("Failed to retrieve metadata for '%s': %v", name, err)
+			} else if metadata.TTL == 0 && metadata.Group != "" {
+				metadata.TTL = groups[metadata.Group].TTL
+			}
+
+			hdr := dns.RR_Header{Name: fqdn, Rrtype: dns.StringToType[recordType], Class: dns.ClassINET}
+			for _, rr := range util.RecordToRRs(hdr, record) {
+				rrs = append(rrs, exportedRR{rr: util.CanonicalRR(rr), ttl: metadata.TTL})
+			}
+		}
+	}
+
+	// Keep a stable order with the SOA and apex NS records first
+	rank := func(rr dns.RR) int {
+		switch {
+		case rr.Header().Rrtype == dns.TypeSOA:
+			return 0
+		case rr.Header().Rrtype == dns.TypeNS && rr.Header().Name == zone:
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(rrs, func(i, j int) bool {
+		a, b := rrs[i].rr, rrs[j].rr
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		} else if c := util.CompareNames(a.Header().Name, b.Header().Name); c != 0 {
+			return c < 0
+		}
+		return typeIndex(a.Header().Rrtype) < typeIndex(b.Header().Rrtype)
+	})
+
+	// Records without a TTL of their own take the configured one
+	var payload []byte
+	contentType, extension := "text/plain", ".zone"
+	switch format {
+	case "json", "ndjson":
+		entries := []exportedEntry{}
+		for _, exported := range rrs {
+			ttl := exported.ttl
+			if ttl == 0 {
+				ttl = viper.GetUint32("dns.ttl")
+			}
+			entries = append(entries, exportedEntry{Name: exported.rr.Header().Name, Type: dns.TypeToString[exported.rr.Header().Rrtype], TTL: ttl, RData: util.RData(exported.rr)})
+		}
+
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		if format == "json" {
+			contentType, extension = "application/json", ".json"
+			err = encoder.Encode(entries)
+		} else {
+			contentType, extension = "application/x-ndjson", ".ndjson"
+			for _, entry := range entries {
+				if err = encoder.Encode(entry); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to encode zone: "+err.Error())
+			return
+		}
+		payload = buffer.Bytes()
+	default:
+		var builder strings.Builder
+		builder.WriteString("$ORIGIN " + zone + "\n")
+		builder.WriteString("$TTL " + strconv.Itoa(viper.GetInt("dns.ttl")) + "\n")
+		for _, exported := range rrs {
+			ttl := ""
+			if exported.ttl != 0 {
+				ttl = strconv.FormatUint(uint64(exported.ttl), 10)
+			}
+			builder.WriteString(fmt.Sprintf("%s\t%s\tIN\t%s\t%s\n", relativeName(exported.rr.Header().Name, zone), ttl, dns.TypeToString[exported.rr.Header().Rrtype], util.RData(exported.rr)))
+		}
+		payload = []byte(builder.String())
+	}
+
+	// Sign the payload as sent so consumers can verify it against the
+	// published key without trusting the transport
+	if r.URL.Query().Get("signed") == "true" {
+		key, err := db.ExportKey(database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve signing key: "+err.Error())
+			return
+		}
+		signature, err := util.SignDetached(payload, key)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to sign zone: "+err.Error())
+			return
+		}
+		w.Header().Set("X-JWS-Signature", signature)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+strings.TrimSuffix(zone, ".")+extension+"\"")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(payload); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// Handle publishing the key exports are signed with
+func exportKey(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Validate initial request with type
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	key, err := db.ExportKey(database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve signing key: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, util.PublicJWK(key.Public().(ed25519.PublicKey)))
+}
+
+// Get the position of a type among the stored types
+func typeIndex(rrtype uint16) int {
+	for i, recordType := range db.RecordTypes {
+		if recordType == dns.TypeToString[rrtype] {
+			return i
+		}
+	}
+	return len(db.RecordTypes)
+}
+
+// Get a name relative to the origin of its zone
+func relativeName(name, zone string) string {
+	if name == zone {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
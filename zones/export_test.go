@@ -0,0 +1,156 @@
+package zones
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	api "github.com/iznotek/dns/records"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Fetch the published key exports are signed with
+func testExportKey(t *testing.T, database *db.Database) ed25519.PublicKey {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ExportKeyHandler(database)(w, httptest.NewRequest("GET", "/api/zones/export/key", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data util.JWK `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	} else if response.Data.KeyType != "OKP" || response.Data.Curve != "Ed25519" {
+		t.Fatalf("expected an Ed25519 key, got %+v", response.Data)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(response.Data.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// Export a zone through the API
+func testExport(t *testing.T, database *db.Database, token, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/zones/export?"+query, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	ExportHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestSignedExportVerifies(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 1, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("www.example.com", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	key := testExportKey(t, database)
+
+	for _, format := range []string{"json", "ndjson"} {
+		w := testExport(t, database, token, "zone=example.com&format="+format+"&signed=true")
+		signature := w.Header().Get("X-JWS-Signature")
+		if signature == "" {
+			t.Fatalf("%s: expected a signature", format)
+		} else if parts := strings.Split(signature, "."); len(parts) != 3 || parts[1] != "" {
+			t.Fatalf("%s: expected a detached JWS, got %s", format, signature)
+		}
+		payload := w.Body.Bytes()
+		if !bytes.Contains(payload, []byte(`"rdata":"192.0.2.1"`)) {
+			t.Errorf("%s: expected the A record in the export, got %s", format, payload)
+		}
+
+		if err := util.VerifyDetached(signature, payload, key); err != nil {
+			t.Errorf("%s: expected the export to verify, got %v", format, err)
+		}
+		tampered := bytes.Replace(payload, []byte("192.0.2.1"), []byte("192.0.2.9"), 1)
+		if err := util.VerifyDetached(signature, tampered, key); err == nil {
+			t.Errorf("%s: expected a tampered export not to verify", format)
+		}
+	}
+
+	// The key stays the same between requests
+	if again := testExportKey(t, database); !bytes.Equal(again, key) {
+		t.Error("expected the same key to be published again")
+	}
+
+	// Exports are only signed when asked to be
+	if w := testExport(t, database, token, "zone=example.com&format=json"); w.Header().Get("X-JWS-Signature") != "" {
+		t.Error("expected no signature without asking for one")
+	}
+}
+
+// Get the sorted records of a zone file, one per line
+func testZoneRecords(t *testing.T, zone string) []string {
+	t.Helper()
+	var rrs []string
+	parser := dns.NewZoneParser(strings.NewReader(zone), "", "")
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		rrs = append(rrs, rr.String())
+	}
+	if err := parser.Err(); err != nil {
+		t.Fatalf("failed to parse exported zone: %v\n%s", err, zone)
+	}
+	sort.Strings(rrs)
+	return rrs
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 42, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	for _, write := range []func() error{
+		func() error { return db.Set.NS("example.com", "ns1.example.com.") },
+		func() error { return db.Set.A("ns1.example.com", "192.0.2.53") },
+		func() error { return db.Set.A("www.example.com", "192.0.2.1", "192.0.2.2") },
+		func() error { return db.Set.AAAA("www.example.com", "2001:db8::1") },
+		func() error { return db.Set.MX("example.com", 10, "mail.example.com.") },
+		func() error { return db.Set.TXT("example.com", []string{"v=spf1 -all", "second string"}) },
+		func() error { return db.Set.CAA("example.com", "issue", "ca.example.net") },
+		func() error { return db.Set.SRV("_sip._tcp.example.com", 10, 5, 5060, "sip.example.com.") },
+		func() error { return db.Set.CNAME("alias.example.com", "www.example.com.") },
+	} {
+		if err := write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpdateMetadata("www.example.com", "AAAA", func(m *db.Metadata) { m.TTL = 60 }, database); err != nil {
+		t.Fatal(err)
+	}
+	exported := testExport(t, database, token, "zone=example.com").Body.String()
+
+	// Import into a fresh database and export it again
+	fresh, freshToken := testDatabase(t, "admin", "example.com")
+	r := httptest.NewRequest("POST", "/api/records/import?atomic=true", strings.NewReader(exported))
+	r.Header.Set("Content-Type", "text/dns")
+	r.Header.Set("Authorization", freshToken)
+	w := httptest.NewRecorder()
+	api.ImportRecordsHandler(fresh)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("failed to import: %d %s", w.Code, w.Body.String())
+	}
+	reexported := testExport(t, fresh, freshToken, "zone=example.com").Body.String()
+
+	before, after := testZoneRecords(t, exported), testZoneRecords(t, reexported)
+	if strings.Join(before, "\n") != strings.Join(after, "\n") {
+		t.Errorf("expected the same records after a round trip\nexported:\n%s\nreimported:\n%s", strings.Join(before, "\n"), strings.Join(after, "\n"))
+	} else if len(before) != 11 {
+		t.Errorf("expected 11 records in the export, got %d:\n%s", len(before), strings.Join(before, "\n"))
+	}
+}
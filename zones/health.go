@@ -0,0 +1,154 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Points deducted from a zone's health score for each kind of finding
+const (
+	penaltyMissingSOA   = 25
+	penaltyMissingNS    = 25
+	penaltyDNSSEC       = 20
+	penaltyDanglingName = 10
+	penaltyTTL          = 2
+	penaltyValidation   = 5
+)
+
+// Bounds outside of which a served TTL is considered a mistake
+const (
+	minSaneTTL = 30
+	maxSaneTTL = 7 * 24 * 60 * 60
+)
+
+// A problem lowering the health score of a zone
+type healthFinding struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+	Penalty int    `json:"penalty"`
+}
+
+// Handle scoring the health of the served zones
+func health(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Score a single zone if one is given
+	zones := db.Zones()
+	if zone := r.URL.Query().Get("zone"); zone != "" {
+		zone = dns.Fqdn(strings.ToLower(zone))
+		if db.ZoneFor(zone) != zone {
+			util.Responses.Error(w, http.StatusNotFound, "zone '"+zone+"' is not served")
+			return
+		}
+		zones = []string{zone}
+	}
+
+	names := db.Get.Names()
+	index := db.Get.Index()
+
+	results := []map[string]interface{}{}
+	for _, zone := range zones {
+		findings := zoneHealth(zone, names, index)
+		score := 100
+		for _, finding := range findings {
+			score -= finding.Penalty
+		}
+		if score < 0 {
+			score = 0
+		}
+		results = append(results, map[string]interface{}{"zone": zone, "score": score, "findings": findings})
+	}
+
+	util.Responses.SuccessWithData(w, results)
+}
+
+// Collect the findings lowering the health of a zone, building on the
+// problems found when validating it
+func zoneHealth(zone string, names []string, index map[string][]string) []healthFinding {
+	findings := []healthFinding{}
+	apex := strings.TrimSuffix(zone, ".")
+
+	if !hasType(index[apex], "SOA") {
+		findings = append(findings, healthFinding{"soa", "zone apex '" + zone + "' has no SOA record", penaltyMissingSOA})
+	}
+
+	for _, problem := range validateZone(zone, names, index) {
+		if problem == missingApexNS(zone) {
+			findings = append(findings, healthFinding{"apex-ns", problem, penaltyMissingNS})
+		} else {
+			findings = append(findings, healthFinding{"validation", problem, penaltyValidation})
+		}
+	}
+
+	if problem := dnssecProblem(zone); problem != "" {
+		findings = append(findings, healthFinding{"dnssec", problem, penaltyDNSSEC})
+	}
+
+	for _, name := range names {
+		if db.ZoneFor(name) != zone {
+			continue
+		}
+
+		// Aliases to names within the served zones must lead somewhere
+		if hasType(index[name], "CNAME") {
+			if cname := db.Get.CNAME(name + "."); cname != nil {
+				target := dns.Fqdn(strings.ToLower(cname.Target))
+				source := util.WildcardSource(target)
+				if db.ZoneFor(target) != "" && len(index[strings.TrimSuffix(source, ".")]) == 0 {
+					findings = append(findings, healthFinding{"dangling-cname", "CNAME '" + name + ".' points to '" + target + "' which has no records", penaltyDanglingName})
+				}
+			}
+		}
+
+		for _, recordType := range index[name] {
+			ttl := util.RecordTTL(name+".", name+".", dns.StringToType[recordType])
+			if ttl < minSaneTTL {
+				findings = append(findings, healthFinding{"ttl", recordType + " records of '" + name + ".' have a TTL of only " + strconv.FormatUint(uint64(ttl), 10) + " seconds", penaltyTTL})
+			} else if ttl > maxSaneTTL {
+				findings = append(findings, healthFinding{"ttl", recordType + " records of '" + name + ".' have a TTL of more than a week", penaltyTTL})
+			}
+		}
+	}
+
+	return findings
+}
+
+// Check that a DS record at the zone apex matches its DNSKEY
+func dnssecProblem(zone string) string {
+	ds := db.Get.DS(zone)
+	if ds == nil {
+		return ""
+	}
+
+	key := db.Get.DNSKEY(zone)
+	if key == nil {
+		return "zone apex '" + zone + "' has a DS record but no DNSKEY record"
+	}
+
+	rr := &dns.DNSKEY{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}, Flags: key.Flags, Protocol: key.Protocol, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+	expected := rr.ToDS(ds.DigestType)
+	if expected == nil || expected.KeyTag != ds.KeyTag || expected.Algorithm != ds.Algorithm || !strings.EqualFold(expected.Digest, ds.Digest) {
+		return "DS record of '" + zone + "' does not match its DNSKEY record"
+	}
+	return ""
+}
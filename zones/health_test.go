@@ -0,0 +1,82 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"net/http/httptest"
+	"testing"
+)
+
+// A zone's score and the findings lowering it
+type testHealth struct {
+	Zone     string          `json:"zone"`
+	Score    int             `json:"score"`
+	Findings []healthFinding `json:"findings"`
+}
+
+// Score a zone through the API, failing the test on an error
+func testZoneHealth(t *testing.T, database *db.Database, token, zone string) testHealth {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/zones/health?zone="+zone, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	HealthHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []testHealth `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	} else if len(response.Data) != 1 {
+		t.Fatalf("expected a single zone, got %+v", response.Data)
+	}
+	return response.Data[0]
+}
+
+// Store the records of a healthy zone
+func testHealthyZone(t *testing.T, zone string) {
+	t.Helper()
+	if err := db.Set.SOA(zone, "ns1."+zone+".", "hostmaster."+zone+".", 1, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.NS(zone, "ns1."+zone+"."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("ns1."+zone, "192.0.2.53"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.CNAME("www."+zone, "ns1."+zone+"."); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthOfCleanZone(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+	viper.Set("dns.ttl", 300)
+	testHealthyZone(t, "example.com")
+
+	if health := testZoneHealth(t, database, token, "example.com"); health.Score != 100 || len(health.Findings) != 0 {
+		t.Errorf("expected full marks, got %+v", health)
+	}
+}
+
+func TestHealthListsIssues(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+	viper.Set("dns.ttl", 300)
+	testHealthyZone(t, "example.com")
+	if err := db.Set.CNAME("old.example.com", "gone.example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	health := testZoneHealth(t, database, token, "example.com")
+	if health.Score != 100-penaltyDanglingName {
+		t.Errorf("expected a score of %d, got %d", 100-penaltyDanglingName, health.Score)
+	}
+	if len(health.Findings) != 1 || health.Findings[0].Check != "dangling-cname" {
+		t.Errorf("expected the dangling CNAME to be listed, got %+v", health.Findings)
+	}
+}
@@ -0,0 +1,38 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Open a fresh database serving the zones, returning a token for a user with role
+func testDatabase(t *testing.T, role string, zones ...string) (*db.Database, string) {
+	t.Helper()
+	viper.Set("http.disabled", true)
+	viper.Set("http.token-ttl", time.Hour)
+	viper.Set("dns.zones", zones)
+	t.Cleanup(func() { viper.Set("dns.zones", []string{}) })
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "records.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	if err := db.Setup(database); err != nil {
+		t.Fatalf("failed to setup database: %v", err)
+	}
+	db.Get.Db, db.Set.Db, db.Delete.Db = database, database, database
+
+	user := db.NewUser("Test", "test", "", role)
+	if err := user.Encode(database); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := db.NewToken(user, database)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return database, token
+}
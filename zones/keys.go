@@ -0,0 +1,134 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handle listing, adding, rolling over and deleting the signing keys of a zone
+func keys(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "GET" && r.Method != "POST" && r.Method != "PUT" && r.Method != "DELETE" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if (r.Method == "POST" || r.Method == "PUT") && r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if (r.Method == "POST" || r.Method == "PUT") && r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Path[len(path):]))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		keys, err := db.GetSigningKeys(zone, false, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve signing keys: "+err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, keys)
+
+	case "POST":
+		// Validate body by decoding json, checking fields exist, and checking field type
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+			return
+		} else if err, _ := util.ValidateBody(body, []string{"type"}, map[string]map[string]string{
+			"type": {"type": "string", "required": "true", "oneOf": "zsk,ksk"},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		key, err := util.GenerateSigningKey(zone, body["type"].(string) == "ksk")
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to generate signing key: "+err.Error())
+			return
+		}
+
+		// Keys are only used once activated, giving resolvers time to cache them
+		if key, err = db.AddSigningKey(zone, key, database); err != nil {
+			util.Responses.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, key)
+
+	case "PUT":
+		// Validate body by decoding json, checking fields exist, and checking field type
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+			return
+		} else if err, _ := util.ValidateBody(body, []string{"key-tag", "state"}, map[string]map[string]string{
+			"key-tag": {"type": "uint16", "required": "true"},
+			"state":   {"type": "string", "required": "true", "oneOf": db.KeyActive + "," + db.KeyRetired},
+		}); err != "" {
+			util.Responses.Error(w, http.StatusBadRequest, err)
+			return
+		}
+
+		key, err := db.TransitionSigningKey(zone, uint16(body["key-tag"].(float64)), body["state"].(string), database)
+		if err == db.ErrSigningKeyNotFound {
+			util.Responses.Error(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			util.Responses.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		util.Responses.SuccessWithData(w, key)
+
+	case "DELETE":
+		tag, err := strconv.ParseUint(r.URL.Query().Get("key-tag"), 10, 16)
+		if err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "query parameter 'key-tag' must be an integer between 0 and 65535")
+			return
+		}
+
+		if err := db.DeleteSigningKey(zone, uint16(tag), database); err == db.ErrSigningKeyNotFound {
+			util.Responses.Error(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			util.Responses.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		util.Responses.Success(w)
+	}
+}
@@ -0,0 +1,117 @@
+package zones
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Make a request to the signing keys of example.com, decoding the data of the
+// response into v
+func testKeysRequest(t *testing.T, database *db.Database, token, method, query string, body map[string]interface{}, v interface{}) int {
+	t.Helper()
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(method, "/api/zones/keys/example.com"+query, bytes.NewReader(encoded))
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	KeysHandler("/api/zones/keys/", database)(w, r)
+
+	if v != nil && w.Code == 200 {
+		response := struct {
+			Data interface{} `json:"data"`
+		}{Data: v}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code
+}
+
+func TestSigningKeyLifecycle(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+
+	var key db.SigningKey
+	if status := testKeysRequest(t, database, token, "POST", "", map[string]interface{}{"type": "ksk"}, &key); status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	} else if key.State != db.KeyPrePublished || key.Flags != 257 || key.PrivateKey != "" {
+		t.Fatalf("expected a pre-published KSK without its private key, got %+v", key)
+	}
+
+	// Keys can't skip a stage or be deleted while in use
+	if status := testKeysRequest(t, database, token, "PUT", "", map[string]interface{}{"key-tag": key.KeyTag, "state": "retired"}, nil); status != 409 {
+		t.Errorf("expected retiring a pre-published key to be refused, got %d", status)
+	}
+	if status := testKeysRequest(t, database, token, "PUT", "", map[string]interface{}{"key-tag": key.KeyTag, "state": "active"}, &key); status != 200 || key.State != db.KeyActive {
+		t.Fatalf("expected the key to be activated, got %d %+v", status, key)
+	}
+	if status := testKeysRequest(t, database, token, "DELETE", "?key-tag="+strconv.Itoa(int(key.KeyTag)), nil, nil); status != 409 {
+		t.Errorf("expected deleting an active key to be refused, got %d", status)
+	}
+
+	// Its replacement deactivates it, and it stays until retired
+	var next db.SigningKey
+	if status := testKeysRequest(t, database, token, "POST", "", map[string]interface{}{"type": "ksk"}, &next); status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	} else if status := testKeysRequest(t, database, token, "PUT", "", map[string]interface{}{"key-tag": next.KeyTag, "state": "active"}, nil); status != 200 {
+		t.Fatalf("expected the next key to be activated, got %d", status)
+	}
+	var keys []db.SigningKey
+	if status := testKeysRequest(t, database, token, "GET", "", nil, &keys); status != 200 || len(keys) != 2 {
+		t.Fatalf("expected two keys, got %d %+v", status, keys)
+	}
+	for _, k := range keys {
+		if k.KeyTag == key.KeyTag && k.State != db.KeyInactive {
+			t.Fatalf("expected the replaced key to be inactive, got %+v", k)
+		}
+	}
+	if status := testKeysRequest(t, database, token, "DELETE", "?key-tag="+strconv.Itoa(int(key.KeyTag)), nil, nil); status != 409 {
+		t.Errorf("expected deleting an inactive key to be refused, got %d", status)
+	}
+
+	if status := testKeysRequest(t, database, token, "PUT", "", map[string]interface{}{"key-tag": key.KeyTag, "state": "retired"}, nil); status != 200 {
+		t.Fatalf("expected the key to be retired, got %d", status)
+	}
+	if status := testKeysRequest(t, database, token, "DELETE", "?key-tag="+strconv.Itoa(int(key.KeyTag)), nil, nil); status != 200 {
+		t.Fatalf("expected the retired key to be deleted, got %d", status)
+	}
+
+	keys = nil
+	if status := testKeysRequest(t, database, token, "GET", "", nil, &keys); status != 200 || len(keys) != 1 || keys[0].KeyTag != next.KeyTag {
+		t.Errorf("expected only the next key to remain, got %d %+v", status, keys)
+	}
+}
+
+func TestSignedSettingWarnsWithoutActiveKey(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+
+	r := httptest.NewRequest("PUT", "/api/zones/settings/example.com", bytes.NewReader([]byte(`{"signed": true}`)))
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	SettingsHandler("/api/zones/settings/", database)(w, r)
+
+	var response struct {
+		Warnings []string `json:"warnings"`
+	}
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	} else if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	} else if len(response.Warnings) != 1 {
+		t.Errorf("expected a warning about the missing active key, got %v", response.Warnings)
+	}
+
+	if settings, err := db.GetZoneSettings("example.com.", database); err != nil {
+		t.Fatal(err)
+	} else if !settings.Signed {
+		t.Error("expected the zone to be signed")
+	}
+}
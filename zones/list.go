@@ -0,0 +1,86 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+)
+
+// Summary of a zone the server is authoritative for
+type zoneSummary struct {
+	Zone    string  `json:"zone"`
+	Records int     `json:"records"`
+	Serial  *uint32 `json:"serial"`
+	Signed  bool    `json:"signed"`
+}
+
+// Handle the listing of the served zones with a summary of each
+func list(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Count the permitted records within each zone
+	counts := map[string]int{}
+	for name, types := range db.Get.Index() {
+		zone := db.ZoneFor(name)
+		if zone == "" {
+			continue
+		}
+
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if allowed {
+			counts[zone] += len(types)
+		}
+	}
+
+	// Admins see every zone, others only those they have records in
+	zones := []zoneSummary{}
+	for _, zone := range db.Zones() {
+		count, ok := counts[zone]
+		if !ok && user.Role != "admin" {
+			continue
+		}
+
+		summary := zoneSummary{Zone: zone, Records: count}
+		if soa := db.Get.SOA(zone); soa != nil {
+			summary.Serial = &soa.Serial
+		}
+
+		// Zones are signed online through their settings, or offline with
+		// their keys stored as records
+		settings, err := db.GetZoneSettings(zone, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone settings: "+err.Error())
+			return
+		}
+		summary.Signed = settings.Signed || db.Get.DNSKEY(zone) != nil
+		zones = append(zones, summary)
+	}
+
+	util.Responses.SuccessWithData(w, zones)
+}
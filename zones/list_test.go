@@ -0,0 +1,83 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http/httptest"
+	"testing"
+)
+
+// List the served zones through the API
+func testZones(t *testing.T, database *db.Database, token string) []zoneSummary {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/zones", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	AllZonesHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []zoneSummary `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Data
+}
+
+// Fill two zones, signing example.org. online and giving example.com. an SOA
+func testListedZones(t *testing.T, database *db.Database) {
+	t.Helper()
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 7, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	for name, host := range map[string]string{
+		"www.example.com":  "192.0.2.1",
+		"mail.example.com": "192.0.2.2",
+		"www.example.org":  "192.0.2.3",
+	} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Set.TXT("www.example.com", []string{"web"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetZoneSettings("example.org.", db.ZoneSettings{Signed: true}, database); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListZonesSummaries(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com", "example.org")
+	testListedZones(t, database)
+
+	zones := testZones(t, database, token)
+	if len(zones) != 2 || zones[0].Zone != "example.com." || zones[1].Zone != "example.org." {
+		t.Fatalf("expected example.com. and example.org., got %+v", zones)
+	}
+
+	// Every record of a name counts, including the SOA
+	com, org := zones[0], zones[1]
+	if com.Records != 4 || com.Serial == nil || *com.Serial != 7 || com.Signed {
+		t.Errorf("expected 4 records, serial 7, and unsigned for example.com., got %+v", com)
+	}
+	if org.Records != 1 || org.Serial != nil || !org.Signed {
+		t.Errorf("expected 1 record, no serial, and signed for example.org., got %+v", org)
+	}
+}
+
+func TestListZonesScopedToRole(t *testing.T) {
+	database, token := testDatabase(t, "restricted", "example.com", "example.org")
+	if err := db.CreateRole("restricted", "", "", "", []string{"www.example.com"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+	testListedZones(t, database)
+
+	zones := testZones(t, database, token)
+	if len(zones) != 1 || zones[0].Zone != "example.com." || zones[0].Records != 2 {
+		t.Errorf("expected only the 2 permitted records of example.com., got %+v", zones)
+	}
+}
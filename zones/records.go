@@ -0,0 +1,68 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"net/http"
+)
+
+// Handle the listing of records grouped under their zone
+func records(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Group permitted records under their zone
+	grouped := map[string][]map[string]string{}
+	index := db.Get.Index()
+	for _, name := range db.Get.Names() {
+		zone := db.ZoneFor(name)
+		if zone == "" {
+			continue
+		}
+
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if !allowed {
+			continue
+		}
+
+		for _, recordType := range index[name] {
+			grouped[zone] = append(grouped[zone], map[string]string{"name": name, "type": recordType})
+		}
+	}
+
+	// Keep the configured zone order and omit zones without permitted records,
+	// summarizing each with its SOA or null if it has none
+	zones := []map[string]interface{}{}
+	for _, zone := range db.Zones() {
+		if records, ok := grouped[zone]; ok {
+			zones = append(zones, map[string]interface{}{"zone": zone, "soa": db.Get.SOA(zone), "records": records})
+		}
+	}
+
+	util.Responses.SuccessWithData(w, zones)
+}
@@ -0,0 +1,74 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordsGroupedByZone(t *testing.T) {
+	database, token := testDatabase(t, "restricted", "example.com", "example.net", "example.org")
+	if err := db.CreateRole("restricted", "", "", "", []string{"*.example.com", "*.example.net"}, nil, database); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Set.SOA("example.com", "ns1.example.com.", "hostmaster.example.com.", 7, 3600, 600, 86400, 300); err != nil {
+		t.Fatal(err)
+	}
+	for name, host := range map[string]string{
+		"www.example.com":  "192.0.2.1",
+		"mail.example.com": "192.0.2.2",
+		"www.example.net":  "192.0.2.3",
+		"www.example.org":  "192.0.2.4",
+	} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/api/zones/records", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	RecordsHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []struct {
+			Zone    string              `json:"zone"`
+			SOA     *db.SOA             `json:"soa"`
+			Records []map[string]string `json:"records"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	// The zone without permitted records is omitted
+	if len(response.Data) != 2 || response.Data[0].Zone != "example.com." || response.Data[1].Zone != "example.net." {
+		t.Fatalf("expected example.com. and example.net., got %+v", response.Data)
+	}
+
+	com, net := response.Data[0], response.Data[1]
+	if len(com.Records) != 2 {
+		t.Errorf("expected 2 records in example.com., got %v", com.Records)
+	}
+	for _, record := range com.Records {
+		if record["type"] != "A" || (record["name"] != "www.example.com" && record["name"] != "mail.example.com") {
+			t.Errorf("unexpected record in example.com.: %v", record)
+		}
+	}
+	if len(net.Records) != 1 || net.Records[0]["name"] != "www.example.net" {
+		t.Errorf("expected only www.example.net in example.net., got %v", net.Records)
+	}
+
+	// Each zone carries its SOA summary, or null without one
+	if com.SOA == nil || com.SOA.Serial != 7 || com.SOA.Nameserver != "ns1.example.com." || com.SOA.Minimum != 300 {
+		t.Errorf("expected the SOA of example.com., got %+v", com.SOA)
+	}
+	if net.SOA != nil {
+		t.Errorf("expected no SOA for example.net., got %+v", net.SOA)
+	}
+}
@@ -0,0 +1,203 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"github.com/spf13/viper"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handle the retrieval of a zone's settings
+func readSettings(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Path[len(path):]))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	settings, err := db.GetZoneSettings(zone, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone settings: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, settings)
+}
+
+// Handle changes to a zone's settings
+func updateSettings(w http.ResponseWriter, r *http.Request, path string, database *db.Database) {
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "PUT" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if len(r.URL.Path[len(path):]) == 0 {
+		util.Responses.Error(w, http.StatusBadRequest, "zone must be specified in path")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from database
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Check role
+	if user.Role != "admin" {
+		util.Responses.Error(w, http.StatusForbidden, "user must be of role 'admin'")
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(r.URL.Path[len(path):]))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	validationErr, valid := util.ValidateBody(body, []string{"max-ttl"}, map[string]map[string]string{
+		"max-ttl": {"type": "uint32", "required": "false"},
+	})
+	if validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	settings, err := db.GetZoneSettings(zone, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve zone settings: "+err.Error())
+		return
+	}
+
+	// Update values if they exist in the body
+	if util.Exists(body, "maintenance") {
+		maintenance, ok := body["maintenance"].(bool)
+		if !ok {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'maintenance' must be a boolean")
+			return
+		}
+		// Leaving maintenance starts the recovery window
+		if settings.Maintenance && !maintenance {
+			settings.Recovered = time.Now().Unix()
+		}
+		settings.Maintenance = maintenance
+	}
+	if util.Exists(body, "signed") {
+		signed, ok := body["signed"].(bool)
+		if !ok {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'signed' must be a boolean")
+			return
+		}
+		settings.Signed = signed
+	}
+	if util.Exists(body, "update-policy") {
+		// Decode the grants through their structure to catch mistyped fields
+		var policy []db.UpdateGrant
+		encoded, _ := json.Marshal(body["update-policy"])
+		if err := json.Unmarshal(encoded, &policy); err != nil {
+			util.Responses.Error(w, http.StatusBadRequest, "field 'update-policy' must be an array of grants: "+err.Error())
+			return
+		}
+		for i, grant := range policy {
+			if err := grant.Validate(); err != nil {
+				util.Responses.Error(w, http.StatusBadRequest, "field 'update-policy' has an invalid grant "+strconv.Itoa(i)+": "+err.Error())
+				return
+			}
+		}
+		settings.UpdatePolicy = policy
+	}
+	if valid["max-ttl"] {
+		settings.MaxTTL = uint32(body["max-ttl"].(float64))
+	}
+
+	// Warn when records of the zone are currently served above the new maximum
+	var warnings []string
+	if ttl := viper.GetUint32("dns.ttl") + viper.GetUint32("dns.ttl-jitter"); settings.MaxTTL != 0 && ttl > settings.MaxTTL {
+		warnings = append(warnings, "records served with a TTL up to "+strconv.FormatUint(uint64(ttl), 10)+" will be clamped to "+strconv.FormatUint(uint64(settings.MaxTTL), 10))
+	}
+
+	// Warn when answers of a signed zone can't be signed yet
+	if settings.Signed {
+		keys, err := db.GetSigningKeys(zone, false, database)
+		if err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve signing keys: "+err.Error())
+			return
+		}
+		active := false
+		for _, key := range keys {
+			active = active || key.State == db.KeyActive
+		}
+		if !active {
+			warnings = append(warnings, "zone has no active signing key, answers will not be signed until one is activated")
+		}
+	}
+
+	if err := db.SetZoneSettings(zone, settings, database); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write zone settings to database: "+err.Error())
+		return
+	}
+
+	if len(warnings) != 0 {
+		util.Responses.SuccessWithWarnings(w, warnings)
+		return
+	}
+	util.Responses.Success(w)
+}
@@ -0,0 +1,144 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handle setting the TTL of every permitted record of a zone at once, such as
+// ahead of a migration
+func bulkTTL(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type, body exists, and headers
+	if r.Method != "POST" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Body == nil {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be present")
+		return
+	} else if r.Header.Get("Content-Type") != "application/json" {
+		util.Responses.Error(w, http.StatusBadRequest, "body must be of type JSON")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	token, err := db.TokenFromString(r.Header.Get("Authorization"), database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Get user from token
+	user, err := db.UserFromToken(token, database)
+	if err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Validate body by decoding json, checking fields exist, and checking field type
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.Responses.Error(w, http.StatusBadRequest, "failed to decode body: "+err.Error())
+		return
+	}
+	if validationErr, _ := util.ValidateBody(body, []string{"zone", "ttl", "types"}, map[string]map[string]string{
+		"zone":  {"type": "string", "required": "true"},
+		"ttl":   {"type": "uint32", "required": "true"},
+		"types": {"type": "stringarray", "required": "false"},
+	}); validationErr != "" {
+		util.Responses.Error(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	zone := dns.Fqdn(strings.ToLower(body["zone"].(string)))
+	if !util.StringInArray(zone, db.Zones()) {
+		util.Responses.Error(w, http.StatusNotFound, "specified zone is not served")
+		return
+	}
+
+	// Only the selected types are changed, all of them if none are given
+	selected := map[string]bool{}
+	if util.Exists(body, "types") {
+		for _, recordType := range body["types"].([]interface{}) {
+			recordType := strings.ToUpper(recordType.(string))
+			if !util.StringInArray(recordType, db.RecordTypes) {
+				util.Responses.Error(w, http.StatusBadRequest, "record type '"+recordType+"' is not supported")
+				return
+			}
+			selected[recordType] = true
+		}
+	}
+
+	// TTLs above the maximum of the zone are lowered to it, the TTL written is
+	// returned
+	ttl, _ := util.LimitTTL(zone, uint32(body["ttl"].(float64)))
+
+	// Collect the records of the zone the role may change, leaving out locked
+	// ones which cannot be changed by anyone
+	type target struct{ name, recordType string }
+	var targets []target
+	locked := []map[string]string{}
+	index := db.Get.Index()
+	for _, name := range db.Get.Names() {
+		if db.ZoneFor(name) != zone {
+			continue
+		}
+
+		if allowed, err := db.EvaluateRole(user.Role, name, database); err != nil {
+			util.Responses.Error(w, http.StatusInternalServerError, "failed to evaluate the role: "+err.Error())
+			return
+		} else if !allowed {
+			continue
+		}
+
+		for _, recordType := range index[name] {
+			if len(selected) != 0 && !selected[recordType] {
+				continue
+			}
+
+			if metadata, err := db.GetMetadata(name, recordType, database); err != nil {
+				util.Responses.Error(w, http.StatusInternalServerError, "failed to retrieve record metadata: "+err.Error())
+				return
+			} else if metadata.Locked {
+				locked = append(locked, map[string]string{"name": name, "type": recordType})
+				continue
+			}
+			targets = append(targets, target{name, recordType})
+		}
+	}
+
+	// Change every record in one transaction, so either all of them or none
+	// get the new TTL
+	counts := map[string]int{}
+	if err := database.Update(func(tx *bolt.Tx) error {
+		for _, t := range targets {
+			if err := db.UpdateMetadataTx(t.name, t.recordType, func(m *db.Metadata) {
+				m.Modified = time.Now().Unix()
+				m.TTL = ttl
+			}, tx); err != nil {
+				return err
+			}
+			if err := db.WriteAudit(db.NewAuditEntry(user.Username, "update", t.name, t.recordType), tx); err != nil {
+				return err
+			}
+			counts[t.recordType]++
+		}
+		return nil
+	}); err != nil {
+		util.Responses.Error(w, http.StatusInternalServerError, "failed to write record metadata: "+err.Error())
+		return
+	}
+
+	util.Responses.SuccessWithData(w, map[string]interface{}{"zone": zone, "ttl": ttl, "counts": counts, "total": len(targets), "locked": locked})
+}
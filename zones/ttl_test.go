@@ -0,0 +1,167 @@
+package zones
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http/httptest"
+	"testing"
+)
+
+// The summary of a bulk TTL change
+type testTTLResult struct {
+	TTL    uint32         `json:"ttl"`
+	Counts map[string]int `json:"counts"`
+	Total  int            `json:"total"`
+	Locked []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"locked"`
+}
+
+// Set the TTL of a zone through the API, failing the test unless it succeeds
+func testBulkTTL(t *testing.T, database *db.Database, token string, body map[string]interface{}) testTTLResult {
+	t.Helper()
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/zones/ttl", bytes.NewReader(encoded))
+	r.Header.Set("Authorization", token)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	TTLHandler(database)(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data testTTLResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Data
+}
+
+// Get the TTL stored for a record
+func testTTL(t *testing.T, database *db.Database, name, recordType string) uint32 {
+	t.Helper()
+	metadata, err := db.GetMetadata(name, recordType, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.TTL
+}
+
+func TestBulkTTLLowersZone(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com", "example.net")
+
+	for name, host := range map[string]string{
+		"www.example.com":  "192.0.2.1",
+		"mail.example.com": "192.0.2.2",
+		"www.example.net":  "192.0.2.3",
+	} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Set.MX("example.com", 10, "mail.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("example.com", []string{"v=spf1 -all"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := testBulkTTL(t, database, token, map[string]interface{}{"zone": "example.com", "ttl": 60})
+	if result.TTL != 60 || result.Total != 4 || len(result.Counts) != 3 || result.Counts["A"] != 2 || result.Counts["MX"] != 1 || result.Counts["TXT"] != 1 {
+		t.Errorf("expected 2 A, 1 MX and 1 TXT records changed, got %+v", result)
+	}
+
+	for _, record := range [][2]string{{"www.example.com", "A"}, {"mail.example.com", "A"}, {"example.com", "MX"}, {"example.com", "TXT"}} {
+		if ttl := testTTL(t, database, record[0], record[1]); ttl != 60 {
+			t.Errorf("expected %s %s to have TTL 60, got %d", record[0], record[1], ttl)
+		}
+	}
+	if ttl := util.RecordTTL("www.example.com.", "www.example.com.", dns.TypeA); ttl != 60 {
+		t.Errorf("expected www.example.com A to be served with TTL 60, got %d", ttl)
+	}
+	if ttl := testTTL(t, database, "www.example.net", "A"); ttl != 0 {
+		t.Errorf("expected records of other zones to be left unchanged, got TTL %d", ttl)
+	}
+
+	// Selecting types only changes records of those types
+	result = testBulkTTL(t, database, token, map[string]interface{}{"zone": "example.com", "ttl": 30, "types": []string{"mx"}})
+	if result.Total != 1 || result.Counts["MX"] != 1 {
+		t.Errorf("expected only the MX record changed, got %+v", result)
+	}
+	if mx, a := testTTL(t, database, "example.com", "MX"), testTTL(t, database, "www.example.com", "A"); mx != 30 || a != 60 {
+		t.Errorf("expected MX TTL 30 and A TTL 60, got %d and %d", mx, a)
+	}
+}
+
+func TestBulkTTLRespectsRole(t *testing.T) {
+	database, token := testDatabase(t, "restricted", "example.com")
+	if err := db.CreateRole("restricted", "", `^www\.example\.com$`, "", nil, nil, database); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, host := range map[string]string{"www.example.com": "192.0.2.1", "mail.example.com": "192.0.2.2"} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := testBulkTTL(t, database, token, map[string]interface{}{"zone": "example.com", "ttl": 60})
+	if result.Total != 1 || result.Counts["A"] != 1 {
+		t.Errorf("expected only the permitted record changed, got %+v", result)
+	}
+	if www, mail := testTTL(t, database, "www.example.com", "A"), testTTL(t, database, "mail.example.com", "A"); www != 60 || mail != 0 {
+		t.Errorf("expected TTL 60 for www and 0 for mail, got %d and %d", www, mail)
+	}
+}
+
+func TestBulkTTLSkipsLockedRecords(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+
+	for name, host := range map[string]string{"www.example.com": "192.0.2.1", "mail.example.com": "192.0.2.2"} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpdateMetadata("mail.example.com", "A", func(m *db.Metadata) { m.Locked = true }, database); err != nil {
+		t.Fatal(err)
+	}
+
+	result := testBulkTTL(t, database, token, map[string]interface{}{"zone": "example.com", "ttl": 60})
+	if result.Total != 1 || len(result.Locked) != 1 || result.Locked[0].Name != "mail.example.com" || result.Locked[0].Type != "A" {
+		t.Errorf("expected one record changed and mail.example.com reported as locked, got %+v", result)
+	}
+	if www, mail := testTTL(t, database, "www.example.com", "A"), testTTL(t, database, "mail.example.com", "A"); www != 60 || mail != 0 {
+		t.Errorf("expected TTL 60 for www and the locked record left unchanged, got %d and %d", www, mail)
+	}
+}
+
+func TestBulkTTLIsAudited(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com")
+
+	for name, host := range map[string]string{"www.example.com": "192.0.2.1", "mail.example.com": "192.0.2.2"} {
+		if err := db.Set.A(name, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testBulkTTL(t, database, token, map[string]interface{}{"zone": "example.com", "ttl": 60})
+
+	// Each changed record gets its own entry
+	for _, name := range []string{"www.example.com", "mail.example.com"} {
+		entries, err := db.QueryAudit(db.AuditFilter{Resource: name, Type: "A"}, database)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 1 || entries[0].Username != "test" || entries[0].Operation != "update" {
+			t.Errorf("expected an update of %s by test in the audit log, got %+v", name, entries)
+		}
+	}
+}
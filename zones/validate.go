@@ -0,0 +1,132 @@
+package zones
+
+import (
+	"github.com/iznotek/dns/db"
+	"github.com/iznotek/dns/util"
+	"github.com/miekg/dns"
+	"net/http"
+	"strings"
+)
+
+// Handle checking the served zones for common mistakes
+func validate(w http.ResponseWriter, r *http.Request, database *db.Database) {
+	// Set database into operations
+	db.Get.Db = database
+
+	// Validate initial request with type and headers
+	if r.Method != "GET" {
+		util.Responses.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	} else if r.Header.Get("Authorization") == "" {
+		util.Responses.Error(w, http.StatusUnauthorized, "header 'Authorization' is required")
+		return
+	}
+
+	// Verify JWT in headers
+	if _, err := db.TokenFromString(r.Header.Get("Authorization"), database); err != nil {
+		util.Responses.Error(w, http.StatusUnauthorized, "failed to authenticate: "+err.Error())
+		return
+	}
+
+	// Check a single zone if one is given
+	zones := db.Zones()
+	if zone := r.URL.Query().Get("zone"); zone != "" {
+		zone = dns.Fqdn(strings.ToLower(zone))
+		if db.ZoneFor(zone) != zone {
+			util.Responses.Error(w, http.StatusNotFound, "zone '"+zone+"' is not served")
+			return
+		}
+		zones = []string{zone}
+	}
+
+	names := db.Get.Names()
+	index := db.Get.Index()
+
+	results := []map[string]interface{}{}
+	for _, zone := range zones {
+		results = append(results, map[string]interface{}{"zone": zone, "problems": validateZone(zone, names, index)})
+	}
+
+	util.Responses.SuccessWithData(w, results)
+}
+
+// Find problems with the records of a zone
+func validateZone(zone string, names []string, index map[string][]string) []string {
+	problems := []string{}
+
+	// Only names whose closest zone is this one are checked
+	var inZone []string
+	for _, name := range names {
+		if db.ZoneFor(name) == zone {
+			inZone = append(inZone, name)
+		}
+	}
+
+	// Every zone needs nameservers at its apex
+	if !hasType(index[strings.TrimSuffix(zone, ".")], "NS") {
+		problems = append(problems, missingApexNS(zone))
+	}
+
+	// Any other name holding nameservers delegates its subtree
+	var delegations []string
+	for _, name := range inZone {
+		if name+"." != zone && hasType(index[name], "NS") {
+			delegations = append(delegations, name)
+		}
+	}
+
+	for _, cut := range delegations {
+		for _, recordType := range index[cut] {
+			if recordType != "NS" && recordType != "DS" {
+				problems = append(problems, "delegation '"+cut+".' also holds "+recordType+" records")
+			}
+		}
+
+		// Nameservers within the delegation can only be found through glue
+		if ns := db.Get.NS(cut + "."); ns != nil {
+			target := dns.Fqdn(strings.ToLower(ns.Nameserver))
+			targetTypes := index[strings.TrimSuffix(target, ".")]
+			if dns.IsSubDomain(cut+".", target) && !hasType(targetTypes, "A") && !hasType(targetTypes, "AAAA") {
+				problems = append(problems, "delegation '"+cut+".' to '"+target+"' has no glue address records")
+			}
+		}
+	}
+
+	for _, name := range inZone {
+		// Only glue may exist below a delegation
+		for _, cut := range delegations {
+			if name == cut || !dns.IsSubDomain(cut+".", name+".") {
+				continue
+			}
+			for _, recordType := range index[name] {
+				if recordType != "A" && recordType != "AAAA" {
+					problems = append(problems, "name '"+name+".' holds "+recordType+" records below delegation '"+cut+".'")
+				}
+			}
+		}
+
+		// Include the advisories given when records are written
+		for _, recordType := range index[name] {
+			if record := db.Get.Record(name+".", recordType); record != nil {
+				problems = append(problems, util.RecordWarnings(name, record)...)
+			}
+		}
+	}
+
+	return problems
+}
+
+// Describe a zone lacking nameservers at its apex
+func missingApexNS(zone string) string {
+	return "zone apex '" + zone + "' has no NS records"
+}
+
+// Check if a record type is in a list of types
+func hasType(types []string, recordType string) bool {
+	for _, t := range types {
+		if t == recordType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package zones
+
+import (
+	"encoding/json"
+	"github.com/iznotek/dns/db"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Validate the served zones through the API, returning the problems by zone
+func testValidate(t *testing.T, database *db.Database, token, query string) map[string][]string {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/zones/validate"+query, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	ValidateHandler(database)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []struct {
+			Zone     string   `json:"zone"`
+			Problems []string `json:"problems"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	problems := map[string][]string{}
+	for _, result := range response.Data {
+		problems[result.Zone] = result.Problems
+	}
+	return problems
+}
+
+func TestValidateNameservers(t *testing.T) {
+	database, token := testDatabase(t, "admin", "example.com", "example.org")
+
+	// A well formed zone, delegating a subdomain with glue
+	if err := db.Set.NS("example.org", "ns1.example.org."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("ns1.example.org", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.NS("sub.example.org", "ns1.sub.example.org."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.A("ns1.sub.example.org", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A zone without apex nameservers, delegating without glue
+	if err := db.Set.A("www.example.com", "192.0.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.NS("sub.example.com", "ns1.sub.example.com."); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set.TXT("sub.example.com", []string{"hidden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"example.com.": {
+			"zone apex 'example.com.' has no NS records",
+			"delegation 'sub.example.com.' also holds TXT records",
+			"delegation 'sub.example.com.' to 'ns1.sub.example.com.' has no glue address records",
+		},
+		"example.org.": {},
+	}
+	if problems := testValidate(t, database, token, ""); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems %v, got %v", expected, problems)
+	}
+
+	// A single zone can be checked
+	if problems := testValidate(t, database, token, "?zone=example.org"); !reflect.DeepEqual(problems, map[string][]string{"example.org.": {}}) {
+		t.Errorf("expected only example.org. without problems, got %v", problems)
+	}
+}